@@ -7,29 +7,73 @@ import(
 	"time"
 	"context"
 	"database/sql"
+	goerrors "errors"
+	"io"
 	"net/http"
+	"sort"
+	"os/signal"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
 
 	"github.com/go-ozzo/ozzo-dbx"
 	_ "github.com/go-sql-driver/mysql"
 
 	"github.com/go-ozzo/ozzo-routing/v2"
-	"github.com/go-ozzo/ozzo-routing/v2/content"
 	"github.com/go-ozzo/ozzo-routing/v2/cors"
+	"github.com/go-ozzo/ozzo-validation/v4"
 
 	"pkg/log"
+	"pkg/apiversion"
+	"pkg/chain"
+	"pkg/deadline"
+	"pkg/slowlog"
+	"pkg/jsonnaming"
+	"pkg/deprecation"
+	"pkg/coalesce"
+	"pkg/metrics"
+	"pkg/ndjson"
+	"pkg/negotiate"
+	"pkg/module"
+	"pkg/realip"
 	"pkg/accesslog"
+	"pkg/contentlength"
+	"pkg/headerlimit"
+	"pkg/httpclient"
+	"pkg/tracing"
+	"pkg/urllimit"
+	"pkg/webhook"
+	"pkg/urlbuilder"
+	"pkg/ratelimit"
+	"pkg/robots"
+	"pkg/restart"
+	"pkg/sqlguard"
+	"pkg/servertiming"
+	"pkg/throttle"
 	"pkg/dbcontext"
 
 	"local/config"
 	_ "local/album"
-	_ "local/auth"
+	"local/apikey"
+	"local/auth"
 	"local/healthcheck"
 	"local/errors"
 	"local/controller"
+	"local/notification"
+	"local/quota"
+	"local/diagnostics"
+	"local/user"
+	"local/job"
 )
 
 var Version = "1.0.0"
+
+// requiredTables are the database tables the server cannot work without.
+var requiredTables = []string{"loguser"}
 var AppConfig = flag.String("config", "./config/dev.yml", "path to the config file")
+var CheckConfig = flag.Bool("check-config", false, "validate the config file, print the effective values and exit")
+var GenerateKey = flag.Bool("generate-key", false, "print a random JWT signing key and exit")
 
 func main(){
 	// parse command line args.
@@ -42,15 +86,52 @@ func main(){
 	logger := log.New().With(nil, "version", Version)
 	logger.Info("server init...")
 
+	// only generate a signing key if requested.
+	if *GenerateKey {
+		key, err := config.GenerateSigningKey()
+		if err != nil {
+			logger.Errorf("failed to generate a signing key: %s", err)
+			os.Exit(-1)
+		}
+		fmt.Println(key)
+		os.Exit(0)
+	}
+
+	// only validate the config file if requested.
+	if *CheckConfig {
+		os.Exit(checkConfig(*AppConfig, os.Stdout, logger))
+	}
+
 	// load application's configurations.
 	cfg, err := config.Load(*AppConfig, logger)
 	if err != nil {
 		logger.Errorf("failed to load application configuration: %s", err)
+		if hint := configHint(*AppConfig, err); hint != "" {
+			logger.Error(hint)
+		}
 		os.Exit(-1)
 	}
+	// send the logs to syslog if configured. they go to stderr if syslog is unreachable.
+	if cfg.LogSink == "syslog" {
+		logger = log.NewSyslog(log.SyslogOptions{
+			Network:  cfg.SyslogNetwork,
+			Address:  cfg.SyslogAddress,
+			Facility: cfg.SyslogFacility,
+			Tag:      cfg.SyslogTag,
+		}).With(nil, "version", Version)
+	}
 
-	// connect to the database.
-	db, err := dbx.MustOpen("mysql", cfg.DSN)
+	// open the database. the connection is verified once the server is listening.
+	// each connection gets the configured statement timeout.
+	var initStatements []string
+	if cfg.StatementTimeout > 0 {
+		initStatements = append(initStatements, dbcontext.StatementTimeout("mysql", time.Duration(cfg.StatementTimeout)*time.Millisecond))
+	}
+	// the statements run for a request are tagged with its route.
+	if cfg.TagQueries {
+		dbcontext.TagQueries("mysql")
+	}
+	db, err := dbcontext.Open("mysql", cfg.DSN, initStatements...)
 	if err != nil {
 		logger.Errorf("failed to connect database: %s", err)
 		os.Exit(-1)
@@ -58,61 +139,647 @@ func main(){
 	// registe callback funcions.
 	db.QueryLogFunc = logDBQuery(logger)
 	db.ExecLogFunc = logDBExec(logger)
-	// registe to close database's connect.
-	defer func() {
-		if err := db.Close(); err != nil {
-			logger.Error(err)
+
+	// connect to the read replica if configured.
+	var replica *dbx.DB
+	if cfg.ReplicaDSN != "" {
+		if replica, err = dbcontext.Open("mysql", cfg.ReplicaDSN, initStatements...); err != nil {
+			logger.Errorf("failed to connect read replica: %s", err)
+			os.Exit(-1)
 		}
-	}()
+		replica.QueryLogFunc = logDBQuery(logger)
+		replica.ExecLogFunc = logDBExec(logger)
+	}
+
+	// create notification hub, metrics registry and client IP resolver.
+	hub := notification.NewHub()
+	registry := metrics.NewRegistry()
+	resolver, err := realip.New(cfg.TrustedProxies)
+	if err != nil {
+		logger.Errorf("invalid trusted proxies: %s", err)
+		os.Exit(-1)
+	}
+
+	// non-health requests are answered with 503 until the dependencies are ready.
+	readiness := &healthcheck.Readiness{}
+
+	// the JWT signing key can be reloaded from the configuration at runtime.
+	keys := auth.NewKeyStore(cfg.JWTSigningKey, func() (string, error) {
+		c, err := config.Load(*AppConfig, logger)
+		if err != nil {
+			return "", err
+		}
+		return c.JWTSigningKey, nil
+	})
 
 	// create HTTP server.
-	address := fmt.Sprintf(":%v", cfg.ServerPort)
+	port, err := resolveServerPort(cfg.ServerPort, os.LookupEnv, logger)
+	if err != nil {
+		logger.Errorf("invalid server port: %s", err)
+		os.Exit(-1)
+	}
+	address := fmt.Sprintf(":%v", port)
+	// login attempts are reported to the webhook in the background.
+	var loginWebhook *webhook.Notifier
+	if cfg.LoginWebhookURL != "" {
+		retries := cfg.LoginWebhookRetries
+		if retries == 0 {
+			retries = -1
+		}
+		loginWebhook = webhook.New(cfg.LoginWebhookURL, logger, webhook.Options{
+			Timeout: time.Duration(cfg.LoginWebhookTimeout) * time.Millisecond,
+			Retries: retries,
+		})
+	}
+
+	// password reset tokens are sent to the webhook, which delivers them to the users.
+	var resetWebhook *webhook.Notifier
+	if cfg.PasswordResetWebhookURL != "" {
+		resetWebhook = webhook.New(cfg.PasswordResetWebhookURL, logger)
+	}
+
+	// long operations, such as asynchronous user imports, run in the background.
+	jobs := job.NewQueue(cfg.JobWorkers, cfg.JobQueueSize, time.Duration(cfg.JobTTL)*time.Minute, logger)
+
+	// the daily requests of the tenants are counted in memory and written to the database periodically.
+	var quotas *quota.Repository
+	if cfg.TenantDailyQuota > 0 || len(cfg.TenantDailyQuotas) > 0 {
+		quotas = quota.NewRepository(dbcontext.New(db), 0, logger)
+	}
+
+	handler, err := HTTPHandler(logger, dbcontext.NewWithReplica(db, replica).WithMaxRows(cfg.MaxRows), hub, registry, resolver, keys, readiness, loginWebhook, resetWebhook, jobs, quotas, cfg)
+	if err != nil {
+		logger.Errorf("invalid middleware chain: %s", err)
+		os.Exit(-1)
+	}
 	hs := &http.Server{
-		Addr:    address,
-		Handler: HTTPHandler(logger, dbcontext.New(db), cfg),
+		Addr:           address,
+		MaxHeaderBytes: cfg.MaxHeaderBytes,
+		Handler:        handler,
+	}
+
+	// registe components to close on shutdown. they are closed in reverse order:
+	// the notification hub first (releasing pending polls), then the HTTP server, then the database.
+	shutdown := &ShutdownSequence{}
+	shutdown.Register("database", CloserFunc(func(ctx context.Context) error {
+		return db.Close()
+	}))
+	if replica != nil {
+		shutdown.Register("read replica", CloserFunc(func(ctx context.Context) error {
+			return replica.Close()
+		}))
+	}
+	// pending webhook deliveries are waited for once the server no longer accepts logins.
+	// the background jobs may still use the database, and are not started once the server stops.
+	shutdown.Register("background jobs", CloserFunc(jobs.Close))
+	if quotas != nil {
+		shutdown.Register("tenant quotas", CloserFunc(quotas.Close))
+	}
+	if loginWebhook != nil {
+		shutdown.Register("login webhook", CloserFunc(loginWebhook.Close))
+	}
+	if resetWebhook != nil {
+		shutdown.Register("password reset webhook", CloserFunc(resetWebhook.Close))
 	}
+	shutdown.Register("http server", CloserFunc(func(ctx context.Context) error {
+		go logDraining(ctx, registry.Gauge(metrics.ActiveRequests), time.Second, logger)
+		return hs.Shutdown(ctx)
+	}))
+	shutdown.Register("notification hub", hub)
+
+	// listen on the socket handed over by the previous process after a graceful restart, if any.
+	ln, inherited, err := restart.Listen(address)
+	if err != nil {
+		logger.Errorf("cannot listen at %v: %s", address, err)
+		os.Exit(-1)
+	}
+
+	// start HTTP server and registe for shutdown. done receives the exit code once the components are closed.
+	done := make(chan int, 1)
+	failed := make(chan error, 1)
+	go func() {
+		stop := make(chan os.Signal, 1)
+		signals := []os.Signal{os.Interrupt, syscall.SIGTERM}
+		if cfg.GracefulRestart {
+			signals = append(signals, syscall.SIGHUP)
+		}
+		signal.Notify(stop, signals...)
+		var sig os.Signal
+		select {
+		case sig = <-stop:
+		case err := <-failed:
+			// the server cannot serve without its dependencies, so it shuts down like on SIGTERM and exits with an error.
+			logger.Error(err)
+			shutdown.Close(time.Duration(cfg.ShutdownTimeout)*time.Second, logger)
+			done <- -1
+			return
+		}
+		// on SIGHUP a new process takes over the socket, then this one drains its requests like on SIGTERM.
+		for sig == syscall.SIGHUP {
+			logger.Info("received hangup signal, restarting")
+			process, err := restart.Restart(ln, time.Duration(cfg.RestartTimeout)*time.Second)
+			if err == nil {
+				logger.Infof("process %v took over, draining the requests of this one", process.Pid)
+				break
+			}
+			logger.Errorf("graceful restart failed: %s", err)
+			sig = <-stop
+		}
+		// on SIGTERM the load balancers are given time to notice /readyz failing while the requests are still served.
+		if grace := time.Duration(cfg.ShutdownGracePeriod) * time.Second; sig == syscall.SIGTERM && grace > 0 {
+			logger.Infof("received %v signal, reporting not ready for %s before shutting down", sig, grace)
+			drainCtx, cancel := context.WithCancel(context.Background())
+			go func() {
+				// another signal ends the grace period
+				<-stop
+				cancel()
+			}()
+			readiness.Drain(drainCtx, grace)
+			cancel()
+		}
+		timeout := shutdownTimeout(sig, cfg)
+		logger.Infof("received %v signal, shutting down within %s", sig, timeout)
+		// stop reusing connections first, so that clients send their next requests elsewhere
+		// while the components are closed and the in-flight requests finish.
+		disableKeepAlives(hs, logger)
+		shutdown.Close(timeout, logger)
+		done <- 0
+	}()
+	// connect to the dependencies while the server is listening, then start serving requests.
+	go func() {
+		if err := prepareDependencies(db, replica, cfg.TableCheck, cfg.MinIdleConns, logger); err != nil {
+			failed <- err
+			return
+		}
+		readiness.SetReady(true)
+		// the previous process shuts down once this one is ready.
+		restart.Ready()
+		logger.Info("server is ready")
+	}()
 
-	// start HTTP server and registe for shutdown.
-	go routing.GracefulShutdown(hs, 10*time.Second, logger.Infof)
-	logger.Infof("server %v is running at %v", Version, address)
+	logBanner(logger, cfg)
+	if inherited {
+		logger.Infof("server %v is running at %v, taken over from the previous process", Version, address)
+	} else {
+		logger.Infof("server %v is running at %v", Version, address)
+	}
 
-	if err := hs.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	if err := hs.Serve(ln); err != nil && err != http.ErrServerClosed {
 		logger.Error(err)
 		os.Exit(-1)
 	}
+	if code := <-done; code != 0 {
+		os.Exit(code)
+	}
+}
+
+// disableKeepAlives makes the server close each connection once its current response has been sent,
+// so that no new request arrives on an existing connection while the server shuts down.
+func disableKeepAlives(hs *http.Server, logger log.Logger) {
+	hs.SetKeepAlivesEnabled(false)
+	logger.Info("keep-alives disabled, connections are closed after their current response")
+}
+
+// shutdownTimeout returns how long the components may take to close after the given signal.
+// SIGTERM is sent by orchestrators stopping the server, which gets the full timeout to drain requests.
+// SIGINT usually comes from a developer pressing Ctrl-C, who gets a faster shutdown.
+func shutdownTimeout(sig os.Signal, cfg *config.Config) time.Duration {
+	if sig == os.Interrupt {
+		return time.Duration(cfg.InterruptShutdownTimeout) * time.Second
+	}
+	return time.Duration(cfg.ShutdownTimeout) * time.Second
+}
+
+// prepareDependencies connects to the database and the read replica if any, and verifies the required tables exist.
+// minIdleConns connections are opened to each database in advance, so that the first requests find them ready.
+func prepareDependencies(db, replica *dbx.DB, tableCheck string, minIdleConns int, logger log.Logger) error {
+	if err := warmPool(db, minIdleConns); err != nil {
+		return fmt.Errorf("failed to connect database: %v", err)
+	}
+	if replica != nil {
+		if err := warmPool(replica, minIdleConns); err != nil {
+			return fmt.Errorf("failed to connect read replica: %v", err)
+		}
+	}
+	return checkTables(dbcontext.New(db), tableCheck, logger)
+}
+
+// warmPool pings the database, and opens n connections that the pool keeps idle if n is positive.
+func warmPool(db *dbx.DB, n int) error {
+	if n <= 0 {
+		return db.DB().Ping()
+	}
+	db.DB().SetMaxIdleConns(n)
+	return dbcontext.Warm(context.Background(), db.DB(), n)
+}
+
+// logBanner logs which optional features and middleware are enabled, as one structured message
+// with a field per feature, so that operators can verify the effective configuration at a glance.
+func logBanner(logger log.Logger, cfg *config.Config) {
+	enabled := func(on bool) string {
+		if on {
+			return "enabled"
+		}
+		return "disabled"
+	}
+
+	auth := "jwt"
+	if cfg.AuthCookie != "" {
+		auth += ", cookie " + cfg.AuthCookie
+	}
+	if len(cfg.AdminUsers) > 0 {
+		auth += fmt.Sprintf(", %v admin users", len(cfg.AdminUsers))
+	}
+	if cfg.FingerprintCookie != "" {
+		auth += ", fingerprint binding"
+	}
+	if len(cfg.IntrospectionClients) > 0 {
+		auth += fmt.Sprintf(", introspection for %v clients", len(cfg.IntrospectionClients))
+	}
+	var limits []string
+	if cfg.RateLimitAnonymous > 0 {
+		limits = append(limits, fmt.Sprintf("%v/min per IP", cfg.RateLimitAnonymous))
+	}
+	if cfg.RateLimitAuthenticated > 0 {
+		limits = append(limits, fmt.Sprintf("%v/min per user", cfg.RateLimitAuthenticated))
+	}
+	if cfg.RateLimitTenant > 0 || len(cfg.RateLimitTenants) > 0 {
+		limits = append(limits, "per tenant")
+	}
+	if cfg.TenantDailyQuota > 0 || len(cfg.TenantDailyQuotas) > 0 {
+		limits = append(limits, "daily tenant quotas")
+	}
+	if cfg.LoginDelay > 0 {
+		limits = append(limits, fmt.Sprintf("login delay %vms-%vms", cfg.LoginDelay, cfg.LoginMaxDelay))
+	}
+	rateLimiting := "disabled"
+	if len(limits) > 0 {
+		rateLimiting = strings.Join(limits, ", ")
+	}
+	formats := append([]string{"json"}, cfg.ResponseFormats...)
+	jsonNaming := "go field names"
+	if cfg.JSONNaming != "" {
+		jsonNaming = cfg.JSONNaming
+	}
+
+	logger.With(nil,
+		"auth", auth,
+		"cors", "allow all",
+		// the server only listens on plain HTTP; TLS is expected to be terminated by a proxy
+		"tls", "disabled",
+		"metrics", "enabled",
+		"log_sink", cfg.LogSink,
+		"tracing", fmt.Sprintf("traceparent, %v%% of new traces sampled", cfg.TraceSampleRate*100),
+		"rate_limiting", rateLimiting,
+		"read_replica", enabled(cfg.ReplicaDSN != ""),
+		"problem_json", enabled(cfg.ProblemJSON),
+		"json_naming", jsonNaming,
+		"response_formats", strings.Join(formats, ", "),
+		"server_timing", enabled(cfg.ServerTiming || cfg.Debug),
+		"request_coalescing", enabled(cfg.CoalesceRequests),
+		"api_key_cache", enabled(cfg.APIKeyCacheTTL > 0),
+		"query_tagging", enabled(cfg.TagQueries),
+		"sql_guard", enabled(cfg.SQLGuard),
+		"slow_request_log", enabled(cfg.SlowRequestThreshold > 0),
+		"email_login", enabled(cfg.LoginWithEmail),
+		"login_webhook", enabled(cfg.LoginWebhookURL != ""),
+		"password_reset", enabled(cfg.PasswordResetWebhookURL != ""),
+		"background_jobs", fmt.Sprintf("%v workers, %v queued at most", cfg.JobWorkers, cfg.JobQueueSize),
+		"graceful_restart", enabled(cfg.GracefulRestart),
+		"shutdown_grace_period", fmt.Sprintf("%vs", cfg.ShutdownGracePeriod),
+		"debug", enabled(cfg.Debug),
+	).Info("server features")
+}
+
+// resolveServerPort returns the port the server should listen on.
+// The PORT environment variable (set by PaaS platforms) takes precedence over the configured port.
+func resolveServerPort(configPort int, lookupEnv func(string) (string, bool), logger log.Logger) (int, error) {
+	value, ok := lookupEnv("PORT")
+	if !ok || value == "" {
+		logger.Infof("using server port %v from config", configPort)
+		return configPort, nil
+	}
+	port, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("PORT environment variable %q is not a number", value)
+	}
+	if port < 1 || port > 65535 {
+		return 0, fmt.Errorf("PORT environment variable %v is out of range", port)
+	}
+	logger.Infof("using server port %v from PORT environment variable", port)
+	return port, nil
 }
 
-func HTTPHandler(logger log.Logger, db *dbcontext.DB, cfg *config.Config) http.Handler {
+// logDraining logs the number of active requests at the given interval until it reaches zero
+// or the context is done.
+func logDraining(ctx context.Context, active *metrics.Gauge, interval time.Duration, logger log.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n := active.Value()
+		logger.Infof("waiting for %v active requests to finish", n)
+		if n <= 0 {
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			logger.Infof("shutdown timeout reached with %v active requests", active.Value())
+			return
+		}
+	}
+}
+
+// Closer represents a component that needs to be closed when the server shuts down.
+type Closer interface {
+	Close(ctx context.Context) error
+}
+
+// CloserFunc is an adapter to allow the use of ordinary functions as Closer.
+type CloserFunc func(ctx context.Context) error
+
+// Close calls f(ctx).
+func (f CloserFunc) Close(ctx context.Context) error {
+	return f(ctx)
+}
+
+// ShutdownSequence closes the registered components in the reverse order of their registration.
+type ShutdownSequence struct {
+	names   []string
+	closers []Closer
+}
+
+// Register adds a component to be closed on shutdown.
+// Components registered later are closed earlier.
+func (s *ShutdownSequence) Register(name string, closer Closer) {
+	s.names = append(s.names, name)
+	s.closers = append(s.closers, closer)
+}
+
+// Close closes all registered components in reverse registration order within the given timeout.
+// A failure in closing one component is logged and does not stop the others from being closed.
+func (s *ShutdownSequence) Close(timeout time.Duration, logger log.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	logger.Infof("shutting down server with %s timeout", timeout)
+	for i := len(s.closers) - 1; i >= 0; i-- {
+		if err := s.closers[i].Close(ctx); err != nil {
+			logger.Errorf("error while closing %s: %v", s.names[i], err)
+		} else {
+			logger.Infof("%s was closed", s.names[i])
+		}
+	}
+}
+
+// middlewareOrder lists the ordering constraints of the middleware chain built by HTTPHandler.
+var middlewareOrder = []chain.Constraint{
+	// the metrics and the access log record the status of the responses rendered by the error handler.
+	chain.Before("metrics", "errors"),
+	chain.Before("accesslog", "errors"),
+	// slow requests are logged with the request ID of their access log message.
+	chain.Before("accesslog", "slowlog"),
+	// the JSON data writer chosen by the content negotiation is replaced.
+	chain.Before("content", "jsonnaming"),
+	// the JSON data writer is wrapped once chosen, by the JSON naming if enabled.
+	chain.Before("content", "deprecation"),
+	chain.Before("jsonnaming", "deprecation"),
+	// the error handler recovers from panics and renders the errors of the middleware that follow it.
+	chain.Before("errors", "headerlimit"),
+	chain.Before("errors", "urllimit"),
+	chain.Before("errors", "readiness"),
+	chain.Before("errors", "contentlength"),
+	chain.Before("errors", "apiversion"),
+	chain.Before("errors", "robots"),
+	chain.Before("errors", "sqlguard"),
+	chain.Before("errors", "ratelimit"),
+	chain.Before("errors", "coalesce"),
+	chain.Before("errors", "deadline"),
+	// requests are rate limited per user once authenticated.
+	chain.Before("auth", "ratelimit"),
+	chain.Before("auth", "logcontext"),
+}
+
+func HTTPHandler(logger log.Logger, db *dbcontext.DB, hub *notification.Hub, registry *metrics.Registry, resolver *realip.Resolver, keys *auth.KeyStore, readiness *healthcheck.Readiness, loginWebhook, resetWebhook *webhook.Notifier, jobs *job.Queue, quotas *quota.Repository, cfg *config.Config) (http.Handler, error) {
 	router := routing.New()
-	router.Use(
-		accesslog.Handler(logger),
-		errors.Handler(logger),
-		content.TypeNegotiator(content.JSON),
-		cors.Handler(cors.AllowAll),
-	)
+	recorder := errors.NewRecorder(cfg.ErrorHistorySize)
+	// the middleware of all routes, named so that their order can be validated.
+	global := []chain.Middleware{
+		chain.Named("metrics", metrics.Handler(registry)),
+		chain.Named("tracing", tracing.Handler(tracing.Options{Sampler: tracing.NewSampler(cfg.TraceSampleRate, cfg.TraceSampleRates)})),
+		chain.Named("accesslog", accesslog.Handler(logger, accesslog.Options{
+			LatencyBuckets: latencyBuckets(cfg.LatencyBuckets),
+			SampleRate:     cfg.AccessLogSampleRate,
+			ResponseTime:   cfg.ResponseTimeHeader,
+			Format:         cfg.AccessLogFormat,
+			ExcludedPaths:  cfg.AccessLogExclude,
+			ClientIP:       resolver.ClientIP,
+		})),
+		chain.Named("errors", errors.Handler(logger, errors.Options{ProblemJSON: cfg.ProblemJSON, Recorder: recorder})),
+		chain.Named("content", negotiate.Handler(responseFormats(cfg.ResponseFormats)...)),
+		chain.Named("cors", cors.Handler(cors.AllowAll)),
+		chain.Named("headerlimit", headerlimit.Handler(cfg.MaxHeaderCount)),
+		chain.Named("urllimit", urllimit.Handler(cfg.MaxURLLength, cfg.MaxQueryLength)),
+		chain.Named("readiness", readiness.Handler()),
+	}
+	if cfg.RequireContentLength {
+		global = append(global, chain.Named("contentlength", contentlength.Handler(cfg.MaxRequestBytes)))
+	}
+	if cfg.ServerTiming || cfg.Debug {
+		global = append(global, chain.Named("servertiming", servertiming.Handler()))
+	}
+	if cfg.TagQueries {
+		global = append(global, chain.Named("querytags", dbcontext.RouteHandler()))
+	}
+	deprecations := deprecation.Options{}
+	if cfg.JSONNaming != "" {
+		global = append(global, chain.Named("jsonnaming", jsonnaming.Handler(jsonnaming.Strategy(cfg.JSONNaming))))
+		deprecations.Writer = &jsonnaming.DataWriter{Strategy: jsonnaming.Strategy(cfg.JSONNaming)}
+	}
+	global = append(global, chain.Named("deprecation", deprecation.Handler(deprecations)))
+	if cfg.SlowRequestThreshold > 0 {
+		global = append(global, chain.Named("slowlog", slowlog.Handler(logger, time.Duration(cfg.SlowRequestThreshold)*time.Millisecond)))
+	}
+	router.Use(chain.Handlers(global)...)
 
 	// register health check handler.
 	// if we want add more handlers with no groups, pls see ref: internal/healthcheck/api.go
 	healthcheck.RegisterHandlers(router, Version)
+	healthcheck.RegisterReadinessHandlers(router, readiness)
+	healthcheck.RegisterStatusHandlers(router, Version,
+		time.Duration(cfg.HealthCheckTimeout)*time.Second,
+		healthCheckers(db, cfg)...,
+	)
 
 	// create v1 router group
+	// the middleware of the v1 routes, which run after the global ones.
 	rg_v1 := router.Group("/v1")
+	var v1 []chain.Middleware
+	if len(cfg.APIVersions) > 0 {
+		v1 = append(v1, chain.Named("apiversion", apiversion.Handler(cfg.APIVersions...)))
+	}
 
-	/* if you need JWT auth, open this comment
-	authHandler := auth.Handler(cfg.JWTSigningKey)
-	album.RegisterHandlers(rg_v1.Group(""),
-		album.NewService(album.NewRepository(db, logger), logger),
-		authHandler, logger,
-	)
-	auth.RegisterHandlers(rg_v1.Group(""),
-		auth.NewService(cfg.JWTSigningKey, cfg.JWTExpiration, logger),
-		logger,
-	)
-	*/
+	// clients may limit the processing time of their requests, up to the server maximum.
+	// long polls are bounded by the poll timeout instead.
+	if cfg.MaxRequestTimeout > 0 || cfg.RequestTimeout > 0 {
+		v1 = append(v1, chain.Named("deadline", deadline.Handler(time.Duration(cfg.MaxRequestTimeout)*time.Millisecond, deadline.Options{
+			Default:       time.Duration(cfg.RequestTimeout) * time.Millisecond,
+			ExcludedPaths: []string{"/v1/notifications/poll"},
+		})))
+	}
+
+	// robots are kept away from the configured routes, such as the login.
+	if cfg.RobotPattern != "" {
+		v1 = append(v1, chain.Named("robots", robots.Handler(regexp.MustCompile(cfg.RobotPattern), robots.Options{Routes: cfg.RobotRoutes})))
+	}
+	if cfg.SQLGuard {
+		v1 = append(v1, chain.Named("sqlguard", sqlguard.Handler(logger, sqlguard.Options{Routes: cfg.SQLGuardRoutes})))
+	}
+
+	authOptions := auth.HandlerOptions{
+		CookieName: cfg.AuthCookie,
+		Leeway:     time.Duration(cfg.JWTLeeway) * time.Second,
+		KeyStore:   keys,
+		// tokens are bound to the client they were issued to if a fingerprint cookie is configured.
+		FingerprintCookie: cfg.FingerprintCookie,
+	}
+	authHandler := auth.Handler(cfg.JWTSigningKey, authOptions)
+
+	// rate limit v1 requests per user if authenticated, per client IP otherwise,
+	// and per tenant if the request has one, whose daily quota is counted in the database.
+	tenantLimits := map[string]ratelimit.Limit{}
+	for id, limit := range cfg.RateLimitTenants {
+		tenantLimits[id] = ratelimit.Limit(limit)
+	}
+	var quotaCounter ratelimit.QuotaCounter
+	if quotas != nil {
+		quotaCounter = quotas
+	}
+	// the tenant header is only trusted for the tenants it may name.
+	headerTenants := map[string]bool{}
+	for _, id := range cfg.TenantHeaderAllowlist {
+		headerTenants[id] = true
+	}
+	// the user of a valid token is known to the rate limits and the request logger, which run before the handlers
+	// of the routes authenticate the requests.
+	v1 = append(v1, chain.Named("auth", auth.OptionalHandler(cfg.JWTSigningKey, authOptions)))
+	if cfg.RateLimitAnonymous > 0 || cfg.RateLimitAuthenticated > 0 || cfg.RateLimitTenant > 0 || len(tenantLimits) > 0 || quotaCounter != nil {
+		v1 = append(v1,
+			chain.Named("ratelimit", ratelimit.Handler(ratelimit.Options{
+				Anonymous:     ratelimit.Limit(cfg.RateLimitAnonymous),
+				Authenticated: ratelimit.Limit(cfg.RateLimitAuthenticated),
+				UserID: func(req *http.Request) string {
+					user, _ := auth.UserFromContext(req.Context())
+					return user.ID
+				},
+				ClientIP: resolver.ClientIP,
+				TenantID: func(req *http.Request) string {
+					if tenant, ok := auth.TenantFromContext(req.Context()); ok {
+						return tenant.ID
+					}
+					if tenant := req.Header.Get(cfg.TenantHeader); cfg.TenantHeader != "" && headerTenants[tenant] {
+						return tenant
+					}
+					return ""
+				},
+				Tenant:      ratelimit.Limit(cfg.RateLimitTenant),
+				Tenants:     tenantLimits,
+				DailyQuota:  cfg.TenantDailyQuota,
+				DailyQuotas: cfg.TenantDailyQuotas,
+				Quotas:      quotaCounter,
+				Error: func(retryAfter int) error {
+					return errors.TooManyRequests(fmt.Sprintf("Too many requests. Please retry in %v seconds.", retryAfter))
+				},
+			})),
+		)
+	}
+
+	// the v1 handlers log with a request-scoped logger, which knows the user once authenticated.
+	v1 = append(v1, chain.Named("logcontext", log.Handler(logger, log.Options{
+		User: func(ctx context.Context) string {
+			user, _ := auth.UserFromContext(ctx)
+			return user.ID
+		},
+	})))
+
+	// administrative endpoints, only available to the configured admin users.
+	adminHandler := auth.AdminHandler(authHandler, cfg.AdminUsers...)
+	auth.RegisterAdminHandlers(router.Group(""), keys, authHandler, cfg.AdminUsers, logger)
+	errors.RegisterRecorderHandlers(router.Group(""), recorder, adminHandler)
+	config.RegisterHandlers(router.Group(""), cfg, adminHandler)
+	metrics.RegisterHandlers(router.Group(""), registry, adminHandler)
 
 	// my core http msg handler code.
-	contoller.RegisterLoginHandlers(rg_v1.Group(""), logger, db)
+	loginOptions := contoller.LoginOptions{ClientIP: resolver.ClientIP, RedirectHosts: cfg.RedirectHosts, Webhook: loginWebhook, EmailLogin: cfg.LoginWithEmail}
+	if cfg.LoginDelay > 0 {
+		loginOptions.Throttle = throttle.New(time.Duration(cfg.LoginDelay)*time.Millisecond, time.Duration(cfg.LoginMaxDelay)*time.Millisecond)
+	}
+
+	// service accounts may authenticate with an API key instead of a JWT.
+	// the keys are read by every request they authenticate, so they may be cached.
+	var apiKeyCache *dbcontext.QueryCache
+	if cfg.APIKeyCacheTTL > 0 {
+		apiKeyCache = dbcontext.NewQueryCache(time.Duration(cfg.APIKeyCacheTTL)*time.Second, 0)
+	}
+	apiKeys := apikey.NewService(apikey.NewRepository(db, apiKeyCache, logger), logger)
+	keyAuthHandler := apikey.Handler(apiKeys, authHandler)
+
+	// identical GET requests in flight at the same time share one execution.
+	// the requests of different users are told apart by their credentials, and long polls are left alone.
+	if cfg.CoalesceRequests {
+		v1 = append(v1, chain.Named("coalesce", coalesce.Handler(coalesce.Options{
+			Vary:          append([]string{apikey.HeaderName}, coalesce.DefaultVary...),
+			ExcludedPaths: []string{"/v1/notifications/poll"},
+		})))
+	}
 
+	// the external URL has been validated with the configuration.
+	urls, err := urlbuilder.New(cfg.ExternalURL, router)
+	if err != nil {
+		return nil, err
+	}
+
+	// the features served under /v1, each in its own route group.
+	modules := []module.Module{
+		// API keys are managed by their owner, who must log in to do so.
+		apikey.NewModule(apiKeys, authHandler, logger),
+		user.NewModule(
+			user.NewService(user.NewRepository(db, logger), db.Transactional, cfg.PasswordPolicy(), logger),
+			jobs, urls, adminHandler, logger,
+		),
+		// the status of the background jobs, polled by their owner.
+		job.NewModule(jobs, keyAuthHandler, logger),
+		contoller.NewLoginModule(db, cfg.TimeoutFor("login", contoller.DefaultLoginTimeout), loginOptions),
+		// long-polling notifications for the authenticated user.
+		notification.NewModule(hub, time.Duration(cfg.PollTimeout)*time.Second, keyAuthHandler, logger),
+	}
+	/* if you need JWT auth, open this comment
+	modules = append(modules,
+		album.NewModule(album.NewService(album.NewRepository(db, logger), logger), cfg.StrictDelete, urls, authHandler, logger),
+		auth.NewModule(auth.NewService(keys, cfg.JWTExpiration, logger), cfg.AuthCookie, cfg.FingerprintCookie, logger),
+	)
+	*/
+	// users who forgot their password receive a reset token from the webhook.
+	if resetWebhook != nil {
+		modules = append(modules, user.NewResetModule(
+			user.NewResetService(user.NewRepository(db, logger), keys.Key, time.Duration(cfg.PasswordResetTTL)*time.Minute, resetWebhook, cfg.PasswordPolicy(), logger),
+			logger,
+		))
+	}
+	// token introspection for other services, which authenticate with their client credentials.
+	if len(cfg.IntrospectionClients) > 0 {
+		modules = append(modules, auth.NewIntrospectionModule(cfg.JWTSigningKey, authOptions, auth.ClientHandler(cfg.IntrospectionClients), logger))
+	}
+	// diagnostic endpoints only available in debug mode.
+	if cfg.Debug {
+		modules = append(modules, diagnostics.NewModule(resolver))
+	}
+	// fail fast if the middleware are misordered.
+	if err := chain.Validate(append(global, v1...), middlewareOrder...); err != nil {
+		return nil, err
+	}
+	rg_v1.Use(chain.Handlers(v1)...)
+	module.Register(rg_v1, modules...)
 
 	/* test code
 	rg_v1.Get("/test1", func(c *routing.Context) error {
@@ -141,14 +808,110 @@ func HTTPHandler(logger log.Logger, db *dbcontext.DB, cfg *config.Config) http.H
 	*/
 
 
-	return router
+	return router, nil
+}
+
+
+
+// checkConfig loads and validates the config file and prints its effective values with secrets redacted.
+// It returns the exit code of the process: 0 if the config is valid, 1 otherwise.
+func checkConfig(file string, w io.Writer, logger log.Logger) int {
+	cfg, err := config.Load(file, logger)
+	if err != nil {
+		fmt.Fprintf(w, "invalid config file %s: %v\n", file, err)
+		if hint := configHint(file, err); hint != "" {
+			fmt.Fprintln(w, hint)
+		}
+		return 1
+	}
+	fmt.Fprintf(w, "config file %s is valid\n", file)
+	for _, line := range cfg.Summary() {
+		fmt.Fprintf(w, "  %s\n", line)
+	}
+	return 0
+}
+
+// configHint returns guidance on fixing a config file that failed to load, or "" if there is none for the error.
+func configHint(file string, err error) string {
+	switch {
+	case goerrors.Is(err, config.ErrConfigNotFound):
+		return fmt.Sprintf("create %s, for example by copying config/local.yml, or point to an existing file with -config", file)
+	case goerrors.Is(err, config.ErrConfigParse):
+		return fmt.Sprintf("fix the syntax of %s; the format is chosen by the file extension (.yml, .yaml, .json or .toml)", file)
+	}
+	if errs, ok := err.(validation.Errors); ok && errs["jwt_signing_key"] == config.ErrShortSigningKey {
+		return "set jwt_signing_key to a random key, which can be generated with -generate-key"
+	}
+	return ""
 }
 
+// checkTables verifies that the required tables exist in the database.
+// Missing tables are logged if mode is "warn", and reported as an error if mode is "fail".
+// Nothing is checked if mode is empty.
+func checkTables(db *dbcontext.DB, mode string, logger log.Logger) error {
+	if mode == "" {
+		return nil
+	}
+	missing, err := db.MissingTables(context.Background(), requiredTables...)
+	if err != nil {
+		err = fmt.Errorf("failed to check required tables: %v", err)
+	} else if len(missing) > 0 {
+		err = fmt.Errorf("required tables are missing: %s", strings.Join(missing, ", "))
+	}
+	if err != nil && mode != "fail" {
+		logger.Error(err)
+		return nil
+	}
+	return err
+}
+
+// healthCheckers returns the checkers of the dependencies reported by the status endpoint:
+// the pools of the database, "database/primary" and "database/replica" if there is a read replica,
+// and the configured downstream services.
+func healthCheckers(db *dbcontext.DB, cfg *config.Config) []healthcheck.Checker {
+	pools := map[string]healthcheck.Pinger{"primary": db.DB().DB()}
+	if replica := db.Replica(); replica != nil {
+		pools["replica"] = replica.DB()
+	}
+	checkers := healthcheck.NewDBPoolCheckers("database", pools)
+	names := make([]string, 0, len(cfg.Downstreams))
+	for name := range cfg.Downstreams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	client := httpclient.New(0)
+	for _, name := range names {
+		checkers = append(checkers, healthcheck.NewURLChecker(name, cfg.Downstreams[name], client))
+	}
+	return checkers
+}
 
+// latencyBuckets converts the configured latency bucket boundaries from milliseconds to durations.
+func latencyBuckets(milliseconds []int) []time.Duration {
+	buckets := make([]time.Duration, len(milliseconds))
+	for i, ms := range milliseconds {
+		buckets[i] = time.Duration(ms) * time.Millisecond
+	}
+	return buckets
+}
+
+// responseFormats returns the response formats offered besides JSON for the configured format names,
+// and the formats the handlers write themselves: NDJSON streams and file downloads.
+func responseFormats(names []string) []negotiate.Format {
+	formats := map[string]negotiate.Format{"xml": negotiate.XML}
+	result := []negotiate.Format{negotiate.Passthrough(ndjson.ContentType), negotiate.Passthrough("application/octet-stream")}
+	for _, name := range names {
+		result = append(result, formats[name])
+	}
+	return result
+}
 
 // logDBQuery returns a logging function that can be used to log SQL queries.
 func logDBQuery(logger log.Logger) dbx.QueryLogFunc {
 	return func(ctx context.Context, t time.Duration, sql string, rows *sql.Rows, err error) {
+		servertiming.Record(ctx, servertiming.DB, t)
+		slowlog.Record(ctx, t)
+		sql = dbcontext.TagSQL(ctx, sql)
 		if err == nil {
 			logger.With(ctx, "duration", t.Milliseconds(), "sql", sql).Info("DB query successful")
 		} else {
@@ -160,6 +923,9 @@ func logDBQuery(logger log.Logger) dbx.QueryLogFunc {
 // logDBExec returns a logging function that can be used to log SQL executions.
 func logDBExec(logger log.Logger) dbx.ExecLogFunc {
 	return func(ctx context.Context, t time.Duration, sql string, result sql.Result, err error) {
+		servertiming.Record(ctx, servertiming.DB, t)
+		slowlog.Record(ctx, t)
+		sql = dbcontext.TagSQL(ctx, sql)
 		if err == nil {
 			logger.With(ctx, "duration", t.Milliseconds(), "sql", sql).Info("DB execution successful")
 		} else {
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"local/auth"
+	"local/config"
+	"local/healthcheck"
+	"local/job"
+	"local/notification"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"pkg/dbcontext"
+	"pkg/dbtest"
+	"pkg/log"
+	"pkg/metrics"
+	"pkg/realip"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestShutdownSequence_Close(t *testing.T) {
+	logger, entries := log.NewForTest()
+	var closed []string
+	closer := func(name string, err error) Closer {
+		return CloserFunc(func(ctx context.Context) error {
+			_, ok := ctx.Deadline()
+			assert.True(t, ok)
+			closed = append(closed, name)
+			return err
+		})
+	}
+
+	s := &ShutdownSequence{}
+	s.Register("database", closer("database", nil))
+	s.Register("http server", closer("http server", errors.New("failed")))
+	s.Register("notification hub", closer("notification hub", nil))
+	s.Close(time.Second, logger)
+
+	// components are closed in reverse order, even if one of them fails
+	assert.Equal(t, []string{"notification hub", "http server", "database"}, closed)
+	assert.Equal(t, 1, entries.FilterMessage("error while closing http server: failed").Len())
+}
+
+func Test_disableKeepAlives(t *testing.T) {
+	logger, _ := log.NewForTest()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	res, err := http.Get(server.URL)
+	if assert.Nil(t, err) {
+		res.Body.Close()
+		assert.False(t, res.Close)
+	}
+
+	// once shutdown begins, the server asks clients to close their connections
+	disableKeepAlives(server.Config, logger)
+	res, err = http.Get(server.URL)
+	if assert.Nil(t, err) {
+		res.Body.Close()
+		assert.True(t, res.Close)
+	}
+}
+
+func Test_resolveServerPort(t *testing.T) {
+	env := func(values map[string]string) func(string) (string, bool) {
+		return func(name string) (string, bool) {
+			value, ok := values[name]
+			return value, ok
+		}
+	}
+	logger, entries := log.NewForTest()
+
+	// env absent: falls back to config
+	port, err := resolveServerPort(8080, env(nil), logger)
+	assert.Nil(t, err)
+	assert.Equal(t, 8080, port)
+	assert.Equal(t, 1, entries.FilterMessage("using server port 8080 from config").Len())
+
+	// env present: wins
+	port, err = resolveServerPort(8080, env(map[string]string{"PORT": "9090"}), logger)
+	assert.Nil(t, err)
+	assert.Equal(t, 9090, port)
+	assert.Equal(t, 1, entries.FilterMessage("using server port 9090 from PORT environment variable").Len())
+
+	// invalid values
+	_, err = resolveServerPort(8080, env(map[string]string{"PORT": "abc"}), logger)
+	assert.NotNil(t, err)
+	_, err = resolveServerPort(8080, env(map[string]string{"PORT": "70000"}), logger)
+	assert.NotNil(t, err)
+	_, err = resolveServerPort(8080, env(map[string]string{"PORT": "0"}), logger)
+	assert.NotNil(t, err)
+}
+
+func Test_shutdownTimeout(t *testing.T) {
+	cfg := &config.Config{ShutdownTimeout: 30, InterruptShutdownTimeout: 2}
+	assert.Equal(t, 30*time.Second, shutdownTimeout(syscall.SIGTERM, cfg))
+	assert.Equal(t, 2*time.Second, shutdownTimeout(os.Interrupt, cfg))
+	assert.Equal(t, 2*time.Second, shutdownTimeout(syscall.SIGINT, cfg))
+}
+
+func Test_logDraining(t *testing.T) {
+	logger, entries := log.NewForTest()
+	active := &metrics.Gauge{}
+	active.Inc()
+	active.Inc()
+	go func() {
+		time.Sleep(15 * time.Millisecond)
+		active.Dec()
+		time.Sleep(15 * time.Millisecond)
+		active.Dec()
+	}()
+	logDraining(context.Background(), active, 10*time.Millisecond, logger)
+	assert.True(t, entries.FilterMessage("waiting for 2 active requests to finish").Len() > 0)
+	assert.True(t, entries.FilterMessage("waiting for 1 active requests to finish").Len() > 0)
+	assert.Equal(t, 1, entries.FilterMessage("waiting for 0 active requests to finish").Len())
+
+	// timeout
+	logger, entries = log.NewForTest()
+	active.Inc()
+	ctx, cancel := context.WithTimeout(context.Background(), 25*time.Millisecond)
+	defer cancel()
+	logDraining(ctx, active, 10*time.Millisecond, logger)
+	assert.Equal(t, 1, entries.FilterMessage("shutdown timeout reached with 1 active requests").Len())
+}
+
+func Test_checkConfig(t *testing.T) {
+	logger, _ := log.NewForTest()
+	dir, err := ioutil.TempDir("", "config")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	valid := filepath.Join(dir, "valid.yml")
+	_ = ioutil.WriteFile(valid, []byte("dsn: \"user:pass@tcp(db)/app\"\njwt_signing_key: \"secret-key-0123456789abcdefghijkl\"\n"), 0644)
+	var out bytes.Buffer
+	assert.Equal(t, 0, checkConfig(valid, &out, logger))
+	assert.Contains(t, out.String(), "is valid")
+	assert.Contains(t, out.String(), "server_port: 8080")
+	assert.Contains(t, out.String(), "dsn: user:***@tcp(db)/app")
+	assert.NotContains(t, out.String(), "secret-key")
+
+	invalid := filepath.Join(dir, "invalid.yml")
+	_ = ioutil.WriteFile(invalid, []byte("server_port: 8080\n"), 0644)
+	out.Reset()
+	assert.Equal(t, 1, checkConfig(invalid, &out, logger))
+	assert.Contains(t, out.String(), "invalid config file")
+
+	malformed := filepath.Join(dir, "malformed.json")
+	_ = ioutil.WriteFile(malformed, []byte("{\"server_port\": "), 0644)
+	out.Reset()
+	assert.Equal(t, 1, checkConfig(malformed, &out, logger))
+	assert.Contains(t, out.String(), "fix the syntax of "+malformed)
+
+	out.Reset()
+	assert.Equal(t, 1, checkConfig(filepath.Join(dir, "missing.yml"), &out, logger))
+	assert.Contains(t, out.String(), "create "+filepath.Join(dir, "missing.yml"))
+}
+
+func Test_configHint(t *testing.T) {
+	assert.Contains(t, configHint("app.yml", fmt.Errorf("%w: app.yml", config.ErrConfigNotFound)), "-config")
+	assert.Contains(t, configHint("app.yml", fmt.Errorf("%w: app.yml", config.ErrConfigParse)), "syntax")
+	assert.Contains(t, configHint("app.yml", config.Config{DSN: "dsn", JWTSigningKey: "key"}.Validate()), "-generate-key")
+	assert.Equal(t, "", configHint("app.yml", errors.New("validation failed")))
+}
+
+func Test_checkTables(t *testing.T) {
+	tables := func(names ...string) *dbcontext.DB {
+		db, _ := dbtest.Open("mysql", func(query string, args []driver.Value) (*dbtest.Result, error) {
+			result := &dbtest.Result{Columns: []string{"table_name"}}
+			for _, name := range names {
+				result.Rows = append(result.Rows, []driver.Value{name})
+			}
+			return result, nil
+		})
+		return dbcontext.New(db)
+	}
+
+	logger, entries := log.NewForTest()
+	assert.Nil(t, checkTables(tables("loguser"), "fail", logger))
+	assert.Nil(t, checkTables(tables(), "", logger))
+	assert.Zero(t, entries.Len())
+
+	err := checkTables(tables(), "fail", logger)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "required tables are missing: loguser", err.Error())
+	}
+	assert.Nil(t, checkTables(tables(), "warn", logger))
+	assert.Equal(t, 1, entries.FilterMessage("required tables are missing: loguser").Len())
+}
+
+func Test_prepareDependencies(t *testing.T) {
+	logger, _ := log.NewForTest()
+	db, server := dbtest.Open("mysql", nil)
+	replica, replicaServer := dbtest.Open("mysql", nil)
+	assert.Nil(t, prepareDependencies(db, replica, "", 3, logger))
+	assert.Equal(t, 3, server.Connections())
+	assert.Equal(t, 3, replicaServer.Connections())
+
+	db, server = dbtest.Open("mysql", nil)
+	assert.Nil(t, prepareDependencies(db, nil, "", 0, logger))
+	assert.Equal(t, 1, server.Connections())
+
+	replica, replicaServer = dbtest.Open("mysql", nil)
+	replicaServer.PingError = errors.New("connection refused")
+	err := prepareDependencies(db, replica, "", 2, logger)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "failed to connect read replica: connection refused", err.Error())
+	}
+}
+
+func Test_healthCheckers(t *testing.T) {
+	db, _ := dbtest.Open("mysql", nil)
+	replica, _ := dbtest.Open("mysql", nil)
+	cfg := &config.Config{Downstreams: map[string]string{"billing": "http://billing/healthz"}}
+	names := func(checkers []healthcheck.Checker) []string {
+		var names []string
+		for _, checker := range checkers {
+			names = append(names, checker.Name())
+		}
+		return names
+	}
+	assert.Equal(t, []string{"database/primary", "database/replica", "billing"}, names(healthCheckers(dbcontext.NewWithReplica(db, replica), cfg)))
+	assert.Equal(t, []string{"database/primary", "billing"}, names(healthCheckers(dbcontext.New(db), cfg)))
+}
+
+func Test_logBanner(t *testing.T) {
+	logger, entries := log.NewForTest()
+	logBanner(logger, &config.Config{AuthCookie: "token", AdminUsers: []string{"100"}, ProblemJSON: true})
+	logBanner(logger, &config.Config{RateLimitAuthenticated: 600, LoginDelay: 100, LoginMaxDelay: 5000, ReplicaDSN: "replica", Debug: true})
+
+	logs := entries.FilterMessage("server features").All()
+	if assert.Equal(t, 2, len(logs)) {
+		fields := logs[0].ContextMap()
+		assert.Equal(t, "jwt, cookie token, 1 admin users", fields["auth"])
+		assert.Equal(t, "allow all", fields["cors"])
+		assert.Equal(t, "disabled", fields["tls"])
+		assert.Equal(t, "enabled", fields["metrics"])
+		assert.Equal(t, "disabled", fields["rate_limiting"])
+		assert.Equal(t, "disabled", fields["read_replica"])
+		assert.Equal(t, "enabled", fields["problem_json"])
+		assert.Equal(t, "disabled", fields["server_timing"])
+
+		fields = logs[1].ContextMap()
+		assert.Equal(t, "jwt", fields["auth"])
+		assert.Equal(t, "600/min per user, login delay 100ms-5000ms", fields["rate_limiting"])
+		assert.Equal(t, "enabled", fields["read_replica"])
+		assert.Equal(t, "disabled", fields["problem_json"])
+		assert.Equal(t, "enabled", fields["server_timing"])
+		assert.Equal(t, "enabled", fields["debug"])
+	}
+}
+
+func TestHTTPHandler_accept(t *testing.T) {
+	logger, _ := log.NewForTest()
+	db, _ := dbtest.Open("mysql", nil)
+	resolver, _ := realip.New(nil)
+	jobs := job.NewQueue(1, 1, 0, logger)
+	defer jobs.Close(context.Background())
+	cfg := &config.Config{JWTSigningKey: "test", MaxRequestTimeout: 1000}
+	handler, err := HTTPHandler(logger, dbcontext.New(db), notification.NewHub(), metrics.NewRegistry(), resolver,
+		auth.NewKeyStore(cfg.JWTSigningKey, nil), &healthcheck.Readiness{}, nil, nil, jobs, nil, cfg)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	// the formats written by the handlers themselves are not rejected
+	for accept, status := range map[string]int{
+		"application/json":         http.StatusOK,
+		"application/x-ndjson":     http.StatusOK,
+		"application/octet-stream": http.StatusOK,
+		"text/csv":                 http.StatusNotAcceptable,
+	} {
+		req := httptest.NewRequest("GET", "/healthz", nil)
+		req.Header.Set("Accept", accept)
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		assert.Equal(t, status, res.Code, accept)
+	}
+}
+
+func TestHTTPHandler_logUser(t *testing.T) {
+	logger, entries := log.NewForTest()
+	db, _ := dbtest.Open("mysql", nil)
+	resolver, _ := realip.New(nil)
+	jobs := job.NewQueue(1, 1, 0, logger)
+	defer jobs.Close(context.Background())
+	readiness := &healthcheck.Readiness{}
+	readiness.SetReady(true)
+	cfg := &config.Config{JWTSigningKey: "test", MaxRequestTimeout: 1000}
+	handler, err := HTTPHandler(logger, dbcontext.New(db), notification.NewHub(), metrics.NewRegistry(), resolver,
+		auth.NewKeyStore(cfg.JWTSigningKey, nil), readiness, nil, nil, jobs, nil, cfg)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	// the v1 requests are logged with the user of their token, without rate limits configured
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"id": "100", "name": "Tester", "exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(cfg.JWTSigningKey))
+	req := httptest.NewRequest("POST", "/v1/login", strings.NewReader("{"))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	logged := entries.FilterMessageSnippet("invalid request").All()
+	if assert.Equal(t, 1, len(logged)) {
+		assert.Equal(t, "100", logged[0].ContextMap()["user"])
+	}
+}
@@ -0,0 +1 @@
+ALTER TABLE album DROP COLUMN version;
\ No newline at end of file
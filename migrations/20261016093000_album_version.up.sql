@@ -0,0 +1 @@
+ALTER TABLE album ADD COLUMN version INT NOT NULL DEFAULT 1;
@@ -0,0 +1,10 @@
+CREATE TABLE api_keys
+(
+    id         VARCHAR PRIMARY KEY,
+    user_id    VARCHAR NOT NULL,
+    name       VARCHAR NOT NULL,
+    prefix     VARCHAR NOT NULL UNIQUE,
+    hash       VARCHAR NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+CREATE INDEX api_keys_user_id ON api_keys (user_id);
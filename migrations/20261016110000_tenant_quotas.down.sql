@@ -0,0 +1 @@
+DROP TABLE tenant_quotas;
\ No newline at end of file
@@ -0,0 +1,8 @@
+CREATE TABLE tenant_quotas
+(
+    tenant_id   VARCHAR(64) NOT NULL,
+    day         DATE NOT NULL,
+    instance_id VARCHAR(64) NOT NULL,
+    requests    INT NOT NULL,
+    PRIMARY KEY (tenant_id, day, instance_id)
+);
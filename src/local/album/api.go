@@ -1,19 +1,30 @@
 package album
 
 import (
+	"database/sql"
+	"fmt"
 	"github.com/go-ozzo/ozzo-routing/v2"
 	"local/errors"
-	"pkg/log"
 	"net/http"
+	"pkg/jsonbody"
+	"pkg/log"
+	"pkg/module"
+	"pkg/ndjson"
 	"pkg/pagination"
+	"pkg/urlbuilder"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // RegisterHandlers sets up the routing of the HTTP handlers.
-func RegisterHandlers(r *routing.RouteGroup, service Service, authHandler routing.Handler, logger log.Logger) {
-	res := resource{service, logger}
+// Deleting an album that does not exist succeeds unless strictDelete is true, in which case 404 is returned.
+// urls builds the Location of created albums from the route named "album".
+func RegisterHandlers(r *routing.RouteGroup, service Service, strictDelete bool, urls *urlbuilder.URLBuilder, authHandler routing.Handler, logger log.Logger) {
+	res := resource{service, strictDelete, urls, logger}
 
-	r.Get("/albums/<id>", res.get)
-	r.Get("/albums", res.query)
+	r.Get("/albums/<id>", res.get).Name("album")
+	r.Get("/albums", pagination.Handler(), res.query)
 
 	r.Use(authHandler)
 
@@ -24,8 +35,10 @@ func RegisterHandlers(r *routing.RouteGroup, service Service, authHandler routin
 }
 
 type resource struct {
-	service Service
-	logger  log.Logger
+	service      Service
+	strictDelete bool
+	urls         *urlbuilder.URLBuilder
+	logger       log.Logger
 }
 
 func (r resource) get(c *routing.Context) error {
@@ -34,58 +47,139 @@ func (r resource) get(c *routing.Context) error {
 		return err
 	}
 
+	c.Response.Header().Set("ETag", etag(album))
 	return c.Write(album)
 }
 
+// query lists the albums a page at a time. If the request accepts application/x-ndjson,
+// all albums are streamed instead, one JSON document per line.
+// A page carries a Link header with the URLs of the first, previous, next and last pages as applicable.
+// It also carries a Last-Modified header with the latest update time of its albums, and 304 is returned
+// if it has not changed since the If-Modified-Since header. Note that removing an album from the page
+// does not change this time.
 func (r resource) query(c *routing.Context) error {
 	ctx := c.Request.Context()
+	if ndjson.Accepts(c.Request) {
+		w := ndjson.NewWriter(c.Response)
+		return r.service.Each(ctx, func(album Album) error {
+			return w.Write(album)
+		})
+	}
 	count, err := r.service.Count(ctx)
 	if err != nil {
 		return err
 	}
-	pages := pagination.NewFromRequest(c.Request, count)
+	pages := pagination.FromContext(ctx).Pages(count)
 	albums, err := r.service.Query(ctx, pages.Offset(), pages.Limit())
 	if err != nil {
 		return err
 	}
+	if notModified(c, lastModified(albums)) {
+		return nil
+	}
+	if links := pages.BuildLinkHeader(r.urls.Path(pagination.BaseURL(c.Request.URL)), pagination.DefaultPageSize); links != "" {
+		c.Response.Header().Set("Link", links)
+	}
 	pages.Items = albums
 	return c.Write(pages)
 }
 
+// lastModified returns the latest update time of the given albums, or the zero time if there are none.
+func lastModified(albums []Album) time.Time {
+	var latest time.Time
+	for _, album := range albums {
+		if album.UpdatedAt.After(latest) {
+			latest = album.UpdatedAt.Time
+		}
+	}
+	return latest
+}
+
+// notModified sets the Last-Modified header of the response and reports whether the client's copy,
+// as indicated by the If-Modified-Since header, is still current. If so, the response status is set to 304.
+// Nothing is done if lastModified is the zero time.
+func notModified(c *routing.Context, lastModified time.Time) bool {
+	if lastModified.IsZero() {
+		return false
+	}
+	// HTTP dates have a resolution of one second
+	lastModified = lastModified.UTC().Truncate(time.Second)
+	c.Response.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+	since, err := http.ParseTime(c.Request.Header.Get("If-Modified-Since"))
+	if err != nil || lastModified.After(since) {
+		return false
+	}
+	c.Response.WriteHeader(http.StatusNotModified)
+	return true
+}
+
 func (r resource) create(c *routing.Context) error {
 	var input CreateAlbumRequest
 	if err := c.Read(&input); err != nil {
 		r.logger.With(c.Request.Context()).Info(err)
-		return errors.BadRequest("")
+		return errors.BadRequest(jsonbody.ErrorMessage(err))
 	}
 	album, err := r.service.Create(c.Request.Context(), input)
 	if err != nil {
 		return err
 	}
 
+	if location, err := r.urls.URL("album", "id", album.ID); err == nil {
+		c.Response.Header().Set("Location", location)
+	}
 	return c.WriteWithStatus(album, http.StatusCreated)
 }
 
+// update modifies an album. If the request has an If-Match header, the update only takes place
+// when the header matches the current ETag of the album, and 412 is returned otherwise.
 func (r resource) update(c *routing.Context) error {
 	var input UpdateAlbumRequest
 	if err := c.Read(&input); err != nil {
 		r.logger.With(c.Request.Context()).Info(err)
-		return errors.BadRequest("")
+		return errors.BadRequest(jsonbody.ErrorMessage(err))
+	}
+	if header := c.Request.Header.Get("If-Match"); header != "" && header != "*" {
+		version, err := strconv.Atoi(strings.Trim(header, `"`))
+		if err != nil || version <= 0 {
+			return errors.PreconditionFailed("")
+		}
+		input.Version = version
 	}
 
 	album, err := r.service.Update(c.Request.Context(), c.Param("id"), input)
 	if err != nil {
+		if err == ErrVersionMismatch {
+			return errors.PreconditionFailed("")
+		}
 		return err
 	}
 
+	c.Response.Header().Set("ETag", etag(album))
 	return c.Write(album)
 }
 
+// etag returns the entity tag of an album, which is derived from its version.
+func etag(album Album) string {
+	return fmt.Sprintf(`"%d"`, album.Version)
+}
+
+// delete removes an album. It is idempotent: 204 is returned whether or not the album existed,
+// unless strict delete mode is on.
 func (r resource) delete(c *routing.Context) error {
-	album, err := r.service.Delete(c.Request.Context(), c.Param("id"))
-	if err != nil {
-		return err
+	if _, err := r.service.Delete(c.Request.Context(), c.Param("id")); err != nil {
+		if err != sql.ErrNoRows || r.strictDelete {
+			return err
+		}
 	}
 
-	return c.Write(album)
+	c.Response.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// NewModule returns the album endpoints as a module. Reading albums is public,
+// changing them requires the authentication performed by authHandler.
+func NewModule(service Service, strictDelete bool, urls *urlbuilder.URLBuilder, authHandler routing.Handler, logger log.Logger) module.Module {
+	return module.New("", func(rg *routing.RouteGroup) {
+		RegisterHandlers(rg, service, strictDelete, urls, authHandler, logger)
+	})
 }
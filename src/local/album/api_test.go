@@ -1,9 +1,20 @@
 package album
 
 import (
-	"internal/auth"
-	"internal/entity"
+	"encoding/json"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"local/auth"
+	"local/entity"
+	"local/test"
 	"net/http"
+	"net/http/httptest"
+	"pkg/jsontime"
+	"pkg/log"
+	"pkg/ndjson"
+	"pkg/urlbuilder"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -12,28 +23,191 @@ func TestAPI(t *testing.T) {
 	logger, _ := log.NewForTest()
 	router := test.MockRouter(logger)
 	repo := &mockRepository{items: []entity.Album{
-		{"123", "album123", time.Now(), time.Now()},
+		{"123", "album123", jsontime.Now(), jsontime.Now(), 1},
 	}}
-	RegisterHandlers(router.Group(""), NewService(repo, logger), auth.MockAuthHandler, logger)
+	RegisterHandlers(router.Group(""), NewService(repo, logger), false, newURLBuilder(router), auth.MockAuthHandler, logger)
 	header := auth.MockAuthHeader()
 
 	tests := []test.APITestCase{
 		{"get all", "GET", "/albums", "", nil, http.StatusOK, `*"total_count":1*`},
+		{"get page", "GET", "/albums?page=1&per_page=10", "", nil, http.StatusOK, `*"per_page":10*`},
+		{"get invalid page", "GET", "/albums?page=0", "", nil, http.StatusBadRequest, ""},
 		{"get 123", "GET", "/albums/123", "", nil, http.StatusOK, `*album123*`},
 		{"get unknown", "GET", "/albums/1234", "", nil, http.StatusNotFound, ""},
 		{"create ok", "POST", "/albums", `{"name":"test"}`, header, http.StatusCreated, "*test*"},
 		{"create ok count", "GET", "/albums", "", nil, http.StatusOK, `*"total_count":2*`},
 		{"create auth error", "POST", "/albums", `{"name":"test"}`, nil, http.StatusUnauthorized, ""},
 		{"create input error", "POST", "/albums", `"name":"test"}`, header, http.StatusBadRequest, ""},
+		{"create syntax error", "POST", "/albums", `{"name":}`, header, http.StatusBadRequest, `*invalid JSON at position 9*`},
+		{"create type mismatch", "POST", "/albums", `{"name":1}`, header, http.StatusBadRequest, `*invalid value for field \"name\": expected string but got number*`},
 		{"update ok", "PUT", "/albums/123", `{"name":"albumxyz"}`, header, http.StatusOK, "*albumxyz*"},
+		{"update matching version", "PUT", "/albums/123", `{"name":"albumxyz"}`, ifMatch(header, `"2"`), http.StatusOK, `*"version":3*`},
+		{"update stale version", "PUT", "/albums/123", `{"name":"albumabc"}`, ifMatch(header, `"2"`), http.StatusPreconditionFailed, ""},
+		{"update invalid version", "PUT", "/albums/123", `{"name":"albumabc"}`, ifMatch(header, `abc`), http.StatusPreconditionFailed, ""},
 		{"update verify", "GET", "/albums/123", "", nil, http.StatusOK, `*albumxyz*`},
 		{"update auth error", "PUT", "/albums/123", `{"name":"albumxyz"}`, nil, http.StatusUnauthorized, ""},
 		{"update input error", "PUT", "/albums/123", `"name":"albumxyz"}`, header, http.StatusBadRequest, ""},
-		{"delete ok", "DELETE", "/albums/123", ``, header, http.StatusOK, "*albumxyz*"},
-		{"delete verify", "DELETE", "/albums/123", ``, header, http.StatusNotFound, ""},
+		{"delete ok", "DELETE", "/albums/123", ``, header, http.StatusNoContent, ""},
+		{"delete verify", "GET", "/albums/123", "", nil, http.StatusNotFound, ""},
+		{"delete again", "DELETE", "/albums/123", ``, header, http.StatusNoContent, ""},
 		{"delete auth error", "DELETE", "/albums/123", ``, nil, http.StatusUnauthorized, ""},
 	}
 	for _, tc := range tests {
 		test.Endpoint(t, router, tc)
 	}
 }
+
+// newURLBuilder returns a URL builder for the routes of the given router, served at https://api.example.com.
+func newURLBuilder(router *routing.Router) *urlbuilder.URLBuilder {
+	urls, _ := urlbuilder.New("https://api.example.com", router)
+	return urls
+}
+
+func TestAPI_location(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+	repo := &mockRepository{}
+	RegisterHandlers(router.Group("/v1"), NewService(repo, logger), false, newURLBuilder(router), auth.MockAuthHandler, logger)
+
+	req, _ := http.NewRequest("POST", "/v1/albums", strings.NewReader(`{"name":"test"}`))
+	req.Header = auth.MockAuthHeader()
+	req.Header.Set("Content-Type", "application/json")
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	if assert.Equal(t, http.StatusCreated, res.Code) && assert.Equal(t, 1, len(repo.items)) {
+		assert.Equal(t, "https://api.example.com/v1/albums/"+repo.items[0].ID, res.Header().Get("Location"))
+	}
+}
+
+// ifMatch returns a copy of the header with If-Match set to the given entity tag.
+func ifMatch(header http.Header, etag string) http.Header {
+	h := header.Clone()
+	h.Set("If-Match", etag)
+	return h
+}
+
+func TestAPI_strictDelete(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+	repo := &mockRepository{items: []entity.Album{
+		{"123", "album123", jsontime.Now(), jsontime.Now(), 1},
+	}}
+	RegisterHandlers(router.Group(""), NewService(repo, logger), true, newURLBuilder(router), auth.MockAuthHandler, logger)
+	header := auth.MockAuthHeader()
+
+	tests := []test.APITestCase{
+		{"delete ok", "DELETE", "/albums/123", ``, header, http.StatusNoContent, ""},
+		{"delete unknown", "DELETE", "/albums/123", ``, header, http.StatusNotFound, ""},
+	}
+	for _, tc := range tests {
+		test.Endpoint(t, router, tc)
+	}
+}
+
+// flushRecorder records the response body sent at each flush.
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushed []string
+}
+
+func (r *flushRecorder) Flush() {
+	r.flushed = append(r.flushed, r.Body.String())
+}
+
+func TestAPI_ndjson(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+	repo := &mockRepository{items: []entity.Album{
+		{"1", "album1", jsontime.Now(), jsontime.Now(), 1},
+		{"2", "album2", jsontime.Now(), jsontime.Now(), 1},
+		{"3", "album3", jsontime.Now(), jsontime.Now(), 1},
+	}}
+	RegisterHandlers(router.Group(""), NewService(repo, logger), false, newURLBuilder(router), auth.MockAuthHandler, logger)
+
+	req, _ := http.NewRequest("GET", "/albums", nil)
+	req.Header.Set("Accept", ndjson.ContentType)
+	res := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	router.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, ndjson.ContentType, res.Header().Get("Content-Type"))
+
+	lines := strings.Split(strings.TrimSuffix(res.Body.String(), "\n"), "\n")
+	if assert.Equal(t, 3, len(lines)) {
+		for i, line := range lines {
+			var album Album
+			assert.Nil(t, json.Unmarshal([]byte(line), &album), line)
+			assert.Equal(t, repo.items[i].ID, album.ID)
+		}
+	}
+	// the albums are sent one by one rather than buffered
+	if assert.Equal(t, 3, len(res.flushed)) {
+		for i, body := range res.flushed {
+			assert.Equal(t, i+1, strings.Count(body, "\n"))
+		}
+	}
+}
+
+func TestAPI_lastModified(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+	updated := time.Date(2026, 10, 1, 12, 0, 0, 500, time.UTC)
+	repo := &mockRepository{items: []entity.Album{
+		{"1", "album1", jsontime.New(updated), jsontime.New(updated.Add(-time.Hour)), 1},
+		{"2", "album2", jsontime.New(updated), jsontime.New(updated), 1},
+	}}
+	RegisterHandlers(router.Group(""), NewService(repo, logger), false, newURLBuilder(router), auth.MockAuthHandler, logger)
+	get := func(ifModifiedSince string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/albums", nil)
+		if ifModifiedSince != "" {
+			req.Header.Set("If-Modified-Since", ifModifiedSince)
+		}
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		return res
+	}
+
+	// the first request gets the list and its modification time
+	res := get("")
+	assert.Equal(t, http.StatusOK, res.Code)
+	lastModified := res.Header().Get("Last-Modified")
+	assert.Equal(t, "Thu, 01 Oct 2026 12:00:00 GMT", lastModified)
+
+	// a conditional request gets 304 while nothing has changed
+	res = get(lastModified)
+	assert.Equal(t, http.StatusNotModified, res.Code)
+	assert.Empty(t, res.Body.String())
+
+	// and the list once an album has been updated
+	repo.items[0].UpdatedAt = jsontime.New(updated.Add(time.Minute))
+	res = get(lastModified)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "Thu, 01 Oct 2026 12:01:00 GMT", res.Header().Get("Last-Modified"))
+}
+
+func TestAPI_links(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+	repo := &mockRepository{}
+	for i := 0; i < 25; i++ {
+		repo.items = append(repo.items, entity.Album{ID: strconv.Itoa(i), Name: "album", CreatedAt: jsontime.Now(), UpdatedAt: jsontime.Now(), Version: 1})
+	}
+	RegisterHandlers(router.Group("/v1"), NewService(repo, logger), false, newURLBuilder(router), auth.MockAuthHandler, logger)
+
+	links := func(url string) string {
+		req, _ := http.NewRequest("GET", url, nil)
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusOK, res.Code)
+		return res.Header().Get("Link")
+	}
+	base := "https://api.example.com/v1/albums?"
+	assert.Equal(t, `<`+base+`page=2&per_page=10>; rel="next", <`+base+`page=3&per_page=10>; rel="last"`,
+		links("/v1/albums?per_page=10"))
+	assert.Equal(t, `<`+base+`page=1&per_page=10>; rel="first", <`+base+`page=1&per_page=10>; rel="prev", `+
+		`<`+base+`page=3&per_page=10>; rel="next", <`+base+`page=3&per_page=10>; rel="last"`,
+		links("/v1/albums?page=2&per_page=10"))
+	assert.Equal(t, `<`+base+`page=1&per_page=10>; rel="first", <`+base+`page=2&per_page=10>; rel="prev"`,
+		links("/v1/albums?page=3&per_page=10"))
+	// a single page has no links
+	assert.Equal(t, "", links("/v1/albums"))
+}
@@ -2,6 +2,7 @@ package album
 
 import (
 	"context"
+	dbx "github.com/go-ozzo/ozzo-dbx"
 	"local/entity"
 	"pkg/dbcontext"
 	"pkg/log"
@@ -15,9 +16,13 @@ type Repository interface {
 	Count(ctx context.Context) (int, error)
 	// Query returns the list of albums with the given offset and limit.
 	Query(ctx context.Context, offset, limit int) ([]entity.Album, error)
+	// Each calls f for every album, one at a time, without loading all of them into memory.
+	Each(ctx context.Context, f func(entity.Album) error) error
 	// Create saves a new album in the storage.
 	Create(ctx context.Context, album entity.Album) error
 	// Update updates the album with given ID in the storage.
+	// The update only takes place if the stored version is the one preceding album.Version.
+	// ErrVersionMismatch is returned otherwise.
 	Update(ctx context.Context, album entity.Album) error
 	// Delete removes the album with given ID from the storage.
 	Delete(ctx context.Context, id string) error
@@ -48,8 +53,22 @@ func (r repository) Create(ctx context.Context, album entity.Album) error {
 }
 
 // Update saves the changes to an album in the database.
+// The version condition makes concurrent updates of the same album fail instead of overwriting each other.
 func (r repository) Update(ctx context.Context, album entity.Album) error {
-	return r.db.With(ctx).Model(&album).Update()
+	result, err := r.db.With(ctx).Update("album", dbx.Params{
+		"name":       album.Name,
+		"updated_at": album.UpdatedAt,
+		"version":    album.Version,
+	}, dbx.HashExp{"id": album.ID, "version": album.Version - 1}).Execute()
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrVersionMismatch
+	}
+	return nil
 }
 
 // Delete deletes an album with the specified ID from the database.
@@ -63,19 +82,27 @@ func (r repository) Delete(ctx context.Context, id string) error {
 
 // Count returns the number of the album records in the database.
 func (r repository) Count(ctx context.Context) (int, error) {
-	var count int
-	err := r.db.With(ctx).Select("COUNT(*)").From("album").Row(&count)
-	return count, err
+	return r.db.Count(ctx, r.db.With(ctx).Select().From("album"))
 }
 
 // Query retrieves the album records with the specified offset and limit from the database.
 func (r repository) Query(ctx context.Context, offset, limit int) ([]entity.Album, error) {
 	var albums []entity.Album
-	err := r.db.With(ctx).
+	err := r.db.All(r.db.With(ctx).
 		Select().
 		OrderBy("id").
 		Offset(int64(offset)).
-		Limit(int64(limit)).
-		All(&albums)
+		Limit(int64(limit)), &albums)
 	return albums, err
 }
+
+// Each reads the album records from the database one at a time and calls f for each of them.
+func (r repository) Each(ctx context.Context, f func(entity.Album) error) error {
+	return r.db.Each(ctx, r.db.With(ctx).Select().From("album").OrderBy("id"), func(rows *dbx.Rows) error {
+		var album entity.Album
+		if err := rows.ScanStruct(&album); err != nil {
+			return err
+		}
+		return f(album)
+	})
+}
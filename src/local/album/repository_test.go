@@ -3,12 +3,12 @@ package album
 import (
 	"context"
 	"database/sql"
-	"internal/entity"
-	"internal/test"
+	"local/entity"
+	"local/test"
+	"pkg/jsontime"
 	"pkg/log"
 	"github.com/stretchr/testify/assert"
 	"testing"
-	"time"
 )
 
 func TestRepository(t *testing.T) {
@@ -27,8 +27,9 @@ func TestRepository(t *testing.T) {
 	err = repo.Create(ctx, entity.Album{
 		ID:        "test1",
 		Name:      "album1",
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		CreatedAt: jsontime.Now(),
+		UpdatedAt: jsontime.Now(),
+		Version:   1,
 	})
 	assert.Nil(t, err)
 	count2, _ := repo.Count(ctx)
@@ -45,18 +46,39 @@ func TestRepository(t *testing.T) {
 	err = repo.Update(ctx, entity.Album{
 		ID:        "test1",
 		Name:      "album1 updated",
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		CreatedAt: jsontime.Now(),
+		UpdatedAt: jsontime.Now(),
+		Version:   2,
 	})
 	assert.Nil(t, err)
 	album, _ = repo.Get(ctx, "test1")
 	assert.Equal(t, "album1 updated", album.Name)
+	assert.Equal(t, 2, album.Version)
+
+	// update with a stale version
+	err = repo.Update(ctx, entity.Album{
+		ID:        "test1",
+		Name:      "album1 stale",
+		CreatedAt: jsontime.Now(),
+		UpdatedAt: jsontime.Now(),
+		Version:   2,
+	})
+	assert.Equal(t, ErrVersionMismatch, err)
 
 	// query
 	albums, err := repo.Query(ctx, 0, count2)
 	assert.Nil(t, err)
 	assert.Equal(t, count2, len(albums))
 
+	// each
+	streamed := 0
+	err = repo.Each(ctx, func(album entity.Album) error {
+		streamed++
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, count2, streamed)
+
 	// delete
 	err = repo.Delete(ctx, "test1")
 	assert.Nil(t, err)
@@ -2,10 +2,12 @@ package album
 
 import (
 	"context"
+	"errors"
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"local/entity"
+	"pkg/dbcontext"
+	"pkg/jsontime"
 	"pkg/log"
-	"time"
 )
 
 // Service encapsulates usecase logic for albums.
@@ -13,11 +15,15 @@ type Service interface {
 	Get(ctx context.Context, id string) (Album, error)
 	Query(ctx context.Context, offset, limit int) ([]Album, error)
 	Count(ctx context.Context) (int, error)
+	Each(ctx context.Context, f func(Album) error) error
 	Create(ctx context.Context, input CreateAlbumRequest) (Album, error)
 	Update(ctx context.Context, id string, input UpdateAlbumRequest) (Album, error)
 	Delete(ctx context.Context, id string) (Album, error)
 }
 
+// ErrVersionMismatch is returned when updating an album whose version differs from the expected one.
+var ErrVersionMismatch = errors.New("album version mismatch")
+
 // Album represents the data about an album.
 type Album struct {
 	entity.Album
@@ -38,6 +44,8 @@ func (m CreateAlbumRequest) Validate() error {
 // UpdateAlbumRequest represents an album update request.
 type UpdateAlbumRequest struct {
 	Name string `json:"name"`
+	// Version is the expected current version of the album. The version is not checked if zero.
+	Version int `json:"-"`
 }
 
 // Validate validates the CreateAlbumRequest fields.
@@ -66,26 +74,36 @@ func (s service) Get(ctx context.Context, id string) (Album, error) {
 	return Album{album}, nil
 }
 
+// Each calls f for every album, one at a time.
+func (s service) Each(ctx context.Context, f func(Album) error) error {
+	return s.repo.Each(ctx, func(album entity.Album) error {
+		return f(Album{album})
+	})
+}
+
 // Create creates a new album.
 func (s service) Create(ctx context.Context, req CreateAlbumRequest) (Album, error) {
 	if err := req.Validate(); err != nil {
 		return Album{}, err
 	}
 	id := entity.GenerateID()
-	now := time.Now()
+	now := jsontime.Now()
 	err := s.repo.Create(ctx, entity.Album{
 		ID:        id,
 		Name:      req.Name,
 		CreatedAt: now,
 		UpdatedAt: now,
+		Version:   1,
 	})
 	if err != nil {
 		return Album{}, err
 	}
-	return s.Get(ctx, id)
+	// read the new album from the primary database as it may not have reached the replica yet
+	return s.Get(dbcontext.WithPrimary(ctx), id)
 }
 
 // Update updates the album with the specified ID.
+// ErrVersionMismatch is returned if the request specifies a version other than the current one.
 func (s service) Update(ctx context.Context, id string, req UpdateAlbumRequest) (Album, error) {
 	if err := req.Validate(); err != nil {
 		return Album{}, err
@@ -95,8 +113,12 @@ func (s service) Update(ctx context.Context, id string, req UpdateAlbumRequest)
 	if err != nil {
 		return album, err
 	}
+	if req.Version != 0 && req.Version != album.Version {
+		return album, ErrVersionMismatch
+	}
 	album.Name = req.Name
-	album.UpdatedAt = time.Now()
+	album.UpdatedAt = jsontime.Now()
+	album.Version++
 
 	if err := s.repo.Update(ctx, album.Album); err != nil {
 		return album, err
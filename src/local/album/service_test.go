@@ -4,7 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"internal/entity"
+	"local/entity"
 	"pkg/log"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -90,6 +90,14 @@ func Test_service_CRUD(t *testing.T) {
 	_, err = s.Update(ctx, "none", UpdateAlbumRequest{Name: "test updated"})
 	assert.NotNil(t, err)
 
+	// update with the current version
+	album, err = s.Update(ctx, id, UpdateAlbumRequest{Name: "test updated", Version: album.Version})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, album.Version)
+	// update with a stale version
+	_, err = s.Update(ctx, id, UpdateAlbumRequest{Name: "test stale", Version: 2})
+	assert.Equal(t, ErrVersionMismatch, err)
+
 	// validation error in update
 	_, err = s.Update(ctx, id, UpdateAlbumRequest{Name: ""})
 	assert.NotNil(t, err)
@@ -145,6 +153,15 @@ func (m mockRepository) Query(ctx context.Context, offset, limit int) ([]entity.
 	return m.items, nil
 }
 
+func (m mockRepository) Each(ctx context.Context, f func(entity.Album) error) error {
+	for _, album := range m.items {
+		if err := f(album); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (m *mockRepository) Create(ctx context.Context, album entity.Album) error {
 	if album.Name == "error" {
 		return errCRUD
@@ -159,6 +176,9 @@ func (m *mockRepository) Update(ctx context.Context, album entity.Album) error {
 	}
 	for i, item := range m.items {
 		if item.ID == album.ID {
+			if item.Version != album.Version-1 {
+				return ErrVersionMismatch
+			}
 			m.items[i] = album
 			break
 		}
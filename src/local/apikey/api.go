@@ -0,0 +1,86 @@
+package apikey
+
+import (
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"local/auth"
+	"local/errors"
+	"net/http"
+	"pkg/jsonbody"
+	"pkg/jsonschema"
+	"pkg/log"
+	"pkg/module"
+)
+
+// RegisterHandlers sets up the routing of the HTTP handlers.
+// The keys are managed by their owner, who is authenticated by authHandler.
+func RegisterHandlers(r *routing.RouteGroup, service Service, authHandler routing.Handler, logger log.Logger) {
+	res := resource{service, logger}
+
+	r.Use(authHandler)
+
+	// the following endpoints require a valid JWT
+	r.Get("/api-keys", res.query)
+	r.Post("/api-keys", jsonschema.Handler(createKeySchema), res.create)
+	r.Delete("/api-keys/<id>", res.revoke)
+}
+
+// createKeySchema is the schema of the body of the API key creation requests.
+var createKeySchema = jsonschema.MustCompile(`{
+	"type": "object",
+	"required": ["name"],
+	"additionalProperties": false,
+	"properties": {
+		"name": {"type": "string", "minLength": 1, "maxLength": 128}
+	}
+}`)
+
+type resource struct {
+	service Service
+	logger  log.Logger
+}
+
+// query lists the API keys of the current user. Only their prefixes are returned.
+func (r resource) query(c *routing.Context) error {
+	keys, err := r.service.Query(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		return err
+	}
+	return c.Write(keys)
+}
+
+// create issues a new API key to the current user. The response is the only place where the full key appears.
+func (r resource) create(c *routing.Context) error {
+	var input CreateKeyRequest
+	if err := c.Read(&input); err != nil {
+		r.logger.With(c.Request.Context()).Info(err)
+		return errors.BadRequest(jsonbody.ErrorMessage(err))
+	}
+	key, err := r.service.Create(c.Request.Context(), currentUserID(c), input)
+	if err != nil {
+		return err
+	}
+	return c.WriteWithStatus(key, http.StatusCreated)
+}
+
+// revoke deletes an API key of the current user. Keys of other users are reported as not found.
+func (r resource) revoke(c *routing.Context) error {
+	if err := r.service.Revoke(c.Request.Context(), currentUserID(c), c.Param("id")); err != nil {
+		return err
+	}
+	c.Response.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// currentUserID returns the ID of the authenticated user.
+func currentUserID(c *routing.Context) string {
+	user, _ := auth.UserFromContext(c.Request.Context())
+	return user.ID
+}
+
+// NewModule returns the API key endpoints as a module. All of them require the authentication
+// performed by authHandler.
+func NewModule(service Service, authHandler routing.Handler, logger log.Logger) module.Module {
+	return module.New("", func(rg *routing.RouteGroup) {
+		RegisterHandlers(rg, service, authHandler, logger)
+	})
+}
@@ -0,0 +1,75 @@
+package apikey
+
+import (
+	"context"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"local/auth"
+	"local/entity"
+	"local/test"
+	"net/http"
+	"pkg/jsontime"
+	"pkg/log"
+	"testing"
+)
+
+func TestAPI(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+	repo := &mockRepository{items: []entity.APIKey{
+		{ID: "1", UserID: "100", Name: "ci", Prefix: "abcd1234", Hash: "hash", CreatedAt: jsontime.Now()},
+		{ID: "2", UserID: "200", Name: "other", Prefix: "ef567890", Hash: "hash", CreatedAt: jsontime.Now()},
+	}}
+	RegisterHandlers(router.Group(""), NewService(repo, logger), auth.MockAuthHandler, logger)
+	header := auth.MockAuthHeader()
+
+	tests := []test.APITestCase{
+		{"list", "GET", "/api-keys", "", header, http.StatusOK, `*"prefix":"abcd1234"*`},
+		{"list auth error", "GET", "/api-keys", "", nil, http.StatusUnauthorized, ""},
+		{"create ok", "POST", "/api-keys", `{"name":"deploy"}`, header, http.StatusCreated, `*"key":"*`},
+		{"create input error", "POST", "/api-keys", `{"name":""}`, header, http.StatusBadRequest, ""},
+		{"create schema error", "POST", "/api-keys", `{"name":1,"owner":"100"}`, header, http.StatusBadRequest,
+			`*"details":[{"field":"name","error":"must be a string"},{"field":"owner","error":"is not allowed"}]*`},
+		{"create auth error", "POST", "/api-keys", `{"name":"deploy"}`, nil, http.StatusUnauthorized, ""},
+		{"revoke other user's key", "DELETE", "/api-keys/2", "", header, http.StatusNotFound, ""},
+		{"revoke ok", "DELETE", "/api-keys/1", "", header, http.StatusNoContent, ""},
+		{"revoke again", "DELETE", "/api-keys/1", "", header, http.StatusNotFound, ""},
+	}
+	for _, tc := range tests {
+		test.Endpoint(t, router, tc)
+	}
+	assert.Equal(t, 2, len(repo.items))
+}
+
+func TestHandler(t *testing.T) {
+	logger, _ := log.NewForTest()
+	service := NewService(&mockRepository{}, logger)
+	key, err := service.Create(context.Background(), "300", CreateKeyRequest{Name: "ci"})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	router := test.MockRouter(logger)
+	router.Get("/me", Handler(service, auth.MockAuthHandler), func(c *routing.Context) error {
+		return c.Write(auth.CurrentUser(c.Request.Context()).GetID())
+	})
+	withKey := func(key string) http.Header {
+		header := http.Header{}
+		header.Set(HeaderName, key)
+		return header
+	}
+
+	tests := []test.APITestCase{
+		{"api key", "GET", "/me", "", withKey(key.Key), http.StatusOK, `"300"`},
+		{"invalid api key", "GET", "/me", "", withKey(key.Prefix + ".wrong"), http.StatusUnauthorized, ""},
+		{"jwt fallback", "GET", "/me", "", auth.MockAuthHeader(), http.StatusOK, `"100"`},
+		{"no credentials", "GET", "/me", "", nil, http.StatusUnauthorized, ""},
+	}
+	for _, tc := range tests {
+		test.Endpoint(t, router, tc)
+	}
+
+	// a revoked key is rejected
+	assert.Nil(t, service.Revoke(context.Background(), "300", key.ID))
+	test.Endpoint(t, router, test.APITestCase{"revoked api key", "GET", "/me", "", withKey(key.Key), http.StatusUnauthorized, ""})
+}
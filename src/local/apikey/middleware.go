@@ -0,0 +1,31 @@
+package apikey
+
+import (
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"local/auth"
+	"local/errors"
+)
+
+// HeaderName is the name of the request header carrying an API key.
+const HeaderName = "X-API-Key"
+
+// Handler returns an authentication middleware accepting API keys.
+// A request with an X-API-Key header is authenticated as the owner of the key, and rejected with 401
+// if the key is invalid. Other requests are passed to authHandler, e.g. for JWT authentication.
+func Handler(service Service, authHandler routing.Handler) routing.Handler {
+	return func(c *routing.Context) error {
+		key := c.Request.Header.Get(HeaderName)
+		if key == "" {
+			return authHandler(c)
+		}
+		ctx := c.Request.Context()
+		apiKey, err := service.Authenticate(ctx, key)
+		if err == ErrInvalidKey {
+			return errors.Unauthorized("The API key is invalid or has been revoked.")
+		} else if err != nil {
+			return err
+		}
+		c.Request = c.Request.WithContext(auth.WithUser(ctx, apiKey.UserID, apiKey.Name))
+		return nil
+	}
+}
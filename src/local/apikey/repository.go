@@ -0,0 +1,78 @@
+package apikey
+
+import (
+	"context"
+	dbx "github.com/go-ozzo/ozzo-dbx"
+	"local/entity"
+	"pkg/dbcontext"
+	"pkg/log"
+)
+
+// Repository encapsulates the logic to access API keys from the data source.
+type Repository interface {
+	// Get returns the API key with the specified ID.
+	Get(ctx context.Context, id string) (entity.APIKey, error)
+	// GetByPrefix returns the API key with the specified prefix.
+	GetByPrefix(ctx context.Context, prefix string) (entity.APIKey, error)
+	// Query returns the API keys of the specified user, oldest first.
+	Query(ctx context.Context, userID string) ([]entity.APIKey, error)
+	// Create saves a new API key in the storage.
+	Create(ctx context.Context, key entity.APIKey) error
+	// Delete removes the API key with the specified ID from the storage.
+	Delete(ctx context.Context, id string) error
+}
+
+// repository persists API keys in database
+type repository struct {
+	db     *dbcontext.DB
+	cache  *dbcontext.QueryCache
+	logger log.Logger
+}
+
+// NewRepository creates a new API key repository. The keys read to authenticate requests are cached by cache,
+// which may be nil, and invalidated when keys are created or deleted.
+func NewRepository(db *dbcontext.DB, cache *dbcontext.QueryCache, logger log.Logger) Repository {
+	return repository{db, cache, logger}
+}
+
+// Get reads the API key with the specified ID from the database.
+func (r repository) Get(ctx context.Context, id string) (entity.APIKey, error) {
+	var key entity.APIKey
+	err := r.db.With(ctx).Select().Model(id, &key)
+	return key, err
+}
+
+// GetByPrefix reads the API key with the specified prefix from the cache or the database.
+// The primary database is used so that a revoked key stops working as soon as the cache is invalidated.
+func (r repository) GetByPrefix(ctx context.Context, prefix string) (entity.APIKey, error) {
+	var key entity.APIKey
+	table := key.TableName()
+	err := r.cache.One(table, r.db.With(dbcontext.WithPrimary(ctx)).
+		Select().
+		From(table).
+		Where(dbx.HashExp{"prefix": prefix}), &key)
+	return key, err
+}
+
+// Query reads the API keys of the specified user from the database.
+func (r repository) Query(ctx context.Context, userID string) ([]entity.APIKey, error) {
+	var keys []entity.APIKey
+	err := r.db.All(r.db.With(ctx).
+		Select().
+		Where(dbx.HashExp{"user_id": userID}).
+		OrderBy("created_at"), &keys)
+	return keys, err
+}
+
+// Create saves a new API key record in the database.
+func (r repository) Create(ctx context.Context, key entity.APIKey) error {
+	defer r.cache.Invalidate(key.TableName())
+	return r.db.With(ctx).Model(&key).Insert()
+}
+
+// Delete deletes the API key with the specified ID from the database.
+func (r repository) Delete(ctx context.Context, id string) error {
+	defer r.cache.Invalidate(entity.APIKey{}.TableName())
+	_, err := r.db.With(ctx).Delete(entity.APIKey{}.TableName(), dbx.HashExp{"id": id}).Execute()
+	return err
+}
@@ -0,0 +1,94 @@
+package apikey
+
+import (
+	"context"
+	"database/sql/driver"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"local/entity"
+	"pkg/dbcontext"
+	"pkg/dbtest"
+	"pkg/jsontime"
+	"pkg/log"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRepository(t *testing.T) {
+	logger, _ := log.NewForTest()
+	now := time.Now()
+	db, server := dbtest.Open("mysql", func(query string, args []driver.Value) (*dbtest.Result, error) {
+		return &dbtest.Result{
+			Columns:      []string{"id", "user_id", "name", "prefix", "hash", "created_at"},
+			Rows:         [][]driver.Value{{"1", "100", "ci", "abcd1234", "hash", now}},
+			RowsAffected: 1,
+		}, nil
+	})
+	repo := NewRepository(dbcontext.New(db), nil, logger)
+	ctx := context.Background()
+
+	assert.Nil(t, repo.Create(ctx, entity.APIKey{ID: "1", UserID: "100", Name: "ci", Prefix: "abcd1234", Hash: "hash", CreatedAt: jsontime.New(now)}))
+	key, err := repo.GetByPrefix(ctx, "abcd1234")
+	assert.Nil(t, err)
+	assert.Equal(t, "100", key.UserID)
+	keys, err := repo.Query(ctx, "100")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(keys))
+	assert.Nil(t, repo.Delete(ctx, "1"))
+
+	statements := server.Statements()
+	if assert.Equal(t, 4, len(statements)) {
+		assert.Equal(t, "INSERT INTO `api_keys` (`created_at`, `hash`, `id`, `name`, `prefix`, `user_id`) VALUES (?, ?, ?, ?, ?, ?)", statements[0].SQL)
+		assert.Equal(t, "SELECT * FROM `api_keys` WHERE `prefix`=?", statements[1].SQL)
+		assert.Equal(t, "SELECT * FROM `api_keys` WHERE `user_id`=? ORDER BY `created_at`", statements[2].SQL)
+		assert.Equal(t, "DELETE FROM `api_keys` WHERE `id`=?", statements[3].SQL)
+	}
+}
+
+func TestRepository_cache(t *testing.T) {
+	logger, _ := log.NewForTest()
+	db, server := dbtest.Open("mysql", func(query string, args []driver.Value) (*dbtest.Result, error) {
+		return &dbtest.Result{
+			Columns:      []string{"id", "user_id", "name", "prefix", "hash", "created_at"},
+			Rows:         [][]driver.Value{{"1", "100", "ci", "abcd1234", "hash", time.Now()}},
+			RowsAffected: 1,
+		}, nil
+	})
+	repo := NewRepository(dbcontext.New(db), dbcontext.NewQueryCache(time.Minute, 0), logger)
+	ctx := context.Background()
+
+	// the key is read once, then again after it is deleted
+	for i := 0; i < 2; i++ {
+		key, err := repo.GetByPrefix(ctx, "abcd1234")
+		assert.Nil(t, err)
+		assert.Equal(t, "100", key.UserID)
+	}
+	assert.Equal(t, 1, len(server.Statements()))
+	assert.Nil(t, repo.Delete(ctx, "1"))
+	_, _ = repo.GetByPrefix(ctx, "abcd1234")
+	assert.Equal(t, 3, len(server.Statements()))
+}
+
+func TestRepository_readOnly(t *testing.T) {
+	logger, _ := log.NewForTest()
+	db, _ := dbtest.Open("mysql", func(query string, args []driver.Value) (*dbtest.Result, error) {
+		if !strings.HasPrefix(query, "SELECT") {
+			return nil, &mysql.MySQLError{Number: 1290, Message: "The MySQL server is running with the --read-only option so it cannot execute this statement"}
+		}
+		return &dbtest.Result{
+			Columns: []string{"id", "user_id", "name", "prefix", "hash", "created_at"},
+			Rows:    [][]driver.Value{{"1", "100", "ci", "abcd1234", "hash", time.Now()}},
+		}, nil
+	})
+	repo := NewRepository(dbcontext.New(db), nil, logger)
+	ctx := context.Background()
+
+	// writes are refused while reads continue
+	err := repo.Create(ctx, entity.APIKey{ID: "2", UserID: "100", Name: "ci", Prefix: "ef567890", Hash: "hash", CreatedAt: jsontime.Now()})
+	assert.True(t, dbcontext.IsReadOnly(err))
+	assert.True(t, dbcontext.IsReadOnly(repo.Delete(ctx, "1")))
+	keys, err := repo.Query(ctx, "100")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(keys))
+}
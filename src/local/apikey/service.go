@@ -0,0 +1,173 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"local/entity"
+	"pkg/dbcontext"
+	"pkg/jsontime"
+	"pkg/log"
+	"strings"
+)
+
+// Service encapsulates usecase logic for API keys.
+type Service interface {
+	// Create issues a new API key to the specified user.
+	Create(ctx context.Context, userID string, req CreateKeyRequest) (NewKey, error)
+	// Query returns the API keys of the specified user.
+	Query(ctx context.Context, userID string) ([]APIKey, error)
+	// Revoke deletes an API key of the specified user.
+	Revoke(ctx context.Context, userID, id string) error
+	// Authenticate returns the API key matching the given key.
+	Authenticate(ctx context.Context, key string) (APIKey, error)
+}
+
+// ErrInvalidKey is returned by Authenticate when the key is malformed, unknown or revoked.
+var ErrInvalidKey = errors.New("invalid API key")
+
+const (
+	// prefixBytes and secretBytes are the numbers of random bytes in the prefix and secret parts of a key.
+	prefixBytes = 4
+	secretBytes = 32
+)
+
+// APIKey represents the data about an API key. The key itself is only known when it is created.
+type APIKey struct {
+	entity.APIKey
+}
+
+// NewKey represents a newly created API key, including the key to be handed to the client.
+type NewKey struct {
+	APIKey
+	// Key is the full API key. It cannot be retrieved later.
+	Key string `json:"key"`
+}
+
+// CreateKeyRequest represents an API key creation request.
+type CreateKeyRequest struct {
+	Name string `json:"name"`
+}
+
+// Validate validates the CreateKeyRequest fields.
+func (m CreateKeyRequest) Validate() error {
+	return validation.ValidateStruct(&m,
+		validation.Field(&m.Name, validation.Required, validation.Length(0, 128)),
+	)
+}
+
+type service struct {
+	repo   Repository
+	logger log.Logger
+}
+
+// NewService creates a new API key service.
+func NewService(repo Repository, logger log.Logger) Service {
+	return service{repo, logger}
+}
+
+// Create issues a new API key to the specified user.
+// The key has the form "<prefix>.<secret>": the prefix identifies the key and is shown when listing keys,
+// while only a hash of the whole key is stored.
+func (s service) Create(ctx context.Context, userID string, req CreateKeyRequest) (NewKey, error) {
+	if err := req.Validate(); err != nil {
+		return NewKey{}, err
+	}
+	prefix, err := randomHex(prefixBytes)
+	if err != nil {
+		return NewKey{}, err
+	}
+	secret, err := randomHex(secretBytes)
+	if err != nil {
+		return NewKey{}, err
+	}
+	key := prefix + "." + secret
+	id := entity.GenerateID()
+	err = s.repo.Create(ctx, entity.APIKey{
+		ID:        id,
+		UserID:    userID,
+		Name:      req.Name,
+		Prefix:    prefix,
+		Hash:      hash(key),
+		CreatedAt: jsontime.Now(),
+	})
+	if err != nil {
+		return NewKey{}, err
+	}
+	// read the new key from the primary database as it may not have reached the replica yet
+	created, err := s.repo.Get(dbcontext.WithPrimary(ctx), id)
+	if err != nil {
+		return NewKey{}, err
+	}
+	s.logger.With(ctx).Infof("API key %v created for user %v", prefix, userID)
+	return NewKey{APIKey{created}, key}, nil
+}
+
+// Query returns the API keys of the specified user.
+func (s service) Query(ctx context.Context, userID string) ([]APIKey, error) {
+	items, err := s.repo.Query(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	result := []APIKey{}
+	for _, item := range items {
+		result = append(result, APIKey{item})
+	}
+	return result, nil
+}
+
+// Revoke deletes an API key of the specified user.
+// sql.ErrNoRows is returned if the user has no such key.
+func (s service) Revoke(ctx context.Context, userID, id string) error {
+	key, err := s.repo.Get(dbcontext.WithPrimary(ctx), id)
+	if err != nil {
+		return err
+	}
+	if key.UserID != userID {
+		return sql.ErrNoRows
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.logger.With(ctx).Infof("API key %v of user %v revoked", key.Prefix, userID)
+	return nil
+}
+
+// Authenticate returns the API key matching the given key.
+// ErrInvalidKey is returned if there is no such key.
+func (s service) Authenticate(ctx context.Context, key string) (APIKey, error) {
+	i := strings.IndexByte(key, '.')
+	if i <= 0 {
+		return APIKey{}, ErrInvalidKey
+	}
+	stored, err := s.repo.GetByPrefix(ctx, key[:i])
+	if err == sql.ErrNoRows {
+		return APIKey{}, ErrInvalidKey
+	} else if err != nil {
+		return APIKey{}, err
+	}
+	if subtle.ConstantTimeCompare([]byte(stored.Hash), []byte(hash(key))) != 1 {
+		return APIKey{}, ErrInvalidKey
+	}
+	return APIKey{stored}, nil
+}
+
+// hash returns the hex-encoded SHA-256 digest of a key. A fast hash is enough as the keys are random.
+func hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomHex returns n random bytes encoded in hex.
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,125 @@
+package apikey
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"local/entity"
+	"pkg/log"
+	"strings"
+	"testing"
+)
+
+var errCRUD = errors.New("error crud")
+
+func TestCreateKeyRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		model     CreateKeyRequest
+		wantError bool
+	}{
+		{"success", CreateKeyRequest{Name: "ci"}, false},
+		{"required", CreateKeyRequest{Name: ""}, true},
+		{"too long", CreateKeyRequest{Name: strings.Repeat("x", 129)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.model.Validate()
+			assert.Equal(t, tt.wantError, err != nil)
+		})
+	}
+}
+
+func Test_service(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := &mockRepository{}
+	s := NewService(repo, logger)
+	ctx := context.Background()
+
+	// creation
+	key, err := s.Create(ctx, "100", CreateKeyRequest{Name: "ci"})
+	assert.Nil(t, err)
+	assert.NotEmpty(t, key.ID)
+	assert.Equal(t, "100", key.UserID)
+	assert.Equal(t, "ci", key.Name)
+	assert.True(t, strings.HasPrefix(key.Key, key.Prefix+"."))
+	assert.NotContains(t, repo.items[0].Hash, key.Key)
+	_, err = s.Create(ctx, "100", CreateKeyRequest{})
+	assert.NotNil(t, err)
+	_, err = s.Create(ctx, "100", CreateKeyRequest{Name: "error"})
+	assert.Equal(t, errCRUD, err)
+
+	// listing
+	keys, err := s.Query(ctx, "100")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(keys))
+	keys, _ = s.Query(ctx, "200")
+	assert.Empty(t, keys)
+
+	// authentication
+	found, err := s.Authenticate(ctx, key.Key)
+	assert.Nil(t, err)
+	assert.Equal(t, key.ID, found.ID)
+	for _, invalid := range []string{"", "nodot", "." + key.Key, key.Prefix + ".wrong", "unknown.secret"} {
+		_, err = s.Authenticate(ctx, invalid)
+		assert.Equal(t, ErrInvalidKey, err, invalid)
+	}
+
+	// revocation
+	assert.Equal(t, sql.ErrNoRows, s.Revoke(ctx, "200", key.ID))
+	assert.Nil(t, s.Revoke(ctx, "100", key.ID))
+	_, err = s.Authenticate(ctx, key.Key)
+	assert.Equal(t, ErrInvalidKey, err)
+	assert.Equal(t, sql.ErrNoRows, s.Revoke(ctx, "100", key.ID))
+}
+
+type mockRepository struct {
+	items []entity.APIKey
+}
+
+func (m mockRepository) Get(ctx context.Context, id string) (entity.APIKey, error) {
+	for _, item := range m.items {
+		if item.ID == id {
+			return item, nil
+		}
+	}
+	return entity.APIKey{}, sql.ErrNoRows
+}
+
+func (m mockRepository) GetByPrefix(ctx context.Context, prefix string) (entity.APIKey, error) {
+	for _, item := range m.items {
+		if item.Prefix == prefix {
+			return item, nil
+		}
+	}
+	return entity.APIKey{}, sql.ErrNoRows
+}
+
+func (m mockRepository) Query(ctx context.Context, userID string) ([]entity.APIKey, error) {
+	var items []entity.APIKey
+	for _, item := range m.items {
+		if item.UserID == userID {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+func (m *mockRepository) Create(ctx context.Context, key entity.APIKey) error {
+	if key.Name == "error" {
+		return errCRUD
+	}
+	m.items = append(m.items, key)
+	return nil
+}
+
+func (m *mockRepository) Delete(ctx context.Context, id string) error {
+	for i, item := range m.items {
+		if item.ID == id {
+			m.items = append(m.items[:i], m.items[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
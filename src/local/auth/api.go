@@ -3,16 +3,47 @@ package auth
 import (
 	routing "github.com/go-ozzo/ozzo-routing/v2"
 	"local/errors"
+	"net/http"
+	"pkg/jsonbody"
 	"pkg/log"
+	"pkg/module"
 )
 
 // RegisterHandlers registers handlers for different HTTP requests.
-func RegisterHandlers(rg *routing.RouteGroup, service Service, logger log.Logger) {
-	rg.Post("/login", login(service, logger))
+// If cookieName is not empty, a successful login also stores the JWT in an HttpOnly cookie of that name
+// so that browser clients can authenticate without the Authorization header.
+// If fingerprintCookie is not empty, the JWT is bound to the client logging in, whose fingerprint nonce
+// is stored in a cookie of that name (see HandlerOptions.FingerprintCookie).
+func RegisterHandlers(rg *routing.RouteGroup, service Service, cookieName, fingerprintCookie string, logger log.Logger) {
+	rg.Post("/login", login(service, cookieName, fingerprintCookie, logger))
+}
+
+// RegisterAdminHandlers registers the handlers of the administrative endpoints.
+// POST /admin/keys/reload reloads the JWT signing key from its source without restarting the server.
+// The endpoints are only available to the users authenticated by authHandler whose ID is one of adminIDs,
+// so nobody may use them if adminIDs is empty.
+func RegisterAdminHandlers(rg *routing.RouteGroup, keys *KeyStore, authHandler routing.Handler, adminIDs []string, logger log.Logger) {
+	rg.Use(AdminHandler(authHandler, adminIDs...))
+
+	// the following endpoints require a valid JWT of an administrator
+	rg.Post("/admin/keys/reload", reloadKeys(keys, logger))
+}
+
+// reloadKeys returns a handler that reloads the JWT signing key.
+func reloadKeys(keys *KeyStore, logger log.Logger) routing.Handler {
+	return func(c *routing.Context) error {
+		if err := keys.Reload(); err != nil {
+			logger.With(c.Request.Context()).Errorf("failed reloading JWT keys: %v", err)
+			return errors.InternalServerError("Failed to reload the keys.")
+		}
+		logger.With(c.Request.Context()).Info("JWT keys reloaded")
+		c.Response.WriteHeader(http.StatusNoContent)
+		return nil
+	}
 }
 
 // login returns a handler that handles user login request.
-func login(service Service, logger log.Logger) routing.Handler {
+func login(service Service, cookieName, fingerprintCookie string, logger log.Logger) routing.Handler {
 	return func(c *routing.Context) error {
 		var req struct {
 			Username string `json:"username"`
@@ -21,15 +52,46 @@ func login(service Service, logger log.Logger) routing.Handler {
 
 		if err := c.Read(&req); err != nil {
 			logger.With(c.Request.Context()).Errorf("invalid request: %v", err)
-			return errors.BadRequest("")
+			return errors.BadRequest(jsonbody.ErrorMessage(err))
+		}
+
+		ctx := c.Request.Context()
+		var fingerprint *http.Cookie
+		if fingerprintCookie != "" {
+			cookie, value, err := NewFingerprintCookie(c.Request, fingerprintCookie)
+			if err != nil {
+				logger.With(ctx).Errorf("failed to create the fingerprint nonce: %v", err)
+				return err
+			}
+			fingerprint, ctx = cookie, WithFingerprint(ctx, value)
 		}
 
-		token, err := service.Login(c.Request.Context(), req.Username, req.Password)
+		token, err := service.Login(ctx, req.Username, req.Password)
 		if err != nil {
 			return err
 		}
+		if fingerprint != nil {
+			http.SetCookie(c.Response, fingerprint)
+		}
+		if cookieName != "" {
+			http.SetCookie(c.Response, &http.Cookie{
+				Name:     cookieName,
+				Value:    token,
+				Path:     "/",
+				HttpOnly: true,
+				Secure:   true,
+				SameSite: http.SameSiteStrictMode,
+			})
+		}
 		return c.Write(struct {
 			Token string `json:"token"`
 		}{token})
 	}
 }
+
+// NewModule returns the login endpoint issuing JWTs as a module.
+func NewModule(service Service, cookieName, fingerprintCookie string, logger log.Logger) module.Module {
+	return module.New("", func(rg *routing.RouteGroup) {
+		RegisterHandlers(rg, service, cookieName, fingerprintCookie, logger)
+	})
+}
@@ -1,11 +1,17 @@
 package auth
 
 import (
+	"bytes"
 	"context"
-	"internal/errors"
-	"internal/test"
-	"pkg/log"
+	"encoding/json"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"local/entity"
+	"local/errors"
+	"local/test"
 	"net/http"
+	"pkg/log"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -21,7 +27,7 @@ func (m mockService) Login(ctx context.Context, username, password string) (stri
 func TestAPI(t *testing.T) {
 	logger, _ := log.NewForTest()
 	router := test.MockRouter(logger)
-	RegisterHandlers(router.Group(""), mockService{}, logger)
+	RegisterHandlers(router.Group(""), mockService{}, "", "", logger)
 
 	tests := []test.APITestCase{
 		{"success", "POST", "/login", `{"username":"test","password":"pass"}`, nil, http.StatusOK, `{"token":"token-100"}`},
@@ -32,3 +38,103 @@ func TestAPI(t *testing.T) {
 		test.Endpoint(t, router, tc)
 	}
 }
+
+func TestAPI_cookie(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+	RegisterHandlers(router.Group(""), mockService{}, "token", "", logger)
+
+	req, _ := http.NewRequest("POST", "/login", bytes.NewBufferString(`{"username":"test","password":"pass"}`))
+	req.Header.Set("Content-Type", "application/json")
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+	cookies := res.Result().Cookies()
+	if assert.Equal(t, 1, len(cookies)) {
+		assert.Equal(t, "token", cookies[0].Name)
+		assert.Equal(t, "token-100", cookies[0].Value)
+		assert.True(t, cookies[0].HttpOnly)
+		assert.True(t, cookies[0].Secure)
+		assert.Equal(t, http.SameSiteStrictMode, cookies[0].SameSite)
+	}
+}
+
+func TestAPI_reloadKeys(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+	source := "old"
+	keys := NewKeyStore("old", func() (string, error) { return source, nil })
+	authHandler := Handler("", HandlerOptions{KeyStore: keys})
+	RegisterAdminHandlers(router.Group(""), keys, authHandler, []string{"100"}, logger)
+	router.Get("/me", authHandler, func(c *routing.Context) error { return c.Write("ok") })
+
+	oldToken, _ := service{keys, 100, logger}.generateJWT(entity.User{ID: "100", Name: "demo"}, "")
+	userToken, _ := service{keys, 100, logger}.generateJWT(entity.User{ID: "200", Name: "user"}, "")
+	newToken, _ := service{NewKeyStore("new", nil), 100, logger}.generateJWT(entity.User{ID: "100", Name: "demo"}, "")
+	bearer := func(token string) http.Header {
+		header := http.Header{}
+		header.Set("Authorization", "Bearer "+token)
+		return header
+	}
+
+	source = "new"
+	tests := []test.APITestCase{
+		{"new key before reload", "GET", "/me", "", bearer(newToken), http.StatusUnauthorized, ""},
+		{"reload without auth", "POST", "/admin/keys/reload", "", nil, http.StatusUnauthorized, ""},
+		{"reload by a user", "POST", "/admin/keys/reload", "", bearer(userToken), http.StatusForbidden, ""},
+		{"reload", "POST", "/admin/keys/reload", "", bearer(oldToken), http.StatusNoContent, ""},
+		{"new key after reload", "GET", "/me", "", bearer(newToken), http.StatusOK, ""},
+		{"old key after reload", "GET", "/me", "", bearer(oldToken), http.StatusUnauthorized, ""},
+	}
+	for _, tc := range tests {
+		test.Endpoint(t, router, tc)
+	}
+}
+
+func TestAPI_fingerprint(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+	keys := NewKeyStore("test", nil)
+	RegisterHandlers(router.Group(""), NewService(keys, 100, logger), "", "fgp", logger)
+	router.Get("/me", Handler("", HandlerOptions{KeyStore: keys, FingerprintCookie: "fgp"}), func(c *routing.Context) error { return c.Write("ok") })
+
+	req, _ := http.NewRequest("POST", "/login", bytes.NewBufferString(`{"username":"demo","password":"pass"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "browser/1.0")
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+	cookies := res.Result().Cookies()
+	if !assert.Equal(t, 1, len(cookies)) {
+		return
+	}
+	nonce := cookies[0]
+	assert.Equal(t, "fgp", nonce.Name)
+	assert.NotEmpty(t, nonce.Value)
+	assert.True(t, nonce.HttpOnly)
+	var body struct {
+		Token string `json:"token"`
+	}
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &body))
+	unbound, _ := service{keys, 100, logger}.generateJWT(entity.User{ID: "100", Name: "demo"}, "")
+
+	header := func(token, userAgent, nonce string) http.Header {
+		header := http.Header{}
+		header.Set("Authorization", "Bearer "+token)
+		header.Set("User-Agent", userAgent)
+		if nonce != "" {
+			header.Set("Cookie", "fgp="+nonce)
+		}
+		return header
+	}
+	tests := []test.APITestCase{
+		{"matching fingerprint", "GET", "/me", "", header(body.Token, "browser/1.0", nonce.Value), http.StatusOK, `"ok"`},
+		{"other user agent", "GET", "/me", "", header(body.Token, "curl/7.0", nonce.Value), http.StatusUnauthorized, ""},
+		{"other nonce", "GET", "/me", "", header(body.Token, "browser/1.0", "stolen"), http.StatusUnauthorized, ""},
+		{"missing nonce", "GET", "/me", "", header(body.Token, "browser/1.0", ""), http.StatusUnauthorized, ""},
+		{"unbound token", "GET", "/me", "", header(unbound, "browser/1.0", nonce.Value), http.StatusUnauthorized, ""},
+	}
+	for _, tc := range tests {
+		test.Endpoint(t, router, tc)
+	}
+}
@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"github.com/dgrijalva/jwt-go"
+	"net/http"
+)
+
+// FingerprintClaim is the JWT claim holding the fingerprint of the client the token was issued to.
+const FingerprintClaim = "fgp"
+
+// Fingerprint returns the fingerprint of a client, which is the SHA-256 hash of its user agent and of the nonce
+// stored in its fingerprint cookie. Binding a token to it makes the token useless to a thief who cannot also
+// present the nonce, which is kept in an HttpOnly cookie out of the reach of scripts.
+func Fingerprint(userAgent, nonce string) string {
+	hash := sha256.Sum256([]byte(userAgent + "\n" + nonce))
+	return hex.EncodeToString(hash[:])
+}
+
+// NewFingerprintCookie creates a cookie of the given name with a random nonce, and returns it with
+// the fingerprint of the client sending the request and holding the cookie.
+func NewFingerprintCookie(req *http.Request, name string) (*http.Cookie, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", err
+	}
+	nonce := base64.RawURLEncoding.EncodeToString(b)
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    nonce,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	}
+	return cookie, Fingerprint(req.UserAgent(), nonce), nil
+}
+
+// verifyFingerprint checks that the token is bound to the client sending the request, whose nonce is read
+// from the cookie of the given name.
+func verifyFingerprint(req *http.Request, cookieName string, claims jwt.MapClaims) error {
+	expected, _ := claims[FingerprintClaim].(string)
+	if expected == "" {
+		return jwt.NewValidationError("token is not bound to a client", jwt.ValidationErrorClaimsInvalid)
+	}
+	var nonce string
+	if cookie, err := req.Cookie(cookieName); err == nil {
+		nonce = cookie.Value
+	}
+	if nonce == "" || subtle.ConstantTimeCompare([]byte(Fingerprint(req.UserAgent(), nonce)), []byte(expected)) != 1 {
+		return jwt.NewValidationError("token fingerprint mismatch", jwt.ValidationErrorClaimsInvalid)
+	}
+	return nil
+}
+
+// WithFingerprint returns a context carrying the fingerprint of the client logging in,
+// to which Service.Login binds the token it issues.
+func WithFingerprint(ctx context.Context, fingerprint string) context.Context {
+	return context.WithValue(ctx, fingerprintKey, fingerprint)
+}
+
+// fingerprintFromContext returns the client fingerprint stored in the given context, or an empty string.
+func fingerprintFromContext(ctx context.Context) string {
+	fingerprint, _ := ctx.Value(fingerprintKey).(string)
+	return fingerprint
+}
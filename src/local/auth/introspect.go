@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"github.com/dgrijalva/jwt-go"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"local/errors"
+	"net/http"
+	"pkg/log"
+	"pkg/module"
+)
+
+// ClientHandler returns a middleware that authenticates services with HTTP Basic authentication.
+// clients maps the client IDs to their secrets. Requests without valid client credentials are rejected with 401.
+func ClientHandler(clients map[string]string) routing.Handler {
+	return func(c *routing.Context) error {
+		id, secret, ok := c.Request.BasicAuth()
+		if ok {
+			expected, found := clients[id]
+			if found && subtle.ConstantTimeCompare([]byte(secret), []byte(expected)) == 1 {
+				return nil
+			}
+		}
+		c.Response.Header().Set("WWW-Authenticate", `Basic realm="introspection"`)
+		return errors.Unauthorized("")
+	}
+}
+
+// RegisterIntrospectionHandlers registers the token introspection endpoint, which lets other services
+// find out whether a JWT is active and read its claims, following RFC 7662.
+// POST /auth/introspect reads the token from the "token" field of a form or JSON body.
+// The tokens are verified like Handler does with the given verification key and options.
+// The caller must pass clientHandler, which should authenticate it with a service credential (see ClientHandler).
+func RegisterIntrospectionHandlers(rg *routing.RouteGroup, verificationKey string, options HandlerOptions, clientHandler routing.Handler, logger log.Logger) {
+	rg.Use(clientHandler)
+
+	// the following endpoints require a service credential
+	rg.Post("/auth/introspect", introspect(newTokenParser(verificationKey, options), logger))
+}
+
+// introspect returns a handler that responds with {"active":true} and the claims of the given token if it is valid,
+// and with {"active":false} otherwise, without telling why the token is not active.
+func introspect(parse func(string) (*jwt.Token, error), logger log.Logger) routing.Handler {
+	return func(c *routing.Context) error {
+		var req struct {
+			Token string `json:"token" form:"token"`
+		}
+		if err := c.Read(&req); err != nil || req.Token == "" {
+			logger.With(c.Request.Context()).Infof("invalid introspection request: %v", err)
+			return errors.BadRequest("The token is required.")
+		}
+
+		response := map[string]interface{}{"active": false}
+		if token, err := parse(req.Token); err == nil {
+			for name, value := range token.Claims.(jwt.MapClaims) {
+				response[name] = value
+			}
+			response["active"] = true
+		}
+		c.Response.Header().Set("Cache-Control", "no-store")
+		return c.WriteWithStatus(response, http.StatusOK)
+	}
+}
+
+// NewIntrospectionModule returns the token introspection endpoint as a module.
+func NewIntrospectionModule(verificationKey string, options HandlerOptions, clientHandler routing.Handler, logger log.Logger) module.Module {
+	return module.New("", func(rg *routing.RouteGroup) {
+		RegisterIntrospectionHandlers(rg, verificationKey, options, clientHandler, logger)
+	})
+}
@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"local/test"
+	"net/http"
+	"pkg/log"
+	"testing"
+	"time"
+)
+
+func TestAPI_introspect(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+	RegisterIntrospectionHandlers(router.Group(""), "test", HandlerOptions{}, ClientHandler(map[string]string{"billing": "s3cret"}), logger)
+
+	sign := func(claims jwt.MapClaims) string {
+		token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("test"))
+		return token
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+	active := sign(jwt.MapClaims{"id": "100", "name": "demo", "exp": exp})
+	expired := sign(jwt.MapClaims{"id": "100", "name": "demo", "exp": time.Now().Add(-time.Hour).Unix()})
+
+	header := func(id, secret string) http.Header {
+		req, _ := http.NewRequest("POST", "/", nil)
+		req.SetBasicAuth(id, secret)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req.Header
+	}
+
+	tests := []test.APITestCase{
+		{"active", "POST", "/auth/introspect", "token=" + active, header("billing", "s3cret"), http.StatusOK,
+			fmt.Sprintf(`{"active":true,"exp":%v,"id":"100","name":"demo"}`, exp)},
+		{"expired", "POST", "/auth/introspect", "token=" + expired, header("billing", "s3cret"), http.StatusOK, `{"active":false}`},
+		{"bad signature", "POST", "/auth/introspect", "token=" + active + "x", header("billing", "s3cret"), http.StatusOK, `{"active":false}`},
+		{"missing token", "POST", "/auth/introspect", "", header("billing", "s3cret"), http.StatusBadRequest, ""},
+		{"unauthenticated", "POST", "/auth/introspect", "token=" + active, nil, http.StatusUnauthorized, ""},
+		{"wrong secret", "POST", "/auth/introspect", "token=" + active, header("billing", "wrong"), http.StatusUnauthorized, ""},
+		{"unknown client", "POST", "/auth/introspect", "token=" + active, header("other", "s3cret"), http.StatusUnauthorized, ""},
+	}
+	for _, tc := range tests {
+		test.Endpoint(t, router, tc)
+	}
+}
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// KeyLoader loads the JWT signing key from its configured source.
+type KeyLoader func() (string, error)
+
+// KeyStore holds the key used to sign and verify JWTs.
+// The key can be reloaded while requests are being served: each reader sees either the old
+// or the new key, never a partially updated one.
+type KeyStore struct {
+	key  atomic.Value
+	load KeyLoader
+	mu   sync.Mutex
+}
+
+// NewKeyStore creates a key store holding the given key.
+// load is used by Reload to read a new key. The key cannot be reloaded if load is nil.
+func NewKeyStore(key string, load KeyLoader) *KeyStore {
+	s := &KeyStore{load: load}
+	s.key.Store(key)
+	return s
+}
+
+// Key returns the current key.
+func (s *KeyStore) Key() string {
+	return s.key.Load().(string)
+}
+
+// Reload reads the key from its source and replaces the current key with it.
+// The current key is kept if the new key cannot be loaded or is empty.
+func (s *KeyStore) Reload() error {
+	if s.load == nil {
+		return errors.New("the key has no source to be reloaded from")
+	}
+
+	// serialize reloads so that a slow load cannot overwrite the result of a later one
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, err := s.load()
+	if err != nil {
+		return err
+	}
+	if key == "" {
+		return errors.New("the reloaded key is empty")
+	}
+	s.key.Store(key)
+	return nil
+}
@@ -0,0 +1,27 @@
+package auth
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestKeyStore(t *testing.T) {
+	source, sourceErr := "new", error(nil)
+	keys := NewKeyStore("old", func() (string, error) { return source, sourceErr })
+	assert.Equal(t, "old", keys.Key())
+
+	assert.Nil(t, keys.Reload())
+	assert.Equal(t, "new", keys.Key())
+
+	// the current key is kept when the reload fails
+	sourceErr = errors.New("unavailable")
+	assert.NotNil(t, keys.Reload())
+	assert.Equal(t, "new", keys.Key())
+	source, sourceErr = "", nil
+	assert.NotNil(t, keys.Reload())
+	assert.Equal(t, "new", keys.Key())
+
+	// keys without a source cannot be reloaded
+	assert.NotNil(t, NewKeyStore("static", nil).Reload())
+}
@@ -4,24 +4,188 @@ import (
 	"context"
 	"github.com/dgrijalva/jwt-go"
 	routing "github.com/go-ozzo/ozzo-routing/v2"
-	"github.com/go-ozzo/ozzo-routing/v2/auth"
-	"net/http"
 	"local/entity"
 	"local/errors"
+	"net/http"
+	"strings"
+	"time"
 )
 
+// DefaultLeeway is the default clock skew tolerated when validating the time-based claims of a JWT.
+const DefaultLeeway = 30 * time.Second
+
+// HandlerOptions represents the options of the authentication middleware.
+type HandlerOptions struct {
+	// CookieName is the name of the cookie from which the JWT is read when the request
+	// has no Authorization header. Cookies are not used if empty.
+	CookieName string
+	// Leeway is the clock skew tolerated when validating the exp, nbf and iat claims.
+	// It defaults to DefaultLeeway if zero. A negative value disables the tolerance.
+	Leeway time.Duration
+	// KeyStore provides the verification key. If set, the key is read from it for every request
+	// so that reloaded keys take effect immediately, and the verificationKey parameter is ignored.
+	KeyStore *KeyStore
+	// FingerprintCookie is the name of the cookie holding the nonce of the client fingerprint the tokens
+	// are bound to (see Fingerprint). If set, tokens are only accepted with a fingerprint claim matching
+	// the user agent and nonce of the request. Tokens are not bound to clients if empty.
+	FingerprintCookie string
+}
+
 // Handler returns a JWT-based authentication middleware.
-func Handler(verificationKey string) routing.Handler {
-	return auth.JWT(verificationKey, auth.JWTOptions{TokenHandler: handleToken})
+// The JWT is read from the "Authorization: Bearer" header, or from the configured cookie
+// if the header is absent. The header takes precedence when both are present.
+func Handler(verificationKey string, options ...HandlerOptions) routing.Handler {
+	authenticate := newAuthenticator(verificationKey, options...)
+	return func(c *routing.Context) error {
+		message, ok := authenticate(c)
+		if ok {
+			return nil
+		}
+		c.Response.Header().Set("WWW-Authenticate", `Bearer realm="API"`)
+		if message != "" {
+			return routing.NewHTTPError(http.StatusUnauthorized, message)
+		}
+		return routing.NewHTTPError(http.StatusUnauthorized)
+	}
+}
+
+// OptionalHandler returns a middleware that authenticates the request like Handler when it carries a valid JWT,
+// and lets it through anonymously otherwise. It allows middleware such as rate limiting to tell the user
+// behind a request before the endpoints requiring authentication are reached.
+func OptionalHandler(verificationKey string, options ...HandlerOptions) routing.Handler {
+	authenticate := newAuthenticator(verificationKey, options...)
+	return func(c *routing.Context) error {
+		authenticate(c)
+		return nil
+	}
+}
+
+// newAuthenticator returns a function that verifies the JWT of a request and stores the user identity
+// in the request context. It reports whether the request is authenticated and, if a token was presented
+// but rejected, the reason.
+func newAuthenticator(verificationKey string, options ...HandlerOptions) func(c *routing.Context) (string, bool) {
+	var opt HandlerOptions
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	parse := newTokenParser(verificationKey, opt)
+
+	return func(c *routing.Context) (string, bool) {
+		header := c.Request.Header.Get("Authorization")
+		if header == "" && opt.CookieName != "" {
+			if cookie, err := c.Request.Cookie(opt.CookieName); err == nil && cookie.Value != "" {
+				header = "Bearer " + cookie.Value
+			}
+		}
+		if !strings.HasPrefix(header, "Bearer ") {
+			return "", false
+		}
+		token, err := parse(header[7:])
+		if err == nil && opt.FingerprintCookie != "" {
+			err = verifyFingerprint(c.Request, opt.FingerprintCookie, token.Claims.(jwt.MapClaims))
+		}
+		if err == nil {
+			err = handleToken(c, token)
+		}
+		if err != nil {
+			return err.Error(), false
+		}
+		return "", true
+	}
+}
+
+// newTokenParser returns a function that parses a JWT, verifying its signature and its time-based claims.
+func newTokenParser(verificationKey string, opt HandlerOptions) func(tokenString string) (*jwt.Token, error) {
+	if opt.Leeway == 0 {
+		opt.Leeway = DefaultLeeway
+	} else if opt.Leeway < 0 {
+		opt.Leeway = 0
+	}
+	parser := &jwt.Parser{
+		ValidMethods: []string{"HS256"},
+		// the time-based claims are validated by validateClaims with leeway
+		SkipClaimsValidation: true,
+	}
+	if opt.KeyStore == nil {
+		opt.KeyStore = NewKeyStore(verificationKey, nil)
+	}
+	keyFunc := func(t *jwt.Token) (interface{}, error) { return []byte(opt.KeyStore.Key()), nil }
+
+	return func(tokenString string) (*jwt.Token, error) {
+		token, err := parser.Parse(tokenString, keyFunc)
+		if err != nil {
+			return nil, err
+		}
+		if err := validateClaims(token.Claims.(jwt.MapClaims), time.Now(), opt.Leeway); err != nil {
+			return nil, err
+		}
+		return token, nil
+	}
 }
 
-// handleToken stores the user identity in the request context so that it can be accessed elsewhere.
+// AdminHandler returns a middleware that only lets administrators through.
+// The request is authenticated by authHandler, then rejected with 403 unless the user ID is one of adminIDs.
+func AdminHandler(authHandler routing.Handler, adminIDs ...string) routing.Handler {
+	admins := map[string]bool{}
+	for _, id := range adminIDs {
+		admins[id] = true
+	}
+	return func(c *routing.Context) error {
+		if err := authHandler(c); err != nil {
+			return err
+		}
+		if user, ok := UserFromContext(c.Request.Context()); !ok || !admins[user.ID] {
+			return errors.Forbidden("")
+		}
+		return nil
+	}
+}
+
+// ConditionalHandler returns a middleware that runs thenHandler if cond holds for the request context,
+// and elseHandler otherwise. The condition is evaluated for every request, after the preceding middleware
+// has run, so it can depend on the claims of the authenticated user (see UserFromContext and TenantFromContext).
+// A nil handler lets the request through unchanged.
+func ConditionalHandler(cond func(ctx context.Context) bool, thenHandler, elseHandler routing.Handler) routing.Handler {
+	return func(c *routing.Context) error {
+		handler := elseHandler
+		if cond(c.Request.Context()) {
+			handler = thenHandler
+		}
+		if handler == nil {
+			return nil
+		}
+		return handler(c)
+	}
+}
+
+// validateClaims verifies the exp, nbf and iat claims against the given time, tolerating
+// the specified clock skew. Claims that are absent are not checked.
+func validateClaims(claims jwt.MapClaims, now time.Time, leeway time.Duration) error {
+	if !claims.VerifyExpiresAt(now.Add(-leeway).Unix(), false) {
+		return jwt.NewValidationError("token is expired", jwt.ValidationErrorExpired)
+	}
+	if !claims.VerifyNotBefore(now.Add(leeway).Unix(), false) {
+		return jwt.NewValidationError("token is not valid yet", jwt.ValidationErrorNotValidYet)
+	}
+	if !claims.VerifyIssuedAt(now.Add(leeway).Unix(), false) {
+		return jwt.NewValidationError("token used before issued", jwt.ValidationErrorIssuedAt)
+	}
+	return nil
+}
+
+// handleToken stores the user identity and tenant in the request context so that they can be accessed elsewhere
+// using UserFromContext and TenantFromContext. The tenant is only stored if the token has a "tenant" claim.
 func handleToken(c *routing.Context, token *jwt.Token) error {
-	ctx := WithUser(
-		c.Request.Context(),
-		token.Claims.(jwt.MapClaims)["id"].(string),
-		token.Claims.(jwt.MapClaims)["name"].(string),
-	)
+	claims := token.Claims.(jwt.MapClaims)
+	id, _ := claims["id"].(string)
+	name, _ := claims["name"].(string)
+	if id == "" {
+		return jwt.NewValidationError("token has no user ID", jwt.ValidationErrorClaimsInvalid)
+	}
+	ctx := WithUser(c.Request.Context(), id, name)
+	if tenant, _ := claims["tenant"].(string); tenant != "" {
+		ctx = WithTenant(ctx, tenant)
+	}
 	c.Request = c.Request.WithContext(ctx)
 	return nil
 }
@@ -30,6 +194,8 @@ type contextKey int
 
 const (
 	userKey contextKey = iota
+	tenantKey
+	fingerprintKey
 )
 
 // WithUser returns a context that contains the user identity from the given JWT.
@@ -46,6 +212,25 @@ func CurrentUser(ctx context.Context) Identity {
 	return nil
 }
 
+// UserFromContext returns the authenticated user stored in the given context.
+// The second return value is false if the request has not been authenticated.
+func UserFromContext(ctx context.Context) (entity.User, bool) {
+	user, ok := ctx.Value(userKey).(entity.User)
+	return user, ok
+}
+
+// WithTenant returns a context that contains the tenant of the authenticated user.
+func WithTenant(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantKey, entity.Tenant{ID: id})
+}
+
+// TenantFromContext returns the tenant of the authenticated user stored in the given context.
+// The second return value is false if there is no tenant in the context.
+func TenantFromContext(ctx context.Context) (entity.Tenant, bool) {
+	tenant, ok := ctx.Value(tenantKey).(entity.Tenant)
+	return tenant, ok
+}
+
 // MockAuthHandler creates a mock authentication middleware for testing purpose.
 // If the request contains an Authorization header whose value is "TEST", then
 // it considers the user is authenticated as "Tester" whose ID is "100".
@@ -3,9 +3,14 @@ package auth
 import (
 	"context"
 	"github.com/dgrijalva/jwt-go"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
 	"github.com/stretchr/testify/assert"
+	"local/entity"
+	"local/errors"
+	"local/test"
 	"net/http"
 	"testing"
+	"time"
 )
 
 func TestCurrentUser(t *testing.T) {
@@ -19,10 +24,153 @@ func TestCurrentUser(t *testing.T) {
 	}
 }
 
+func TestUserFromContext(t *testing.T) {
+	ctx := context.Background()
+	_, ok := UserFromContext(ctx)
+	assert.False(t, ok)
+	_, ok = TenantFromContext(ctx)
+	assert.False(t, ok)
+
+	ctx = WithTenant(WithUser(ctx, "100", "test"), "acme")
+	user, ok := UserFromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, entity.User{ID: "100", Name: "test"}, user)
+	tenant, ok := TenantFromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, entity.Tenant{ID: "acme"}, tenant)
+}
+
 func TestHandler(t *testing.T) {
 	assert.NotNil(t, Handler("test"))
 }
 
+func TestHandler_cookie(t *testing.T) {
+	s := service{NewKeyStore("test", nil), 100, nil}
+	token, _ := s.generateJWT(entity.User{ID: "100", Name: "demo"}, "")
+	handler := Handler("test", HandlerOptions{CookieName: "token"})
+
+	// token in cookie is accepted when the Authorization header is absent
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	req.AddCookie(&http.Cookie{Name: "token", Value: token})
+	ctx, _ := test.MockRoutingContext(req)
+	assert.Nil(t, handler(ctx))
+	if identity := CurrentUser(ctx.Request.Context()); assert.NotNil(t, identity) {
+		assert.Equal(t, "100", identity.GetID())
+	}
+
+	// the Authorization header takes precedence over the cookie
+	req, _ = http.NewRequest("GET", "http://example.com", nil)
+	req.AddCookie(&http.Cookie{Name: "token", Value: token})
+	req.Header.Set("Authorization", "Bearer invalid")
+	ctx, _ = test.MockRoutingContext(req)
+	assert.NotNil(t, handler(ctx))
+
+	// cookies are ignored unless enabled
+	req, _ = http.NewRequest("GET", "http://example.com", nil)
+	req.AddCookie(&http.Cookie{Name: "token", Value: token})
+	ctx, _ = test.MockRoutingContext(req)
+	assert.NotNil(t, Handler("test")(ctx))
+}
+
+func TestOptionalHandler(t *testing.T) {
+	s := service{NewKeyStore("test", nil), 100, nil}
+	token, _ := s.generateJWT(entity.User{ID: "100", Name: "demo"}, "")
+	handler := OptionalHandler("test")
+
+	// a valid token authenticates the request
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	ctx, _ := test.MockRoutingContext(req)
+	assert.Nil(t, handler(ctx))
+	if user, ok := UserFromContext(ctx.Request.Context()); assert.True(t, ok) {
+		assert.Equal(t, "100", user.ID)
+	}
+
+	// requests without a token or with an invalid one pass anonymously
+	for _, header := range []string{"", "Bearer invalid"} {
+		req, _ := http.NewRequest("GET", "http://example.com", nil)
+		req.Header.Set("Authorization", header)
+		ctx, res := test.MockRoutingContext(req)
+		assert.Nil(t, handler(ctx))
+		_, ok := UserFromContext(ctx.Request.Context())
+		assert.False(t, ok)
+		assert.Empty(t, res.Header().Get("WWW-Authenticate"))
+	}
+}
+
+func TestHandler_leeway(t *testing.T) {
+	call := func(handler func(*routing.Context) error, claims jwt.MapClaims) error {
+		token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("test"))
+		req, _ := http.NewRequest("GET", "http://example.com", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		ctx, _ := test.MockRoutingContext(req)
+		return handler(ctx)
+	}
+	now := time.Now()
+	handler := Handler("test", HandlerOptions{Leeway: 30 * time.Second})
+
+	// expired just now, within the leeway
+	assert.Nil(t, call(handler, jwt.MapClaims{"id": "100", "name": "demo", "exp": now.Add(-10 * time.Second).Unix()}))
+	// expired beyond the leeway
+	assert.NotNil(t, call(handler, jwt.MapClaims{"id": "100", "name": "demo", "exp": now.Add(-60 * time.Second).Unix()}))
+	// issued and valid slightly in the future, within the leeway
+	assert.Nil(t, call(handler, jwt.MapClaims{"id": "100", "name": "demo",
+		"iat": now.Add(10 * time.Second).Unix(), "nbf": now.Add(10 * time.Second).Unix()}))
+	// not valid until beyond the leeway
+	assert.NotNil(t, call(handler, jwt.MapClaims{"id": "100", "name": "demo", "nbf": now.Add(60 * time.Second).Unix()}))
+	assert.NotNil(t, call(handler, jwt.MapClaims{"id": "100", "name": "demo", "iat": now.Add(60 * time.Second).Unix()}))
+
+	// the default leeway applies when none is configured
+	assert.Nil(t, call(Handler("test"), jwt.MapClaims{"id": "100", "name": "demo", "exp": now.Add(-10 * time.Second).Unix()}))
+	// a negative leeway disables the tolerance
+	assert.NotNil(t, call(Handler("test", HandlerOptions{Leeway: -1}),
+		jwt.MapClaims{"id": "100", "name": "demo", "exp": now.Add(-10 * time.Second).Unix()}))
+}
+
+func TestAdminHandler(t *testing.T) {
+	handler := AdminHandler(MockAuthHandler, "1", "100")
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	ctx, _ := test.MockRoutingContext(req)
+	assert.Equal(t, errors.Unauthorized(""), handler(ctx))
+
+	req.Header = MockAuthHeader()
+	ctx, _ = test.MockRoutingContext(req)
+	assert.Nil(t, handler(ctx))
+
+	ctx, _ = test.MockRoutingContext(req)
+	assert.Equal(t, errors.Forbidden(""), AdminHandler(MockAuthHandler, "1")(ctx))
+}
+
+func TestConditionalHandler(t *testing.T) {
+	inTenant := func(ctx context.Context) bool {
+		tenant, ok := TenantFromContext(ctx)
+		return ok && tenant.ID == "acme"
+	}
+	deny := func(c *routing.Context) error { return errors.Forbidden("") }
+	mark := func(c *routing.Context) error {
+		c.Response.Header().Set("X-Tenant", "acme")
+		return nil
+	}
+	handler := ConditionalHandler(inTenant, mark, deny)
+
+	// the condition holds
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	req = req.WithContext(WithTenant(req.Context(), "acme"))
+	ctx, res := test.MockRoutingContext(req)
+	assert.Nil(t, handler(ctx))
+	assert.Equal(t, "acme", res.Header().Get("X-Tenant"))
+
+	// the condition does not hold
+	req, _ = http.NewRequest("GET", "http://example.com", nil)
+	ctx, res = test.MockRoutingContext(req)
+	assert.Equal(t, errors.Forbidden(""), handler(ctx))
+	assert.Equal(t, "", res.Header().Get("X-Tenant"))
+
+	// a nil handler lets the request through
+	ctx, _ = test.MockRoutingContext(req)
+	assert.Nil(t, ConditionalHandler(inTenant, deny, nil)(ctx))
+}
+
 func Test_handleToken(t *testing.T) {
 	req, _ := http.NewRequest("GET", "http://example.com", nil)
 	ctx, _ := test.MockRoutingContext(req)
@@ -40,6 +188,31 @@ func Test_handleToken(t *testing.T) {
 		assert.Equal(t, "100", identity.GetID())
 		assert.Equal(t, "test", identity.GetName())
 	}
+	_, ok := TenantFromContext(ctx.Request.Context())
+	assert.False(t, ok)
+
+	// a token without user ID is rejected
+	ctx, _ = test.MockRoutingContext(req)
+	assert.NotNil(t, handleToken(ctx, &jwt.Token{Claims: jwt.MapClaims{"name": "test"}}))
+}
+
+func TestHandler_context(t *testing.T) {
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"id":     "100",
+		"name":   "demo",
+		"tenant": "acme",
+	}).SignedString([]byte("test"))
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	ctx, _ := test.MockRoutingContext(req)
+	assert.Nil(t, Handler("test")(ctx))
+
+	user, ok := UserFromContext(ctx.Request.Context())
+	assert.True(t, ok)
+	assert.Equal(t, entity.User{ID: "100", Name: "demo"}, user)
+	tenant, ok := TenantFromContext(ctx.Request.Context())
+	assert.True(t, ok)
+	assert.Equal(t, "acme", tenant.ID)
 }
 
 func TestMocks(t *testing.T) {
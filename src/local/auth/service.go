@@ -25,21 +25,22 @@ type Identity interface {
 }
 
 type service struct {
-	signingKey      string
+	keys            *KeyStore
 	tokenExpiration int
 	logger          log.Logger
 }
 
 // NewService creates a new authentication service.
-func NewService(signingKey string, tokenExpiration int, logger log.Logger) Service {
-	return service{signingKey, tokenExpiration, logger}
+// Tokens are signed with the current key of the given key store.
+func NewService(keys *KeyStore, tokenExpiration int, logger log.Logger) Service {
+	return service{keys, tokenExpiration, logger}
 }
 
 // Login authenticates a user and generates a JWT token if authentication succeeds.
-// Otherwise, an error is returned.
+// Otherwise, an error is returned. The token is bound to the client fingerprint in the context, if any (see WithFingerprint).
 func (s service) Login(ctx context.Context, username, password string) (string, error) {
 	if identity := s.authenticate(ctx, username, password); identity != nil {
-		return s.generateJWT(identity)
+		return s.generateJWT(identity, fingerprintFromContext(ctx))
 	}
 	return "", errors.Unauthorized("")
 }
@@ -59,11 +60,15 @@ func (s service) authenticate(ctx context.Context, username, password string) Id
 	return nil
 }
 
-// generateJWT generates a JWT that encodes an identity.
-func (s service) generateJWT(identity Identity) (string, error) {
-	return jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+// generateJWT generates a JWT that encodes an identity. The token is bound to the given client fingerprint unless it is empty.
+func (s service) generateJWT(identity Identity, fingerprint string) (string, error) {
+	claims := jwt.MapClaims{
 		"id":   identity.GetID(),
 		"name": identity.GetName(),
 		"exp":  time.Now().Add(time.Duration(s.tokenExpiration) * time.Hour).Unix(),
-	}).SignedString([]byte(s.signingKey))
+	}
+	if fingerprint != "" {
+		claims[FingerprintClaim] = fingerprint
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.keys.Key()))
 }
@@ -2,13 +2,17 @@ package auth
 
 import (
 	"context"
+	"github.com/dgrijalva/jwt-go"
 	"github.com/stretchr/testify/assert"
+	"local/entity"
+	"local/errors"
+	"pkg/log"
 	"testing"
 )
 
 func Test_service_Authenticate(t *testing.T) {
 	logger, _ := log.NewForTest()
-	s := NewService("test", 100, logger)
+	s := NewService(NewKeyStore("test", nil), 100, logger)
 	_, err := s.Login(context.Background(), "unknown", "bad")
 	assert.Equal(t, errors.Unauthorized(""), err)
 	token, err := s.Login(context.Background(), "demo", "pass")
@@ -18,19 +22,31 @@ func Test_service_Authenticate(t *testing.T) {
 
 func Test_service_authenticate(t *testing.T) {
 	logger, _ := log.NewForTest()
-	s := service{"test", 100, logger}
+	s := service{NewKeyStore("test", nil), 100, logger}
 	assert.Nil(t, s.authenticate(context.Background(), "unknown", "bad"))
 	assert.NotNil(t, s.authenticate(context.Background(), "demo", "pass"))
 }
 
 func Test_service_GenerateJWT(t *testing.T) {
 	logger, _ := log.NewForTest()
-	s := service{"test", 100, logger}
+	s := service{NewKeyStore("test", nil), 100, logger}
 	token, err := s.generateJWT(entity.User{
 		ID:   "100",
 		Name: "demo",
-	})
+	}, "")
 	if assert.Nil(t, err) {
 		assert.NotEmpty(t, token)
 	}
 }
+
+func Test_service_Login_fingerprint(t *testing.T) {
+	logger, _ := log.NewForTest()
+	s := NewService(NewKeyStore("test", nil), 100, logger)
+	token, err := s.Login(WithFingerprint(context.Background(), "abc"), "demo", "pass")
+	if assert.Nil(t, err) {
+		claims := jwt.MapClaims{}
+		_, err = jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) { return []byte("test"), nil })
+		assert.Nil(t, err)
+		assert.Equal(t, "abc", claims[FingerprintClaim])
+	}
+}
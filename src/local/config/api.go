@@ -0,0 +1,18 @@
+package config
+
+import (
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+)
+
+// RegisterHandlers registers the administrative endpoint GET /admin/config, which returns
+// the effective configuration of the running server with its secrets redacted.
+func RegisterHandlers(rg *routing.RouteGroup, c *Config, authHandler routing.Handler) {
+	redacted := c.Redacted()
+
+	rg.Use(authHandler)
+
+	// the following endpoints require admin authentication
+	rg.Get("/admin/config", func(c *routing.Context) error {
+		return c.Write(redacted)
+	})
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/go-ozzo/ozzo-routing/v2/content"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPI(t *testing.T) {
+	router := routing.New()
+	router.Use(content.TypeNegotiator(content.JSON))
+	RegisterHandlers(router.Group(""), &Config{
+		ServerPort:    8080,
+		DSN:           "admin:qwer1234@tcp(localhost:3306)/mytestdb",
+		JWTSigningKey: "LxsKJywDL5O5PvgODZhBH12KE6k2yL8E",
+	}, func(c *routing.Context) error {
+		if c.Request.Header.Get("Authorization") != "TEST" {
+			return routing.NewHTTPError(http.StatusUnauthorized)
+		}
+		return nil
+	})
+
+	req, _ := http.NewRequest("GET", "http://127.0.0.1/admin/config", nil)
+	req.Header.Set("Authorization", "TEST")
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Contains(t, res.Body.String(), `"server_port":8080`)
+	assert.Contains(t, res.Body.String(), `"dsn":"admin:***@tcp(localhost:3306)/mytestdb"`)
+	assert.Contains(t, res.Body.String(), `"jwt_signing_key":"***"`)
+	assert.NotContains(t, res.Body.String(), "qwer1234")
+	assert.NotContains(t, res.Body.String(), "LxsKJywDL5O5PvgODZhBH12KE6k2yL8E")
+
+	req, _ = http.NewRequest("GET", "http://127.0.0.1/admin/config", nil)
+	res = httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+}
@@ -1,52 +1,475 @@
 package config
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/BurntSushi/toml"
 	"github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/qiangxue/go-env"
 	"gopkg.in/yaml.v2"
 	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"pkg/accesslog"
+	"pkg/jsonnaming"
 	"pkg/log"
+	"pkg/password"
+	"pkg/urlbuilder"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
 )
 
 const (
 	defaultServerPort         = 8080
 	defaultJWTExpirationHours = 72
+	defaultPollTimeoutSeconds = 30
+	defaultShutdownTimeout    = 10
+	defaultInterruptTimeout   = 2
+	defaultHealthCheckTimeout = 5
+	defaultRestartTimeout     = 30
+	defaultJWTLeewaySeconds   = 30
+	defaultErrorHistorySize   = 100
+	defaultMaxHeaderBytes     = 1 << 20
+	defaultMaxHeaderCount     = 100
+	defaultMaxURLLength       = 8192
+	defaultLoginMaxDelay      = 4000
+	defaultLoginTimeout       = 5000
+	defaultWebhookTimeout     = 2000
+	defaultWebhookRetries     = 3
+	defaultPasswordMinLength  = 6
+	defaultPasswordResetTTL   = 15
+	defaultMaxRows            = 10000
+	defaultJobWorkers         = 2
+	defaultJobQueueSize       = 10
+	defaultJobTTL             = 60
 )
 
 // Config represents an application configuration.
 type Config struct {
 	// the server port. Defaults to 8080
-	ServerPort int `yaml:"server_port" env:"SERVER_PORT"`
+	ServerPort int `yaml:"server_port" json:"server_port" toml:"server_port" env:"SERVER_PORT"`
 	// the data source name (DSN) for connecting to the database. required.
-	DSN string `yaml:"dsn" env:"DSN,secret"`
-	// JWT signing key. required.
-	JWTSigningKey string `yaml:"jwt_signing_key" env:"JWT_SIGNING_KEY,secret"`
+	DSN string `yaml:"dsn" json:"dsn" toml:"dsn" env:"DSN,secret"`
+	// the data source name (DSN) of a read replica receiving SELECT queries. Every query goes to the primary database if empty.
+	ReplicaDSN string `yaml:"replica_dsn" json:"replica_dsn" toml:"replica_dsn" env:"REPLICA_DSN,secret"`
+	// the maximum execution time in milliseconds of a database statement, enforced by the database server
+	// on each connection. With MySQL it only applies to SELECT statements. No limit is set if 0.
+	StatementTimeout int `yaml:"statement_timeout" json:"statement_timeout" toml:"statement_timeout" env:"STATEMENT_TIMEOUT"`
+	// the maximum number of rows a query may load into memory. Queries returning more fail. Defaults to 10000. Not limited if 0.
+	MaxRows int `yaml:"max_rows" json:"max_rows" toml:"max_rows" env:"MAX_ROWS"`
+	// whether SQL statements run for a request are prefixed with a comment naming its route, e.g. "/* route:/v1/login */",
+	// to attribute slow queries to endpoints.
+	TagQueries bool `yaml:"tag_queries" json:"tag_queries" toml:"tag_queries" env:"TAG_QUERIES"`
+	// the number of database connections opened at startup, before the server is ready, and kept idle in the pool.
+	// Connections are opened on demand if 0.
+	MinIdleConns int `yaml:"min_idle_conns" json:"min_idle_conns" toml:"min_idle_conns" env:"MIN_IDLE_CONNS"`
+	// JWT signing key, at least 32 bytes long. required.
+	JWTSigningKey string `yaml:"jwt_signing_key" json:"jwt_signing_key" toml:"jwt_signing_key" env:"JWT_SIGNING_KEY,secret"`
 	// JWT expiration in hours. Defaults to 72 hours (3 days)
-	JWTExpiration int `yaml:"jwt_expiration" env:"JWT_EXPIRATION"`
+	JWTExpiration int `yaml:"jwt_expiration" json:"jwt_expiration" toml:"jwt_expiration" env:"JWT_EXPIRATION"`
+	// the clock skew tolerated when validating JWT expiration and issue times in seconds. Defaults to 30 seconds.
+	// A negative value disables the tolerance.
+	JWTLeeway int `yaml:"jwt_leeway" json:"jwt_leeway" toml:"jwt_leeway" env:"JWT_LEEWAY"`
+	// how long a notification poll request is held in seconds. Defaults to 30 seconds
+	PollTimeout int `yaml:"poll_timeout" json:"poll_timeout" toml:"poll_timeout" env:"POLL_TIMEOUT"`
+	// how long the server waits for components to close on shutdown in seconds. Defaults to 10 seconds
+	ShutdownTimeout int `yaml:"shutdown_timeout" json:"shutdown_timeout" toml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+	// how long the server waits for components to close when interrupted (SIGINT, e.g. Ctrl-C) in seconds.
+	// Defaults to 2 seconds. ShutdownTimeout applies to SIGTERM.
+	InterruptShutdownTimeout int `yaml:"interrupt_shutdown_timeout" json:"interrupt_shutdown_timeout" toml:"interrupt_shutdown_timeout" env:"INTERRUPT_SHUTDOWN_TIMEOUT"`
+	// how long /readyz answers 503 on SIGTERM while the server keeps serving requests, before it starts shutting down,
+	// so that load balancers stop sending it requests first. In seconds. There is no grace period if 0.
+	ShutdownGracePeriod int `yaml:"shutdown_grace_period" json:"shutdown_grace_period" toml:"shutdown_grace_period" env:"SHUTDOWN_GRACE_PERIOD"`
+	// whether SIGHUP restarts the server without downtime: a new process takes over the listening socket and
+	// the old one shuts down like on SIGTERM once the new one is ready.
+	GracefulRestart bool `yaml:"graceful_restart" json:"graceful_restart" toml:"graceful_restart" env:"GRACEFUL_RESTART"`
+	// how long the new process of a graceful restart may take to get ready in seconds. Defaults to 30 seconds.
+	RestartTimeout int `yaml:"restart_timeout" json:"restart_timeout" toml:"restart_timeout" env:"RESTART_TIMEOUT"`
+	// the API versions accepted in the X-API-Version header of v1 requests.
+	// The header is not required if empty.
+	APIVersions []string `yaml:"api_versions" json:"api_versions" toml:"api_versions" env:"API_VERSIONS"`
+	// whether debug mode is enabled. Debug endpoints such as /v1/whoami are only available in debug mode.
+	Debug bool `yaml:"debug" json:"debug" toml:"debug" env:"DEBUG"`
+	// the IP addresses or CIDR ranges of reverse proxies whose X-Forwarded-* headers are trusted.
+	TrustedProxies []string `yaml:"trusted_proxies" json:"trusted_proxies" toml:"trusted_proxies" env:"TRUSTED_PROXIES"`
+	// whether deleting a resource that does not exist returns 404 instead of 204.
+	StrictDelete bool `yaml:"strict_delete" json:"strict_delete" toml:"strict_delete" env:"STRICT_DELETE"`
+	// the health check URLs of downstream services reported by /status, keyed by service name.
+	Downstreams map[string]string `yaml:"downstreams" json:"downstreams" toml:"downstreams" env:"DOWNSTREAMS"`
+	// how long each dependency check of /status may take in seconds. Defaults to 5 seconds
+	HealthCheckTimeout int `yaml:"health_check_timeout" json:"health_check_timeout" toml:"health_check_timeout" env:"HEALTH_CHECK_TIMEOUT"`
+	// the boundaries of the latency buckets reported in access logs in milliseconds, e.g. [10, 100, 1000].
+	// Defaults to 10ms, 100ms and 1s if empty.
+	LatencyBuckets []int `yaml:"latency_buckets" json:"latency_buckets" toml:"latency_buckets" env:"LATENCY_BUCKETS"`
+	// the fraction of successful requests recorded in access logs, between 0 and 1. Failed requests are always recorded.
+	// Defaults to 1 (every request).
+	AccessLogSampleRate float64 `yaml:"access_log_sample_rate" json:"access_log_sample_rate" toml:"access_log_sample_rate" env:"ACCESS_LOG_SAMPLE_RATE"`
+	// where the application logs are written: "stderr", or "syslog" to send them to the syslog daemon or server
+	// configured below. Logs fall back to stderr if syslog is unreachable. Syslog is not available on Windows and Plan 9.
+	// Defaults to "stderr".
+	LogSink string `yaml:"log_sink" json:"log_sink" toml:"log_sink" env:"LOG_SINK"`
+	// the network of a remote syslog server, "udp" or "tcp". The local syslog daemon is used if empty.
+	SyslogNetwork string `yaml:"syslog_network" json:"syslog_network" toml:"syslog_network" env:"SYSLOG_NETWORK"`
+	// the address of the remote syslog server, e.g. "logs.example.com:514". Required with syslog_network.
+	SyslogAddress string `yaml:"syslog_address" json:"syslog_address" toml:"syslog_address" env:"SYSLOG_ADDRESS"`
+	// the syslog facility of the logs, e.g. "daemon". Defaults to "local0".
+	SyslogFacility string `yaml:"syslog_facility" json:"syslog_facility" toml:"syslog_facility" env:"SYSLOG_FACILITY"`
+	// the tag identifying the server in syslog. Defaults to the name of the executable.
+	SyslogTag string `yaml:"syslog_tag" json:"syslog_tag" toml:"syslog_tag" env:"SYSLOG_TAG"`
+	// the format of the access log: "json" logs structured messages, "common" and "combined" write lines
+	// in the Apache Common or Combined Log Format to the standard output. Defaults to "json".
+	AccessLogFormat string `yaml:"access_log_format" json:"access_log_format" toml:"access_log_format" env:"ACCESS_LOG_FORMAT"`
+	// the patterns of the request paths left out of the access log, e.g. "/internal/*".
+	// Defaults to the health and metrics endpoints /healthz, /readyz and /metrics.
+	AccessLogExclude []string `yaml:"access_log_exclude" json:"access_log_exclude" toml:"access_log_exclude" env:"ACCESS_LOG_EXCLUDE"`
+	// the fraction of the new traces that are sampled, between 0 and 1. Defaults to 1 (every trace).
+	// The sampling decision of a trace continued from an incoming traceparent header is always honored.
+	TraceSampleRate float64 `yaml:"trace_sample_rate" json:"trace_sample_rate" toml:"trace_sample_rate" env:"TRACE_SAMPLE_RATE"`
+	// the fractions of the new traces that are sampled for individual routes, keyed by route pattern (e.g. "/v1/login").
+	TraceSampleRates map[string]float64 `yaml:"trace_sample_rates" json:"trace_sample_rates" toml:"trace_sample_rates" env:"TRACE_SAMPLE_RATES"`
+	// whether errors are returned as RFC 7807 problem details (application/problem+json).
+	ProblemJSON bool `yaml:"problem_json" json:"problem_json" toml:"problem_json" env:"PROBLEM_JSON"`
+	// the naming convention of the JSON fields without an explicit name in their json tag: "snake_case" or "camelCase".
+	// The Go field names are kept if empty.
+	JSONNaming string `yaml:"json_naming" json:"json_naming" toml:"json_naming" env:"JSON_NAMING"`
+	// the response formats offered besides JSON, the default, which clients request with the Accept header. Only "xml" is supported.
+	ResponseFormats []string `yaml:"response_formats" json:"response_formats" toml:"response_formats" env:"RESPONSE_FORMATS"`
+	// whether concurrent identical GET requests under /v1 share a single execution of their handler.
+	CoalesceRequests bool `yaml:"coalesce_requests" json:"coalesce_requests" toml:"coalesce_requests" env:"COALESCE_REQUESTS"`
+	// the number of seconds the API keys read to authenticate requests are cached. A key revoked on another instance
+	// keeps working on this one for up to that long. Not cached if 0.
+	APIKeyCacheTTL int `yaml:"api_key_cache_ttl" json:"api_key_cache_ttl" toml:"api_key_cache_ttl" env:"API_KEY_CACHE_TTL"`
+	// whether responses carry an X-Response-Time header giving the server-measured request duration in milliseconds.
+	ResponseTimeHeader bool `yaml:"response_time_header" json:"response_time_header" toml:"response_time_header" env:"RESPONSE_TIME_HEADER"`
+	// whether responses carry a Server-Timing header reporting the time spent in the database and the handler.
+	// Always enabled in debug mode.
+	ServerTiming bool `yaml:"server_timing" json:"server_timing" toml:"server_timing" env:"SERVER_TIMING"`
+	// the duration in milliseconds from which requests are also logged as slow, with their database activity. Not logged if 0.
+	SlowRequestThreshold int `yaml:"slow_request_threshold" json:"slow_request_threshold" toml:"slow_request_threshold" env:"SLOW_REQUEST_THRESHOLD"`
+	// whether write requests must have a Content-Length header. Chunked uploads are rejected if true.
+	RequireContentLength bool `yaml:"require_content_length" json:"require_content_length" toml:"require_content_length" env:"REQUIRE_CONTENT_LENGTH"`
+	// the maximum Content-Length of write requests in bytes when Content-Length is required. Not limited if 0.
+	MaxRequestBytes int64 `yaml:"max_request_bytes" json:"max_request_bytes" toml:"max_request_bytes" env:"MAX_REQUEST_BYTES"`
+	// what to do at startup if required database tables are missing: "warn" logs them, "fail" stops the server.
+	// Tables are not checked if empty.
+	TableCheck string `yaml:"table_check" json:"table_check" toml:"table_check" env:"TABLE_CHECK"`
+	// the number of most recent error responses listed by GET /admin/errors. Defaults to 100.
+	ErrorHistorySize int `yaml:"error_history_size" json:"error_history_size" toml:"error_history_size" env:"ERROR_HISTORY_SIZE"`
+	// the maximum size of request headers in bytes. Defaults to 1MB.
+	MaxHeaderBytes int `yaml:"max_header_bytes" json:"max_header_bytes" toml:"max_header_bytes" env:"MAX_HEADER_BYTES"`
+	// the maximum number of request headers. Requests with more headers are rejected with 431. Defaults to 100.
+	MaxHeaderCount int `yaml:"max_header_count" json:"max_header_count" toml:"max_header_count" env:"MAX_HEADER_COUNT"`
+	// the maximum length in bytes of a request URL, including the query string. Longer URLs are rejected with 414.
+	// Defaults to 8192. Not limited if negative.
+	MaxURLLength int `yaml:"max_url_length" json:"max_url_length" toml:"max_url_length" env:"MAX_URL_LENGTH"`
+	// the maximum length in bytes of a query string. Longer query strings are rejected with 414. Not limited if 0.
+	MaxQueryLength int `yaml:"max_query_length" json:"max_query_length" toml:"max_query_length" env:"MAX_QUERY_LENGTH"`
+	// the maximum processing time of a v1 request in milliseconds. Clients may ask for less with the
+	// X-Request-Timeout header; requests over the limit are answered with 504. Not limited if 0.
+	MaxRequestTimeout int `yaml:"max_request_timeout" json:"max_request_timeout" toml:"max_request_timeout" env:"MAX_REQUEST_TIMEOUT"`
+	// the processing time limit in milliseconds of the v1 requests without an X-Request-Timeout header. Not limited if 0.
+	RequestTimeout int `yaml:"request_timeout" json:"request_timeout" toml:"request_timeout" env:"REQUEST_TIMEOUT"`
+	// the IDs of the users allowed to access the administrative endpoints under /admin.
+	AdminUsers []string `yaml:"admin_users" json:"admin_users" toml:"admin_users" env:"ADMIN_USERS"`
+	// the name of the cookie carrying the JWT for browser clients. Cookie authentication is disabled if empty.
+	AuthCookie string `yaml:"auth_cookie" json:"auth_cookie" toml:"auth_cookie" env:"AUTH_COOKIE"`
+	// the name of the HttpOnly cookie holding the nonce of the client fingerprint JWTs are bound to, so that a stolen
+	// token is rejected when presented by another client. Tokens are not bound to clients if empty.
+	FingerprintCookie string `yaml:"fingerprint_cookie" json:"fingerprint_cookie" toml:"fingerprint_cookie" env:"FINGERPRINT_COOKIE"`
+	// the IDs and secrets of the services allowed to call POST /v1/auth/introspect with HTTP Basic authentication.
+	// Token introspection is disabled if empty.
+	IntrospectionClients map[string]string `yaml:"introspection_clients" json:"introspection_clients" toml:"introspection_clients" env:"INTROSPECTION_CLIENTS,secret"`
+	// the number of requests per minute each client IP may send to the v1 API without authentication. Not limited if 0.
+	RateLimitAnonymous int `yaml:"rate_limit_anonymous" json:"rate_limit_anonymous" toml:"rate_limit_anonymous" env:"RATE_LIMIT_ANONYMOUS"`
+	// the number of requests per minute each authenticated user may send to the v1 API. Not limited if 0.
+	RateLimitAuthenticated int `yaml:"rate_limit_authenticated" json:"rate_limit_authenticated" toml:"rate_limit_authenticated" env:"RATE_LIMIT_AUTHENTICATED"`
+	// the number of requests per minute each tenant may send to the v1 API, shared by all of its users. Not limited if 0.
+	RateLimitTenant int `yaml:"rate_limit_tenant" json:"rate_limit_tenant" toml:"rate_limit_tenant" env:"RATE_LIMIT_TENANT"`
+	// the per-minute limits of the tenants with the given IDs, overriding RateLimitTenant. 0 means not limited.
+	RateLimitTenants map[string]int `yaml:"rate_limit_tenants" json:"rate_limit_tenants" toml:"rate_limit_tenants" env:"RATE_LIMIT_TENANTS"`
+	// the number of requests each tenant may send to the v1 API per day (UTC), counted in the database. Not limited if 0.
+	TenantDailyQuota int `yaml:"tenant_daily_quota" json:"tenant_daily_quota" toml:"tenant_daily_quota" env:"TENANT_DAILY_QUOTA"`
+	// the daily quotas of the tenants with the given IDs, overriding TenantDailyQuota. 0 means not limited.
+	TenantDailyQuotas map[string]int `yaml:"tenant_daily_quotas" json:"tenant_daily_quotas" toml:"tenant_daily_quotas" env:"TENANT_DAILY_QUOTAS"`
+	// the header naming the tenant of the requests whose JWT has no tenant claim, e.g. "X-Tenant-ID".
+	// Only set it behind a gateway that sets the header, as clients could otherwise use the quota of other tenants.
+	TenantHeader string `yaml:"tenant_header" json:"tenant_header" toml:"tenant_header" env:"TENANT_HEADER"`
+	// the IDs of the tenants the TenantHeader may name. Other values are ignored, so that clients can't make the server
+	// count the requests of tenants at will. Required with TenantHeader.
+	TenantHeaderAllowlist []string `yaml:"tenant_header_allowlist" json:"tenant_header_allowlist" toml:"tenant_header_allowlist" env:"TENANT_HEADER_ALLOWLIST"`
+	// the minimum length of new passwords. Defaults to 6.
+	PasswordMinLength int `yaml:"password_min_length" json:"password_min_length" toml:"password_min_length" env:"PASSWORD_MIN_LENGTH"`
+	// whether new passwords must contain an uppercase letter, a lowercase letter, a digit and a symbol respectively.
+	PasswordRequireUpper  bool `yaml:"password_require_upper" json:"password_require_upper" toml:"password_require_upper" env:"PASSWORD_REQUIRE_UPPER"`
+	PasswordRequireLower  bool `yaml:"password_require_lower" json:"password_require_lower" toml:"password_require_lower" env:"PASSWORD_REQUIRE_LOWER"`
+	PasswordRequireDigit  bool `yaml:"password_require_digit" json:"password_require_digit" toml:"password_require_digit" env:"PASSWORD_REQUIRE_DIGIT"`
+	PasswordRequireSymbol bool `yaml:"password_require_symbol" json:"password_require_symbol" toml:"password_require_symbol" env:"PASSWORD_REQUIRE_SYMBOL"`
+	// whether common passwords are rejected, along with those listed in PasswordDisallowed.
+	PasswordRejectCommon bool `yaml:"password_reject_common" json:"password_reject_common" toml:"password_reject_common" env:"PASSWORD_REJECT_COMMON"`
+	// additional passwords rejected when PasswordRejectCommon is true, e.g. the company name.
+	PasswordDisallowed []string `yaml:"password_disallowed" json:"password_disallowed" toml:"password_disallowed" env:"PASSWORD_DISALLOWED"`
+	// the delay imposed on a login attempt after a failed one for the same login name and IP in milliseconds.
+	// It doubles after each further consecutive failure and is reset by a successful login. Disabled if 0.
+	LoginDelay int `yaml:"login_delay" json:"login_delay" toml:"login_delay" env:"LOGIN_DELAY"`
+	// the maximum delay imposed on a login attempt in milliseconds. Defaults to 4 seconds.
+	// It must be less than the login timeout, which defaults to 5 seconds, and than the request timeout if any.
+	LoginMaxDelay int `yaml:"login_max_delay" json:"login_max_delay" toml:"login_max_delay" env:"LOGIN_MAX_DELAY"`
+	// whether users may log in with their email address, stored in the email column of the loguser table, as well as their login name.
+	LoginWithEmail bool `yaml:"login_with_email" json:"login_with_email" toml:"login_with_email" env:"LOGIN_WITH_EMAIL"`
+	// the URL receiving a JSON event for each login attempt, successful or not. No events are sent if empty.
+	LoginWebhookURL string `yaml:"login_webhook_url" json:"login_webhook_url" toml:"login_webhook_url" env:"LOGIN_WEBHOOK_URL,secret"`
+	// the time limit of a login webhook delivery attempt in milliseconds. Defaults to 2 seconds.
+	LoginWebhookTimeout int `yaml:"login_webhook_timeout" json:"login_webhook_timeout" toml:"login_webhook_timeout" env:"LOGIN_WEBHOOK_TIMEOUT"`
+	// the number of times a failed login webhook delivery is retried. Defaults to 3.
+	LoginWebhookRetries int `yaml:"login_webhook_retries" json:"login_webhook_retries" toml:"login_webhook_retries" env:"LOGIN_WEBHOOK_RETRIES"`
+	// the URL receiving the password reset tokens as JSON events, e.g. to email them. The password reset endpoints are disabled if empty.
+	PasswordResetWebhookURL string `yaml:"password_reset_webhook_url" json:"password_reset_webhook_url" toml:"password_reset_webhook_url" env:"PASSWORD_RESET_WEBHOOK_URL,secret"`
+	// the lifetime of the password reset tokens in minutes. Defaults to 15 minutes.
+	PasswordResetTTL int `yaml:"password_reset_ttl" json:"password_reset_ttl" toml:"password_reset_ttl" env:"PASSWORD_RESET_TTL"`
+	// the hosts the redirect_uri of a login may point to. Only relative redirects are allowed if empty.
+	RedirectHosts []string `yaml:"redirect_hosts" json:"redirect_hosts" toml:"redirect_hosts" env:"REDIRECT_HOSTS"`
+	// a regular expression matching the user agents of the robots denied the v1 routes with 403, e.g. "(?i)bot|crawl|spider".
+	// Robots are not blocked if empty.
+	RobotPattern string `yaml:"robot_pattern" json:"robot_pattern" toml:"robot_pattern" env:"ROBOT_PATTERN"`
+	// the patterns of the routes denied to robots, e.g. "/v1/login". All v1 routes are denied if empty.
+	RobotRoutes []string `yaml:"robot_routes" json:"robot_routes" toml:"robot_routes" env:"ROBOT_ROUTES"`
+	// whether v1 requests whose query parameters or path look like SQL injection attempts are rejected with 403.
+	SQLGuard bool `yaml:"sql_guard" json:"sql_guard" toml:"sql_guard" env:"SQL_GUARD"`
+	// the patterns of the routes checked for SQL injection attempts, e.g. "/v1/albums". All v1 routes are checked if empty.
+	SQLGuardRoutes []string `yaml:"sql_guard_routes" json:"sql_guard_routes" toml:"sql_guard_routes" env:"SQL_GUARD_ROUTES"`
+	// the number of background jobs, such as asynchronous user imports, run at the same time. Defaults to 2.
+	JobWorkers int `yaml:"job_workers" json:"job_workers" toml:"job_workers" env:"JOB_WORKERS"`
+	// the number of background jobs waiting for a worker. Jobs started while it is full are refused with 503. Defaults to 10,
+	// as each waiting import holds its CSV document, of up to 10 MB, in memory.
+	JobQueueSize int `yaml:"job_queue_size" json:"job_queue_size" toml:"job_queue_size" env:"JOB_QUEUE_SIZE"`
+	// how long the status of a finished background job is kept in minutes. Defaults to 60 minutes.
+	JobTTL int `yaml:"job_ttl" json:"job_ttl" toml:"job_ttl" env:"JOB_TTL"`
+	// the URL under which clients reach the server, e.g. "https://api.example.com", used to build absolute URLs
+	// in responses such as Location headers. Its path is kept as a prefix. URLs are relative to the host if empty.
+	ExternalURL string `yaml:"external_url" json:"external_url" toml:"external_url" env:"EXTERNAL_URL"`
+	// the timeouts of individual operations in milliseconds, keyed by operation name (e.g. "login").
+	// Operations without a configured timeout use their own default.
+	Timeouts map[string]int `yaml:"timeouts" json:"timeouts" toml:"timeouts" env:"TIMEOUTS"`
+}
+
+// PasswordPolicy returns the policy new passwords must follow.
+func (c Config) PasswordPolicy() password.Policy {
+	return password.Policy{
+		MinLength:     c.PasswordMinLength,
+		RequireUpper:  c.PasswordRequireUpper,
+		RequireLower:  c.PasswordRequireLower,
+		RequireDigit:  c.PasswordRequireDigit,
+		RequireSymbol: c.PasswordRequireSymbol,
+		RejectCommon:  c.PasswordRejectCommon,
+		Disallowed:    c.PasswordDisallowed,
+	}
+}
+
+// TimeoutFor returns the configured timeout of the named operation, or defaultTimeout if none is configured.
+func (c Config) TimeoutFor(name string, defaultTimeout time.Duration) time.Duration {
+	if ms, ok := c.Timeouts[name]; ok && ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	return defaultTimeout
 }
 
 // Validate validates the application configuration.
 func (c Config) Validate() error {
 	return validation.ValidateStruct(&c,
 		validation.Field(&c.DSN, validation.Required),
-		validation.Field(&c.JWTSigningKey, validation.Required),
+		validation.Field(&c.JWTSigningKey, validation.Required, validation.By(signingKey)),
+		validation.Field(&c.TableCheck, validation.In("warn", "fail")),
+		validation.Field(&c.AccessLogFormat, validation.In("json", "common", "combined")),
+		validation.Field(&c.LogSink, validation.In("stderr", "syslog"),
+			validation.When(!log.SyslogSupported, validation.NotIn("syslog").Error("syslog is not supported on this platform"))),
+		validation.Field(&c.SyslogNetwork, validation.In("udp", "tcp")),
+		validation.Field(&c.SyslogAddress, validation.When(c.SyslogNetwork != "", validation.Required)),
+		validation.Field(&c.SyslogFacility, validation.By(func(value interface{}) error {
+			if value.(string) == "" || c.LogSink != "syslog" {
+				return nil
+			}
+			_, err := log.ParseFacility(value.(string))
+			return err
+		})),
+		validation.Field(&c.JSONNaming, validation.In(string(jsonnaming.SnakeCase), string(jsonnaming.CamelCase))),
+		validation.Field(&c.ResponseFormats, validation.Each(validation.In("xml"))),
+		validation.Field(&c.AccessLogExclude, validation.Each(validation.By(func(value interface{}) error {
+			_, err := path.Match(value.(string), "")
+			return err
+		}))),
+		validation.Field(&c.Timeouts, validation.Each(validation.Min(1))),
+		validation.Field(&c.StatementTimeout, validation.Min(0)),
+		validation.Field(&c.InterruptShutdownTimeout, validation.Min(0)),
+		validation.Field(&c.RestartTimeout, validation.Min(1)),
+		validation.Field(&c.ShutdownGracePeriod, validation.Min(0)),
+		validation.Field(&c.MaxRows, validation.Min(0)),
+		validation.Field(&c.MinIdleConns, validation.Min(0)),
+		validation.Field(&c.MaxQueryLength, validation.Min(0)),
+		validation.Field(&c.MaxRequestTimeout, validation.Min(0)),
+		validation.Field(&c.RequestTimeout, validation.Min(0)),
+		validation.Field(&c.LoginMaxDelay, validation.When(c.LoginDelay > 0, validation.By(c.loginMaxDelay))),
+		validation.Field(&c.SlowRequestThreshold, validation.Min(0)),
+		validation.Field(&c.RateLimitTenant, validation.Min(0)),
+		validation.Field(&c.RateLimitTenants, validation.Each(validation.Min(0))),
+		validation.Field(&c.TenantDailyQuota, validation.Min(0)),
+		validation.Field(&c.TenantDailyQuotas, validation.Each(validation.Min(0))),
+		validation.Field(&c.TenantHeaderAllowlist, validation.When(c.TenantHeader != "", validation.Required)),
+		validation.Field(&c.TraceSampleRate, validation.Min(0.0), validation.Max(1.0)),
+		validation.Field(&c.TraceSampleRates, validation.Each(validation.Min(0.0), validation.Max(1.0))),
+		validation.Field(&c.RobotPattern, validation.By(func(value interface{}) error {
+			_, err := regexp.Compile(value.(string))
+			return err
+		})),
+		validation.Field(&c.LoginWebhookTimeout, validation.Min(1)),
+		validation.Field(&c.LoginWebhookRetries, validation.Min(0)),
+		validation.Field(&c.LoginWebhookURL, validation.By(webhookURL)),
+		validation.Field(&c.PasswordResetWebhookURL, validation.By(webhookURL)),
+		validation.Field(&c.PasswordResetTTL, validation.Min(1)),
+		validation.Field(&c.JobWorkers, validation.Min(1)),
+		validation.Field(&c.JobQueueSize, validation.Min(0)),
+		validation.Field(&c.JobTTL, validation.Min(1)),
+		validation.Field(&c.APIKeyCacheTTL, validation.Min(0)),
+		validation.Field(&c.ExternalURL, validation.By(func(value interface{}) error {
+			_, err := urlbuilder.New(value.(string), nil)
+			return err
+		})),
 	)
 }
 
+// loginMaxDelay validates the maximum login delay, which must leave time to verify the password
+// within the login timeout and the request timeout.
+func (c Config) loginMaxDelay(value interface{}) error {
+	delay := time.Duration(value.(int)) * time.Millisecond
+	if timeout := c.TimeoutFor("login", defaultLoginTimeout*time.Millisecond); delay >= timeout {
+		return fmt.Errorf("must be less than the login timeout of %v", timeout)
+	}
+	if c.RequestTimeout > 0 && value.(int) >= c.RequestTimeout {
+		return fmt.Errorf("must be less than the request timeout of %vms", c.RequestTimeout)
+	}
+	return nil
+}
+
+// webhookURL validates an optional webhook URL, which must be absolute.
+func webhookURL(value interface{}) error {
+	if value.(string) == "" {
+		return nil
+	}
+	u, err := url.Parse(value.(string))
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return errors.New("must be an absolute http or https URL")
+	}
+	return nil
+}
+
+// Summary returns the effective configuration values, one "name: value" line per field in declaration order.
+// Values of fields tagged as secret are redacted.
+func (c Config) Summary() []string {
+	v := reflect.ValueOf(c.Redacted())
+	t := v.Type()
+	lines := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		lines = append(lines, fmt.Sprintf("%v: %v", name, v.Field(i).Interface()))
+	}
+	return lines
+}
+
+// Redacted returns a copy of the configuration in which the values of the fields tagged as secret are masked.
+// Only the password of a data source name, such as DSN, is masked, so that the rest of it remains visible.
+// The other secrets, such as webhook URLs and signing keys, are masked entirely.
+func (c Config) Redacted() Config {
+	v := reflect.ValueOf(&c).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := v.Field(i)
+		if !strings.HasSuffix(t.Field(i).Tag.Get("env"), ",secret") {
+			continue
+		}
+		if field.Kind() == reflect.String && field.String() != "" {
+			if strings.HasSuffix(t.Field(i).Name, "DSN") {
+				field.SetString(redactDSN(field.String()))
+			} else {
+				field.SetString("***")
+			}
+		}
+		// the values of a map, e.g. the secrets of clients, are masked in a new map so that c is left unchanged
+		if field.Kind() == reflect.Map && field.Type().Elem().Kind() == reflect.String && field.Len() > 0 {
+			masked := reflect.MakeMapWithSize(field.Type(), field.Len())
+			for _, key := range field.MapKeys() {
+				masked.SetMapIndex(key, reflect.ValueOf("***").Convert(field.Type().Elem()))
+			}
+			field.Set(masked)
+		}
+	}
+	return c
+}
+
+// redactDSN masks the password of a data source name such as "user:password@tcp(host)/db",
+// or the whole value if it has no password.
+func redactDSN(value string) string {
+	if at := strings.LastIndex(value, "@"); at > 0 {
+		if colon := strings.Index(value[:at], ":"); colon >= 0 {
+			return value[:colon+1] + "***" + value[at:]
+		}
+	}
+	return "***"
+}
+
+var (
+	// ErrConfigNotFound is returned by Load when the config file does not exist.
+	ErrConfigNotFound = errors.New("config file not found")
+	// ErrConfigParse is returned by Load when the config file is not valid YAML, JSON or TOML.
+	ErrConfigParse = errors.New("config file is malformed")
+)
+
 // Load returns an application configuration which is populated from the given configuration file and environment variables.
+// The error wraps ErrConfigNotFound if the file does not exist and ErrConfigParse if it cannot be parsed.
 func Load(file string, logger log.Logger) (*Config, error) {
 	// default config
 	c := Config{
-		ServerPort:    defaultServerPort,
-		JWTExpiration: defaultJWTExpirationHours,
+		ServerPort:               defaultServerPort,
+		JWTExpiration:            defaultJWTExpirationHours,
+		PollTimeout:              defaultPollTimeoutSeconds,
+		ShutdownTimeout:          defaultShutdownTimeout,
+		InterruptShutdownTimeout: defaultInterruptTimeout,
+		RestartTimeout:           defaultRestartTimeout,
+		HealthCheckTimeout:       defaultHealthCheckTimeout,
+		JWTLeeway:                defaultJWTLeewaySeconds,
+		LogSink:                  "stderr",
+		AccessLogSampleRate:      1,
+		AccessLogExclude:         append([]string(nil), accesslog.DefaultExcludedPaths...),
+		TraceSampleRate:          1,
+		ErrorHistorySize:         defaultErrorHistorySize,
+		MaxHeaderBytes:           defaultMaxHeaderBytes,
+		MaxHeaderCount:           defaultMaxHeaderCount,
+		MaxURLLength:             defaultMaxURLLength,
+		LoginMaxDelay:            defaultLoginMaxDelay,
+		LoginWebhookTimeout:      defaultWebhookTimeout,
+		LoginWebhookRetries:      defaultWebhookRetries,
+		PasswordMinLength:        defaultPasswordMinLength,
+		PasswordResetTTL:         defaultPasswordResetTTL,
+		MaxRows:                  defaultMaxRows,
+		JobWorkers:               defaultJobWorkers,
+		JobQueueSize:             defaultJobQueueSize,
+		JobTTL:                   defaultJobTTL,
 	}
 
-	// load from YAML config file
+	// load from the config file in the format indicated by its extension
 	bytes, err := ioutil.ReadFile(file)
-	if err != nil {
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", ErrConfigNotFound, file)
+	} else if err != nil {
 		return nil, err
 	}
-	if err = yaml.Unmarshal(bytes, &c); err != nil {
+	if err = unmarshal(file, bytes, &c); err != nil {
 		return nil, err
 	}
 
@@ -59,6 +482,29 @@ func Load(file string, logger log.Logger) (*Config, error) {
 	if err = c.Validate(); err != nil {
 		return nil, err
 	}
+	if weakness := SigningKeyWeakness(c.JWTSigningKey); weakness != "" {
+		logger.Infof("warning: the JWT signing key is weak because %v; generate a random one", weakness)
+	}
 
 	return &c, err
 }
+
+// unmarshal parses the config file content according to the file extension.
+// Supported extensions are .yml, .yaml, .json and .toml.
+func unmarshal(file string, bytes []byte, c *Config) error {
+	var err error
+	switch ext := strings.ToLower(filepath.Ext(file)); ext {
+	case ".yml", ".yaml":
+		err = yaml.Unmarshal(bytes, c)
+	case ".json":
+		err = json.Unmarshal(bytes, c)
+	case ".toml":
+		err = toml.Unmarshal(bytes, c)
+	default:
+		return fmt.Errorf("unsupported config file format %q: use .yml, .yaml, .json or .toml", ext)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrConfigParse, file, err)
+	}
+	return nil
+}
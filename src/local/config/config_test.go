@@ -0,0 +1,231 @@
+package config
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"pkg/log"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	logger, _ := log.NewForTest()
+
+	yml, err := Load("testdata/app.yml", logger)
+	if assert.Nil(t, err) {
+		assert.Equal(t, 8081, yml.ServerPort)
+		assert.Equal(t, 24, yml.JWTExpiration)
+		assert.Equal(t, defaultShutdownTimeout, yml.ShutdownTimeout)
+		assert.Equal(t, []string{"1", "1.1"}, yml.APIVersions)
+		assert.Equal(t, map[string]int{"login": 2000}, yml.Timeouts)
+	}
+
+	json, err := Load("testdata/app.json", logger)
+	if assert.Nil(t, err) {
+		assert.Equal(t, yml, json)
+	}
+
+	toml, err := Load("testdata/app.toml", logger)
+	if assert.Nil(t, err) {
+		assert.Equal(t, yml, toml)
+	}
+
+	_, err = Load("testdata/app.ini", logger)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), `unsupported config file format ".ini"`)
+	}
+}
+
+func TestLoad_errors(t *testing.T) {
+	logger, _ := log.NewForTest()
+
+	_, err := Load("testdata/missing.yml", logger)
+	assert.True(t, errors.Is(err, ErrConfigNotFound))
+	assert.False(t, errors.Is(err, ErrConfigParse))
+	assert.Contains(t, err.Error(), "testdata/missing.yml")
+
+	for _, file := range []string{"testdata/invalid.yml", "testdata/invalid.json", "testdata/invalid.toml"} {
+		_, err = Load(file, logger)
+		assert.True(t, errors.Is(err, ErrConfigParse), file)
+		assert.False(t, errors.Is(err, ErrConfigNotFound), file)
+		assert.Contains(t, err.Error(), file)
+	}
+}
+
+func TestConfig_Summary(t *testing.T) {
+	summary := Config{ServerPort: 8080, DSN: "user:pass@tcp(db)/app", APIVersions: []string{"1"}}.Summary()
+	assert.Contains(t, summary, "server_port: 8080")
+	assert.Contains(t, summary, "dsn: user:***@tcp(db)/app")
+	assert.Contains(t, summary, "jwt_signing_key: ")
+	assert.Contains(t, summary, "api_versions: [1]")
+	// the password must not appear in any value; the names of the password policy fields contain "pass"
+	for _, line := range summary {
+		assert.NotContains(t, strings.SplitN(line, ": ", 2)[1], "pass")
+	}
+}
+
+func TestConfig_Redacted(t *testing.T) {
+	c := Config{
+		ServerPort:           8080,
+		DSN:                  "admin:qwer1234@tcp(localhost:3306)/mytestdb",
+		ReplicaDSN:           "replica",
+		JWTSigningKey:        "signing-key",
+		IntrospectionClients: map[string]string{"billing": "s3cret"},
+		LoginWebhookURL:      "https://u:p@hooks.example.com/hook?sig=abc",
+	}
+	r := c.Redacted()
+	assert.Equal(t, 8080, r.ServerPort)
+	assert.Equal(t, "admin:***@tcp(localhost:3306)/mytestdb", r.DSN)
+	assert.Equal(t, "***", r.ReplicaDSN)
+	assert.Equal(t, "***", r.JWTSigningKey)
+	assert.Equal(t, "***", r.LoginWebhookURL)
+	assert.Equal(t, "***", Config{JWTSigningKey: "a:b@c"}.Redacted().JWTSigningKey)
+	assert.Equal(t, map[string]string{"billing": "***"}, r.IntrospectionClients)
+	assert.Equal(t, "signing-key", c.JWTSigningKey, "the original config must not be modified")
+	assert.Equal(t, "s3cret", c.IntrospectionClients["billing"], "the original config must not be modified")
+	assert.Equal(t, "", Config{}.Redacted().DSN)
+}
+
+func TestConfig_Validate_externalURL(t *testing.T) {
+	c := Config{DSN: "dsn", JWTSigningKey: testSigningKey}
+	assert.Nil(t, c.Validate())
+	c.ExternalURL = "https://api.example.com/service"
+	assert.Nil(t, c.Validate())
+	c.ExternalURL = "api.example.com"
+	assert.NotNil(t, c.Validate())
+}
+
+func TestConfig_Validate_traceSampleRates(t *testing.T) {
+	c := Config{DSN: "dsn", JWTSigningKey: testSigningKey, TraceSampleRate: 0.5, TraceSampleRates: map[string]float64{"/v1/login": 1}}
+	assert.Nil(t, c.Validate())
+	c.TraceSampleRates["/healthcheck"] = 1.5
+	assert.NotNil(t, c.Validate())
+	c.TraceSampleRates["/healthcheck"] = 0.01
+	c.TraceSampleRate = -1
+	assert.NotNil(t, c.Validate())
+}
+
+func TestConfig_Validate_robotPattern(t *testing.T) {
+	c := Config{DSN: "dsn", JWTSigningKey: testSigningKey, RobotPattern: "(?i)bot|crawl"}
+	assert.Nil(t, c.Validate())
+	c.RobotPattern = "(bot"
+	assert.NotNil(t, c.Validate())
+}
+
+func TestConfig_Validate_loginWebhookURL(t *testing.T) {
+	c := Config{DSN: "dsn", JWTSigningKey: testSigningKey, LoginWebhookURL: "https://hooks.example.com/login?token=x"}
+	assert.Nil(t, c.Validate())
+	for _, invalid := range []string{"hooks.example.com/login", "ftp://hooks.example.com", "http://", "http://[::1"} {
+		c.LoginWebhookURL = invalid
+		assert.NotNil(t, c.Validate(), invalid)
+	}
+}
+
+func TestConfig_Validate_passwordResetWebhookURL(t *testing.T) {
+	c := Config{DSN: "dsn", JWTSigningKey: testSigningKey, PasswordResetWebhookURL: "https://mail.example.com/reset"}
+	assert.Nil(t, c.Validate())
+	c.PasswordResetWebhookURL = "mail.example.com/reset"
+	assert.NotNil(t, c.Validate())
+}
+
+func TestConfig_Validate_accessLogExclude(t *testing.T) {
+	c := Config{DSN: "dsn", JWTSigningKey: testSigningKey, AccessLogExclude: []string{"/healthz", "/internal/*"}}
+	assert.Nil(t, c.Validate())
+	c.AccessLogExclude = []string{"/internal/["}
+	assert.NotNil(t, c.Validate())
+}
+
+func TestConfig_Validate_tenantHeader(t *testing.T) {
+	c := Config{DSN: "dsn", JWTSigningKey: testSigningKey, TenantHeader: "X-Tenant-ID"}
+	assert.NotNil(t, c.Validate())
+	c.TenantHeaderAllowlist = []string{"acme"}
+	assert.Nil(t, c.Validate())
+}
+
+func TestConfig_PasswordPolicy(t *testing.T) {
+	c := Config{PasswordMinLength: 10, PasswordRequireDigit: true, PasswordRejectCommon: true, PasswordDisallowed: []string{"acme"}}
+	policy := c.PasswordPolicy()
+	assert.Equal(t, 10, policy.MinLength)
+	assert.True(t, policy.RequireDigit)
+	assert.False(t, policy.RequireUpper)
+	assert.Equal(t, []string{"acme"}, policy.Disallowed)
+	assert.Equal(t, []string{"must be at least 10 characters long", "must contain a digit", "is too common"}, policy.Check("acme"))
+}
+
+func TestConfig_TimeoutFor(t *testing.T) {
+	c := Config{Timeouts: map[string]int{"login": 2000, "export": 0}}
+	assert.Equal(t, 2*time.Second, c.TimeoutFor("login", time.Second), "configured")
+	assert.Equal(t, time.Minute, c.TimeoutFor("report", time.Minute), "default")
+	assert.Equal(t, time.Minute, c.TimeoutFor("export", time.Minute), "zero uses the default")
+	assert.Equal(t, time.Minute, Config{}.TimeoutFor("login", time.Minute), "no timeouts")
+}
+
+func TestLoad_timeoutOverride(t *testing.T) {
+	logger, _ := log.NewForTest()
+	os.Setenv("APP_TIMEOUTS", `{"login":500,"export":60000}`)
+	defer os.Unsetenv("APP_TIMEOUTS")
+
+	c, err := Load("testdata/app.yml", logger)
+	if assert.Nil(t, err) {
+		assert.Equal(t, 500*time.Millisecond, c.TimeoutFor("login", time.Second))
+		assert.Equal(t, time.Minute, c.TimeoutFor("export", time.Second))
+	}
+
+	os.Setenv("APP_TIMEOUTS", `{"login":-1}`)
+	_, err = Load("testdata/app.yml", logger)
+	assert.NotNil(t, err)
+}
+
+func TestConfig_Validate_jsonNaming(t *testing.T) {
+	c := Config{DSN: "dsn", JWTSigningKey: testSigningKey}
+	for _, valid := range []string{"", "snake_case", "camelCase"} {
+		c.JSONNaming = valid
+		assert.Nil(t, c.Validate(), valid)
+	}
+	c.JSONNaming = "kebab-case"
+	assert.NotNil(t, c.Validate())
+}
+
+func TestConfig_Validate_syslog(t *testing.T) {
+	c := Config{DSN: "dsn", JWTSigningKey: testSigningKey, LogSink: "syslog", SyslogFacility: "daemon"}
+	if !log.SyslogSupported {
+		assert.NotNil(t, c.Validate())
+		return
+	}
+	assert.Nil(t, c.Validate())
+	c.SyslogNetwork, c.SyslogAddress = "udp", "logs.example.com:514"
+	assert.Nil(t, c.Validate())
+
+	for _, invalid := range []Config{
+		{DSN: "dsn", JWTSigningKey: testSigningKey, LogSink: "file"},
+		{DSN: "dsn", JWTSigningKey: testSigningKey, LogSink: "syslog", SyslogNetwork: "tcp"},
+		{DSN: "dsn", JWTSigningKey: testSigningKey, LogSink: "syslog", SyslogNetwork: "unix", SyslogAddress: "/dev/log"},
+		{DSN: "dsn", JWTSigningKey: testSigningKey, LogSink: "syslog", SyslogFacility: "local9"},
+	} {
+		assert.NotNil(t, invalid.Validate())
+	}
+}
+
+func TestConfig_Validate_interruptShutdownTimeout(t *testing.T) {
+	c := Config{DSN: "dsn", JWTSigningKey: testSigningKey}
+	assert.Nil(t, c.Validate())
+	c.InterruptShutdownTimeout = -1
+	assert.NotNil(t, c.Validate())
+}
+
+func TestConfig_Validate_loginMaxDelay(t *testing.T) {
+	c := Config{DSN: "dsn", JWTSigningKey: testSigningKey, LoginDelay: 100, LoginMaxDelay: defaultLoginMaxDelay}
+	assert.Nil(t, c.Validate())
+	c.LoginMaxDelay = 5000
+	assert.NotNil(t, c.Validate())
+	c.Timeouts = map[string]int{"login": 8000}
+	assert.Nil(t, c.Validate())
+	c.RequestTimeout = 3000
+	assert.NotNil(t, c.Validate())
+
+	// the delay is not used without throttling
+	c = Config{DSN: "dsn", JWTSigningKey: testSigningKey, LoginMaxDelay: 60000}
+	assert.Nil(t, c.Validate())
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+)
+
+// MinSigningKeyBytes is the minimum length of the JWT signing key. An HS256 key shorter than
+// the 32-byte output of SHA-256 weakens the HMAC signatures.
+const MinSigningKeyBytes = 32
+
+// minSigningKeyVariety is the number of distinct bytes below which a signing key is considered weak.
+const minSigningKeyVariety = 10
+
+// ErrShortSigningKey is reported by Validate for the JWT signing keys shorter than MinSigningKeyBytes.
+var ErrShortSigningKey = fmt.Errorf("must be at least %v bytes long", MinSigningKeyBytes)
+
+// GenerateSigningKey returns a random JWT signing key of MinSigningKeyBytes bytes, encoded in URL-safe base64.
+func GenerateSigningKey() (string, error) {
+	b := make([]byte, MinSigningKeyBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// SigningKeyWeakness returns why a JWT signing key that is long enough is still easy to guess,
+// or "" if it does not look weak. Keys made of few distinct characters, such as repeated words, are weak.
+func SigningKeyWeakness(key string) string {
+	distinct := map[byte]bool{}
+	for i := 0; i < len(key); i++ {
+		distinct[key[i]] = true
+	}
+	if len(distinct) < minSigningKeyVariety {
+		return fmt.Sprintf("it only uses %v distinct characters", len(distinct))
+	}
+	return ""
+}
+
+// signingKey validates the length of a JWT signing key.
+func signingKey(value interface{}) error {
+	if key := value.(string); key != "" && len(key) < MinSigningKeyBytes {
+		return ErrShortSigningKey
+	}
+	return nil
+}
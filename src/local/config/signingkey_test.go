@@ -0,0 +1,44 @@
+package config
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+// testSigningKey is a valid JWT signing key for the tests.
+const testSigningKey = "LxsKJywDL5O5PvgODZhBH12KE6k2yL8E"
+
+func TestConfig_Validate_jwtSigningKey(t *testing.T) {
+	c := Config{DSN: "dsn", JWTSigningKey: testSigningKey}
+	assert.Nil(t, c.Validate())
+
+	for _, short := range []string{"key", testSigningKey[:MinSigningKeyBytes-1]} {
+		c.JWTSigningKey = short
+		err := c.Validate()
+		if assert.NotNil(t, err, short) {
+			assert.Contains(t, err.Error(), "jwt_signing_key: must be at least 32 bytes long")
+		}
+	}
+
+	c.JWTSigningKey = ""
+	assert.NotNil(t, c.Validate())
+}
+
+func TestGenerateSigningKey(t *testing.T) {
+	key, err := GenerateSigningKey()
+	assert.Nil(t, err)
+	assert.True(t, len(key) >= MinSigningKeyBytes, key)
+	assert.Equal(t, "", SigningKeyWeakness(key))
+	c := Config{DSN: "dsn", JWTSigningKey: key}
+	assert.Nil(t, c.Validate())
+
+	other, _ := GenerateSigningKey()
+	assert.NotEqual(t, key, other)
+}
+
+func TestSigningKeyWeakness(t *testing.T) {
+	assert.Equal(t, "", SigningKeyWeakness(testSigningKey))
+	assert.Equal(t, "it only uses 1 distinct characters", SigningKeyWeakness(strings.Repeat("a", 40)))
+	assert.NotEqual(t, "", SigningKeyWeakness(strings.Repeat("secret", 6)))
+}
@@ -1,17 +1,33 @@
 package contoller
 
 import (
+	"context"
 	routing "github.com/go-ozzo/ozzo-routing/v2"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/go-ozzo/ozzo-dbx"
 	"pkg/dbcontext"
 	"pkg/log"
+	"pkg/module"
+	"pkg/jsonbody"
+	"pkg/jsontime"
+	"pkg/password"
+	"crypto/subtle"
 	"encoding/json"
+	"net"
+	"net/http"
+	"pkg/redirect"
+	"pkg/throttle"
+	"pkg/webhook"
+	"local/errors"
+	"strings"
+	"time"
 )
 
 type requestData struct{
 	LoginName string `json:"loginname"`
 	Password string `json:"password"`
+	// RedirectURI is where web clients go after logging in. It must be on the redirect allowlist.
+	RedirectURI string `json:"redirect_uri"`
 }
 
 type responseData struct{
@@ -19,6 +35,7 @@ type responseData struct{
 	Department string `json:"department"`
 	Purview string `json:"purview"`
 	Logname string `json:"logname"`
+	RedirectURI string `json:"redirect_uri,omitempty"`
 }
 
 type DB_Login struct {
@@ -26,59 +43,195 @@ type DB_Login struct {
 	Department string `db:"department"`
 	Purview string `db:"purview"`
 	Logname string `db:"logname"`
+	Logpassword string `db:"logpassword"`
 }
 
 type ErrorResponseData struct{
 	Error string `json:"error"`
+	// Code is the machine-readable error code, e.g. errors.CodeInvalidCredentials.
+	Code string `json:"code"`
 }
 
-func RegisterLoginHandlers(rg *routing.RouteGroup, logger log.Logger, db *dbcontext.DB) {
-	rg.Post("/login", loginHandler(logger, db))
+// statusClientClosedRequest is the status recorded for requests whose client has gone away.
+const statusClientClosedRequest = 499
+
+// DefaultLoginTimeout is the default time limit of the login query.
+const DefaultLoginTimeout = 5 * time.Second
+
+// LoginOptions represents the options of the login endpoint.
+type LoginOptions struct {
+	// Throttle delays login attempts after consecutive failures for the same login name and client IP.
+	// Attempts are not delayed if nil.
+	Throttle *throttle.Throttle
+	// ClientIP returns the IP address of the client sending the request. The remote address is used if nil.
+	ClientIP func(*http.Request) string
+	// RedirectHosts are the hosts a redirect_uri may point to. Only relative redirects are allowed if empty.
+	RedirectHosts []string
+	// Webhook is notified of each login attempt with a LoginEvent. No events are sent if nil.
+	Webhook *webhook.Notifier
+	// EmailLogin lets users log in with their email address, stored in the email column of the loguser table,
+	// as well as with their login name. The users are identified by login name only if false.
+	EmailLogin bool
+}
+
+// LoginEvent is sent to the login webhook for each login attempt, successful or not.
+type LoginEvent struct {
+	Success bool `json:"success"`
+	// User is the login name or email address the client tried to log in with.
+	User string        `json:"user"`
+	IP   string        `json:"ip"`
+	Time jsontime.Time `json:"time"`
 }
 
-func loginHandler(logger log.Logger, db *dbcontext.DB) routing.Handler {
+// RegisterLoginHandlers registers the login endpoint. timeout limits the login query; there is no limit if it is 0.
+// The endpoint logs with the request-scoped logger set up by log.Handler.
+func RegisterLoginHandlers(rg *routing.RouteGroup, db *dbcontext.DB, timeout time.Duration, options ...LoginOptions) {
+	var opt LoginOptions
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	if opt.ClientIP == nil {
+		opt.ClientIP = func(req *http.Request) string {
+			host, _, _ := net.SplitHostPort(req.RemoteAddr)
+			return host
+		}
+	}
+	rg.Post("/login", loginHandler(db, timeout, opt))
+}
+
+func loginHandler(db *dbcontext.DB, timeout time.Duration, opt LoginOptions) routing.Handler {
+	redirects := redirect.NewAllowlist(opt.RedirectHosts...)
 	return func(c *routing.Context) error {
 		rd := requestData{}
-		if err := c.Read(&rd); err != nil {
-			logger.With(c.Request.Context()).Errorf("invalid request: %v", err)
+		if err := jsonbody.Read(c, &rd, jsonbody.Options{}); err != nil {
+			log.FromContext(c.Request.Context()).Errorf("invalid request: %v", err)
 			return err
 		}
+		if rd.RedirectURI != "" {
+			if err := redirects.Validate(rd.RedirectURI); err != nil {
+				log.FromContext(c.Request.Context()).Infof("rejected redirect_uri %q", rd.RedirectURI)
+				return errors.BadRequest("The redirect_uri is not allowed.")
+			}
+		}
+
+		// don't query the database for a client that has already disconnected
+		ctx := c.Request.Context()
+		if ctx.Err() != nil {
+			return loginCancelled(c, ctx)
+		}
+
+		// slow down repeated failures; the delay ends early if the client goes away or the request times out.
+		// the attempts of a user are serialized, so that concurrent ones cannot share a delay.
+		// the identifier is normalized, so that changing its case does not dodge the delay.
+		throttleKey := strings.ToLower(strings.TrimSpace(rd.LoginName)) + "|" + opt.ClientIP(c.Request)
+		if opt.Throttle != nil {
+			release, err := opt.Throttle.Acquire(ctx, throttleKey)
+			if err != nil {
+				return loginCancelled(c, ctx)
+			}
+			defer release()
+		}
 
-		q := db.DB().Select("id", "department", "purview", "logname").
-			From("loguser").
-			Where(dbx.HashExp{"logname": rd.LoginName, "logpassword": rd.Password}).
-			OrderBy("id")
+		// the timeout only limits the query, not the delay imposed on the attempt
+		queryCtx := ctx
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			queryCtx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
 
-		var users [] DB_Login
-		err := q.All(&users)
+		// the user is selected by login name, or else by email address, so that nobody can shadow an account
+		// by taking its login name as email address. Then the password is verified against the stored hash.
+		user, found, err := findUser(queryCtx, db, dbx.HashExp{"logname": rd.LoginName})
+		if err == nil && !found && opt.EmailLogin {
+			user, found, err = findUser(queryCtx, db, dbx.HashExp{"email": rd.LoginName})
+		}
 		if err != nil {
-			logger.With(c.Request.Context()).Errorf("database query error: %v", err)
+			if ctx.Err() != nil {
+				return loginCancelled(c, ctx)
+			}
+			log.FromContext(c.Request.Context()).Errorf("database query error: %v", err)
 			return err
 		}
 
-		var usersNum = len(users)
-		if usersNum <= 0 {
-			logger.With(c.Request.Context()).Errorf("database query error: %v", err)
+		// imported users have hashed passwords, older rows still store them in plain text
+		if !found || !checkPassword(user.Logpassword, rd.Password) {
+			if opt.Throttle != nil {
+				opt.Throttle.Fail(throttleKey)
+			}
+			log.FromContext(c.Request.Context()).Infof("login failed for %q", rd.LoginName)
+			opt.Webhook.Send(LoginEvent{false, rd.LoginName, opt.ClientIP(c.Request), jsontime.Now()})
 			rp := &ErrorResponseData{}
 			rp.Error = "Loginname or password not correct."
+			rp.Code = errors.CodeInvalidCredentials
 			b, err := json.Marshal(rp)
 			if err != nil {
-				logger.With(c.Request.Context()).Errorf("response format to json error: %v", err)
+				log.FromContext(c.Request.Context()).Errorf("response format to json error: %v", err)
 				return err
 			}
 			return c.Write(string(b))
 		}
 
+		if opt.Throttle != nil {
+			opt.Throttle.Reset(throttleKey)
+		}
+		opt.Webhook.Send(LoginEvent{true, rd.LoginName, opt.ClientIP(c.Request), jsontime.Now()})
 		rp := &responseData{}
-		rp.Id = users[0].Id
-		rp.Department = users[0].Department
-		rp.Purview = users[0].Purview
-		rp.Logname = users[0].Logname
+		rp.Id = user.Id
+		rp.Department = user.Department
+		rp.Purview = user.Purview
+		rp.Logname = user.Logname
+		rp.RedirectURI = rd.RedirectURI
 		b, err := json.Marshal(rp)
 		if err != nil {
-			logger.With(c.Request.Context()).Errorf("response format to json error: %v", err)
+			log.FromContext(c.Request.Context()).Errorf("response format to json error: %v", err)
 			return err
 		}
 		return c.Write(string(b))
     }
-}
\ No newline at end of file
+}
+
+// findUser returns the first user matching the condition, and whether there is one.
+func findUser(ctx context.Context, db *dbcontext.DB, where dbx.Expression) (DB_Login, bool, error) {
+	q := db.DB().Select("id", "department", "purview", "logname", "logpassword").
+		From("loguser").
+		Where(where).
+		OrderBy("id").
+		Limit(1).
+		WithContext(ctx)
+
+	var users []DB_Login
+	if err := db.All(q, &users); err != nil || len(users) == 0 {
+		return DB_Login{}, false, err
+	}
+	return users[0], true, nil
+}
+
+// checkPassword reports whether the given password matches the stored one, which is either a hash
+// or, for accounts created before passwords were hashed, the plain text.
+func checkPassword(stored, given string) bool {
+	if password.IsHash(stored) {
+		return password.Verify(stored, given)
+	}
+	return subtle.ConstantTimeCompare([]byte(stored), []byte(given)) == 1
+}
+
+// loginCancelled ends a login request whose context has been cancelled, typically because the client
+// has disconnected. It is logged as such rather than as an error, and nothing is sent back.
+// A request whose deadline has passed fails with the context error, which the deadline middleware answers with 504.
+func loginCancelled(c *routing.Context, ctx context.Context) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		log.FromContext(ctx).Infof("login request timed out: %v", ctx.Err())
+		return ctx.Err()
+	}
+	log.FromContext(ctx).Infof("login request cancelled: %v", ctx.Err())
+	c.Response.WriteHeader(statusClientClosedRequest)
+	return nil
+}
+
+// NewLoginModule returns the login endpoint as a module.
+func NewLoginModule(db *dbcontext.DB, timeout time.Duration, options ...LoginOptions) module.Module {
+	return module.New("", func(rg *routing.RouteGroup) {
+		RegisterLoginHandlers(rg, db, timeout, options...)
+	})
+}
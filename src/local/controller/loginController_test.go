@@ -0,0 +1,342 @@
+package contoller
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+	"local/errors"
+	"net/http"
+	"net/http/httptest"
+	"pkg/dbcontext"
+	"pkg/dbtest"
+	"pkg/log"
+	"pkg/password"
+	"pkg/throttle"
+	"pkg/webhook"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLoginHandler(t *testing.T) {
+	logger, entries := log.NewForTest()
+	db, server := dbtest.Open("mysql", func(query string, args []driver.Value) (*dbtest.Result, error) {
+		return &dbtest.Result{
+			Columns: []string{"id", "department", "purview", "logname", "logpassword"},
+			Rows:    [][]driver.Value{{int64(1), "sales", "user", "alice", "secret"}},
+		}, nil
+	})
+	router := routing.New()
+	router.Use(log.Handler(logger))
+	RegisterLoginHandlers(router.Group(""), dbcontext.New(db), time.Second)
+
+	t.Run("normal request", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "/login", strings.NewReader(`{"loginname":"alice","password":"secret"}`))
+		req.Header.Set("Content-Type", "application/json")
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.JSONEq(t, `{"id":1,"department":"sales","purview":"user","logname":"alice"}`, res.Body.String())
+		assert.Equal(t, 1, len(server.Statements()))
+	})
+
+	t.Run("invalid credentials", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "/login", strings.NewReader(`{"loginname":"alice","password":"wrong"}`))
+		req.Header.Set("Content-Type", "application/json")
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		assert.JSONEq(t, `{"error":"Loginname or password not correct.","code":"AUTH_INVALID_CREDENTIALS"}`, res.Body.String())
+		assert.Equal(t, 2, len(server.Statements()))
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		req, _ := http.NewRequest("POST", "/login", strings.NewReader(`{"loginname":"alice","password":"secret"}`))
+		req.Header.Set("Content-Type", "application/json")
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req.WithContext(ctx))
+		assert.Equal(t, statusClientClosedRequest, res.Code)
+		assert.Empty(t, res.Body.String())
+		// the database is not queried
+		assert.Equal(t, 2, len(server.Statements()))
+		logs := entries.FilterMessageSnippet("cancelled").All()
+		if assert.Equal(t, 1, len(logs)) {
+			assert.Equal(t, zapcore.InfoLevel, logs[0].Level)
+			assert.Equal(t, "/login", logs[0].ContextMap()["route"])
+		}
+		for _, entry := range entries.All() {
+			assert.NotEqual(t, zapcore.ErrorLevel, entry.Level)
+		}
+	})
+
+	t.Run("expired deadline", func(t *testing.T) {
+		ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+		defer cancel()
+		req, _ := http.NewRequest("POST", "/login", strings.NewReader(`{"loginname":"alice","password":"secret"}`))
+		req.Header.Set("Content-Type", "application/json")
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req.WithContext(ctx))
+		// the error is left to the deadline middleware
+		assert.NotEqual(t, statusClientClosedRequest, res.Code)
+		assert.Equal(t, 2, len(server.Statements()))
+		assert.Equal(t, 1, entries.FilterMessageSnippet("timed out").Len())
+	})
+}
+
+func TestLoginHandler_throttle(t *testing.T) {
+	logger, _ := log.NewForTest()
+	db, _ := dbtest.Open("mysql", func(query string, args []driver.Value) (*dbtest.Result, error) {
+		return &dbtest.Result{
+			Columns: []string{"id", "department", "purview", "logname", "logpassword"},
+			Rows:    [][]driver.Value{{int64(1), "sales", "user", "alice", "secret"}},
+		}, nil
+	})
+	router := routing.New()
+	router.Use(log.Handler(logger))
+	RegisterLoginHandlers(router.Group(""), dbcontext.New(db), time.Second, LoginOptions{
+		Throttle: throttle.New(20*time.Millisecond, time.Second),
+	})
+	login := func(password string) (string, time.Duration) {
+		req, _ := http.NewRequest("POST", "/login", strings.NewReader(`{"loginname":"alice","password":"`+password+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = "10.0.0.1:12345"
+		res := httptest.NewRecorder()
+		start := time.Now()
+		router.ServeHTTP(res, req)
+		return res.Body.String(), time.Since(start)
+	}
+
+	// consecutive failures increase the delay
+	var delays []time.Duration
+	for i := 0; i < 3; i++ {
+		body, delay := login("wrong")
+		assert.Contains(t, body, "not correct")
+		delays = append(delays, delay)
+	}
+	assert.True(t, delays[0] < 20*time.Millisecond, delays[0].String())
+	assert.True(t, delays[1] >= 20*time.Millisecond, delays[1].String())
+	assert.True(t, delays[2] >= 40*time.Millisecond, delays[2].String())
+
+	// a successful login is still delayed, then resets the delay
+	body, delay := login("secret")
+	assert.Contains(t, body, `"logname":"alice"`)
+	assert.True(t, delay >= 80*time.Millisecond, delay.String())
+	_, delay = login("wrong")
+	assert.True(t, delay < 20*time.Millisecond, delay.String())
+}
+
+func TestLoginHandler_throttleTimeout(t *testing.T) {
+	logger, _ := log.NewForTest()
+	db, _ := dbtest.Open("mysql", func(query string, args []driver.Value) (*dbtest.Result, error) {
+		return &dbtest.Result{
+			Columns: []string{"id", "department", "purview", "logname", "logpassword"},
+			Rows:    [][]driver.Value{{int64(1), "sales", "user", "alice", "secret"}},
+		}, nil
+	})
+	router := routing.New()
+	router.Use(log.Handler(logger))
+	// the delays exceed the timeout of the login query
+	RegisterLoginHandlers(router.Group(""), dbcontext.New(db), 10*time.Millisecond, LoginOptions{
+		Throttle: throttle.New(30*time.Millisecond, time.Second),
+	})
+	login := func(password string) (int, string) {
+		req, _ := http.NewRequest("POST", "/login", strings.NewReader(`{"loginname":"alice","password":"`+password+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = "10.0.0.1:12345"
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		return res.Code, res.Body.String()
+	}
+
+	login("wrong")
+	login("wrong")
+	code, body := login("secret")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `"logname":"alice"`)
+
+	// concurrent attempts do not share a delay
+	login("wrong")
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			login("wrong")
+		}()
+	}
+	wg.Wait()
+	// 30ms, then 60ms, then 120ms
+	assert.True(t, time.Since(start) >= 210*time.Millisecond, time.Since(start).String())
+}
+
+func TestLoginHandler_redirect(t *testing.T) {
+	logger, _ := log.NewForTest()
+	db, server := dbtest.Open("mysql", func(query string, args []driver.Value) (*dbtest.Result, error) {
+		return &dbtest.Result{
+			Columns: []string{"id", "department", "purview", "logname", "logpassword"},
+			Rows:    [][]driver.Value{{int64(1), "sales", "user", "alice", "secret"}},
+		}, nil
+	})
+	router := routing.New()
+	router.Use(errors.Handler(logger))
+	RegisterLoginHandlers(router.Group(""), dbcontext.New(db), time.Second, LoginOptions{
+		RedirectHosts: []string{"app.example.com"},
+	})
+	login := func(redirectURI string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/login", strings.NewReader(`{"loginname":"alice","password":"secret","redirect_uri":"`+redirectURI+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		return res
+	}
+
+	res := login("https://app.example.com/home")
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Contains(t, res.Body.String(), `"redirect_uri":"https://app.example.com/home"`)
+
+	res = login("https://evil.com/home")
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+	assert.Contains(t, res.Body.String(), "redirect_uri")
+	// the login is not attempted
+	assert.Equal(t, 1, len(server.Statements()))
+}
+
+func TestLoginHandler_email(t *testing.T) {
+	logger, _ := log.NewForTest()
+	hash, _ := password.Hash("secret")
+	db, server := dbtest.Open("mysql", func(query string, args []driver.Value) (*dbtest.Result, error) {
+		result := &dbtest.Result{Columns: []string{"id", "department", "purview", "logname", "logpassword"}}
+		emails := strings.Contains(query, "`email`")
+		for _, arg := range args {
+			if arg == "alice" || emails && arg == "alice@example.com" {
+				result.Rows = [][]driver.Value{{int64(1), "sales", "user", "alice", hash}}
+			}
+		}
+		return result, nil
+	})
+	login := func(router *routing.Router, name string) string {
+		req, _ := http.NewRequest("POST", "/login", strings.NewReader(`{"loginname":"`+name+`","password":"secret"}`))
+		req.Header.Set("Content-Type", "application/json")
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		return res.Body.String()
+	}
+	alice := `{"id":1,"department":"sales","purview":"user","logname":"alice"}`
+
+	router := routing.New()
+	router.Use(log.Handler(logger))
+	RegisterLoginHandlers(router.Group(""), dbcontext.New(db), time.Second, LoginOptions{EmailLogin: true})
+	assert.JSONEq(t, alice, login(router, "alice"))
+	assert.JSONEq(t, alice, login(router, "alice@example.com"))
+	assert.Contains(t, login(router, "bob@example.com"), errors.CodeInvalidCredentials)
+	assert.Equal(t, []string{
+		"SELECT `id`, `department`, `purview`, `logname`, `logpassword` FROM `loguser` WHERE `logname`=? ORDER BY `id` LIMIT 1",
+		"SELECT `id`, `department`, `purview`, `logname`, `logpassword` FROM `loguser` WHERE `logname`=? ORDER BY `id` LIMIT 1",
+		"SELECT `id`, `department`, `purview`, `logname`, `logpassword` FROM `loguser` WHERE `email`=? ORDER BY `id` LIMIT 1",
+	}, server.SQL()[:3])
+
+	// the users are identified by login name only by default
+	router = routing.New()
+	router.Use(log.Handler(logger))
+	RegisterLoginHandlers(router.Group(""), dbcontext.New(db), time.Second)
+	assert.JSONEq(t, alice, login(router, "alice"))
+	assert.Contains(t, login(router, "alice@example.com"), errors.CodeInvalidCredentials)
+}
+
+func TestLoginHandler_emailCollision(t *testing.T) {
+	logger, _ := log.NewForTest()
+	// mallory and eve, created first, took the login name of bob as email address
+	db, server := dbtest.Open("mysql", func(query string, args []driver.Value) (*dbtest.Result, error) {
+		result := &dbtest.Result{Columns: []string{"id", "department", "purview", "logname", "logpassword"}}
+		if strings.Contains(query, "`email`") {
+			result.Rows = [][]driver.Value{{int64(1), "sales", "admin", "mallory", "secret"}, {int64(2), "sales", "admin", "eve", "secret"}}
+		} else {
+			result.Rows = [][]driver.Value{{int64(3), "it", "user", "bob", "secret"}}
+		}
+		return result, nil
+	})
+	router := routing.New()
+	router.Use(log.Handler(logger))
+	RegisterLoginHandlers(router.Group(""), dbcontext.New(db), time.Second, LoginOptions{EmailLogin: true})
+
+	// the user whose login name matches is logged in
+	req, _ := http.NewRequest("POST", "/login", strings.NewReader(`{"loginname":"bob","password":"secret"}`))
+	req.Header.Set("Content-Type", "application/json")
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	assert.JSONEq(t, `{"id":3,"department":"it","purview":"user","logname":"bob"}`, res.Body.String())
+	assert.Equal(t, 1, len(server.SQL()), "the email addresses are not looked up")
+}
+
+func TestLoginHandler_webhook(t *testing.T) {
+	logger, entries := log.NewForTest()
+	db, _ := dbtest.Open("mysql", func(query string, args []driver.Value) (*dbtest.Result, error) {
+		return &dbtest.Result{
+			Columns: []string{"id", "department", "purview", "logname", "logpassword"},
+			Rows:    [][]driver.Value{{int64(1), "sales", "user", "alice", "secret"}},
+		}, nil
+	})
+	login := func(router *routing.Router, password string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/login", strings.NewReader(`{"loginname":"alice","password":"`+password+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = "10.0.0.1:12345"
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		return res
+	}
+
+	t.Run("events", func(t *testing.T) {
+		var mu sync.Mutex
+		var events []LoginEvent
+		hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var event LoginEvent
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&event))
+			mu.Lock()
+			events = append(events, event)
+			mu.Unlock()
+		}))
+		defer hook.Close()
+		notifier := webhook.New(hook.URL, logger)
+		router := routing.New()
+		RegisterLoginHandlers(router.Group(""), dbcontext.New(db), time.Second, LoginOptions{Webhook: notifier})
+
+		start := time.Now().Add(-time.Second)
+		assert.Equal(t, http.StatusOK, login(router, "secret").Code)
+		assert.Equal(t, http.StatusOK, login(router, "wrong").Code)
+		assert.Nil(t, notifier.Close(context.Background()))
+
+		if assert.Equal(t, 2, len(events)) {
+			// the events are delivered concurrently
+			sort.Slice(events, func(i, j int) bool { return events[i].Success })
+			for i, success := range []bool{true, false} {
+				assert.Equal(t, success, events[i].Success)
+				assert.Equal(t, "alice", events[i].User)
+				assert.Equal(t, "10.0.0.1", events[i].IP)
+				assert.True(t, events[i].Time.After(start), events[i].Time.String())
+			}
+		}
+	})
+
+	t.Run("failing webhook", func(t *testing.T) {
+		hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer hook.Close()
+		notifier := webhook.New(hook.URL, logger, webhook.Options{Retries: 1, Backoff: time.Millisecond})
+		router := routing.New()
+		RegisterLoginHandlers(router.Group(""), dbcontext.New(db), time.Second, LoginOptions{Webhook: notifier})
+
+		res := login(router, "secret")
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Contains(t, res.Body.String(), `"logname":"alice"`)
+		assert.Nil(t, notifier.Close(context.Background()))
+		assert.Equal(t, 1, entries.FilterMessageSnippet("failed to deliver webhook event").Len())
+	})
+}
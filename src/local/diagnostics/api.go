@@ -0,0 +1,73 @@
+package diagnostics
+
+import (
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"net/http"
+	"pkg/module"
+	"pkg/realip"
+	"pkg/routeinfo"
+	"strings"
+)
+
+// redactedHeaders lists the request headers whose values are not echoed back.
+var redactedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Cookie":              true,
+	"Proxy-Authorization": true,
+	"X-Api-Key":           true,
+}
+
+// RegisterHandlers sets up the routing of the HTTP handlers.
+// These handlers expose request details and should only be registered in debug mode.
+func RegisterHandlers(r *routing.RouteGroup, resolver *realip.Resolver) {
+	r.Get("/whoami", whoami(resolver))
+}
+
+// whoamiResponse describes the request as seen by the server.
+type whoamiResponse struct {
+	ClientIP   string            `json:"client_ip"`
+	RemoteAddr string            `json:"remote_addr"`
+	Scheme     string            `json:"scheme"`
+	Host       string            `json:"host"`
+	Method     string            `json:"method"`
+	Path       string            `json:"path"`
+	Route      string            `json:"route"`
+	Headers    map[string]string `json:"headers"`
+}
+
+// whoami returns a handler that echoes the metadata of the request.
+func whoami(resolver *realip.Resolver) routing.Handler {
+	return func(c *routing.Context) error {
+		req := c.Request
+		return c.Write(whoamiResponse{
+			ClientIP:   resolver.ClientIP(req),
+			RemoteAddr: req.RemoteAddr,
+			Scheme:     resolver.Scheme(req),
+			Host:       resolver.Host(req),
+			Method:     req.Method,
+			Path:       req.URL.Path,
+			Route:      routeinfo.Pattern(c),
+			Headers:    redactHeaders(req.Header),
+		})
+	}
+}
+
+// redactHeaders flattens the given headers, hiding the values of sensitive ones.
+func redactHeaders(header http.Header) map[string]string {
+	result := make(map[string]string, len(header))
+	for name, values := range header {
+		if redactedHeaders[http.CanonicalHeaderKey(name)] {
+			result[name] = "***"
+		} else {
+			result[name] = strings.Join(values, ", ")
+		}
+	}
+	return result
+}
+
+// NewModule returns the diagnostic endpoints as a module.
+func NewModule(resolver *realip.Resolver) module.Module {
+	return module.New("", func(rg *routing.RouteGroup) {
+		RegisterHandlers(rg, resolver)
+	})
+}
@@ -0,0 +1,47 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"local/test"
+	"net/http"
+	"net/http/httptest"
+	"pkg/log"
+	"pkg/realip"
+	"testing"
+)
+
+func TestAPI(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+	resolver, _ := realip.New([]string{"10.0.0.1"})
+	RegisterHandlers(router.Group("/v1"), resolver)
+
+	tests := []struct {
+		name, remoteAddr, wantIP, wantScheme string
+	}{
+		{"trusted proxy", "10.0.0.1:1234", "5.6.7.8", "https"},
+		{"untrusted proxy", "1.2.3.4:1234", "1.2.3.4", "http"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/v1/whoami", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set("X-Forwarded-For", "5.6.7.8")
+			req.Header.Set("X-Forwarded-Proto", "https")
+			req.Header.Set("Authorization", "Bearer secret")
+			res := httptest.NewRecorder()
+			router.ServeHTTP(res, req)
+
+			assert.Equal(t, http.StatusOK, res.Code)
+			var data whoamiResponse
+			if assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &data)) {
+				assert.Equal(t, tt.wantIP, data.ClientIP)
+				assert.Equal(t, tt.wantScheme, data.Scheme)
+				assert.Equal(t, "/v1/whoami", data.Route)
+				assert.Equal(t, "***", data.Headers["Authorization"])
+				assert.Equal(t, "5.6.7.8", data.Headers["X-Forwarded-For"])
+			}
+		})
+	}
+}
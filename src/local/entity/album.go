@@ -1,13 +1,15 @@
 package entity
 
 import (
-	"time"
+	"pkg/jsontime"
 )
 
 // Album represents an album record.
 type Album struct {
-	ID        string    `json:"id"`
-	Name      string    `json:"name"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        string        `json:"id"`
+	Name      string        `json:"name"`
+	CreatedAt jsontime.Time `json:"created_at"`
+	UpdatedAt jsontime.Time `json:"updated_at"`
+	// Version is incremented on every update and is used for optimistic locking.
+	Version int `json:"version"`
 }
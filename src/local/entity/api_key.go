@@ -0,0 +1,22 @@
+package entity
+
+import (
+	"pkg/jsontime"
+)
+
+// APIKey represents an API key record. Only a hash of the key is stored.
+type APIKey struct {
+	ID     string `json:"id"`
+	UserID string `json:"user_id"`
+	Name   string `json:"name"`
+	// Prefix is the public part of the key, which identifies it.
+	Prefix string `json:"prefix"`
+	// Hash is the hex-encoded SHA-256 digest of the whole key.
+	Hash      string        `json:"-"`
+	CreatedAt jsontime.Time `json:"created_at"`
+}
+
+// TableName returns the name of the table storing API keys.
+func (k APIKey) TableName() string {
+	return "api_keys"
+}
@@ -0,0 +1,15 @@
+package entity
+
+// LoginUser represents a user account record which can be used to log in.
+type LoginUser struct {
+	ID          int    `json:"id"`
+	Logname     string `json:"logname"`
+	Logpassword string `json:"-"`
+	Department  string `json:"department"`
+	Purview     string `json:"purview"`
+}
+
+// TableName returns the name of the table storing user accounts.
+func (u LoginUser) TableName() string {
+	return "loguser"
+}
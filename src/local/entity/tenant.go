@@ -0,0 +1,6 @@
+package entity
+
+// Tenant represents a tenant, which owns the data accessed by its users.
+type Tenant struct {
+	ID string
+}
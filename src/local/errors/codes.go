@@ -0,0 +1,47 @@
+package errors
+
+import "net/http"
+
+// Machine-readable error codes carried by the "code" field of error responses.
+// Clients may switch on them instead of parsing the messages, which may change.
+const (
+	CodeInternal           = "INTERNAL_ERROR"
+	CodeNotFound           = "NOT_FOUND"
+	CodeUnauthorized       = "UNAUTHORIZED"
+	CodeForbidden          = "FORBIDDEN"
+	CodePreconditionFailed = "PRECONDITION_FAILED"
+	CodeServiceUnavailable = "SERVICE_UNAVAILABLE"
+	CodeBadRequest         = "BAD_REQUEST"
+	CodeInvalidInput       = "INVALID_INPUT"
+	CodeRateLimited        = "RATE_LIMITED"
+	CodeRequestTooLarge    = "REQUEST_TOO_LARGE"
+	// CodeInvalidCredentials is returned when a login name and password do not match.
+	CodeInvalidCredentials = "AUTH_INVALID_CREDENTIALS"
+	// CodeMaintenance is returned when a write is refused because the database is read-only for maintenance.
+	CodeMaintenance = "SERVICE_IN_MAINTENANCE"
+)
+
+// statusCodes maps HTTP statuses to the codes of the errors that have no specific code.
+var statusCodes = map[int]string{
+	http.StatusBadRequest:            CodeBadRequest,
+	http.StatusUnauthorized:          CodeUnauthorized,
+	http.StatusForbidden:             CodeForbidden,
+	http.StatusNotFound:              CodeNotFound,
+	http.StatusPreconditionFailed:    CodePreconditionFailed,
+	http.StatusRequestEntityTooLarge: CodeRequestTooLarge,
+	http.StatusTooManyRequests:       CodeRateLimited,
+	http.StatusInternalServerError:   CodeInternal,
+	http.StatusServiceUnavailable:    CodeServiceUnavailable,
+}
+
+// codeForStatus returns the error code of an HTTP status. Statuses without a specific code map to
+// CodeBadRequest if they are client errors and CodeInternal otherwise.
+func codeForStatus(status int) string {
+	if code, ok := statusCodes[status]; ok {
+		return code
+	}
+	if status >= 400 && status < 500 {
+		return CodeBadRequest
+	}
+	return CodeInternal
+}
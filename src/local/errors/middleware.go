@@ -5,14 +5,37 @@ import (
 	"errors"
 	"fmt"
 	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/go-ozzo/ozzo-routing/v2/content"
 	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"mime"
 	"net/http"
-	"runtime/debug"
+	"pkg/dbcontext"
 	"pkg/log"
+	"runtime/debug"
+	"strings"
 )
 
+// Options represents the options of the error handling middleware.
+type Options struct {
+	// ProblemJSON specifies whether errors are written as RFC 7807 problem details
+	// with the "application/problem+json" content type instead of ErrorResponse.
+	ProblemJSON bool
+	// Recorder records the error responses if set.
+	Recorder *Recorder
+	// DataWriter writes the errors of the requests whose negotiated response format is not JSON, such as CSV,
+	// so that errors are always sent in a JSON format. Defaults to content.JSONDataWriter.
+	DataWriter routing.DataWriter
+}
+
 // Handler creates a middleware that handles panics and errors encountered during HTTP request processing.
-func Handler(logger log.Logger) routing.Handler {
+func Handler(logger log.Logger, options ...Options) routing.Handler {
+	var opt Options
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	if opt.DataWriter == nil {
+		opt.DataWriter = &content.JSONDataWriter{}
+	}
 	return func(c *routing.Context) (err error) {
 		defer func() {
 			l := logger.With(c.Request.Context())
@@ -27,11 +50,22 @@ func Handler(logger log.Logger) routing.Handler {
 
 			if err != nil {
 				res := buildErrorResponse(err)
+				if opt.Recorder != nil {
+					opt.Recorder.record(c, res)
+				}
 				if res.StatusCode() == http.StatusInternalServerError {
 					l.Errorf("encountered internal server error: %v", err)
 				}
-				c.Response.WriteHeader(res.StatusCode())
-				if err = c.Write(res); err != nil {
+				if opt.ProblemJSON {
+					err = writeProblem(c, res)
+				} else {
+					if !isJSON(c.Response.Header().Get("Content-Type")) {
+						c.SetDataWriter(opt.DataWriter)
+					}
+					c.Response.WriteHeader(res.StatusCode())
+					err = c.Write(res)
+				}
+				if err != nil {
 					l.Errorf("failed writing error response: %v", err)
 				}
 				c.Abort() // skip any pending handlers since an error has occurred
@@ -42,11 +76,22 @@ func Handler(logger log.Logger) routing.Handler {
 	}
 }
 
+// isJSON reports whether the content type is JSON or a type based on JSON, such as application/problem+json.
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
+
 // buildErrorResponse builds an error response from an error.
+// Error responses without a code get the code of their HTTP status.
 func buildErrorResponse(err error) ErrorResponse {
 	switch err.(type) {
 	case ErrorResponse:
-		return err.(ErrorResponse)
+		res := err.(ErrorResponse)
+		if res.Code == "" {
+			res.Code = codeForStatus(res.Status)
+		}
+		return res
 	case validation.Errors:
 		return InvalidInput(err.(validation.Errors))
 	case routing.HTTPError:
@@ -56,6 +101,7 @@ func buildErrorResponse(err error) ErrorResponse {
 		default:
 			return ErrorResponse{
 				Status:  err.(routing.HTTPError).StatusCode(),
+				Code:    codeForStatus(err.(routing.HTTPError).StatusCode()),
 				Message: err.Error(),
 			}
 		}
@@ -64,5 +110,9 @@ func buildErrorResponse(err error) ErrorResponse {
 	if errors.Is(err, sql.ErrNoRows) {
 		return NotFound("")
 	}
+	// the database refuses writes during maintenance
+	if dbcontext.IsReadOnly(err) {
+		return Maintenance("")
+	}
 	return InternalServerError("")
 }
@@ -5,9 +5,12 @@ import (
 	"fmt"
 	routing "github.com/go-ozzo/ozzo-routing/v2"
 	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-sql-driver/mysql"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"net/http/httptest"
+	"pkg/log"
+	"pkg/ratelimit"
 	"testing"
 )
 
@@ -49,6 +52,78 @@ func TestHandler(t *testing.T) {
 	})
 }
 
+func TestHandler_problemJSON(t *testing.T) {
+	logger, _ := log.NewForTest()
+	handler := Handler(logger, Options{ProblemJSON: true})
+	ctx, res := buildContext(handler, handlerHTTPError)
+	assert.Nil(t, ctx.Next())
+	assert.Equal(t, http.StatusNotFound, res.Code)
+	assert.Equal(t, ProblemContentType, res.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"type":"about:blank","title":"Not Found","status":404,"detail":"The requested resource was not found.","instance":"/users","code":"NOT_FOUND"}`, res.Body.String())
+
+	ctx, res = buildContext(handler, func(c *routing.Context) error {
+		return validation.Errors{"name": fmt.Errorf("is required")}
+	})
+	assert.Nil(t, ctx.Next())
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+	assert.JSONEq(t, `{"type":"about:blank","title":"Bad Request","status":400,"detail":"There is some problem with the data you submitted.","instance":"/users","code":"INVALID_INPUT","details":[{"field":"name","error":"is required"}]}`, res.Body.String())
+
+	// the requests rejected by the rate limiter are problems too
+	limiter := ratelimit.Handler(ratelimit.Options{Anonymous: 1, Error: func(retryAfter int) error {
+		return TooManyRequests(fmt.Sprintf("Too many requests. Please retry in %v seconds.", retryAfter))
+	}})
+	assert.Nil(t, limiter(routing.NewContext(httptest.NewRecorder(), &http.Request{RemoteAddr: "10.0.0.1:1000", Header: http.Header{}})))
+	ctx, res = buildContext(handler, limiter, handlerOK)
+	ctx.Request.RemoteAddr = "10.0.0.1:1000"
+	assert.Nil(t, ctx.Next())
+	assert.Equal(t, http.StatusTooManyRequests, res.Code)
+	assert.Equal(t, ProblemContentType, res.Header().Get("Content-Type"))
+	assert.Equal(t, "60", res.Header().Get("Retry-After"))
+	assert.Contains(t, res.Body.String(), `"code":"RATE_LIMITED"`)
+}
+
+func TestHandler_contentType(t *testing.T) {
+	logger, _ := log.NewForTest()
+	csv := func(c *routing.Context) error {
+		// the client negotiated CSV
+		c.SetDataWriter(stubWriter{"text/csv"})
+		return c.Next()
+	}
+
+	ctx, res := buildContext(Handler(logger), csv, handlerHTTPError)
+	assert.Nil(t, ctx.Next())
+	assert.Equal(t, http.StatusNotFound, res.Code)
+	assert.Equal(t, "application/json", res.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"status":404,"code":"NOT_FOUND","message":"The requested resource was not found."}`, res.Body.String())
+
+	ctx, res = buildContext(Handler(logger, Options{ProblemJSON: true}), csv, handlerHTTPError)
+	assert.Nil(t, ctx.Next())
+	assert.Equal(t, ProblemContentType, res.Header().Get("Content-Type"))
+	assert.Contains(t, res.Body.String(), `"code":"NOT_FOUND"`)
+
+	// the negotiated JSON writer is kept
+	ctx, res = buildContext(Handler(logger), func(c *routing.Context) error {
+		c.SetDataWriter(stubWriter{"application/json; charset=UTF-8"})
+		return c.Next()
+	}, handlerHTTPError)
+	assert.Nil(t, ctx.Next())
+	assert.Equal(t, "stub", res.Body.String())
+}
+
+// stubWriter stands for the data writer of a negotiated content type.
+type stubWriter struct {
+	contentType string
+}
+
+func (w stubWriter) SetHeader(res http.ResponseWriter) {
+	res.Header().Set("Content-Type", w.contentType)
+}
+
+func (w stubWriter) Write(res http.ResponseWriter, data interface{}) error {
+	_, err := res.Write([]byte("stub"))
+	return err
+}
+
 func Test_buildErrorResponse(t *testing.T) {
 	res := NotFound("")
 	assert.Equal(t, res, buildErrorResponse(res))
@@ -61,12 +136,24 @@ func Test_buildErrorResponse(t *testing.T) {
 
 	res = buildErrorResponse(routing.NewHTTPError(http.StatusForbidden))
 	assert.Equal(t, http.StatusForbidden, res.Status)
+	assert.Equal(t, CodeForbidden, res.Code)
+
+	res = buildErrorResponse(routing.NewHTTPError(http.StatusRequestEntityTooLarge))
+	assert.Equal(t, CodeRequestTooLarge, res.Code)
+
+	res = buildErrorResponse(ErrorResponse{Status: http.StatusTooManyRequests, Message: "slow down"})
+	assert.Equal(t, CodeRateLimited, res.Code)
 
 	res = buildErrorResponse(sql.ErrNoRows)
 	assert.Equal(t, http.StatusNotFound, res.Status)
 
+	res = buildErrorResponse(fmt.Errorf("create album: %w", &mysql.MySQLError{Number: 1290, Message: "The MySQL server is running with the --read-only option so it cannot execute this statement"}))
+	assert.Equal(t, http.StatusServiceUnavailable, res.Status)
+	assert.Equal(t, CodeMaintenance, res.Code)
+
 	res = buildErrorResponse(fmt.Errorf("test"))
 	assert.Equal(t, http.StatusInternalServerError, res.Status)
+	assert.Equal(t, CodeInternal, res.Code)
 }
 
 func buildContext(handlers ...routing.Handler) (*routing.Context, *httptest.ResponseRecorder) {
@@ -0,0 +1,44 @@
+package errors
+
+import (
+	"encoding/json"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"net/http"
+)
+
+// ProblemContentType is the content type of RFC 7807 problem details.
+const ProblemContentType = "application/problem+json"
+
+// Problem represents an error in the format of RFC 7807 problem details.
+// Code is an extension member carrying the machine-readable error code.
+type Problem struct {
+	Type     string      `json:"type"`
+	Title    string      `json:"title"`
+	Status   int         `json:"status"`
+	Detail   string      `json:"detail"`
+	Instance string      `json:"instance"`
+	Code     string      `json:"code"`
+	Details  interface{} `json:"details,omitempty"`
+}
+
+// NewProblem converts an error response into problem details about the given request path.
+// The problem type is "about:blank" as no specific type is defined by the API,
+// and the title is the standard text of the HTTP status.
+func NewProblem(res ErrorResponse, instance string) Problem {
+	return Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(res.Status),
+		Status:   res.Status,
+		Detail:   res.Message,
+		Instance: instance,
+		Code:     res.Code,
+		Details:  res.Details,
+	}
+}
+
+// writeProblem writes an error response as problem details.
+func writeProblem(c *routing.Context, res ErrorResponse) error {
+	c.Response.Header().Set("Content-Type", ProblemContentType)
+	c.Response.WriteHeader(res.StatusCode())
+	return json.NewEncoder(c.Response).Encode(NewProblem(res, c.Request.URL.Path))
+}
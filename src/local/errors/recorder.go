@@ -0,0 +1,82 @@
+package errors
+
+import (
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"pkg/jsontime"
+	"sync"
+)
+
+// RecordedError is an error response recorded by a Recorder.
+type RecordedError struct {
+	Status  int           `json:"status"`
+	Method  string        `json:"method"`
+	Path    string        `json:"path"`
+	Message string        `json:"message"`
+	Time    jsontime.Time `json:"time"`
+}
+
+// Recorder keeps the most recent error responses in memory.
+// Only the information sent to clients is kept: the query string of the request and
+// the underlying errors of internal server errors are never recorded.
+type Recorder struct {
+	mu      sync.Mutex
+	entries []RecordedError
+	next    int
+	full    bool
+}
+
+// NewRecorder creates a recorder keeping the specified number of most recent errors.
+func NewRecorder(capacity int) *Recorder {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &Recorder{entries: make([]RecordedError, capacity)}
+}
+
+// Record adds an error to the recorder, evicting the oldest one if the recorder is full.
+func (r *Recorder) Record(e RecordedError) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries[r.next] = e
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// Errors returns the recorded errors from the oldest to the most recent.
+func (r *Recorder) Errors() []RecordedError {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.full {
+		return append([]RecordedError{}, r.entries[:r.next]...)
+	}
+	return append(append([]RecordedError{}, r.entries[r.next:]...), r.entries[:r.next]...)
+}
+
+// record records the error response of the request being processed.
+func (r *Recorder) record(c *routing.Context, res ErrorResponse) {
+	r.Record(RecordedError{
+		Status:  res.Status,
+		Method:  c.Request.Method,
+		Path:    c.Request.URL.Path,
+		Message: res.Message,
+		Time:    jsontime.Now(),
+	})
+}
+
+// RegisterRecorderHandlers registers the administrative endpoint GET /admin/errors listing the recent errors.
+// As the errors come from the requests of every user, adminHandler must only let administrators through,
+// such as the middleware returned by auth.AdminHandler.
+func RegisterRecorderHandlers(rg *routing.RouteGroup, recorder *Recorder, adminHandler routing.Handler) {
+	rg.Use(adminHandler)
+
+	// the following endpoints require a valid JWT of an administrator
+	rg.Get("/admin/errors", func(c *routing.Context) error {
+		return c.Write(struct {
+			Errors []RecordedError `json:"errors"`
+		}{recorder.Errors()})
+	})
+}
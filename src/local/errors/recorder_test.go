@@ -0,0 +1,87 @@
+package errors
+
+import (
+	"fmt"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/go-ozzo/ozzo-routing/v2/content"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"pkg/log"
+	"strings"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder(3)
+	assert.Empty(t, r.Errors())
+
+	r.Record(RecordedError{Path: "/1"})
+	r.Record(RecordedError{Path: "/2"})
+	assert.Equal(t, []RecordedError{{Path: "/1"}, {Path: "/2"}}, r.Errors())
+
+	// the oldest errors are evicted past capacity
+	r.Record(RecordedError{Path: "/3"})
+	r.Record(RecordedError{Path: "/4"})
+	r.Record(RecordedError{Path: "/5"})
+	assert.Equal(t, []RecordedError{{Path: "/3"}, {Path: "/4"}, {Path: "/5"}}, r.Errors())
+}
+
+func TestHandler_recorder(t *testing.T) {
+	logger, _ := log.NewForTest()
+	recorder := NewRecorder(10)
+	router := routing.New()
+	router.Use(Handler(logger, Options{Recorder: recorder}), content.TypeNegotiator(content.JSON))
+	router.Get("/ok", handlerOK)
+	router.Get("/missing", handlerHTTPError)
+	router.Get("/failing", func(c *routing.Context) error {
+		return fmt.Errorf("connection to db:3306 refused")
+	})
+	RegisterRecorderHandlers(router.Group(""), recorder, func(c *routing.Context) error {
+		switch c.Request.Header.Get("Authorization") {
+		case "TEST":
+			return nil
+		case "USER":
+			return Forbidden("")
+		}
+		return Unauthorized("")
+	})
+
+	for _, path := range []string{"/ok", "/missing?token=secret", "/failing"} {
+		req, _ := http.NewRequest("GET", "http://127.0.0.1"+path, nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	errs := recorder.Errors()
+	if assert.Equal(t, 2, len(errs)) {
+		assert.Equal(t, http.StatusNotFound, errs[0].Status)
+		assert.Equal(t, "GET", errs[0].Method)
+		assert.Equal(t, "/missing", errs[0].Path)
+		assert.NotZero(t, errs[0].Time)
+		assert.Equal(t, http.StatusInternalServerError, errs[1].Status)
+		assert.Equal(t, InternalServerError("").Message, errs[1].Message)
+	}
+
+	// the errors are listed by the admin endpoint
+	req, _ := http.NewRequest("GET", "http://127.0.0.1/admin/errors", nil)
+	req.Header.Set("Authorization", "TEST")
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.True(t, strings.HasPrefix(res.Body.String(), `{"errors":[{"status":404,"method":"GET","path":"/missing"`))
+	assert.NotContains(t, res.Body.String(), "secret")
+	assert.NotContains(t, res.Body.String(), "3306")
+
+	// the admin endpoint requires authentication
+	req, _ = http.NewRequest("GET", "http://127.0.0.1/admin/errors", nil)
+	res = httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+
+	// and is denied to the users who are not administrators
+	req, _ = http.NewRequest("GET", "http://127.0.0.1/admin/errors", nil)
+	req.Header.Set("Authorization", "USER")
+	res = httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusForbidden, res.Code)
+}
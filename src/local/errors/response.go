@@ -7,8 +7,10 @@ import (
 )
 
 // ErrorResponse is the response that represents an error.
+// Code is a machine-readable error code such as CodeNotFound.
 type ErrorResponse struct {
 	Status  int         `json:"status"`
+	Code    string      `json:"code"`
 	Message string      `json:"message"`
 	Details interface{} `json:"details,omitempty"`
 }
@@ -23,6 +25,12 @@ func (e ErrorResponse) StatusCode() int {
 	return e.Status
 }
 
+// WithCode returns a copy of the error response with the given error code.
+func (e ErrorResponse) WithCode(code string) ErrorResponse {
+	e.Code = code
+	return e
+}
+
 // InternalServerError creates a new error response representing an internal server error (HTTP 500)
 func InternalServerError(msg string) ErrorResponse {
 	if msg == "" {
@@ -30,6 +38,7 @@ func InternalServerError(msg string) ErrorResponse {
 	}
 	return ErrorResponse{
 		Status:  http.StatusInternalServerError,
+		Code:    CodeInternal,
 		Message: msg,
 	}
 }
@@ -41,6 +50,7 @@ func NotFound(msg string) ErrorResponse {
 	}
 	return ErrorResponse{
 		Status:  http.StatusNotFound,
+		Code:    CodeNotFound,
 		Message: msg,
 	}
 }
@@ -52,6 +62,7 @@ func Unauthorized(msg string) ErrorResponse {
 	}
 	return ErrorResponse{
 		Status:  http.StatusUnauthorized,
+		Code:    CodeUnauthorized,
 		Message: msg,
 	}
 }
@@ -63,6 +74,56 @@ func Forbidden(msg string) ErrorResponse {
 	}
 	return ErrorResponse{
 		Status:  http.StatusForbidden,
+		Code:    CodeForbidden,
+		Message: msg,
+	}
+}
+
+// PreconditionFailed creates a new error response representing a failed precondition such as a stale If-Match header (HTTP 412)
+func PreconditionFailed(msg string) ErrorResponse {
+	if msg == "" {
+		msg = "The resource has been modified since you last retrieved it."
+	}
+	return ErrorResponse{
+		Status:  http.StatusPreconditionFailed,
+		Code:    CodePreconditionFailed,
+		Message: msg,
+	}
+}
+
+// ServiceUnavailable creates a new error response representing a service that cannot handle requests yet (HTTP 503)
+func ServiceUnavailable(msg string) ErrorResponse {
+	if msg == "" {
+		msg = "The service is temporarily unavailable."
+	}
+	return ErrorResponse{
+		Status:  http.StatusServiceUnavailable,
+		Code:    CodeServiceUnavailable,
+		Message: msg,
+	}
+}
+
+// Maintenance creates a new error response representing a write refused while the service is in maintenance (HTTP 503).
+// Reads are still served.
+func Maintenance(msg string) ErrorResponse {
+	if msg == "" {
+		msg = "The service is in maintenance and cannot save changes. Please retry later."
+	}
+	return ErrorResponse{
+		Status:  http.StatusServiceUnavailable,
+		Code:    CodeMaintenance,
+		Message: msg,
+	}
+}
+
+// TooManyRequests creates a new error response representing a rate limited request (HTTP 429)
+func TooManyRequests(msg string) ErrorResponse {
+	if msg == "" {
+		msg = "Too many requests. Please retry later."
+	}
+	return ErrorResponse{
+		Status:  http.StatusTooManyRequests,
+		Code:    CodeRateLimited,
 		Message: msg,
 	}
 }
@@ -74,6 +135,7 @@ func BadRequest(msg string) ErrorResponse {
 	}
 	return ErrorResponse{
 		Status:  http.StatusBadRequest,
+		Code:    CodeBadRequest,
 		Message: msg,
 	}
 }
@@ -100,6 +162,7 @@ func InvalidInput(errs validation.Errors) ErrorResponse {
 
 	return ErrorResponse{
 		Status:  http.StatusBadRequest,
+		Code:    CodeInvalidInput,
 		Message: "There is some problem with the data you submitted.",
 		Details: details,
 	}
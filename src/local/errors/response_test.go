@@ -54,6 +54,14 @@ func TestForbidden(t *testing.T) {
 	assert.NotEmpty(t, res.Error())
 }
 
+func TestServiceUnavailable(t *testing.T) {
+	res := ServiceUnavailable("test")
+	assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode())
+	assert.Equal(t, "test", res.Error())
+	res = ServiceUnavailable("")
+	assert.NotEmpty(t, res.Error())
+}
+
 func TestBadRequest(t *testing.T) {
 	res := BadRequest("test")
 	assert.Equal(t, http.StatusBadRequest, res.StatusCode())
@@ -70,3 +78,34 @@ func TestInvalidInput(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, err.Status)
 	assert.Equal(t, []invalidField{{"abc", "1"}, {"xyz", "2"}}, err.Details)
 }
+
+func TestErrorCodes(t *testing.T) {
+	tests := []struct {
+		name string
+		res  ErrorResponse
+		code string
+	}{
+		{"internal server error", InternalServerError(""), CodeInternal},
+		{"not found", NotFound(""), CodeNotFound},
+		{"unauthorized", Unauthorized(""), CodeUnauthorized},
+		{"forbidden", Forbidden(""), CodeForbidden},
+		{"precondition failed", PreconditionFailed(""), CodePreconditionFailed},
+		{"service unavailable", ServiceUnavailable(""), CodeServiceUnavailable},
+		{"too many requests", TooManyRequests(""), CodeRateLimited},
+		{"bad request", BadRequest(""), CodeBadRequest},
+		{"invalid input", InvalidInput(validation.Errors{}), CodeInvalidInput},
+		{"custom code", Unauthorized("").WithCode(CodeInvalidCredentials), CodeInvalidCredentials},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.code, tt.res.Code)
+		})
+	}
+}
+
+func Test_codeForStatus(t *testing.T) {
+	assert.Equal(t, CodeRateLimited, codeForStatus(http.StatusTooManyRequests))
+	assert.Equal(t, CodeRequestTooLarge, codeForStatus(http.StatusRequestEntityTooLarge))
+	assert.Equal(t, CodeBadRequest, codeForStatus(http.StatusConflict))
+	assert.Equal(t, CodeInternal, codeForStatus(http.StatusBadGateway))
+}
@@ -1,15 +1,99 @@
 package healthcheck
 
-import routing "github.com/go-ozzo/ozzo-routing/v2"
+import (
+	"context"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"net/http"
+	"sync"
+	"time"
+)
 
 // RegisterHandlers registers the handlers that perform healthchecks.
 func RegisterHandlers(r *routing.Router, version string) {
 	r.To("GET,HEAD", "/healthcheck", healthcheck(version))
 }
 
+// RegisterStatusHandlers registers the handler reporting the health of the service and its dependencies.
+// Each checker is given at most the specified timeout to complete.
+func RegisterStatusHandlers(r *routing.Router, version string, timeout time.Duration, checkers ...Checker) {
+	r.Get("/status", status(version, timeout, checkers))
+}
+
 // healthcheck responds to a healthcheck request.
 func healthcheck(version string) routing.Handler {
 	return func(c *routing.Context) error {
 		return c.Write("API succes, current version is " + version)
 	}
 }
+
+const (
+	statusHealthy   = "healthy"
+	statusUnhealthy = "unhealthy"
+)
+
+// CheckResult is the outcome of a single dependency check.
+type CheckResult struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// StatusReport aggregates the health of the service and its dependencies.
+type StatusReport struct {
+	Status  string        `json:"status"`
+	Version string        `json:"version"`
+	Checks  []CheckResult `json:"checks"`
+}
+
+// status responds with a report of all dependency checks.
+// The response status is 503 if any dependency is unhealthy.
+func status(version string, timeout time.Duration, checkers []Checker) routing.Handler {
+	return func(c *routing.Context) error {
+		report := runChecks(c.Request.Context(), timeout, checkers)
+		report.Version = version
+		if report.Status != statusHealthy {
+			return c.WriteWithStatus(report, http.StatusServiceUnavailable)
+		}
+		return c.Write(report)
+	}
+}
+
+// runChecks runs the checkers concurrently and collects their results in order.
+func runChecks(ctx context.Context, timeout time.Duration, checkers []Checker) StatusReport {
+	report := StatusReport{
+		Status: statusHealthy,
+		Checks: make([]CheckResult, len(checkers)),
+	}
+
+	var wg sync.WaitGroup
+	for i, checker := range checkers {
+		wg.Add(1)
+		go func(i int, checker Checker) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := checker.Check(ctx)
+			result := CheckResult{
+				Name:      checker.Name(),
+				Status:    statusHealthy,
+				LatencyMS: time.Since(start).Milliseconds(),
+			}
+			if err != nil {
+				result.Status = statusUnhealthy
+				result.Error = err.Error()
+			}
+			report.Checks[i] = result
+		}(i, checker)
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status != statusHealthy {
+			report.Status = statusUnhealthy
+		}
+	}
+	return report
+}
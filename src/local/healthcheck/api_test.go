@@ -1,8 +1,13 @@
 package healthcheck
 
 import (
+	"errors"
+	"local/test"
 	"net/http"
+	"net/http/httptest"
+	"pkg/log"
 	"testing"
+	"time"
 )
 
 func TestAPI(t *testing.T) {
@@ -10,6 +15,60 @@ func TestAPI(t *testing.T) {
 	router := test.MockRouter(logger)
 	RegisterHandlers(router, "0.9.0")
 	test.Endpoint(t, router, test.APITestCase{
-		"ok", "GET", "/healthcheck", "", nil, http.StatusOK, `"OK 0.9.0"`,
+		"ok", "GET", "/healthcheck", "", nil, http.StatusOK, `"API succes, current version is 0.9.0"`,
+	})
+}
+
+func TestStatusAPI(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer healthy.Close()
+	unhealthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer unhealthy.Close()
+
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+	RegisterStatusHandlers(router, "0.9.0", time.Second,
+		NewDBChecker("database", mockPinger{}),
+		NewURLChecker("users", healthy.URL+"/healthz", nil),
+	)
+	test.Endpoint(t, router, test.APITestCase{
+		"healthy", "GET", "/status", "", nil, http.StatusOK,
+		`*"status":"healthy","version":"0.9.0","checks":[{"name":"database","status":"healthy","latency_ms":*`,
+	})
+
+	router = test.MockRouter(logger)
+	RegisterStatusHandlers(router, "0.9.0", time.Second,
+		NewDBChecker("database", mockPinger{errors.New("down")}),
+		NewURLChecker("users", healthy.URL+"/healthz", nil),
+		NewURLChecker("orders", unhealthy.URL+"/healthz", nil),
+	)
+	test.Endpoint(t, router, test.APITestCase{
+		"unhealthy downstream", "GET", "/status", "", nil, http.StatusServiceUnavailable,
+		`*"status":"unhealthy","version":"0.9.0"*`,
+	})
+	test.Endpoint(t, router, test.APITestCase{
+		"unhealthy downstream error", "GET", "/status", "", nil, http.StatusServiceUnavailable,
+		`*{"name":"orders","status":"unhealthy","latency_ms":*`,
+	})
+}
+
+func TestStatusAPI_pools(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+	RegisterStatusHandlers(router, "0.9.0", time.Second, NewDBPoolCheckers("database", map[string]Pinger{
+		"tenant1": mockPinger{},
+		"tenant2": mockPinger{errors.New("down")},
+	})...)
+	test.Endpoint(t, router, test.APITestCase{
+		"healthy pool", "GET", "/status", "", nil, http.StatusServiceUnavailable,
+		`*{"name":"database/tenant1","status":"healthy","latency_ms":*`,
+	})
+	test.Endpoint(t, router, test.APITestCase{
+		"unhealthy pool", "GET", "/status", "", nil, http.StatusServiceUnavailable,
+		`*{"name":"database/tenant2","status":"unhealthy","latency_ms":*`,
 	})
 }
@@ -0,0 +1,95 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// Checker checks the health of a dependency the service relies on.
+type Checker interface {
+	// Name returns the name of the dependency being checked.
+	Name() string
+	// Check returns an error if the dependency is unhealthy.
+	Check(ctx context.Context) error
+}
+
+// Pinger is implemented by connections that can verify they are alive, such as *sql.DB.
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+type dbChecker struct {
+	name string
+	db   Pinger
+}
+
+// NewDBChecker creates a checker which pings the given database connection.
+func NewDBChecker(name string, db Pinger) Checker {
+	return dbChecker{name, db}
+}
+
+// Name returns the name of the database.
+func (c dbChecker) Name() string {
+	return c.name
+}
+
+// Check pings the database.
+func (c dbChecker) Check(ctx context.Context) error {
+	return c.db.PingContext(ctx)
+}
+
+// NewDBPoolCheckers creates a checker for each of the given database pools, such as the pools of
+// different tenants, so that the health of every pool is reported separately. The checkers are
+// named "<name>/<pool>" and are ordered by pool name.
+func NewDBPoolCheckers(name string, pools map[string]Pinger) []Checker {
+	names := make([]string, 0, len(pools))
+	for pool := range pools {
+		names = append(names, pool)
+	}
+	sort.Strings(names)
+
+	checkers := make([]Checker, 0, len(names))
+	for _, pool := range names {
+		checkers = append(checkers, NewDBChecker(name+"/"+pool, pools[pool]))
+	}
+	return checkers
+}
+
+type urlChecker struct {
+	name   string
+	url    string
+	client *http.Client
+}
+
+// NewURLChecker creates a checker which requests the health endpoint of a downstream service
+// at the given URL and considers the service healthy if it responds with a 2xx status.
+func NewURLChecker(name, url string, client *http.Client) Checker {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return urlChecker{name, url, client}
+}
+
+// Name returns the name of the downstream service.
+func (c urlChecker) Name() string {
+	return c.name
+}
+
+// Check requests the health endpoint of the downstream service.
+func (c urlChecker) Check(ctx context.Context) error {
+	req, err := http.NewRequest("GET", c.url, nil)
+	if err != nil {
+		return err
+	}
+	res, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %v", res.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type mockPinger struct {
+	err error
+}
+
+func (m mockPinger) PingContext(ctx context.Context) error {
+	return m.err
+}
+
+func TestNewDBChecker(t *testing.T) {
+	c := NewDBChecker("db", mockPinger{})
+	assert.Equal(t, "db", c.Name())
+	assert.Nil(t, c.Check(context.Background()))
+	c = NewDBChecker("db", mockPinger{errors.New("down")})
+	assert.NotNil(t, c.Check(context.Background()))
+}
+
+func TestNewDBPoolCheckers(t *testing.T) {
+	checkers := NewDBPoolCheckers("database", map[string]Pinger{
+		"tenant2": mockPinger{errors.New("down")},
+		"tenant1": mockPinger{},
+	})
+	if assert.Equal(t, 2, len(checkers)) {
+		assert.Equal(t, "database/tenant1", checkers[0].Name())
+		assert.Nil(t, checkers[0].Check(context.Background()))
+		assert.Equal(t, "database/tenant2", checkers[1].Name())
+		assert.NotNil(t, checkers[1].Check(context.Background()))
+	}
+	assert.Empty(t, NewDBPoolCheckers("database", nil))
+}
+
+func TestNewURLChecker(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer healthy.Close()
+	unhealthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unhealthy.Close()
+
+	c := NewURLChecker("users", healthy.URL+"/healthz", nil)
+	assert.Equal(t, "users", c.Name())
+	assert.Nil(t, c.Check(context.Background()))
+	c = NewURLChecker("orders", unhealthy.URL+"/healthz", nil)
+	assert.NotNil(t, c.Check(context.Background()))
+}
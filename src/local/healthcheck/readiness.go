@@ -0,0 +1,88 @@
+package healthcheck
+
+import (
+	"context"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"local/errors"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Readiness tracks whether the server has finished starting up and is ready to serve requests,
+// and whether it is draining before shutting down.
+// It is safe for concurrent use. The zero value is not ready.
+type Readiness struct {
+	ready    int32
+	draining int32
+}
+
+// SetReady marks the server as ready or not ready.
+func (r *Readiness) SetReady(ready bool) {
+	var value int32
+	if ready {
+		value = 1
+	}
+	atomic.StoreInt32(&r.ready, value)
+}
+
+// IsReady reports whether the server is ready.
+func (r *Readiness) IsReady() bool {
+	return atomic.LoadInt32(&r.ready) == 1
+}
+
+// Drain makes /readyz answer 503 while the server keeps serving the other requests, then waits for
+// the grace period, so that the load balancers notice the server is going away and stop sending it
+// requests before it stops accepting connections. It returns early if the context is done.
+func (r *Readiness) Drain(ctx context.Context, grace time.Duration) {
+	atomic.StoreInt32(&r.draining, 1)
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// IsDraining reports whether the server is shutting down.
+func (r *Readiness) IsDraining() bool {
+	return atomic.LoadInt32(&r.draining) == 1
+}
+
+// healthPaths are the paths answered before the server is ready.
+var healthPaths = map[string]bool{
+	"/healthz":     true,
+	"/readyz":      true,
+	"/healthcheck": true,
+	"/status":      true,
+}
+
+// Handler returns a middleware that answers every request other than the health endpoints
+// with 503 until the server is ready.
+func (r *Readiness) Handler() routing.Handler {
+	return func(c *routing.Context) error {
+		if r.IsReady() || healthPaths[c.Request.URL.Path] {
+			return nil
+		}
+		c.Response.Header().Set("Retry-After", "1")
+		return errors.ServiceUnavailable("The server is starting up. Please retry later.")
+	}
+}
+
+// RegisterReadinessHandlers registers the liveness and readiness probes.
+// /healthz always answers 200 while the process is running. /readyz answers 200 once the server
+// is ready and 503 before, as well as once it is draining before shutting down.
+func RegisterReadinessHandlers(r *routing.Router, readiness *Readiness) {
+	r.To("GET,HEAD", "/healthz", func(c *routing.Context) error {
+		return c.Write("OK")
+	})
+	r.To("GET,HEAD", "/readyz", func(c *routing.Context) error {
+		if !readiness.IsReady() {
+			return c.WriteWithStatus("not ready", http.StatusServiceUnavailable)
+		}
+		if readiness.IsDraining() {
+			return c.WriteWithStatus("shutting down", http.StatusServiceUnavailable)
+		}
+		return c.Write("ready")
+	})
+}
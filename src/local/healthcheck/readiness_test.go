@@ -0,0 +1,90 @@
+package healthcheck
+
+import (
+	"context"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"local/test"
+	"net/http"
+	"pkg/log"
+	"testing"
+	"time"
+)
+
+func TestReadiness(t *testing.T) {
+	logger, _ := log.NewForTest()
+	readiness := &Readiness{}
+	router := test.MockRouter(logger)
+	router.Use(readiness.Handler())
+	RegisterReadinessHandlers(router, readiness)
+	RegisterHandlers(router, "0.9.0")
+	router.Get("/v1/albums", func(c *routing.Context) error {
+		return c.Write("albums")
+	})
+
+	// before ready
+	for _, tc := range []test.APITestCase{
+		{"route before ready", "GET", "/v1/albums", "", nil, http.StatusServiceUnavailable, "*starting up*"},
+		{"healthz before ready", "GET", "/healthz", "", nil, http.StatusOK, `"OK"`},
+		{"readyz before ready", "GET", "/readyz", "", nil, http.StatusServiceUnavailable, `"not ready"`},
+		{"healthcheck before ready", "GET", "/healthcheck", "", nil, http.StatusOK, "*0.9.0*"},
+	} {
+		test.Endpoint(t, router, tc)
+	}
+
+	// after ready
+	readiness.SetReady(true)
+	for _, tc := range []test.APITestCase{
+		{"route after ready", "GET", "/v1/albums", "", nil, http.StatusOK, `"albums"`},
+		{"healthz after ready", "GET", "/healthz", "", nil, http.StatusOK, `"OK"`},
+		{"readyz after ready", "GET", "/readyz", "", nil, http.StatusOK, `"ready"`},
+	} {
+		test.Endpoint(t, router, tc)
+	}
+
+	readiness.SetReady(false)
+	test.Endpoint(t, router, test.APITestCase{"route when not ready again", "GET", "/v1/albums", "", nil, http.StatusServiceUnavailable, ""})
+}
+
+func TestReadiness_Drain(t *testing.T) {
+	logger, _ := log.NewForTest()
+	readiness := &Readiness{}
+	readiness.SetReady(true)
+	router := test.MockRouter(logger)
+	router.Use(readiness.Handler())
+	RegisterReadinessHandlers(router, readiness)
+	router.Get("/v1/albums", func(c *routing.Context) error {
+		return c.Write("albums")
+	})
+
+	done := make(chan struct{})
+	go func() {
+		readiness.Drain(context.Background(), 200*time.Millisecond)
+		close(done)
+	}()
+	for !readiness.IsDraining() {
+		time.Sleep(time.Millisecond)
+	}
+
+	// during the grace period
+	for _, tc := range []test.APITestCase{
+		{"readyz while draining", "GET", "/readyz", "", nil, http.StatusServiceUnavailable, `"shutting down"`},
+		{"healthz while draining", "GET", "/healthz", "", nil, http.StatusOK, `"OK"`},
+		{"route while draining", "GET", "/v1/albums", "", nil, http.StatusOK, `"albums"`},
+	} {
+		test.Endpoint(t, router, tc)
+	}
+	select {
+	case <-done:
+		t.Fatal("the grace period ended early")
+	default:
+	}
+	<-done
+
+	// a canceled context ends the grace period
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	readiness.Drain(ctx, time.Minute)
+	assert.True(t, time.Since(start) < time.Second)
+}
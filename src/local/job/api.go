@@ -0,0 +1,51 @@
+package job
+
+import (
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"net/http"
+	"pkg/log"
+	"pkg/module"
+	"pkg/urlbuilder"
+)
+
+// RouteName is the name of the route of the job status endpoint, from which Accepted builds the status URLs.
+const RouteName = "job"
+
+// RegisterHandlers sets up the routing of the job status endpoint, which requires authentication.
+func RegisterHandlers(r *routing.RouteGroup, queue *Queue, authHandler routing.Handler, logger log.Logger) {
+	res := resource{queue, logger}
+
+	r.Use(authHandler)
+
+	r.Get("/jobs/<id>", res.get).Name(RouteName)
+}
+
+type resource struct {
+	queue  *Queue
+	logger log.Logger
+}
+
+// get reports the status of a job, and its result or error once it is finished.
+func (r resource) get(c *routing.Context) error {
+	job, err := r.queue.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		return err
+	}
+	return c.Write(job)
+}
+
+// Accepted responds to the request that started a job with 202 Accepted, the job, and a Location header
+// pointing to the status endpoint of the job.
+func Accepted(c *routing.Context, job Job, urls *urlbuilder.URLBuilder) error {
+	if location, err := urls.URL(RouteName, "id", job.ID); err == nil {
+		c.Response.Header().Set("Location", location)
+	}
+	return c.WriteWithStatus(job, http.StatusAccepted)
+}
+
+// NewModule returns the job status endpoint as a module.
+func NewModule(queue *Queue, authHandler routing.Handler, logger log.Logger) module.Module {
+	return module.New("", func(rg *routing.RouteGroup) {
+		RegisterHandlers(rg, queue, authHandler, logger)
+	})
+}
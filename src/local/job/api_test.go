@@ -0,0 +1,60 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"local/auth"
+	"local/test"
+	"net/http"
+	"net/http/httptest"
+	"pkg/log"
+	"pkg/urlbuilder"
+	"testing"
+)
+
+func TestAPI(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+	queue := NewQueue(1, 10, 0, logger)
+	defer queue.Close(context.Background())
+	RegisterHandlers(router.Group("/v1"), queue, auth.MockAuthHandler, logger)
+	urls, _ := urlbuilder.New("", router)
+
+	release := make(chan struct{})
+	router.Post("/v1/imports", auth.MockAuthHandler, func(c *routing.Context) error {
+		j, err := queue.Enqueue(c.Request.Context(), "import", func(ctx context.Context, progress func(int)) (interface{}, error) {
+			<-release
+			return map[string]int{"created": 1}, nil
+		})
+		if err != nil {
+			return err
+		}
+		return Accepted(c, j, urls)
+	})
+
+	// enqueue
+	req, _ := http.NewRequest("POST", "/v1/imports", nil)
+	req.Header = auth.MockAuthHeader()
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusAccepted, res.Code)
+	var accepted Job
+	assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &accepted))
+	assert.Equal(t, StatusQueued, accepted.Status)
+	assert.Equal(t, "/v1/jobs/"+accepted.ID, res.Header().Get("Location"))
+
+	// status
+	close(release)
+	wait(t, queue, auth.WithUser(context.Background(), "100", "Tester"), accepted.ID)
+	tests := []test.APITestCase{
+		{"status", "GET", res.Header().Get("Location"), "", auth.MockAuthHeader(), http.StatusOK,
+			`*"status":"succeeded","progress":100,"result":{"created":1}*`},
+		{"unknown", "GET", "/v1/jobs/unknown", "", auth.MockAuthHeader(), http.StatusNotFound, ""},
+		{"auth error", "GET", res.Header().Get("Location"), "", nil, http.StatusUnauthorized, ""},
+	}
+	for _, tc := range tests {
+		test.Endpoint(t, router, tc)
+	}
+}
@@ -0,0 +1,169 @@
+// Package job runs long operations, such as bulk imports, in the background. The endpoints starting them respond
+// with 202 Accepted and the URL of a status endpoint, which clients poll for the progress and result of the job.
+package job
+
+import (
+	"context"
+	"local/auth"
+	"local/entity"
+	"local/errors"
+	"pkg/jsontime"
+	"pkg/log"
+	"pkg/workerpool"
+	"sync"
+	"time"
+)
+
+// The statuses of a job.
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// DefaultTTL is how long the finished jobs are kept by default.
+const DefaultTTL = time.Hour
+
+// Job describes a job and, once it is finished, its outcome.
+type Job struct {
+	ID     string `json:"id"`
+	Type   string `json:"type"`
+	Status string `json:"status"`
+	// Progress is the percentage of the work done.
+	Progress int `json:"progress"`
+	// Result is the result of a successful job, such as an import summary.
+	Result interface{} `json:"result,omitempty"`
+	// Error is the message of the error a failed job returned.
+	Error     string        `json:"error,omitempty"`
+	CreatedAt jsontime.Time `json:"created_at"`
+	UpdatedAt jsontime.Time `json:"updated_at"`
+
+	// owner is the ID of the user who started the job, who alone may see it.
+	owner string
+}
+
+// Func does the work of a job, reporting its progress as a percentage, and returns its result.
+// The context is canceled if the server shuts down before the job is finished.
+type Func func(ctx context.Context, progress func(percent int)) (interface{}, error)
+
+// Queue runs the jobs on a worker pool and keeps their status in memory, so the status of a job is only
+// available from the server running it, and is lost if the server restarts.
+type Queue struct {
+	pool   *workerpool.Pool
+	ttl    time.Duration
+	logger log.Logger
+	now    func() time.Time
+
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+// NewQueue creates a queue running the jobs on the given numbers of workers and of waiting jobs.
+// The finished jobs are kept for ttl (DefaultTTL if zero).
+func NewQueue(workers, queueSize int, ttl time.Duration, logger log.Logger) *Queue {
+	if ttl == 0 {
+		ttl = DefaultTTL
+	}
+	return &Queue{
+		pool:   workerpool.New(workers, queueSize, logger),
+		ttl:    ttl,
+		logger: logger,
+		now:    time.Now,
+		jobs:   map[string]*Job{},
+	}
+}
+
+// Enqueue starts a job of the given type in the background and returns it. The job belongs to the user
+// of the context. The service is reported unavailable if too many jobs are waiting already.
+func (q *Queue) Enqueue(ctx context.Context, kind string, f Func) (Job, error) {
+	now := q.now()
+	job := &Job{ID: entity.GenerateID(), Type: kind, Status: StatusQueued, CreatedAt: jsontime.New(now), UpdatedAt: jsontime.New(now)}
+	if user := auth.CurrentUser(ctx); user != nil {
+		job.owner = user.GetID()
+	}
+
+	q.mu.Lock()
+	q.purge(now)
+	q.jobs[job.ID] = job
+	queued := *job
+	q.mu.Unlock()
+
+	if !q.pool.Submit(func(ctx context.Context) { q.run(ctx, job, f) }) {
+		q.mu.Lock()
+		delete(q.jobs, job.ID)
+		q.mu.Unlock()
+		return Job{}, errors.ServiceUnavailable("Too many jobs are waiting, please retry later.")
+	}
+	q.logger.With(ctx).Infof("queued %v job %v", kind, job.ID)
+	return queued, nil
+}
+
+// Get returns the job with the given ID. Jobs of other users are not found.
+func (q *Queue) Get(ctx context.Context, id string) (Job, error) {
+	owner := ""
+	if user := auth.CurrentUser(ctx); user != nil {
+		owner = user.GetID()
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.purge(q.now())
+	job, ok := q.jobs[id]
+	if !ok || job.owner != owner {
+		return Job{}, errors.NotFound("")
+	}
+	return *job, nil
+}
+
+// Close stops accepting jobs and waits until the running and waiting ones are finished.
+// If the context is done first, the running jobs are canceled and the waiting ones dropped.
+func (q *Queue) Close(ctx context.Context) error {
+	return q.pool.Close(ctx)
+}
+
+// run runs a job and records its outcome.
+func (q *Queue) run(ctx context.Context, job *Job, f Func) {
+	q.update(job, func(j *Job) { j.Status = StatusRunning })
+	result, err := f(ctx, func(percent int) {
+		q.update(job, func(j *Job) { j.Progress = percent })
+	})
+	if err != nil {
+		q.logger.Errorf("%v job %v failed: %v", job.Type, job.ID, err)
+		q.update(job, func(j *Job) {
+			j.Status = StatusFailed
+			j.Error = message(err)
+		})
+		return
+	}
+	q.logger.Infof("%v job %v succeeded", job.Type, job.ID)
+	q.update(job, func(j *Job) {
+		j.Status = StatusSucceeded
+		j.Progress = 100
+		j.Result = result
+	})
+}
+
+func (q *Queue) update(job *Job, f func(*Job)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	f(job)
+	job.UpdatedAt = jsontime.New(q.now())
+}
+
+// purge removes the jobs finished for longer than the TTL. The caller must hold the lock.
+func (q *Queue) purge(now time.Time) {
+	for id, job := range q.jobs {
+		if (job.Status == StatusSucceeded || job.Status == StatusFailed) && now.Sub(job.UpdatedAt.Time) > q.ttl {
+			delete(q.jobs, id)
+		}
+	}
+}
+
+// message returns the message of a job error that can be shown to the client: that of an error response,
+// such as a bad request, or a generic one for internal errors, whose details are only logged.
+func message(err error) string {
+	if res, ok := err.(errors.ErrorResponse); ok {
+		return res.Message
+	}
+	return errors.InternalServerError("").Message
+}
@@ -0,0 +1,119 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"local/auth"
+	"local/errors"
+	"net/http"
+	"pkg/log"
+	"testing"
+	"time"
+)
+
+// wait waits until the job is finished.
+func wait(t *testing.T, q *Queue, ctx context.Context, id string) Job {
+	for i := 0; i < 500; i++ {
+		job, err := q.Get(ctx, id)
+		if assert.Nil(t, err) && (job.Status == StatusSucceeded || job.Status == StatusFailed) {
+			return job
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	t.Fatalf("job %v not finished", id)
+	return Job{}
+}
+
+func TestQueue(t *testing.T) {
+	logger, _ := log.NewForTest()
+	q := NewQueue(1, 10, 0, logger)
+	defer q.Close(context.Background())
+	ctx := auth.WithUser(context.Background(), "100", "Tester")
+
+	release := make(chan struct{})
+	progressed := make(chan struct{})
+	job, err := q.Enqueue(ctx, "import", func(ctx context.Context, progress func(int)) (interface{}, error) {
+		progress(50)
+		close(progressed)
+		<-release
+		return map[string]int{"created": 2}, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "import", job.Type)
+	assert.Equal(t, StatusQueued, job.Status)
+
+	<-progressed
+	running, err := q.Get(ctx, job.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, StatusRunning, running.Status)
+	assert.Equal(t, 50, running.Progress)
+	close(release)
+
+	done := wait(t, q, ctx, job.ID)
+	assert.Equal(t, StatusSucceeded, done.Status)
+	assert.Equal(t, 100, done.Progress)
+	assert.Equal(t, map[string]int{"created": 2}, done.Result)
+
+	// the jobs of other users are not found
+	_, err = q.Get(auth.WithUser(context.Background(), "200", "Other"), job.ID)
+	assert.Equal(t, http.StatusNotFound, err.(errors.ErrorResponse).Status)
+	_, err = q.Get(ctx, "unknown")
+	assert.NotNil(t, err)
+
+	// the finished jobs expire
+	q.now = func() time.Time { return time.Now().Add(2 * DefaultTTL) }
+	_, err = q.Get(ctx, job.ID)
+	assert.NotNil(t, err)
+}
+
+func TestQueue_failure(t *testing.T) {
+	logger, entries := log.NewForTest()
+	q := NewQueue(1, 10, time.Minute, logger)
+	defer q.Close(context.Background())
+	ctx := context.Background()
+
+	job, _ := q.Enqueue(ctx, "import", func(ctx context.Context, progress func(int)) (interface{}, error) {
+		return nil, errors.BadRequest("The CSV document is empty.")
+	})
+	done := wait(t, q, ctx, job.ID)
+	assert.Equal(t, StatusFailed, done.Status)
+	assert.Equal(t, "The CSV document is empty.", done.Error)
+
+	// internal errors are only logged
+	job, _ = q.Enqueue(ctx, "import", func(ctx context.Context, progress func(int)) (interface{}, error) {
+		return nil, fmt.Errorf("connection refused")
+	})
+	done = wait(t, q, ctx, job.ID)
+	assert.Equal(t, "We encountered an error while processing your request.", done.Error)
+	assert.Equal(t, 1, entries.FilterMessageSnippet("connection refused").Len())
+}
+
+func TestQueue_full(t *testing.T) {
+	logger, _ := log.NewForTest()
+	q := NewQueue(1, 1, 0, logger)
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	_, err := q.Enqueue(ctx, "import", func(ctx context.Context, progress func(int)) (interface{}, error) {
+		close(started)
+		<-release
+		return nil, nil
+	})
+	assert.Nil(t, err)
+	<-started
+	// the second job waits for the worker, the third one does not fit in the queue
+	_, err = q.Enqueue(ctx, "import", func(ctx context.Context, progress func(int)) (interface{}, error) {
+		return nil, nil
+	})
+	assert.Nil(t, err)
+	_, err = q.Enqueue(ctx, "import", func(ctx context.Context, progress func(int)) (interface{}, error) {
+		return nil, nil
+	})
+	if assert.NotNil(t, err) {
+		assert.Equal(t, http.StatusServiceUnavailable, err.(errors.ErrorResponse).Status)
+	}
+	close(release)
+	assert.Nil(t, q.Close(context.Background()))
+}
@@ -0,0 +1,61 @@
+package notification
+
+import (
+	"context"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"local/auth"
+	"local/errors"
+	"pkg/log"
+	"pkg/module"
+	"time"
+)
+
+// RegisterHandlers sets up the routing of the HTTP handlers.
+// timeout specifies how long a poll request is held while waiting for events.
+func RegisterHandlers(r *routing.RouteGroup, hub *Hub, timeout time.Duration, authHandler routing.Handler, logger log.Logger) {
+	res := resource{hub, timeout, logger}
+
+	r.Use(authHandler)
+
+	// the following endpoints require a valid JWT
+	r.Get("/notifications/poll", res.poll)
+}
+
+type resource struct {
+	hub     *Hub
+	timeout time.Duration
+	logger  log.Logger
+}
+
+// poll holds the request until an event arrives for the current user or the timeout is reached.
+// An empty event list is returned on timeout or when the hub is closed.
+func (r resource) poll(c *routing.Context) error {
+	identity := auth.CurrentUser(c.Request.Context())
+	if identity == nil {
+		return errors.Unauthorized("")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), r.timeout)
+	defer cancel()
+
+	events, err := r.hub.Wait(ctx, identity.GetID())
+	if err != nil {
+		if err != context.DeadlineExceeded && err != ErrClosed {
+			// the client has gone away
+			r.logger.With(c.Request.Context()).Infof("notification poll cancelled: %v", err)
+			return nil
+		}
+		events = []Event{}
+	}
+
+	return c.Write(struct {
+		Events []Event `json:"events"`
+	}{events})
+}
+
+// NewModule returns the notification polling endpoint as a module.
+func NewModule(hub *Hub, timeout time.Duration, authHandler routing.Handler, logger log.Logger) module.Module {
+	return module.New("", func(rg *routing.RouteGroup) {
+		RegisterHandlers(rg, hub, timeout, authHandler, logger)
+	})
+}
@@ -0,0 +1,33 @@
+package notification
+
+import (
+	"local/auth"
+	"local/test"
+	"net/http"
+	"pkg/log"
+	"testing"
+	"time"
+)
+
+func TestAPI(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+	hub := NewHub()
+	RegisterHandlers(router.Group(""), hub, 50*time.Millisecond, auth.MockAuthHandler, logger)
+	header := auth.MockAuthHeader()
+
+	test.Endpoint(t, router, test.APITestCase{
+		"auth error", "GET", "/notifications/poll", "", nil, http.StatusUnauthorized, "",
+	})
+	test.Endpoint(t, router, test.APITestCase{
+		"timeout", "GET", "/notifications/poll", "", header, http.StatusOK, `{"events":[]}`,
+	})
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		hub.Publish("100", Event{Type: "album.created", Data: "123"})
+	}()
+	test.Endpoint(t, router, test.APITestCase{
+		"event delivered", "GET", "/notifications/poll", "", header, http.StatusOK, `*"type":"album.created","data":"123"*`,
+	})
+}
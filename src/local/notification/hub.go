@@ -0,0 +1,122 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"pkg/jsontime"
+	"sync"
+)
+
+// maxPendingEvents is the maximum number of undelivered events kept for each user.
+// Older events are dropped when the limit is exceeded.
+const maxPendingEvents = 100
+
+// ErrClosed is returned when waiting on a hub that has been closed.
+var ErrClosed = errors.New("notification hub is closed")
+
+// Event represents a notification sent to a user.
+type Event struct {
+	Type      string        `json:"type"`
+	Data      interface{}   `json:"data,omitempty"`
+	CreatedAt jsontime.Time `json:"created_at"`
+}
+
+// Hub dispatches events to the users who are waiting for them.
+// Events published while a user is not waiting are kept until the user's next wait.
+type Hub struct {
+	mu      sync.Mutex
+	pending map[string][]Event
+	waiters map[string][]chan struct{}
+	closed  bool
+}
+
+// NewHub creates a new notification hub.
+func NewHub() *Hub {
+	return &Hub{
+		pending: map[string][]Event{},
+		waiters: map[string][]chan struct{}{},
+	}
+}
+
+// Publish sends an event to the specified user.
+func (h *Hub) Publish(userID string, event Event) {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = jsontime.Now()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	events := append(h.pending[userID], event)
+	if len(events) > maxPendingEvents {
+		events = events[len(events)-maxPendingEvents:]
+	}
+	h.pending[userID] = events
+
+	for _, ch := range h.waiters[userID] {
+		close(ch)
+	}
+	delete(h.waiters, userID)
+}
+
+// Wait returns the pending events of the specified user.
+// If there is no pending event, it blocks until an event is published or the context is done,
+// in which case the context error is returned. ErrClosed is returned if the hub is closed.
+func (h *Hub) Wait(ctx context.Context, userID string) ([]Event, error) {
+	for {
+		h.mu.Lock()
+		if h.closed {
+			h.mu.Unlock()
+			return nil, ErrClosed
+		}
+		if events := h.pending[userID]; len(events) > 0 {
+			delete(h.pending, userID)
+			h.mu.Unlock()
+			return events, nil
+		}
+		ch := make(chan struct{})
+		h.waiters[userID] = append(h.waiters[userID], ch)
+		h.mu.Unlock()
+
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			h.removeWaiter(userID, ch)
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// removeWaiter unregisters a waiting channel which is no longer listened.
+func (h *Hub) removeWaiter(userID string, ch chan struct{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	waiters := h.waiters[userID]
+	for i, w := range waiters {
+		if w == ch {
+			waiters = append(waiters[:i], waiters[i+1:]...)
+			break
+		}
+	}
+	if len(waiters) == 0 {
+		delete(h.waiters, userID)
+	} else {
+		h.waiters[userID] = waiters
+	}
+}
+
+// Close closes the hub and releases all waiting users.
+func (h *Hub) Close(ctx context.Context) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for _, waiters := range h.waiters {
+		for _, ch := range waiters {
+			close(ch)
+		}
+	}
+	h.waiters = map[string][]chan struct{}{}
+	return nil
+}
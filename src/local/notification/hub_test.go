@@ -0,0 +1,64 @@
+package notification
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestHub_Wait(t *testing.T) {
+	hub := NewHub()
+
+	// pending events are returned immediately
+	hub.Publish("100", Event{Type: "test"})
+	events, err := hub.Wait(context.Background(), "100")
+	assert.Nil(t, err)
+	if assert.Equal(t, 1, len(events)) {
+		assert.Equal(t, "test", events[0].Type)
+		assert.False(t, events[0].CreatedAt.IsZero())
+	}
+
+	// waiting for an event published later
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		hub.Publish("200", Event{Type: "other"})
+		hub.Publish("100", Event{Type: "later"})
+	}()
+	events, err = hub.Wait(context.Background(), "100")
+	assert.Nil(t, err)
+	if assert.Equal(t, 1, len(events)) {
+		assert.Equal(t, "later", events[0].Type)
+	}
+
+	// timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	events, err = hub.Wait(ctx, "100")
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Empty(t, events)
+	assert.Empty(t, hub.waiters["100"])
+}
+
+func TestHub_Publish(t *testing.T) {
+	hub := NewHub()
+	for i := 0; i < maxPendingEvents+10; i++ {
+		hub.Publish("100", Event{Type: "test"})
+	}
+	assert.Equal(t, maxPendingEvents, len(hub.pending["100"]))
+}
+
+func TestHub_Close(t *testing.T) {
+	hub := NewHub()
+	done := make(chan error)
+	go func() {
+		_, err := hub.Wait(context.Background(), "100")
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	assert.Nil(t, hub.Close(context.Background()))
+	assert.Equal(t, ErrClosed, <-done)
+
+	_, err := hub.Wait(context.Background(), "100")
+	assert.Equal(t, ErrClosed, err)
+}
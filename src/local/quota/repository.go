@@ -0,0 +1,215 @@
+// Package quota persists the daily request counts of the tenants, against which their quotas are enforced.
+package quota
+
+import (
+	"context"
+	dbx "github.com/go-ozzo/ozzo-dbx"
+	"local/entity"
+	"pkg/dbcontext"
+	"pkg/log"
+	"sync"
+	"time"
+)
+
+// DefaultFlushInterval is how often the counts are written to the database by default.
+const DefaultFlushInterval = 10 * time.Second
+
+// dayFormat is the format of the days the requests are counted for.
+const dayFormat = "2006-01-02"
+
+// usage is the number of requests a server has counted for a tenant on a day. Each server writes rows
+// of its own, so that they can be upserted with the counts held in memory on any database.
+type usage struct {
+	TenantID   string `db:"pk,tenant_id"`
+	Day        string `db:"pk,day"`
+	InstanceID string `db:"pk,instance_id"`
+	Requests   int
+}
+
+// TableName returns the name of the table storing the daily request counts.
+func (u usage) TableName() string {
+	return "tenant_quotas"
+}
+
+// key identifies the requests of a tenant on a day.
+type key struct {
+	tenantID, day string
+}
+
+// count holds the requests of a tenant on a day.
+type count struct {
+	// local is the number of requests counted by this server, written to the database when dirty.
+	local int
+	dirty bool
+	// others is the number of requests counted by the other servers as of the last flush.
+	others int
+}
+
+// Repository counts the requests of each tenant per day, so that the quotas hold across the servers and
+// restarts. It implements ratelimit.QuotaCounter. The requests are counted in memory and written to the
+// database periodically, rather than once per request, and the counts of the other servers are read back
+// at the same time: a tenant may thus exceed its quota by the requests other servers accept between flushes.
+type Repository struct {
+	db       *dbcontext.DB
+	instance string
+	logger   log.Logger
+	now      func() time.Time
+
+	mu     sync.Mutex
+	counts map[key]*count
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewRepository creates a new quota repository, which writes the counts to the database at the given
+// interval (DefaultFlushInterval if zero) until it is closed.
+func NewRepository(db *dbcontext.DB, interval time.Duration, logger log.Logger) *Repository {
+	if interval == 0 {
+		interval = DefaultFlushInterval
+	}
+	r := &Repository{
+		db:       db,
+		instance: entity.GenerateID(),
+		logger:   logger,
+		now:      time.Now,
+		counts:   map[key]*count{},
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go r.run(interval)
+	return r
+}
+
+// Increment counts a request of the tenant on the given day and returns the number of requests
+// of the tenant on that day, including this one. The first request of a tenant on a day reads
+// the counts of the other servers from the database.
+func (r *Repository) Increment(ctx context.Context, tenantID, day string) (int, error) {
+	k := key{tenantID, day}
+	r.mu.Lock()
+	c, ok := r.counts[k]
+	r.mu.Unlock()
+	if !ok {
+		others, err := r.others(ctx, tenantID, day)
+		if err != nil {
+			r.logger.With(ctx, "tenant", tenantID).Errorf("failed to count the request against the quota: %v", err)
+			return 0, err
+		}
+		r.mu.Lock()
+		if c, ok = r.counts[k]; !ok {
+			c = &count{others: others}
+			r.counts[k] = c
+		}
+		r.mu.Unlock()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c.local++
+	c.dirty = true
+	return c.local + c.others, nil
+}
+
+// Flush writes the counts changed since the last flush to the database, reads the counts of the other
+// servers, and forgets the counts of the days before today.
+func (r *Repository) Flush(ctx context.Context) error {
+	var rows []usage
+	days := map[string]bool{}
+	r.mu.Lock()
+	for k, c := range r.counts {
+		days[k.day] = true
+		if c.dirty {
+			rows = append(rows, usage{k.tenantID, k.day, r.instance, c.local})
+			c.dirty = false
+		}
+	}
+	r.mu.Unlock()
+
+	for i, row := range rows {
+		if err := r.db.Upsert(ctx, &row, "tenant_id", "day", "instance_id"); err != nil {
+			r.redirty(rows[i:])
+			return err
+		}
+	}
+
+	today := r.now().UTC().Format(dayFormat)
+	for day := range days {
+		if day != today {
+			r.forget(day)
+			continue
+		}
+		var others []struct {
+			TenantID string
+			Requests int
+		}
+		err := r.db.With(ctx).NewQuery("SELECT [[tenant_id]], SUM([[requests]]) AS [[requests]] FROM {{tenant_quotas}} " +
+			"WHERE [[day]] = {:day} AND [[instance_id]] <> {:instance} GROUP BY [[tenant_id]]").
+			Bind(dbx.Params{"day": day, "instance": r.instance}).All(&others)
+		if err != nil {
+			return err
+		}
+		r.mu.Lock()
+		for _, o := range others {
+			if c, ok := r.counts[key{o.TenantID, day}]; ok {
+				c.others = o.Requests
+			}
+		}
+		r.mu.Unlock()
+	}
+	return nil
+}
+
+// Close stops the periodic flushes and writes the last counts to the database.
+func (r *Repository) Close(ctx context.Context) error {
+	close(r.stop)
+	<-r.done
+	return r.Flush(ctx)
+}
+
+// run flushes the counts at the given interval until the repository is closed.
+func (r *Repository) run(interval time.Duration) {
+	defer close(r.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := r.Flush(context.Background()); err != nil {
+				r.logger.Errorf("failed to write the quota counts: %v", err)
+			}
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// others reads the number of requests the other servers have counted for the tenant on the day.
+func (r *Repository) others(ctx context.Context, tenantID, day string) (int, error) {
+	var requests int
+	err := r.db.With(ctx).NewQuery("SELECT COALESCE(SUM([[requests]]), 0) FROM {{tenant_quotas}} " +
+		"WHERE [[tenant_id]] = {:tenant} AND [[day]] = {:day} AND [[instance_id]] <> {:instance}").
+		Bind(dbx.Params{"tenant": tenantID, "day": day, "instance": r.instance}).Row(&requests)
+	return requests, err
+}
+
+// redirty marks the counts of the rows that could not be written as changed, so that the next flush writes them.
+func (r *Repository) redirty(rows []usage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, row := range rows {
+		if c, ok := r.counts[key{row.TenantID, row.Day}]; ok {
+			c.dirty = true
+		}
+	}
+}
+
+// forget removes the counts of the day that are written to the database.
+func (r *Repository) forget(day string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for k, c := range r.counts {
+		if k.day == day && !c.dirty {
+			delete(r.counts, k)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package quota
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"pkg/dbcontext"
+	"pkg/dbtest"
+	"pkg/log"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRepository(t *testing.T) {
+	logger, entries := log.NewForTest()
+	others := int64(5)
+	db, server := dbtest.Open("mysql", func(query string, args []driver.Value) (*dbtest.Result, error) {
+		switch {
+		case len(args) > 0 && args[0] == "broken":
+			return nil, errors.New("deadlock")
+		case strings.Contains(query, "GROUP BY"):
+			return &dbtest.Result{Columns: []string{"tenant_id", "requests"}, Rows: [][]driver.Value{{"acme", others}}}, nil
+		case strings.HasPrefix(query, "SELECT"):
+			return &dbtest.Result{Columns: []string{"requests"}, Rows: [][]driver.Value{{others}}}, nil
+		}
+		return &dbtest.Result{RowsAffected: 1}, nil
+	})
+	repo := NewRepository(dbcontext.New(db), time.Hour, logger)
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	repo.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	// the requests of the other servers are read once, then the requests are counted in memory
+	for i, want := range []int{6, 7} {
+		count, err := repo.Increment(ctx, "acme", "2026-10-16")
+		assert.Nil(t, err, i)
+		assert.Equal(t, want, count, i)
+	}
+	assert.Equal(t, []string{
+		"SELECT COALESCE(SUM(`requests`), 0) FROM `tenant_quotas` WHERE `tenant_id` = ? AND `day` = ? AND `instance_id` <> ?",
+	}, server.SQL())
+
+	// the count of the server is written, and the counts of the others are refreshed
+	others = 9
+	assert.Nil(t, repo.Flush(ctx))
+	statements := server.Statements()
+	if assert.Equal(t, 3, len(statements)) {
+		assert.Equal(t, "INSERT INTO `tenant_quotas` (`day`, `instance_id`, `requests`, `tenant_id`) VALUES (?, ?, ?, ?) "+
+			"ON DUPLICATE KEY UPDATE `day`=?, `instance_id`=?, `requests`=?, `tenant_id`=?", statements[1].SQL)
+		assert.Equal(t, []driver.Value{"2026-10-16", repo.instance, int64(2), "acme"}, statements[1].Args[:4])
+		assert.Equal(t, "SELECT `tenant_id`, SUM(`requests`) AS `requests` FROM `tenant_quotas` "+
+			"WHERE `day` = ? AND `instance_id` <> ? GROUP BY `tenant_id`", statements[2].SQL)
+	}
+	count, err := repo.Increment(ctx, "acme", "2026-10-16")
+	assert.Nil(t, err)
+	assert.Equal(t, 12, count)
+
+	// the counts of the past days are forgotten once written
+	now = now.Add(24 * time.Hour)
+	assert.Nil(t, repo.Flush(ctx))
+	assert.Equal(t, 4, len(server.Statements()))
+	assert.Equal(t, 0, len(repo.counts))
+
+	_, err = repo.Increment(ctx, "broken", "2026-10-17")
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, entries.FilterMessageSnippet("failed to count the request").Len())
+	assert.Nil(t, repo.Close(ctx))
+}
@@ -2,10 +2,10 @@ package test
 
 import (
 	dbx "github.com/go-ozzo/ozzo-dbx"
-	_ "github.com/lib/pq" // initialize posgresql for test
-	"
-	"
-	"
+	_ "github.com/go-sql-driver/mysql" // initialize mysql for test
+	"local/config"
+	"pkg/dbcontext"
+	"pkg/log"
 	"path"
 	"runtime"
 	"testing"
@@ -20,12 +20,12 @@ func DB(t *testing.T) *dbcontext.DB {
 	}
 	logger, _ := log.NewForTest()
 	dir := getSourcePath()
-	cfg, err := config.Load(dir+"/../../config/local.yml", logger)
+	cfg, err := config.Load(dir+"/../../../config/local.yml", logger)
 	if err != nil {
 		t.Error(err)
 		t.FailNow()
 	}
-	dbc, err := dbx.MustOpen("postgres", cfg.DSN)
+	dbc, err := dbx.MustOpen("mysql", cfg.DSN)
 	if err != nil {
 		t.Error(err)
 		t.FailNow()
@@ -4,7 +4,7 @@ import (
 	routing "github.com/go-ozzo/ozzo-routing/v2"
 	"github.com/go-ozzo/ozzo-routing/v2/content"
 	"github.com/go-ozzo/ozzo-routing/v2/cors"
-	"internal/errors"
+	"local/errors"
 	"pkg/accesslog"
 	"pkg/log"
 	"net/http"
@@ -27,6 +27,7 @@ func MockRouter(logger log.Logger) *routing.Router {
 	router := routing.New()
 	router.Use(
 		accesslog.Handler(logger),
+		log.Handler(logger),
 		errors.Handler(logger),
 		content.TypeNegotiator(content.JSON),
 		cors.Handler(cors.AllowAll),
@@ -0,0 +1,158 @@
+package user
+
+import (
+	"bytes"
+	"context"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"io"
+	"io/ioutil"
+	"local/errors"
+	"local/job"
+	"net/http"
+	"pkg/jsonbody"
+	"pkg/log"
+	"pkg/module"
+	"pkg/upload"
+	"pkg/urlbuilder"
+	"strings"
+)
+
+// maxImportBytes is the maximum size of an uploaded CSV document.
+const maxImportBytes = 10 << 20
+
+// RegisterHandlers sets up the routing of the HTTP handlers. Imports requested with the "Prefer: respond-async"
+// header run in the background on the jobs queue, and urls builds the URLs of their status. Imports always
+// run in the request if jobs is nil.
+func RegisterHandlers(r *routing.RouteGroup, service Service, jobs *job.Queue, urls *urlbuilder.URLBuilder, authHandler routing.Handler, logger log.Logger) {
+	res := resource{service, jobs, urls, logger}
+
+	r.Use(authHandler)
+
+	// the following endpoints require admin authentication
+	r.Post("/users/import", upload.Handler(upload.Options{MaxBytes: maxImportBytes}), res.importUsers)
+}
+
+type resource struct {
+	service Service
+	jobs    *job.Queue
+	urls    *urlbuilder.URLBuilder
+	logger  log.Logger
+}
+
+// importUsers creates the user accounts listed in an uploaded CSV document and responds with a summary.
+// The document is either the request body or, for multipart requests, the "file" form field.
+// Multipart requests have already been parsed by the upload middleware. If the client prefers an asynchronous
+// response, the import runs as a job whose status URL is returned with 202, the summary being its result.
+func (r resource) importUsers(c *routing.Context) error {
+	var body io.Reader
+	if c.Request.MultipartForm != nil {
+		file, _, err := c.Request.FormFile("file")
+		if err != nil {
+			r.logger.With(c.Request.Context()).Info(err)
+			return errors.BadRequest("The CSV document must be uploaded in the \"file\" field.")
+		}
+		defer file.Close()
+		body = file
+	} else {
+		c.Request.Body = http.MaxBytesReader(c.Response, c.Request.Body, maxImportBytes)
+		body = c.Request.Body
+	}
+
+	if r.jobs != nil && preferAsync(c.Request) {
+		// the document is read before responding, as the request body is closed afterwards
+		document, err := ioutil.ReadAll(body)
+		if err != nil {
+			r.logger.With(c.Request.Context()).Info(err)
+			return errors.BadRequest("The CSV document could not be read.")
+		}
+		j, err := r.jobs.Enqueue(c.Request.Context(), importJobType, func(ctx context.Context, progress func(int)) (interface{}, error) {
+			return r.service.Import(ctx, bytes.NewReader(document), progress)
+		})
+		if err != nil {
+			return err
+		}
+		c.Response.Header().Set("Preference-Applied", "respond-async")
+		return job.Accepted(c, j, r.urls)
+	}
+
+	summary, err := r.service.Import(c.Request.Context(), body, nil)
+	if err != nil {
+		return err
+	}
+	return c.Write(summary)
+}
+
+// importJobType is the type of the jobs importing users.
+const importJobType = "user_import"
+
+// preferAsync reports whether the request has the "Prefer: respond-async" header of RFC 7240.
+func preferAsync(req *http.Request) bool {
+	for _, value := range req.Header["Prefer"] {
+		for _, preference := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(preference), "respond-async") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// NewModule returns the user endpoints as a module. All of them require the administrator
+// authentication performed by adminHandler.
+func NewModule(service Service, jobs *job.Queue, urls *urlbuilder.URLBuilder, adminHandler routing.Handler, logger log.Logger) module.Module {
+	return module.New("", func(rg *routing.RouteGroup) {
+		RegisterHandlers(rg, service, jobs, urls, adminHandler, logger)
+	})
+}
+
+// RegisterResetHandlers sets up the routing of the password reset endpoints, which do not require authentication.
+func RegisterResetHandlers(r *routing.RouteGroup, service ResetService, logger log.Logger) {
+	res := resetResource{service, logger}
+
+	r.Post("/auth/forgot", res.forgot)
+	r.Post("/auth/reset", res.reset)
+}
+
+type resetResource struct {
+	service ResetService
+	logger  log.Logger
+}
+
+// forgot sends a password reset token to the user with the given login name. It responds with 202
+// whether the user exists or not.
+func (r resetResource) forgot(c *routing.Context) error {
+	var input ForgotPasswordRequest
+	if err := c.Read(&input); err != nil {
+		r.logger.With(c.Request.Context()).Info(err)
+		return errors.BadRequest(jsonbody.ErrorMessage(err))
+	}
+	if err := input.Validate(); err != nil {
+		return err
+	}
+	if err := r.service.Forgot(c.Request.Context(), input.Logname); err != nil {
+		return err
+	}
+	c.Response.WriteHeader(http.StatusAccepted)
+	return nil
+}
+
+// reset sets a new password with a reset token.
+func (r resetResource) reset(c *routing.Context) error {
+	var input ResetPasswordRequest
+	if err := c.Read(&input); err != nil {
+		r.logger.With(c.Request.Context()).Info(err)
+		return errors.BadRequest(jsonbody.ErrorMessage(err))
+	}
+	if err := r.service.Reset(c.Request.Context(), input); err != nil {
+		return err
+	}
+	c.Response.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// NewResetModule returns the password reset endpoints as a module.
+func NewResetModule(service ResetService, logger log.Logger) module.Module {
+	return module.New("", func(rg *routing.RouteGroup) {
+		RegisterResetHandlers(rg, service, logger)
+	})
+}
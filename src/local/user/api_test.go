@@ -0,0 +1,127 @@
+package user
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"local/auth"
+	"local/entity"
+	"local/job"
+	"local/test"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"pkg/log"
+	"pkg/password"
+	"pkg/urlbuilder"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAPI(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+	repo := &mockRepository{items: []entity.LoginUser{{ID: 1, Logname: "admin"}}}
+	RegisterHandlers(router.Group(""), NewService(repo, repo.transactional, password.Policy{}, logger), nil, nil, auth.MockAuthHandler, logger)
+	header := auth.MockAuthHeader()
+
+	tests := []test.APITestCase{
+		{"import ok", "POST", "/users/import", "logname,password\nalice,secret1\nbob,secret2\n", header, http.StatusOK,
+			`{"created":2,"skipped":0,"errored":0,"rows":[]}`},
+		{"import duplicate", "POST", "/users/import", "logname,password\nadmin,secret1\ncarol,secret2\n", header, http.StatusOK,
+			`{"created":1,"skipped":1,"errored":0,"rows":[{"row":2,"status":"skipped","reason":"user \"admin\" already exists"}]}`},
+		{"import malformed row", "POST", "/users/import", "logname,password\ndave\nerin,secret3\n", header, http.StatusOK,
+			`{"created":1,"skipped":0,"errored":1,"rows":[{"row":2,"status":"error","reason":"wrong number of fields"}]}`},
+		{"import missing column", "POST", "/users/import", "logname\nfrank\n", header, http.StatusBadRequest, ""},
+		{"import auth error", "POST", "/users/import", "logname,password\ngrace,secret4\n", nil, http.StatusUnauthorized, ""},
+	}
+	for _, tc := range tests {
+		test.Endpoint(t, router, tc)
+	}
+}
+
+func TestAPI_multipart(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+	repo := &mockRepository{}
+	RegisterHandlers(router.Group(""), NewService(repo, repo.transactional, password.Policy{}, logger), nil, nil, auth.MockAuthHandler, logger)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("file", "users.csv")
+	_, _ = part.Write([]byte("logname,password\nalice,secret1\n"))
+	_ = writer.Close()
+
+	req, _ := http.NewRequest("POST", "/users/import", body)
+	req.Header = auth.MockAuthHeader()
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Contains(t, res.Body.String(), `"created":1`)
+	assert.Equal(t, 1, len(repo.items))
+}
+
+func TestAPI_async(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+	repo := &mockRepository{}
+	jobs := job.NewQueue(1, 10, 0, logger)
+	defer jobs.Close(context.Background())
+	urls, _ := urlbuilder.New("", router)
+	RegisterHandlers(router.Group(""), NewService(repo, repo.transactional, password.Policy{}, logger), jobs, urls, auth.MockAuthHandler, logger)
+	job.RegisterHandlers(router.Group(""), jobs, auth.MockAuthHandler, logger)
+
+	req, _ := http.NewRequest("POST", "/users/import", strings.NewReader("logname,password\nalice,secret1\n"))
+	req.Header = auth.MockAuthHeader()
+	req.Header.Set("Prefer", "respond-async")
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusAccepted, res.Code)
+	assert.Equal(t, "respond-async", res.Header().Get("Preference-Applied"))
+	location := res.Header().Get("Location")
+	assert.True(t, strings.HasPrefix(location, "/jobs/"))
+
+	var status string
+	for i := 0; i < 500 && status != job.StatusSucceeded; i++ {
+		req, _ = http.NewRequest("GET", location, nil)
+		req.Header = auth.MockAuthHeader()
+		res = httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		var j job.Job
+		_ = json.Unmarshal(res.Body.Bytes(), &j)
+		status = j.Status
+		time.Sleep(2 * time.Millisecond)
+	}
+	assert.Equal(t, job.StatusSucceeded, status)
+	assert.Contains(t, res.Body.String(), `"result":{"created":1,"skipped":0,"errored":0,"rows":[]}`)
+	assert.Equal(t, 1, len(repo.items))
+}
+
+func TestAPI_reset(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+	repo := &mockRepository{items: []entity.LoginUser{{ID: 1, Logname: "alice", Logpassword: "old"}}}
+	sender := &mockSender{}
+	RegisterResetHandlers(router.Group(""), NewResetService(repo, testKey, 0, sender, password.Policy{MinLength: 6}, logger), logger)
+
+	test.Endpoint(t, router, test.APITestCase{"forgot", "POST", "/auth/forgot", `{"logname":"alice"}`, nil, http.StatusAccepted, ""})
+	test.Endpoint(t, router, test.APITestCase{"forgot unknown", "POST", "/auth/forgot", `{"logname":"bob"}`, nil, http.StatusAccepted, ""})
+	test.Endpoint(t, router, test.APITestCase{"forgot input error", "POST", "/auth/forgot", `{}`, nil, http.StatusBadRequest, ""})
+	if !assert.Equal(t, 1, len(sender.events)) {
+		return
+	}
+	token := sender.events[0].(ResetEvent).Token
+
+	tests := []test.APITestCase{
+		{"reset input error", "POST", "/auth/reset", `{"token":"` + token + `","password":"x"}`, nil, http.StatusBadRequest, `*"field":"password"*`},
+		{"reset", "POST", "/auth/reset", `{"token":"` + token + `","password":"secret1"}`, nil, http.StatusNoContent, ""},
+		{"reset reused token", "POST", "/auth/reset", `{"token":"` + token + `","password":"secret2"}`, nil, http.StatusBadRequest, `*invalid or has expired*`},
+	}
+	for _, tc := range tests {
+		test.Endpoint(t, router, tc)
+	}
+	assert.True(t, password.Verify(repo.items[0].Logpassword, "secret1"))
+}
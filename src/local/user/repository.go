@@ -0,0 +1,105 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	dbx "github.com/go-ozzo/ozzo-dbx"
+	"local/entity"
+	"pkg/dbcontext"
+	"pkg/log"
+)
+
+// Repository encapsulates the logic to access user accounts from the data source.
+type Repository interface {
+	// Existing returns those of the given login names that are already taken.
+	Existing(ctx context.Context, lognames []string) ([]string, error)
+	// CreateBatch saves the given user accounts in the storage using a single statement.
+	CreateBatch(ctx context.Context, users []entity.LoginUser) error
+	// GetByLogname returns the user account with the given login name.
+	GetByLogname(ctx context.Context, logname string) (entity.LoginUser, error)
+	// UpdatePassword replaces the stored password of a user, provided it has not changed since it was read.
+	UpdatePassword(ctx context.Context, id int, oldPassword, newPassword string) error
+}
+
+// ErrPasswordChanged is returned by UpdatePassword when the stored password is not the expected one.
+var ErrPasswordChanged = errors.New("the password has changed")
+
+// repository persists user accounts in database
+type repository struct {
+	db     *dbcontext.DB
+	logger log.Logger
+}
+
+// NewRepository creates a new user repository
+func NewRepository(db *dbcontext.DB, logger log.Logger) Repository {
+	return repository{db, logger}
+}
+
+// Existing returns those of the given login names that are already used by user records in the database.
+func (r repository) Existing(ctx context.Context, lognames []string) ([]string, error) {
+	if len(lognames) == 0 {
+		return nil, nil
+	}
+	names := make([]interface{}, len(lognames))
+	for i, name := range lognames {
+		names[i] = name
+	}
+	var existing []string
+	err := r.db.With(dbcontext.WithPrimary(ctx)).
+		Select("logname").
+		From(entity.LoginUser{}.TableName()).
+		Where(dbx.In("logname", names...)).
+		Column(&existing)
+	return existing, err
+}
+
+// CreateBatch inserts the user records into the database with a multi-row INSERT statement.
+func (r repository) CreateBatch(ctx context.Context, users []entity.LoginUser) error {
+	if len(users) == 0 {
+		return nil
+	}
+	sql := "INSERT INTO {{" + entity.LoginUser{}.TableName() + "}} ([[logname]], [[logpassword]], [[department]], [[purview]]) VALUES "
+	params := dbx.Params{}
+	for i, u := range users {
+		if i > 0 {
+			sql += ", "
+		}
+		sql += fmt.Sprintf("({:n%d}, {:p%d}, {:d%d}, {:u%d})", i, i, i, i)
+		params[fmt.Sprintf("n%d", i)] = u.Logname
+		params[fmt.Sprintf("p%d", i)] = u.Logpassword
+		params[fmt.Sprintf("d%d", i)] = u.Department
+		params[fmt.Sprintf("u%d", i)] = u.Purview
+	}
+	_, err := r.db.With(ctx).NewQuery(sql).Bind(params).Execute()
+	return err
+}
+
+// GetByLogname reads the user with the given login name from the primary database,
+// since the password of the user may have just been changed.
+func (r repository) GetByLogname(ctx context.Context, logname string) (entity.LoginUser, error) {
+	var user entity.LoginUser
+	err := r.db.With(dbcontext.WithPrimary(ctx)).
+		Select("id", "logname", "logpassword", "department", "purview").
+		From(entity.LoginUser{}.TableName()).
+		Where(dbx.HashExp{"logname": logname}).
+		One(&user)
+	return user, err
+}
+
+// UpdatePassword saves the new password of a user. The condition on the old password makes
+// concurrent changes of the same password fail with ErrPasswordChanged instead of overwriting each other.
+func (r repository) UpdatePassword(ctx context.Context, id int, oldPassword, newPassword string) error {
+	result, err := r.db.With(ctx).Update(entity.LoginUser{}.TableName(), dbx.Params{
+		"logpassword": newPassword,
+	}, dbx.HashExp{"id": id, "logpassword": oldPassword}).Execute()
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrPasswordChanged
+	}
+	return nil
+}
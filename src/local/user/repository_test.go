@@ -0,0 +1,75 @@
+package user
+
+import (
+	"context"
+	"database/sql/driver"
+	"github.com/stretchr/testify/assert"
+	"local/entity"
+	"pkg/dbcontext"
+	"pkg/dbtest"
+	"pkg/log"
+	"testing"
+)
+
+func TestRepository(t *testing.T) {
+	logger, _ := log.NewForTest()
+	db, server := dbtest.Open("mysql", func(query string, args []driver.Value) (*dbtest.Result, error) {
+		return &dbtest.Result{Columns: []string{"logname"}, Rows: [][]driver.Value{{"alice"}}, RowsAffected: 2}, nil
+	})
+	repo := NewRepository(dbcontext.New(db), logger)
+	ctx := context.Background()
+
+	existing, err := repo.Existing(ctx, []string{"alice", "bob"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"alice"}, existing)
+
+	err = repo.CreateBatch(ctx, []entity.LoginUser{
+		{Logname: "bob", Logpassword: "hash1", Department: "sales"},
+		{Logname: "carol", Logpassword: "hash2", Purview: "user"},
+	})
+	assert.Nil(t, err)
+
+	statements := server.Statements()
+	if assert.Equal(t, 2, len(statements)) {
+		assert.Equal(t, "SELECT `logname` FROM `loguser` WHERE `logname` IN (?, ?)", statements[0].SQL)
+		assert.Equal(t, "INSERT INTO `loguser` (`logname`, `logpassword`, `department`, `purview`) VALUES (?, ?, ?, ?), (?, ?, ?, ?)", statements[1].SQL)
+		assert.Equal(t, []driver.Value{"bob", "hash1", "sales", "", "carol", "hash2", "", "user"}, statements[1].Args)
+	}
+
+	// nothing is sent to the database for empty input
+	existing, err = repo.Existing(ctx, nil)
+	assert.Nil(t, err)
+	assert.Empty(t, existing)
+	assert.Nil(t, repo.CreateBatch(ctx, nil))
+	assert.Equal(t, 2, len(server.Statements()))
+}
+
+func TestRepository_password(t *testing.T) {
+	logger, _ := log.NewForTest()
+	var affected int64
+	db, server := dbtest.Open("mysql", func(query string, args []driver.Value) (*dbtest.Result, error) {
+		return &dbtest.Result{
+			Columns:      []string{"id", "logname", "logpassword", "department", "purview"},
+			Rows:         [][]driver.Value{{int64(1), "alice", "hash1", "sales", "user"}},
+			RowsAffected: affected,
+		}, nil
+	})
+	repo := NewRepository(dbcontext.New(db), logger)
+	ctx := context.Background()
+
+	user, err := repo.GetByLogname(ctx, "alice")
+	assert.Nil(t, err)
+	assert.Equal(t, entity.LoginUser{ID: 1, Logname: "alice", Logpassword: "hash1", Department: "sales", Purview: "user"}, user)
+
+	affected = 1
+	assert.Nil(t, repo.UpdatePassword(ctx, 1, "hash1", "hash2"))
+	affected = 0
+	assert.Equal(t, ErrPasswordChanged, repo.UpdatePassword(ctx, 1, "hash1", "hash2"))
+
+	statements := server.Statements()
+	if assert.Equal(t, 3, len(statements)) {
+		assert.Equal(t, "SELECT `id`, `logname`, `logpassword`, `department`, `purview` FROM `loguser` WHERE `logname`=?", statements[0].SQL)
+		assert.Equal(t, "UPDATE `loguser` SET `logpassword`=? WHERE `id`=? AND `logpassword`=?", statements[1].SQL)
+		assert.Equal(t, []driver.Value{"hash2", int64(1), "hash1"}, statements[1].Args)
+	}
+}
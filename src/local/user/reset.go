@@ -0,0 +1,192 @@
+package user
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"github.com/dgrijalva/jwt-go"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"local/errors"
+	"pkg/jsontime"
+	"pkg/log"
+	"pkg/password"
+	"strings"
+	"time"
+)
+
+// DefaultResetTTL is the default lifetime of the password reset tokens.
+const DefaultResetTTL = 15 * time.Minute
+
+// ResetEventName names the events sending password reset tokens.
+const ResetEventName = "password_reset"
+
+// resetPurpose is the "purpose" claim of the reset tokens, which tells them apart from other JWTs.
+const resetPurpose = "password_reset"
+
+// errInvalidResetToken is returned for the reset tokens that are malformed, expired or already used.
+var errInvalidResetToken = errors.BadRequest("The reset token is invalid or has expired.")
+
+// Sender delivers events to the users, e.g. a *webhook.Notifier forwarding them to an email service.
+type Sender interface {
+	Send(event interface{})
+}
+
+// ResetEvent carries a password reset token to the user it was issued to.
+type ResetEvent struct {
+	Event     string        `json:"event"`
+	User      string        `json:"user"`
+	Token     string        `json:"token"`
+	ExpiresAt jsontime.Time `json:"expires_at"`
+}
+
+// ResetService lets the users who forgot their password choose a new one.
+type ResetService interface {
+	// Forgot issues a reset token for the user with the given login name and sends it to them.
+	Forgot(ctx context.Context, logname string) error
+	// Reset sets the password of the user a reset token was issued to.
+	Reset(ctx context.Context, req ResetPasswordRequest) error
+}
+
+// ForgotPasswordRequest represents a request for a password reset token.
+type ForgotPasswordRequest struct {
+	Logname string `json:"logname"`
+}
+
+// Validate validates the ForgotPasswordRequest fields.
+func (m ForgotPasswordRequest) Validate() error {
+	return validation.ValidateStruct(&m,
+		validation.Field(&m.Logname, validation.Required, validation.Length(0, 64)),
+	)
+}
+
+// ResetPasswordRequest represents a request setting a new password with a reset token.
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// Validate validates the ResetPasswordRequest fields. The minimum length of the password is set by the password policy
+// the service checks next.
+func (m ResetPasswordRequest) Validate() error {
+	return validation.ValidateStruct(&m,
+		validation.Field(&m.Token, validation.Required),
+		validation.Field(&m.Password, validation.Required, validation.Length(0, password.MaxLength)),
+	)
+}
+
+type resetService struct {
+	repo   Repository
+	key    func() string
+	ttl    time.Duration
+	sender Sender
+	policy password.Policy
+	logger log.Logger
+}
+
+// NewResetService creates a new password reset service. The reset tokens are JWTs signed with a key derived
+// from the key returned by key, so that they are not accepted as access tokens, valid for ttl (DefaultResetTTL if zero), and delivered by sender in a ResetEvent.
+// The new passwords must follow the given policy.
+func NewResetService(repo Repository, key func() string, ttl time.Duration, sender Sender, policy password.Policy, logger log.Logger) ResetService {
+	if ttl == 0 {
+		ttl = DefaultResetTTL
+	}
+	return resetService{repo, key, ttl, sender, policy, logger}
+}
+
+// Forgot issues a reset token for the user with the given login name and sends it to them.
+// Unknown login names are only logged, so that callers cannot find out which accounts exist.
+func (s resetService) Forgot(ctx context.Context, logname string) error {
+	user, err := s.repo.GetByLogname(ctx, logname)
+	if err == sql.ErrNoRows {
+		s.logger.With(ctx).Infof("password reset requested for unknown user %q", logname)
+		return nil
+	} else if err != nil {
+		return err
+	}
+	expiresAt := time.Now().Add(s.ttl)
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":     user.Logname,
+		"purpose": resetPurpose,
+		"pwd":     s.stamp(user.Logpassword),
+		"exp":     expiresAt.Unix(),
+	}).SignedString(resetKey(s.key()))
+	if err != nil {
+		return err
+	}
+	s.sender.Send(ResetEvent{
+		Event:     ResetEventName,
+		User:      user.Logname,
+		Token:     token,
+		ExpiresAt: jsontime.New(expiresAt),
+	})
+	s.logger.With(ctx).Infof("password reset token issued to user %q", user.Logname)
+	return nil
+}
+
+// Reset sets the password of the user a reset token was issued to. The tokens are single use:
+// each one is bound to the password it was issued for and is rejected once that password has changed.
+func (s resetService) Reset(ctx context.Context, req ResetPasswordRequest) error {
+	if err := req.Validate(); err != nil {
+		return err
+	}
+	if violations := s.policy.Check(req.Password); len(violations) > 0 {
+		return errors.InvalidInput(validation.Errors{
+			"password": validation.NewError("validation_password_policy", strings.Join(violations, ", ")),
+		})
+	}
+
+	claims := jwt.MapClaims{}
+	parser := &jwt.Parser{ValidMethods: []string{"HS256"}}
+	if _, err := parser.ParseWithClaims(req.Token, claims, func(t *jwt.Token) (interface{}, error) {
+		return resetKey(s.key()), nil
+	}); err != nil {
+		s.logger.With(ctx).Infof("invalid password reset token: %v", err)
+		return errInvalidResetToken
+	}
+	logname, _ := claims["sub"].(string)
+	stamp, _ := claims["pwd"].(string)
+	if claims["purpose"] != resetPurpose || logname == "" {
+		s.logger.With(ctx).Info("invalid password reset token: not a reset token")
+		return errInvalidResetToken
+	}
+
+	user, err := s.repo.GetByLogname(ctx, logname)
+	if err == sql.ErrNoRows {
+		return errInvalidResetToken
+	} else if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(stamp), []byte(s.stamp(user.Logpassword))) {
+		s.logger.With(ctx).Infof("password reset token of user %q already used", logname)
+		return errInvalidResetToken
+	}
+	hash, err := password.Hash(req.Password)
+	if err != nil {
+		return err
+	}
+	// a concurrent reset with the same token changes the password first
+	if err := s.repo.UpdatePassword(ctx, user.ID, user.Logpassword, hash); err == ErrPasswordChanged {
+		return errInvalidResetToken
+	} else if err != nil {
+		return err
+	}
+	s.logger.With(ctx).Infof("password of user %q reset", logname)
+	return nil
+}
+
+// resetKey derives the key signing the reset tokens from the key signing the access tokens.
+func resetKey(key string) []byte {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(resetPurpose))
+	return mac.Sum(nil)
+}
+
+// stamp derives the value binding a reset token to the stored password. It is keyed with the
+// signing key so that the token, which is readable by anyone, does not disclose anything about the password.
+func (s resetService) stamp(storedPassword string) string {
+	mac := hmac.New(sha256.New, []byte(s.key()))
+	mac.Write([]byte(storedPassword))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
@@ -0,0 +1,109 @@
+package user
+
+import (
+	"context"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"local/entity"
+	"local/errors"
+	"pkg/log"
+	"pkg/password"
+	"testing"
+	"time"
+)
+
+func Test_resetService_Forgot(t *testing.T) {
+	logger, entries := log.NewForTest()
+	repo := &mockRepository{items: []entity.LoginUser{{ID: 1, Logname: "alice", Logpassword: "old"}}}
+	sender := &mockSender{}
+	s := NewResetService(repo, testKey, time.Minute, sender, password.Policy{}, logger)
+	ctx := context.Background()
+
+	assert.Nil(t, s.Forgot(ctx, "alice"))
+	if assert.Equal(t, 1, len(sender.events)) {
+		event := sender.events[0].(ResetEvent)
+		assert.Equal(t, ResetEventName, event.Event)
+		assert.Equal(t, "alice", event.User)
+		assert.NotEmpty(t, event.Token)
+		assert.WithinDuration(t, time.Now().Add(time.Minute), event.ExpiresAt.Time, 2*time.Second)
+		// the token is not signed with the key of the access tokens
+		_, err := jwt.Parse(event.Token, func(*jwt.Token) (interface{}, error) { return []byte(testKey()), nil })
+		assert.NotNil(t, err)
+	}
+
+	// unknown users are not told apart from the existing ones
+	assert.Nil(t, s.Forgot(ctx, "bob"))
+	assert.Equal(t, 1, len(sender.events))
+	assert.Equal(t, 1, entries.FilterMessageSnippet("unknown user").Len())
+}
+
+func Test_resetService_Reset(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := &mockRepository{items: []entity.LoginUser{{ID: 1, Logname: "alice", Logpassword: "old"}}}
+	sender := &mockSender{}
+	s := NewResetService(repo, testKey, time.Minute, sender, password.Policy{RequireDigit: true}, logger)
+	ctx := context.Background()
+	assert.Nil(t, s.Forgot(ctx, "alice"))
+	token := sender.events[0].(ResetEvent).Token
+
+	// the new password must follow the policy
+	err := s.Reset(ctx, ResetPasswordRequest{token, "nodigits"})
+	if assert.IsType(t, errors.ErrorResponse{}, err) {
+		assert.Equal(t, errors.CodeInvalidInput, err.(errors.ErrorResponse).Code)
+	}
+
+	assert.Nil(t, s.Reset(ctx, ResetPasswordRequest{token, "secret1"}))
+	assert.True(t, password.Verify(repo.items[0].Logpassword, "secret1"))
+
+	// the token cannot be used again
+	assert.Equal(t, errInvalidResetToken, s.Reset(ctx, ResetPasswordRequest{token, "secret2"}))
+	assert.True(t, password.Verify(repo.items[0].Logpassword, "secret1"))
+
+	// a new token is needed
+	assert.Nil(t, s.Forgot(ctx, "alice"))
+	assert.Nil(t, s.Reset(ctx, ResetPasswordRequest{sender.events[1].(ResetEvent).Token, "secret2"}))
+	assert.True(t, password.Verify(repo.items[0].Logpassword, "secret2"))
+}
+
+func Test_resetService_Reset_invalid(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := &mockRepository{items: []entity.LoginUser{{ID: 1, Logname: "alice", Logpassword: "old"}}}
+	sender := &mockSender{}
+	ctx := context.Background()
+
+	expired := NewResetService(repo, testKey, -time.Minute, sender, password.Policy{}, logger)
+	assert.Nil(t, expired.Forgot(ctx, "alice"))
+	s := NewResetService(repo, testKey, 0, sender, password.Policy{}, logger)
+	assert.Nil(t, s.Forgot(ctx, "alice"))
+	token := sender.events[1].(ResetEvent).Token
+
+	sign := func(claims jwt.MapClaims, key []byte) string {
+		token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(key)
+		return token
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+	for name, token := range map[string]string{
+		"expired":      sender.events[0].(ResetEvent).Token,
+		"malformed":    "abc",
+		"tampered":     token + "x",
+		"wrong key":    sign(jwt.MapClaims{"sub": "alice", "purpose": resetPurpose, "exp": exp}, resetKey("other")),
+		"access key":   sign(jwt.MapClaims{"sub": "alice", "purpose": resetPurpose, "exp": exp}, []byte(testKey())),
+		"access token": sign(jwt.MapClaims{"id": "1", "name": "alice", "exp": exp}, resetKey(testKey())),
+		"unknown user": sign(jwt.MapClaims{"sub": "bob", "purpose": resetPurpose, "exp": exp}, resetKey(testKey())),
+	} {
+		assert.Equal(t, errInvalidResetToken, s.Reset(ctx, ResetPasswordRequest{token, "secret1"}), name)
+	}
+	assert.Equal(t, "old", repo.items[0].Logpassword)
+}
+
+func testKey() string {
+	return "test"
+}
+
+type mockSender struct {
+	events []interface{}
+}
+
+func (m *mockSender) Send(event interface{}) {
+	m.events = append(m.events, event)
+}
@@ -0,0 +1,226 @@
+package user
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"io"
+	"local/entity"
+	"local/errors"
+	"pkg/dbcontext"
+	"pkg/log"
+	"pkg/password"
+	"sort"
+	"strings"
+)
+
+// importBatchSize is the number of users inserted by each INSERT statement of an import.
+const importBatchSize = 100
+
+// Service encapsulates usecase logic for user accounts.
+type Service interface {
+	// Import creates the user accounts listed in a CSV document, reporting its progress as a percentage if progress is not nil.
+	Import(ctx context.Context, r io.Reader, progress func(percent int)) (ImportSummary, error)
+}
+
+// ImportUserRequest represents a user account to be created by an import.
+type ImportUserRequest struct {
+	Logname    string `json:"logname"`
+	Password   string `json:"password"`
+	Department string `json:"department"`
+	Purview    string `json:"purview"`
+}
+
+// Validate validates the ImportUserRequest fields. The minimum length of the password is set by the password policy
+// the service checks next.
+func (m ImportUserRequest) Validate() error {
+	return validation.ValidateStruct(&m,
+		validation.Field(&m.Logname, validation.Required, validation.Length(0, 64)),
+		validation.Field(&m.Password, validation.Required, validation.Length(0, password.MaxLength)),
+		validation.Field(&m.Department, validation.Length(0, 128)),
+		validation.Field(&m.Purview, validation.Length(0, 128)),
+	)
+}
+
+const (
+	rowSkipped = "skipped"
+	rowError   = "error"
+)
+
+// ImportRow describes a CSV row that has not been imported.
+type ImportRow struct {
+	// Row is the number of the row in the CSV document, the header being row 1.
+	Row    int    `json:"row"`
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+}
+
+// ImportSummary reports the outcome of an import.
+type ImportSummary struct {
+	Created int         `json:"created"`
+	Skipped int         `json:"skipped"`
+	Errored int         `json:"errored"`
+	Rows    []ImportRow `json:"rows"`
+}
+
+func (s *ImportSummary) skip(row int, reason string) {
+	s.Skipped++
+	s.Rows = append(s.Rows, ImportRow{row, rowSkipped, reason})
+}
+
+func (s *ImportSummary) fail(row int, reason string) {
+	s.Errored++
+	s.Rows = append(s.Rows, ImportRow{row, rowError, reason})
+}
+
+type service struct {
+	repo          Repository
+	transactional dbcontext.TransactionFunc
+	policy        password.Policy
+	logger        log.Logger
+}
+
+// NewService creates a new user service. The users of an import are inserted within a transaction
+// started by transactional. The passwords of new users must follow the given policy.
+func NewService(repo Repository, transactional dbcontext.TransactionFunc, policy password.Policy, logger log.Logger) Service {
+	return service{repo, transactional, policy, logger}
+}
+
+// Import creates the user accounts listed in a CSV document.
+// The first row of the document is a header naming the columns: "logname" and "password" are required,
+// "department" and "purview" are optional. Rows whose login name is already taken are skipped, and
+// invalid rows, including those whose password breaks the password policy, are reported as errors.
+// The passwords are stored hashed. Either all the other rows are created or, if the database fails, none of them.
+// If progress is not nil, it is called with the percentage of the users inserted after each batch.
+func (s service) Import(ctx context.Context, r io.Reader, progress func(percent int)) (ImportSummary, error) {
+	summary := ImportSummary{Rows: []ImportRow{}}
+	requests, rows, err := s.parse(r, &summary)
+	if err != nil {
+		return summary, err
+	}
+
+	// skip the users that already exist
+	names := make([]string, len(requests))
+	for i, req := range requests {
+		names[i] = req.Logname
+	}
+	existing, err := s.repo.Existing(ctx, names)
+	if err != nil {
+		return summary, err
+	}
+	taken := map[string]bool{}
+	for _, name := range existing {
+		taken[strings.ToLower(name)] = true
+	}
+
+	var users []entity.LoginUser
+	for i, req := range requests {
+		if taken[strings.ToLower(req.Logname)] {
+			summary.skip(rows[i], fmt.Sprintf("user %q already exists", req.Logname))
+			continue
+		}
+		hash, err := password.Hash(req.Password)
+		if err != nil {
+			return summary, err
+		}
+		users = append(users, entity.LoginUser{
+			Logname:     req.Logname,
+			Logpassword: hash,
+			Department:  req.Department,
+			Purview:     req.Purview,
+		})
+	}
+
+	err = s.transactional(ctx, func(ctx context.Context) error {
+		for start := 0; start < len(users); start += importBatchSize {
+			end := start + importBatchSize
+			if end > len(users) {
+				end = len(users)
+			}
+			if err := s.repo.CreateBatch(ctx, users[start:end]); err != nil {
+				return err
+			}
+			if progress != nil {
+				progress(end * 100 / len(users))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return summary, err
+	}
+	summary.Created = len(users)
+	sort.Slice(summary.Rows, func(i, j int) bool { return summary.Rows[i].Row < summary.Rows[j].Row })
+	s.logger.With(ctx).Infof("imported %v users: %v skipped, %v errored", summary.Created, summary.Skipped, summary.Errored)
+	return summary, nil
+}
+
+// parse reads the valid import requests from a CSV document together with their row numbers.
+// Invalid rows and rows duplicating an earlier login name are recorded in the summary.
+func (s service) parse(r io.Reader, summary *ImportSummary) ([]ImportUserRequest, []int, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, errors.BadRequest("The CSV document is empty.")
+	} else if err != nil {
+		return nil, nil, errors.BadRequest(fmt.Sprintf("The CSV header is malformed: %v", err))
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"logname", "password"} {
+		if _, ok := columns[name]; !ok {
+			return nil, nil, errors.BadRequest(fmt.Sprintf("The CSV header has no %q column.", name))
+		}
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var requests []ImportUserRequest
+	var rows []int
+	seen := map[string]bool{}
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if perr, ok := err.(*csv.ParseError); ok && perr.Err == csv.ErrFieldCount {
+				summary.fail(row, "wrong number of fields")
+				continue
+			}
+			return nil, nil, errors.BadRequest(fmt.Sprintf("The CSV document is malformed: %v", err))
+		}
+
+		req := ImportUserRequest{
+			Logname:    field(record, "logname"),
+			Password:   field(record, "password"),
+			Department: field(record, "department"),
+			Purview:    field(record, "purview"),
+		}
+		if err := req.Validate(); err != nil {
+			summary.fail(row, err.Error())
+			continue
+		}
+		if violations := s.policy.Check(req.Password); len(violations) > 0 {
+			summary.fail(row, "password: "+strings.Join(violations, ", ")+".")
+			continue
+		}
+		key := strings.ToLower(req.Logname)
+		if seen[key] {
+			summary.skip(row, fmt.Sprintf("user %q is listed more than once", req.Logname))
+			continue
+		}
+		seen[key] = true
+		requests = append(requests, req)
+		rows = append(rows, row)
+	}
+	return requests, rows, nil
+}
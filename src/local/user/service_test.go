@@ -0,0 +1,185 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"local/entity"
+	"pkg/log"
+	"pkg/password"
+	"strings"
+	"testing"
+)
+
+func init() {
+	// keep hashing fast in tests
+	password.Cost = bcrypt.MinCost
+}
+
+func TestImportUserRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		model     ImportUserRequest
+		wantError bool
+	}{
+		{"success", ImportUserRequest{Logname: "test", Password: "secret"}, false},
+		{"logname required", ImportUserRequest{Logname: "", Password: "secret"}, true},
+		{"password required", ImportUserRequest{Logname: "test", Password: ""}, true},
+		{"password too long", ImportUserRequest{Logname: "test", Password: strings.Repeat("a", password.MaxLength+1)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.model.Validate()
+			assert.Equal(t, tt.wantError, err != nil)
+		})
+	}
+}
+
+func Test_service_Import(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := &mockRepository{}
+	s := NewService(repo, repo.transactional, password.Policy{}, logger)
+	ctx := context.Background()
+
+	// valid CSV
+	summary, err := s.Import(ctx, strings.NewReader("logname,password,department,purview\nalice,secret1,sales,user\nbob,secret2,it,admin\n"), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, ImportSummary{Created: 2, Rows: []ImportRow{}}, summary)
+	if assert.Equal(t, 2, len(repo.items)) {
+		assert.Equal(t, "alice", repo.items[0].Logname)
+		assert.Equal(t, "sales", repo.items[0].Department)
+		assert.True(t, password.Verify(repo.items[0].Logpassword, "secret1"))
+		assert.Equal(t, "admin", repo.items[1].Purview)
+	}
+
+	// duplicates in the database and within the document are skipped
+	summary, err = s.Import(ctx, strings.NewReader("password,logname\nsecret3,carol\nsecret4,Alice\nsecret5,carol\n"), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, summary.Created)
+	assert.Equal(t, 2, summary.Skipped)
+	assert.Equal(t, []ImportRow{
+		{3, rowSkipped, `user "Alice" already exists`},
+		{4, rowSkipped, `user "carol" is listed more than once`},
+	}, summary.Rows)
+	assert.Equal(t, 3, len(repo.items))
+
+	// malformed and invalid rows are reported as errors
+	summary, err = s.Import(ctx, strings.NewReader("logname,password\ndave\nerin,\nfrank,secret6\n"), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, summary.Created)
+	assert.Equal(t, 2, summary.Errored)
+	if assert.Equal(t, 2, len(summary.Rows)) {
+		assert.Equal(t, ImportRow{2, rowError, "wrong number of fields"}, summary.Rows[0])
+		assert.Equal(t, 3, summary.Rows[1].Row)
+		assert.Contains(t, summary.Rows[1].Reason, "password")
+	}
+
+	// invalid documents are rejected
+	_, err = s.Import(ctx, strings.NewReader(""), nil)
+	assert.NotNil(t, err)
+	_, err = s.Import(ctx, strings.NewReader("name,password\nalice,secret\n"), nil)
+	assert.NotNil(t, err)
+
+	// nothing is created if the database fails
+	count := len(repo.items)
+	summary, err = s.Import(ctx, strings.NewReader("logname,password\ngrace,secret7\nerror,secret8\n"), nil)
+	assert.Equal(t, errCRUD, err)
+	assert.Equal(t, count, len(repo.items))
+}
+
+func Test_service_Import_policy(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := &mockRepository{}
+	s := NewService(repo, repo.transactional, password.Policy{MinLength: 8, RequireDigit: true, RejectCommon: true}, logger)
+
+	summary, err := s.Import(context.Background(), strings.NewReader("logname,password\nalice,longsecret1\nbob,longsecret\ncarol,password1\ndave,short1\n"), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, summary.Created)
+	assert.Equal(t, []ImportRow{
+		{3, rowError, "password: must contain a digit."},
+		{4, rowError, "password: is too common."},
+		{5, rowError, "password: must be at least 8 characters long."},
+	}, summary.Rows)
+}
+
+func Test_service_Import_progress(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := &mockRepository{}
+	s := NewService(repo, repo.transactional, password.Policy{}, logger)
+
+	// the progress is reported once per inserted batch
+	document := "logname,password\n"
+	for i := 0; i < importBatchSize+importBatchSize/2; i++ {
+		document += fmt.Sprintf("user%v,secret%v\n", i, i)
+	}
+	var percents []int
+	summary, err := s.Import(context.Background(), strings.NewReader(document), func(percent int) {
+		percents = append(percents, percent)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, importBatchSize+importBatchSize/2, summary.Created)
+	assert.Equal(t, []int{66, 100}, percents)
+}
+
+var errCRUD = errors.New("error crud")
+
+type mockRepository struct {
+	items []entity.LoginUser
+}
+
+func (m *mockRepository) Existing(ctx context.Context, lognames []string) ([]string, error) {
+	var existing []string
+	for _, name := range lognames {
+		for _, item := range m.items {
+			if strings.EqualFold(item.Logname, name) {
+				existing = append(existing, item.Logname)
+			}
+		}
+	}
+	return existing, nil
+}
+
+func (m *mockRepository) CreateBatch(ctx context.Context, users []entity.LoginUser) error {
+	for _, u := range users {
+		if u.Logname == "error" {
+			return errCRUD
+		}
+	}
+	m.items = append(m.items, users...)
+	return nil
+}
+
+func (m *mockRepository) GetByLogname(ctx context.Context, logname string) (entity.LoginUser, error) {
+	for _, item := range m.items {
+		if item.Logname == logname {
+			return item, nil
+		}
+	}
+	return entity.LoginUser{}, sql.ErrNoRows
+}
+
+func (m *mockRepository) UpdatePassword(ctx context.Context, id int, oldPassword, newPassword string) error {
+	for i, item := range m.items {
+		if item.ID == id {
+			if item.Logpassword != oldPassword {
+				return ErrPasswordChanged
+			}
+			m.items[i].Logpassword = newPassword
+			return nil
+		}
+	}
+	return ErrPasswordChanged
+}
+
+// transactional rolls back the users created by f if f fails.
+func (m *mockRepository) transactional(ctx context.Context, f func(ctx context.Context) error) error {
+	count := len(m.items)
+	if err := f(ctx); err != nil {
+		m.items = m.items[:count]
+		return err
+	}
+	return nil
+}
@@ -2,19 +2,105 @@
 package accesslog
 
 import (
+	"fmt"
 	routing "github.com/go-ozzo/ozzo-routing/v2"
-	"github.com/go-ozzo/ozzo-routing/v2/access"
-	"pkg/log"
+	"io"
+	"math/rand"
+	"net"
 	"net/http"
+	"os"
+	"path"
+	"pkg/log"
+	"pkg/routeinfo"
+	"sort"
+	"strconv"
+	"sync"
 	"time"
 )
 
+// DefaultExcludedPaths are the paths of the health and metrics endpoints, whose frequent requests
+// would drown the others in the access log.
+var DefaultExcludedPaths = []string{"/healthz", "/readyz", "/metrics"}
+
+// DefaultLatencyBuckets are the boundaries of the latency buckets used when none is specified.
+var DefaultLatencyBuckets = []time.Duration{10 * time.Millisecond, 100 * time.Millisecond, time.Second}
+
+// Options represents the options of the access log middleware.
+type Options struct {
+	// LatencyBuckets are the boundaries of the latency buckets. DefaultLatencyBuckets is used if empty.
+	LatencyBuckets []time.Duration
+	// SampleRate is the fraction of successful requests being logged, between 0 and 1.
+	// Requests failing with a 4xx or 5xx status are always logged.
+	// Every request is logged if SampleRate is zero or not less than 1.
+	SampleRate float64
+	// ResponseTime adds an X-Response-Time header to every response, giving the time in milliseconds
+	// the server has spent on the request until the response header is written.
+	ResponseTime bool
+	// Format is the format of the access log: FormatJSON (the default) logs a structured message with the logger,
+	// while FormatCommon and FormatCombined write a line in the Apache log format to Writer, for legacy tools.
+	Format string
+	// Writer receives the lines of the Apache log formats. Defaults to os.Stdout.
+	Writer io.Writer
+	// ClientIP returns the IP address of the client sending the request, logged as the host of the Apache log formats.
+	// The remote address is used if nil.
+	ClientIP func(*http.Request) string
+	// ExcludedPaths are the patterns of the request paths that are not logged, as matched by path.Match,
+	// e.g. "/healthz" or "/internal/*". See DefaultExcludedPaths. Every request is logged if empty.
+	ExcludedPaths []string
+}
+
+// The formats of the access log.
+const (
+	// FormatJSON logs a structured message per request with the logger.
+	FormatJSON = "json"
+	// FormatCommon writes a line per request in the Common Log Format.
+	FormatCommon = "common"
+	// FormatCombined writes a line per request in the Combined Log Format, which adds the referer and user agent.
+	FormatCombined = "combined"
+)
+
+// clfTimeFormat is the layout of the request times in the Apache log formats.
+const clfTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// ResponseTimeHeader is the name of the header reporting the server-measured request duration.
+const ResponseTimeHeader = "X-Response-Time"
+
 // Handler returns a middleware that records an access log message for every HTTP request being processed.
-func Handler(logger log.Logger) routing.Handler {
+// Besides the raw duration, each message carries a "latency_bucket" field naming the range the duration
+// falls in, as delimited by the configured bucket boundaries.
+func Handler(logger log.Logger, options ...Options) routing.Handler {
+	var opt Options
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	if len(opt.LatencyBuckets) == 0 {
+		opt.LatencyBuckets = DefaultLatencyBuckets
+	}
+	buckets := append([]time.Duration{}, opt.LatencyBuckets...)
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i] < buckets[j] })
+	sampled := opt.SampleRate > 0 && opt.SampleRate < 1
+	if opt.Writer == nil {
+		opt.Writer = os.Stdout
+	}
+	if opt.ClientIP == nil {
+		opt.ClientIP = func(req *http.Request) string {
+			host, _, err := net.SplitHostPort(req.RemoteAddr)
+			if err != nil {
+				return req.RemoteAddr
+			}
+			return host
+		}
+	}
+	// the lines of concurrent requests must not interleave
+	var mu sync.Mutex
+
 	return func(c *routing.Context) error {
 		start := time.Now()
 
-		rw := &access.LogResponseWriter{ResponseWriter: c.Response, Status: http.StatusOK}
+		rw := &responseWriter{ResponseWriter: c.Response, Status: http.StatusOK}
+		if opt.ResponseTime {
+			rw.start = start
+		}
 		c.Response = rw
 
 		// associate request ID and session ID with the request context
@@ -24,11 +110,142 @@ func Handler(logger log.Logger) routing.Handler {
 		c.Request = c.Request.WithContext(ctx)
 
 		err := c.Next()
+		if err == nil {
+			// make sure the response time is reported if the handler has written nothing
+			rw.writeResponseTime()
+		}
+
+		if excluded(c.Request.URL.Path, opt.ExcludedPaths) {
+			return err
+		}
+
+		// successful requests are only logged at the sample rate
+		if sampled && err == nil && rw.Status < http.StatusBadRequest && rand.Float64() >= opt.SampleRate {
+			return nil
+		}
+
+		if opt.Format == FormatCommon || opt.Format == FormatCombined {
+			line := clfLine(c.Request, opt.ClientIP(c.Request), start, rw.Status, rw.BytesWritten, opt.Format == FormatCombined)
+			mu.Lock()
+			_, _ = io.WriteString(opt.Writer, line)
+			mu.Unlock()
+			return err
+		}
 
 		// generate an access log message
-		logger.With(ctx, "duration", time.Now().Sub(start).Milliseconds(), "status", rw.Status).
+		duration := time.Now().Sub(start)
+		logger.With(ctx, "duration", duration.Milliseconds(), "latency_bucket", latencyBucket(duration, buckets),
+			"status", rw.Status, "route", routeinfo.Pattern(c)).
 			Infof("%s %s %s %d %d", c.Request.Method, c.Request.URL.Path, c.Request.Proto, rw.Status, rw.BytesWritten)
 
 		return err
 	}
 }
+
+// excluded reports whether the path matches one of the patterns.
+func excluded(p string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// clfLine returns the line describing a request in the Common Log Format, or in the Combined Log Format
+// if combined is true, with the given client IP as the host, e.g.:
+//
+//	127.0.0.1 - - [10/Oct/2026:13:55:36 +0000] "GET /v1/albums?page=2 HTTP/1.1" 200 2326 "-" "curl/7.68.0"
+func clfLine(req *http.Request, host string, start time.Time, status int, size int64, combined bool) string {
+	if host == "" {
+		host = "-"
+	}
+	uri := req.RequestURI
+	if uri == "" {
+		uri = req.URL.RequestURI()
+	}
+	bytes := "-"
+	if size > 0 {
+		bytes = strconv.FormatInt(size, 10)
+	}
+	line := fmt.Sprintf("%s - - [%s] \"%s %s %s\" %d %s", host, start.Format(clfTimeFormat),
+		req.Method, quoteCLF(uri), req.Proto, status, bytes)
+	if combined {
+		line += fmt.Sprintf(" \"%s\" \"%s\"", quoteCLF(orDash(req.Referer())), quoteCLF(orDash(req.UserAgent())))
+	}
+	return line + "\n"
+}
+
+// quoteCLF escapes the quotes, backslashes and control characters of a value logged between quotes, like Apache does.
+func quoteCLF(value string) string {
+	quoted := strconv.Quote(value)
+	return quoted[1 : len(quoted)-1]
+}
+
+// orDash returns value, or "-" if it is empty.
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// latencyBucket returns the name of the bucket the given duration falls in.
+// The boundaries must be sorted in ascending order. Each boundary belongs to the bucket above it.
+func latencyBucket(d time.Duration, boundaries []time.Duration) string {
+	if len(boundaries) == 0 {
+		return ""
+	}
+	if d < boundaries[0] {
+		return "<" + boundaries[0].String()
+	}
+	for i := 1; i < len(boundaries); i++ {
+		if d < boundaries[i] {
+			return boundaries[i-1].String() + "-" + boundaries[i].String()
+		}
+	}
+	return ">" + boundaries[len(boundaries)-1].String()
+}
+
+// responseWriter records the status and size of a response.
+// Unlike access.LogResponseWriter, it lets streaming handlers flush the response.
+type responseWriter struct {
+	http.ResponseWriter
+	Status       int
+	BytesWritten int64
+	// start is the time the request started at, if the response time is to be reported.
+	start         time.Time
+	headerWritten bool
+}
+
+// writeResponseTime sets the X-Response-Time header unless the response header has already been written.
+func (w *responseWriter) writeResponseTime() {
+	if w.headerWritten {
+		return
+	}
+	w.headerWritten = true
+	if !w.start.IsZero() {
+		w.Header().Set(ResponseTimeHeader, strconv.FormatInt(time.Since(w.start).Milliseconds(), 10))
+	}
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	w.writeResponseTime()
+	n, err := w.ResponseWriter.Write(p)
+	w.BytesWritten += int64(n)
+	return n, err
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	w.writeResponseTime()
+	w.Status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Flush sends any buffered data to the client if the underlying writer supports it.
+func (w *responseWriter) Flush() {
+	w.writeResponseTime()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -1,12 +1,17 @@
 package accesslog
 
 import (
+	"bytes"
 	routing "github.com/go-ozzo/ozzo-routing/v2"
 	"pkg/log"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestHandler(t *testing.T) {
@@ -22,3 +27,178 @@ func TestHandler(t *testing.T) {
 	assert.Equal(t, 1, entries.Len())
 	assert.Equal(t, "GET /users HTTP/1.1 200 0", entries.All()[0].Message)
 }
+
+func TestHandler_route(t *testing.T) {
+	logger, entries := log.NewForTest()
+	router := routing.New()
+	router.Use(Handler(logger))
+	router.Get("/v1/test2/<id>", func(c *routing.Context) error {
+		return c.Write("ok")
+	})
+
+	req, _ := http.NewRequest("GET", "http://127.0.0.1/v1/test2/123", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	if assert.Equal(t, 1, entries.Len()) {
+		assert.Equal(t, "/v1/test2/<id>", entries.All()[0].ContextMap()["route"])
+	}
+
+	req, _ = http.NewRequest("GET", "http://127.0.0.1/unknown", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	if assert.Equal(t, 2, entries.Len()) {
+		assert.Equal(t, "", entries.All()[1].ContextMap()["route"])
+	}
+}
+
+func Test_latencyBucket(t *testing.T) {
+	tests := []struct {
+		name     string
+		duration time.Duration
+		want     string
+	}{
+		{"fast", 2 * time.Millisecond, "<10ms"},
+		{"lower boundary", 10 * time.Millisecond, "10ms-100ms"},
+		{"medium", 50 * time.Millisecond, "10ms-100ms"},
+		{"slow", 500 * time.Millisecond, "100ms-1s"},
+		{"very slow", 3 * time.Second, ">1s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, latencyBucket(tt.duration, DefaultLatencyBuckets))
+		})
+	}
+
+	assert.Equal(t, "", latencyBucket(time.Second, nil))
+	assert.Equal(t, "<250ms", latencyBucket(time.Millisecond, []time.Duration{250 * time.Millisecond}))
+}
+
+func TestHandler_latencyBucket(t *testing.T) {
+	req, _ := http.NewRequest("GET", "http://127.0.0.1/users", nil)
+	ctx := routing.NewContext(httptest.NewRecorder(), req)
+
+	logger, entries := log.NewForTest()
+	assert.Nil(t, Handler(logger, Options{LatencyBuckets: []time.Duration{5 * time.Second, time.Minute}})(ctx))
+	if assert.Equal(t, 1, entries.Len()) {
+		assert.Equal(t, "<5s", entries.All()[0].ContextMap()["latency_bucket"])
+	}
+}
+
+func TestHandler_sampling(t *testing.T) {
+	logger, entries := log.NewForTest()
+	router := routing.New()
+	router.Use(Handler(logger, Options{SampleRate: 0.2}))
+	router.Get("/ok", func(c *routing.Context) error {
+		return c.Write("ok")
+	})
+	router.Get("/bad", func(c *routing.Context) error {
+		c.Response.WriteHeader(http.StatusBadRequest)
+		return nil
+	})
+	router.Get("/error", func(c *routing.Context) error {
+		return routing.NewHTTPError(http.StatusInternalServerError)
+	})
+
+	send := func(path string, n int) int {
+		before := entries.Len()
+		for i := 0; i < n; i++ {
+			req, _ := http.NewRequest("GET", "http://127.0.0.1"+path, nil)
+			router.ServeHTTP(httptest.NewRecorder(), req)
+		}
+		return entries.Len() - before
+	}
+
+	// errors are always logged
+	assert.Equal(t, 100, send("/bad", 100))
+	assert.Equal(t, 100, send("/error", 100))
+
+	// roughly the sampled fraction of successful requests is logged
+	logged := send("/ok", 2000)
+	assert.True(t, logged > 300 && logged < 500, "logged %v of 2000 successful requests", logged)
+}
+
+func TestHandler_responseTime(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := routing.New()
+	router.Use(Handler(logger, Options{ResponseTime: true}))
+	router.Get("/slow", func(c *routing.Context) error {
+		time.Sleep(5 * time.Millisecond)
+		return c.Write("ok")
+	})
+	router.Get("/empty", func(c *routing.Context) error { return nil })
+
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/slow", nil)
+	router.ServeHTTP(res, req)
+	ms, err := strconv.Atoi(res.Header().Get(ResponseTimeHeader))
+	assert.Nil(t, err)
+	assert.True(t, ms >= 5, res.Header().Get(ResponseTimeHeader))
+
+	res = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/empty", nil)
+	router.ServeHTTP(res, req)
+	_, err = strconv.Atoi(res.Header().Get(ResponseTimeHeader))
+	assert.Nil(t, err)
+
+	// the header is off by default
+	router = routing.New()
+	router.Use(Handler(logger))
+	router.Get("/empty", func(c *routing.Context) error { return nil })
+	res = httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	assert.Empty(t, res.Header().Get(ResponseTimeHeader))
+}
+
+func TestHandler_combined(t *testing.T) {
+	logger, entries := log.NewForTest()
+	var buf bytes.Buffer
+	router := routing.New()
+	router.Use(Handler(logger, Options{Format: FormatCombined, Writer: &buf}))
+	router.Get("/v1/albums", func(c *routing.Context) error {
+		return c.Write("albums")
+	})
+
+	req := httptest.NewRequest("GET", "/v1/albums?page=2", nil)
+	req.RemoteAddr = "192.168.1.20:52311"
+	req.Header.Set("Referer", "https://example.com/")
+	req.Header.Set("User-Agent", `Mozilla/5.0 "quoted"`)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	pattern := regexp.MustCompile(`^192\.168\.1\.20 - - \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] ` +
+		`"GET /v1/albums\?page=2 HTTP/1\.1" 200 6 "https://example\.com/" "Mozilla/5\.0 \\"quoted\\""\n$`)
+	assert.Regexp(t, pattern, buf.String())
+	assert.Zero(t, entries.Len(), "no structured message is logged")
+
+	// the common format omits the referer and the user agent, and logs "-" for an empty response
+	buf.Reset()
+	handler := Handler(logger, Options{Format: FormatCommon, Writer: &buf})
+	assert.Nil(t, handler(routing.NewContext(httptest.NewRecorder(), req)))
+	assert.Regexp(t, regexp.MustCompile(`^192\.168\.1\.20 - - \[[^]]+\] "GET /v1/albums\?page=2 HTTP/1\.1" 200 -\n$`), buf.String())
+
+	// the host is the client IP resolved behind a proxy
+	buf.Reset()
+	handler = Handler(logger, Options{Format: FormatCommon, Writer: &buf, ClientIP: func(*http.Request) string { return "203.0.113.7" }})
+	assert.Nil(t, handler(routing.NewContext(httptest.NewRecorder(), req)))
+	assert.Regexp(t, regexp.MustCompile(`^203\.0\.113\.7 - - `), buf.String())
+}
+
+func TestHandler_excludedPaths(t *testing.T) {
+	logger, entries := log.NewForTest()
+	var lines bytes.Buffer
+	handlers := []routing.Handler{
+		Handler(logger, Options{ExcludedPaths: append([]string{"/internal/*"}, DefaultExcludedPaths...)}),
+		Handler(logger, Options{ExcludedPaths: DefaultExcludedPaths, Format: FormatCommon, Writer: &lines}),
+	}
+	for _, handler := range handlers {
+		for _, path := range []string{"/healthz", "/readyz", "/metrics", "/internal/debug", "/users", "/healthz/extra", "/internal"} {
+			req, _ := http.NewRequest("GET", "http://127.0.0.1"+path, nil)
+			assert.Nil(t, handler(routing.NewContext(httptest.NewRecorder(), req)))
+		}
+	}
+
+	var logged []string
+	for _, entry := range entries.All() {
+		logged = append(logged, entry.Message)
+	}
+	assert.Equal(t, []string{"GET /users HTTP/1.1 200 0", "GET /healthz/extra HTTP/1.1 200 0", "GET /internal HTTP/1.1 200 0"}, logged)
+	assert.Equal(t, 4, strings.Count(lines.String(), "\n"))
+	assert.NotContains(t, lines.String(), "/readyz")
+}
@@ -0,0 +1,33 @@
+// Package apiversion provides a middleware that requires clients to specify a supported API version.
+package apiversion
+
+import (
+	"fmt"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"net/http"
+	"strings"
+)
+
+// HeaderName is the name of the HTTP header carrying the API version requested by the client.
+const HeaderName = "X-API-Version"
+
+// Handler returns a middleware that rejects requests whose X-API-Version header is missing
+// or does not match one of the supported versions with a 400 error.
+func Handler(versions ...string) routing.Handler {
+	supported := make(map[string]bool, len(versions))
+	for _, v := range versions {
+		supported[v] = true
+	}
+	list := strings.Join(versions, ", ")
+
+	return func(c *routing.Context) error {
+		version := c.Request.Header.Get(HeaderName)
+		if version == "" {
+			return routing.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("The %v header is required. Supported versions: %v.", HeaderName, list))
+		}
+		if !supported[version] {
+			return routing.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("API version %q is not supported. Supported versions: %v.", version, list))
+		}
+		return nil
+	}
+}
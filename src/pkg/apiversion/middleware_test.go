@@ -0,0 +1,37 @@
+package apiversion
+
+import (
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	handler := Handler("1", "1.1")
+
+	tests := []struct {
+		name       string
+		version    string
+		wantStatus int
+	}{
+		{"matching", "1.1", 0},
+		{"mismatching", "2", http.StatusBadRequest},
+		{"missing", "", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "http://127.0.0.1/users", nil)
+			if tt.version != "" {
+				req.Header.Set(HeaderName, tt.version)
+			}
+			err := handler(routing.NewContext(httptest.NewRecorder(), req))
+			if tt.wantStatus == 0 {
+				assert.Nil(t, err)
+			} else if assert.NotNil(t, err) {
+				assert.Equal(t, tt.wantStatus, err.(routing.HTTPError).StatusCode())
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+// Package chain names the middleware of a router so that their order can be checked at startup.
+//
+// The order of middleware matters: e.g. the error handler must wrap the middleware whose errors it renders,
+// and authentication must run before the middleware that depend on the authenticated user. Validate checks
+// that a chain satisfies such constraints, so that a misordering fails the startup instead of misbehaving.
+package chain
+
+import (
+	"fmt"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"strings"
+)
+
+// Middleware is a middleware of a chain with the name used by the ordering constraints.
+type Middleware struct {
+	Name    string
+	Handler routing.Handler
+}
+
+// Named returns a named middleware.
+func Named(name string, handler routing.Handler) Middleware {
+	return Middleware{name, handler}
+}
+
+// Handlers returns the handlers of the chain in order, to be passed to the Use method of a router or route group.
+func Handlers(chain []Middleware) []routing.Handler {
+	handlers := make([]routing.Handler, len(chain))
+	for i, m := range chain {
+		handlers[i] = m.Handler
+	}
+	return handlers
+}
+
+// Constraint is an ordering constraint between the middleware of a chain.
+// A constraint naming a middleware that is not in the chain always holds, so that optional middleware
+// can be constrained as well.
+type Constraint struct {
+	// First is the name of the middleware that must run before the other one.
+	First string
+	// Then is the name of the middleware that must run after the first one. If empty, the first
+	// middleware must be the outermost one, running before all the others.
+	Then string
+}
+
+// Before returns a constraint requiring the middleware named first to run before the one named then.
+func Before(first, then string) Constraint {
+	return Constraint{First: first, Then: then}
+}
+
+// Outermost returns a constraint requiring the named middleware to run before all the others.
+func Outermost(name string) Constraint {
+	return Constraint{First: name}
+}
+
+// Validate checks that the chain satisfies the constraints. The error describes the first violated constraint.
+func Validate(chain []Middleware, constraints ...Constraint) error {
+	positions := map[string]int{}
+	for i := len(chain) - 1; i >= 0; i-- {
+		positions[chain[i].Name] = i
+	}
+	for _, c := range constraints {
+		first, ok := positions[c.First]
+		if !ok {
+			continue
+		}
+		if c.Then == "" {
+			if first != 0 {
+				return fmt.Errorf("middleware %q must be the outermost one, but the chain is %v", c.First, names(chain))
+			}
+			continue
+		}
+		// every occurrence of the second middleware must follow the first occurrence of the first one
+		for i, m := range chain {
+			if m.Name == c.Then && i < first {
+				return fmt.Errorf("middleware %q must run before %q, but the chain is %v", c.First, c.Then, names(chain))
+			}
+		}
+	}
+	return nil
+}
+
+// names returns the names of the middleware of the chain in order.
+func names(chain []Middleware) string {
+	list := make([]string, len(chain))
+	for i, m := range chain {
+		list[i] = m.Name
+	}
+	return "[" + strings.Join(list, ", ") + "]"
+}
@@ -0,0 +1,54 @@
+package chain
+
+import (
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	handler := func(c *routing.Context) error { return nil }
+	chain := func(names ...string) []Middleware {
+		var c []Middleware
+		for _, name := range names {
+			c = append(c, Named(name, handler))
+		}
+		return c
+	}
+	constraints := []Constraint{
+		Outermost("recovery"),
+		Before("auth", "authz"),
+		Before("auth", "ratelimit"),
+	}
+
+	// valid chains
+	assert.Nil(t, Validate(chain("recovery", "auth", "ratelimit", "authz"), constraints...))
+	assert.Nil(t, Validate(chain("recovery", "authz"), constraints...), "constraints on missing middleware hold")
+	assert.Nil(t, Validate(nil, constraints...))
+
+	// invalid chains
+	err := Validate(chain("recovery", "authz", "auth"), constraints...)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, `middleware "auth" must run before "authz", but the chain is [recovery, authz, auth]`, err.Error())
+	}
+	err = Validate(chain("auth", "recovery", "authz"), constraints...)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, `middleware "recovery" must be the outermost one, but the chain is [auth, recovery, authz]`, err.Error())
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	var calls []string
+	record := func(name string) routing.Handler {
+		return func(c *routing.Context) error {
+			calls = append(calls, name)
+			return nil
+		}
+	}
+	handlers := Handlers([]Middleware{Named("a", record("a")), Named("b", record("b"))})
+	if assert.Equal(t, 2, len(handlers)) {
+		_ = handlers[0](nil)
+		_ = handlers[1](nil)
+	}
+	assert.Equal(t, []string{"a", "b"}, calls)
+}
@@ -0,0 +1,127 @@
+// Package coalesce provides a middleware that lets concurrent identical GET requests share a single
+// execution of their handler.
+package coalesce
+
+import (
+	"bytes"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"golang.org/x/sync/singleflight"
+	"net/http"
+	"path"
+	"pkg/ndjson"
+	"strings"
+)
+
+// DefaultVary lists the request headers that distinguish otherwise identical requests by default.
+// Requests from different users never share a response as long as their credentials are among them.
+var DefaultVary = []string{"Accept", "Authorization", "Cookie"}
+
+// Options represents the options of the coalescing middleware.
+type Options struct {
+	// Vary lists the request headers whose values must match, in addition to the path and query,
+	// for two requests to be considered identical. It defaults to DefaultVary if empty.
+	Vary []string
+	// ExcludedPaths are the patterns of the request paths that are never coalesced, as matched by path.Match,
+	// e.g. the long-polling endpoints, whose responses are personal and held for a long time.
+	ExcludedPaths []string
+}
+
+// Handler returns a middleware that coalesces concurrent identical GET requests: while the handlers of
+// a request are running, identical requests wait for them and receive a copy of the same response
+// instead of running the handlers themselves. Requests are identical if they have the same path, query
+// and values of the Vary headers.
+//
+// The middleware must only be applied to idempotent endpoints. As the response is buffered, the requests
+// accepting an NDJSON stream and those matching the excluded paths are passed through untouched.
+// If the handlers return an error, every waiting request returns the same error.
+func Handler(options ...Options) routing.Handler {
+	var opt Options
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	if len(opt.Vary) == 0 {
+		opt.Vary = DefaultVary
+	}
+	var group singleflight.Group
+
+	return func(c *routing.Context) error {
+		if c.Request.Method != http.MethodGet || ndjson.Accepts(c.Request) || excluded(c.Request.URL.Path, opt.ExcludedPaths) {
+			return nil
+		}
+		executed := false
+		v, err, _ := group.Do(key(c.Request, opt.Vary), func() (interface{}, error) {
+			executed = true
+			w := c.Response
+			rec := &recorder{header: http.Header{}}
+			c.Response = rec
+			defer func() { c.Response = w }()
+			err := c.Next()
+			return rec, err
+		})
+		if !executed {
+			// the remaining handlers were run by another request
+			c.Abort()
+		}
+		if err != nil {
+			return err
+		}
+		v.(*recorder).copyTo(c.Response)
+		return nil
+	}
+}
+
+// excluded reports whether the path matches one of the patterns.
+func excluded(p string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// key returns the key identifying the requests identical to req.
+func key(req *http.Request, vary []string) string {
+	var b strings.Builder
+	b.WriteString(req.URL.RequestURI())
+	for _, name := range vary {
+		b.WriteByte('\n')
+		b.WriteString(strings.Join(req.Header[http.CanonicalHeaderKey(name)], ","))
+	}
+	return b.String()
+}
+
+// recorder buffers a response so that it can be written to several clients.
+type recorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) Header() http.Header {
+	return r.header
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(b)
+}
+
+// copyTo writes the recorded response to w. The recorder is not modified, so it can be copied to
+// several writers concurrently.
+func (r *recorder) copyTo(w http.ResponseWriter) {
+	header := w.Header()
+	for name, values := range r.header {
+		header[name] = append([]string(nil), values...)
+	}
+	if r.status != 0 {
+		w.WriteHeader(r.status)
+	}
+	_, _ = w.Write(r.body.Bytes())
+}
@@ -0,0 +1,120 @@
+package coalesce
+
+import (
+	"errors"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHandler(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	router := routing.New()
+	router.Use(Handler())
+	router.Get("/albums", func(c *routing.Context) error {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		c.Response.Header().Set("X-Page", "1")
+		c.Response.WriteHeader(http.StatusAccepted)
+		_, err := c.Response.Write([]byte("albums " + c.Request.URL.RawQuery))
+		return err
+	})
+
+	const n = 5
+	responses := make([]*httptest.ResponseRecorder, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req, _ := http.NewRequest("GET", "/albums?page=1", nil)
+			responses[i] = httptest.NewRecorder()
+			router.ServeHTTP(responses[i], req)
+		}(i)
+	}
+	// let every request reach the middleware before the first one completes
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	for _, res := range responses {
+		assert.Equal(t, http.StatusAccepted, res.Code)
+		assert.Equal(t, "1", res.Header().Get("X-Page"))
+		assert.Equal(t, "albums page=1", res.Body.String())
+	}
+
+	// requests that are not concurrent are not coalesced
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/albums?page=1", nil)
+	router.ServeHTTP(res, req)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	assert.Equal(t, "albums page=1", res.Body.String())
+}
+
+func TestHandler_distinct(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	router := routing.New()
+	router.Use(Handler(Options{ExcludedPaths: []string{"/notifications/*"}}))
+	handler := func(c *routing.Context) error {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return nil
+	}
+	router.Get("/albums", handler)
+	router.Post("/albums", handler)
+	router.Get("/notifications/poll", handler)
+
+	requests := []*http.Request{
+		httptest.NewRequest("GET", "/albums?page=1", nil),
+		httptest.NewRequest("GET", "/albums?page=2", nil),
+		httptest.NewRequest("GET", "/albums?page=1", nil),
+		httptest.NewRequest("POST", "/albums", nil),
+		httptest.NewRequest("POST", "/albums", nil),
+		httptest.NewRequest("GET", "/albums?page=3", nil),
+		httptest.NewRequest("GET", "/albums?page=3", nil),
+		httptest.NewRequest("GET", "/notifications/poll", nil),
+		httptest.NewRequest("GET", "/notifications/poll", nil),
+	}
+	requests[2].Header.Set("Authorization", "Bearer other")
+	// streamed responses are not buffered
+	requests[5].Header.Set("Accept", "application/x-ndjson")
+	requests[6].Header.Set("Accept", "application/x-ndjson")
+	var wg sync.WaitGroup
+	for _, req := range requests {
+		wg.Add(1)
+		go func(req *http.Request) {
+			defer wg.Done()
+			router.ServeHTTP(httptest.NewRecorder(), req)
+		}(req)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(len(requests)), atomic.LoadInt32(&calls))
+}
+
+func TestHandler_error(t *testing.T) {
+	errFailed := errors.New("failed")
+	handler := Handler()
+	req, _ := http.NewRequest("GET", "/albums", nil)
+	c := routing.NewContext(httptest.NewRecorder(), req, handler, func(c *routing.Context) error {
+		return errFailed
+	})
+	assert.Equal(t, errFailed, c.Next())
+}
+
+func Test_key(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/albums?page=1", nil)
+	req.Header.Set("Accept", "application/json")
+	assert.Equal(t, "/albums?page=1\napplication/json\n\n", key(req, DefaultVary))
+	assert.Equal(t, "/albums?page=1", key(req, nil))
+}
@@ -0,0 +1,30 @@
+// Package contentlength provides a middleware that requires write requests to declare the length of their bodies.
+package contentlength
+
+import (
+	"fmt"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"net/http"
+)
+
+// Handler returns a middleware that requires POST, PUT and PATCH requests to have a Content-Length header.
+// Requests of unknown length, such as chunked uploads, are rejected with 411 Length Required.
+// Requests whose declared length exceeds maxBytes are rejected with 413 Request Entity Too Large.
+// The length is not limited if maxBytes is not positive.
+func Handler(maxBytes int64) routing.Handler {
+	return func(c *routing.Context) error {
+		switch c.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+		default:
+			return nil
+		}
+		if c.Request.ContentLength < 0 {
+			return routing.NewHTTPError(http.StatusLengthRequired, "the Content-Length header is required")
+		}
+		if maxBytes > 0 && c.Request.ContentLength > maxBytes {
+			return routing.NewHTTPError(http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("the request body must not be larger than %v bytes", maxBytes))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,45 @@
+package contentlength
+
+import (
+	"bytes"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	handler := Handler(10)
+
+	tests := []struct {
+		name          string
+		method        string
+		body          string
+		contentLength int64
+		wantStatus    int
+	}{
+		{"present length", "POST", `{"a":1}`, 7, 0},
+		{"empty body", "PUT", "", 0, 0},
+		{"missing length", "POST", `{"a":1}`, -1, http.StatusLengthRequired},
+		{"missing length on patch", "PATCH", `{"a":1}`, -1, http.StatusLengthRequired},
+		{"oversized length", "PUT", `{"name":"too long"}`, 19, http.StatusRequestEntityTooLarge},
+		{"read request", "GET", "", -1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, "http://127.0.0.1/albums", bytes.NewBufferString(tt.body))
+			req.ContentLength = tt.contentLength
+			err := handler(routing.NewContext(httptest.NewRecorder(), req))
+			if tt.wantStatus == 0 {
+				assert.Nil(t, err)
+			} else if assert.NotNil(t, err) {
+				assert.Equal(t, tt.wantStatus, err.(routing.HTTPError).StatusCode())
+			}
+		})
+	}
+
+	// the length is not limited without a maximum
+	req, _ := http.NewRequest("POST", "http://127.0.0.1/albums", bytes.NewBufferString(`{"name":"not too long"}`))
+	assert.Nil(t, Handler(0)(routing.NewContext(httptest.NewRecorder(), req)))
+}
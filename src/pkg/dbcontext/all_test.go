@@ -0,0 +1,114 @@
+package dbcontext
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"pkg/dbtest"
+	"strings"
+	"testing"
+)
+
+func TestDB_All(t *testing.T) {
+	rows := 0
+	db, _ := dbtest.Open("mysql", func(query string, args []driver.Value) (*dbtest.Result, error) {
+		result := &dbtest.Result{Columns: []string{"id"}}
+		named := strings.Contains(query, "`name`")
+		if named {
+			result.Columns = append(result.Columns, "name")
+		}
+		for i := 1; i <= rows; i++ {
+			row := []driver.Value{int64(i)}
+			if named {
+				row = append(row, "name")
+			}
+			result.Rows = append(result.Rows, row)
+		}
+		return result, nil
+	})
+	dbc := New(db).WithMaxRows(3)
+	ctx := context.Background()
+	q := dbc.With(ctx).Select("id", "name").From("item")
+
+	type item struct {
+		ID   int
+		Name string
+	}
+	tests := []struct {
+		name      string
+		rows      int
+		count     int
+		truncated bool
+	}{
+		{"under cap", 2, 2, false},
+		{"at cap", 3, 3, false},
+		{"over cap", 4, 3, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rows = tc.rows
+
+			items := []item{{ID: 100}}
+			err := dbc.All(q, &items)
+			if tc.truncated {
+				assert.True(t, errors.Is(err, ErrTooManyRows))
+				assert.Equal(t, []item{{ID: 100}}, items)
+			} else if assert.Nil(t, err) {
+				assert.Equal(t, tc.count, len(items))
+				assert.Equal(t, item{1, "name"}, items[0])
+			}
+
+			var ids []int
+			truncated, err := dbc.AllTruncated(dbc.With(ctx).Select("id").From("item"), &ids)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.truncated, truncated)
+			assert.Equal(t, tc.count, len(ids))
+			assert.Equal(t, 1, ids[0])
+		})
+	}
+
+	// the rows are not limited by default
+	rows = 4
+	var items []item
+	assert.Nil(t, New(db).All(q, &items))
+	assert.Equal(t, 4, len(items))
+	items = nil
+	truncated, err := New(db).AllTruncated(q, &items)
+	assert.Nil(t, err)
+	assert.False(t, truncated)
+	assert.Equal(t, 4, len(items))
+}
+
+func TestDB_All_scalars(t *testing.T) {
+	db, server := dbtest.Open("mysql", func(query string, args []driver.Value) (*dbtest.Result, error) {
+		return &dbtest.Result{Columns: []string{"id"}, Rows: [][]driver.Value{{int64(1)}, {int64(2)}}}, nil
+	})
+	ctx := context.Background()
+
+	// scalars are read the same whether the rows are limited or not
+	for _, maxRows := range []int{0, 10} {
+		dbc := New(db).WithMaxRows(maxRows)
+		var ids []int
+		assert.Nil(t, dbc.All(dbc.With(ctx).Select("id").From("item"), &ids), maxRows)
+		assert.Equal(t, []int{1, 2}, ids, maxRows)
+		ids = nil
+		truncated, err := dbc.AllTruncated(dbc.With(ctx).Select("id").From("item"), &ids)
+		assert.Nil(t, err, maxRows)
+		assert.False(t, truncated, maxRows)
+		assert.Equal(t, []int{1, 2}, ids, maxRows)
+		ids = nil
+		assert.Nil(t, dbc.RawQuery(ctx, "SELECT [[id]] FROM {{item}}", nil, &ids), maxRows)
+		assert.Equal(t, []int{1, 2}, ids, maxRows)
+	}
+
+	// like q.All, the table is that of the elements if the query has none
+	type item struct {
+		ID int
+	}
+	var items []item
+	assert.Nil(t, New(db).WithMaxRows(10).All(New(db).With(ctx).Select(), &items))
+	assert.Equal(t, []item{{1}, {2}}, items)
+	statements := server.Statements()
+	assert.Equal(t, "SELECT * FROM `item`", statements[len(statements)-1].SQL)
+}
@@ -0,0 +1,156 @@
+package dbcontext
+
+import (
+	"fmt"
+	dbx "github.com/go-ozzo/ozzo-dbx"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// QueryCache is a read-through cache of query results, meant for reference data that is read much more
+// often than it is written. Results are keyed by table, SQL and parameters, expire after a TTL,
+// and are invalidated per table by Invalidate, which repositories call when they write to the table.
+// The number of cached results is capped: the expired results are pruned when the cache is full,
+// and new results are not cached while it remains full.
+//
+// A nil *QueryCache is valid and runs every query, so that caching can be made optional.
+// It is safe for concurrent use.
+type QueryCache struct {
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]map[string]cacheEntry
+	size    int
+	// generations counts the invalidations of each table, so that a result read before
+	// an invalidation is not cached after it.
+	generations map[string]int
+}
+
+type cacheEntry struct {
+	value   reflect.Value
+	expires time.Time
+}
+
+// DefaultMaxEntries is the number of results a query cache holds at most by default.
+const DefaultMaxEntries = 10000
+
+// NewQueryCache creates a query cache whose results expire after the given TTL. It holds at most
+// maxEntries results, or DefaultMaxEntries if maxEntries is not positive.
+func NewQueryCache(ttl time.Duration, maxEntries int) *QueryCache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	return &QueryCache{
+		ttl:         ttl,
+		maxEntries:  maxEntries,
+		now:         time.Now,
+		entries:     map[string]map[string]cacheEntry{},
+		generations: map[string]int{},
+	}
+}
+
+// All populates slice with the rows of the query like q.All, which is only run if the result is not cached.
+// Unlike q.All, the rows replace the previous content of slice. table is the table the query reads from,
+// used for invalidation.
+func (c *QueryCache) All(table string, q *dbx.SelectQuery, slice interface{}) error {
+	if c == nil {
+		return q.All(slice)
+	}
+	return c.load(table, q, slice, func() error { return q.All(slice) })
+}
+
+// One populates dest with the first row of the query like q.One, which is only run if the result is not cached.
+// table is the table the query reads from, used for invalidation. Missing rows are not cached.
+func (c *QueryCache) One(table string, q *dbx.SelectQuery, dest interface{}) error {
+	if c == nil {
+		return q.One(dest)
+	}
+	return c.load(table, q, dest, func() error { return q.One(dest) })
+}
+
+// Invalidate removes the cached results of the queries reading from the given tables.
+func (c *QueryCache) Invalidate(tables ...string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, table := range tables {
+		c.size -= len(c.entries[table])
+		delete(c.entries, table)
+		c.generations[table]++
+	}
+}
+
+// load copies the cached result of the query into dest, or runs the query and caches its result.
+func (c *QueryCache) load(table string, q *dbx.SelectQuery, dest interface{}, run func() error) error {
+	built := q.Build()
+	key := fmt.Sprintf("%s\x00%v", built.SQL(), built.Params())
+	target := reflect.ValueOf(dest).Elem()
+
+	c.mu.Lock()
+	entry, ok := c.entries[table][key]
+	generation := c.generations[table]
+	c.mu.Unlock()
+	if ok && c.now().Before(entry.expires) {
+		target.Set(clone(entry.value))
+		return nil
+	}
+
+	// the result replaces the content of dest, so that it is exactly what gets cached
+	target.Set(reflect.Zero(target.Type()))
+	if err := run(); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generations[table] != generation {
+		return nil
+	}
+	if _, ok := c.entries[table][key]; !ok {
+		if c.size >= c.maxEntries {
+			c.prune()
+		}
+		if c.size >= c.maxEntries {
+			return nil
+		}
+		c.size++
+	}
+	if c.entries[table] == nil {
+		c.entries[table] = map[string]cacheEntry{}
+	}
+	c.entries[table][key] = cacheEntry{clone(target), c.now().Add(c.ttl)}
+	return nil
+}
+
+// prune removes the expired results. The caller must hold the lock.
+func (c *QueryCache) prune() {
+	now := c.now()
+	for table, entries := range c.entries {
+		for key, entry := range entries {
+			if !now.Before(entry.expires) {
+				delete(entries, key)
+				c.size--
+			}
+		}
+		if len(entries) == 0 {
+			delete(c.entries, table)
+		}
+	}
+}
+
+// clone returns a copy of v. A slice is copied into a new backing array,
+// so that callers cannot modify the cached results.
+func clone(v reflect.Value) reflect.Value {
+	if v.Kind() == reflect.Slice && !v.IsNil() {
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		reflect.Copy(c, v)
+		return c
+	}
+	c := reflect.New(v.Type()).Elem()
+	c.Set(v)
+	return c
+}
@@ -0,0 +1,126 @@
+package dbcontext
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	dbx "github.com/go-ozzo/ozzo-dbx"
+	"github.com/stretchr/testify/assert"
+	"pkg/dbtest"
+	"testing"
+	"time"
+)
+
+func TestQueryCache(t *testing.T) {
+	name := "sales"
+	db, server := dbtest.Open("mysql", func(query string, args []driver.Value) (*dbtest.Result, error) {
+		result := &dbtest.Result{Columns: []string{"id", "name"}}
+		if len(args) == 0 || args[0] == int64(1) {
+			result.Rows = [][]driver.Value{{int64(1), name}}
+		}
+		return result, nil
+	})
+	dbc := New(db)
+	ctx := context.Background()
+	type department struct {
+		ID   int
+		Name string
+	}
+	now := time.Now()
+	cache := NewQueryCache(time.Minute, 0)
+	cache.now = func() time.Time { return now }
+	query := func() *dbx.SelectQuery {
+		return dbc.With(ctx).Select("id", "name").From("department")
+	}
+
+	// miss, then hit
+	var items []department
+	assert.Nil(t, cache.All("department", query(), &items))
+	assert.Equal(t, []department{{1, "sales"}}, items)
+	items[0].Name = "modified by the caller"
+	items = nil
+	assert.Nil(t, cache.All("department", query(), &items))
+	assert.Equal(t, []department{{1, "sales"}}, items)
+	assert.Equal(t, 1, len(server.Statements()))
+
+	// the parameters are part of the key
+	var item department
+	assert.Nil(t, cache.One("department", query().Where(dbx.HashExp{"id": 1}), &item))
+	assert.Nil(t, cache.One("department", query().Where(dbx.HashExp{"id": 1}), &item))
+	assert.Equal(t, department{1, "sales"}, item)
+	assert.Equal(t, sql.ErrNoRows, cache.One("department", query().Where(dbx.HashExp{"id": 2}), &item))
+	assert.Equal(t, sql.ErrNoRows, cache.One("department", query().Where(dbx.HashExp{"id": 2}), &item))
+	assert.Equal(t, 4, len(server.Statements()))
+
+	// TTL expiry
+	now = now.Add(time.Minute)
+	name = "marketing"
+	assert.Nil(t, cache.All("department", query(), &items))
+	assert.Equal(t, []department{{1, "marketing"}}, items)
+	assert.Equal(t, 5, len(server.Statements()))
+
+	// invalidation on write
+	name = "support"
+	cache.Invalidate("album")
+	assert.Nil(t, cache.All("department", query(), &items))
+	assert.Equal(t, "marketing", items[0].Name)
+	cache.Invalidate("department")
+	assert.Nil(t, cache.All("department", query(), &items))
+	assert.Equal(t, "support", items[0].Name)
+	assert.Nil(t, cache.One("department", query().Where(dbx.HashExp{"id": 1}), &item))
+	assert.Equal(t, "support", item.Name)
+	assert.Equal(t, 7, len(server.Statements()))
+}
+
+func TestQueryCache_maxEntries(t *testing.T) {
+	db, server := dbtest.Open("mysql", func(query string, args []driver.Value) (*dbtest.Result, error) {
+		return &dbtest.Result{Columns: []string{"id"}, Rows: [][]driver.Value{{args[0]}}}, nil
+	})
+	type item struct {
+		ID int
+	}
+	now := time.Now()
+	cache := NewQueryCache(time.Minute, 2)
+	cache.now = func() time.Time { return now }
+	get := func(id int) {
+		var i item
+		assert.Nil(t, cache.One("item", db.Select("id").From("item").Where(dbx.HashExp{"id": id}), &i))
+		assert.Equal(t, id, i.ID)
+	}
+
+	// the results beyond the cap are not cached
+	get(1)
+	get(2)
+	get(3)
+	get(3)
+	get(1)
+	assert.Equal(t, 4, len(server.Statements()))
+	assert.Equal(t, 2, cache.size)
+
+	// the expired results make room for the new ones
+	now = now.Add(time.Minute)
+	get(3)
+	get(3)
+	assert.Equal(t, 5, len(server.Statements()))
+	assert.Equal(t, 1, cache.size)
+
+	cache.Invalidate("item")
+	assert.Equal(t, 0, cache.size)
+}
+
+func TestQueryCache_nil(t *testing.T) {
+	db, server := dbtest.Open("mysql", func(query string, args []driver.Value) (*dbtest.Result, error) {
+		return &dbtest.Result{Columns: []string{"id"}, Rows: [][]driver.Value{{int64(1)}}}, nil
+	})
+	type item struct {
+		ID int
+	}
+	var cache *QueryCache
+	for i := 0; i < 2; i++ {
+		var items []item
+		assert.Nil(t, cache.All("item", db.Select("id").From("item"), &items))
+		assert.Equal(t, []item{{1}}, items)
+	}
+	cache.Invalidate("item")
+	assert.Equal(t, 2, len(server.Statements()))
+}
@@ -0,0 +1,142 @@
+package dbcontext
+
+import (
+	"database/sql"
+	dbx "github.com/go-ozzo/ozzo-dbx"
+	"reflect"
+	"strings"
+)
+
+// column is a column of a struct as dbx maps it.
+type column struct {
+	// name is the name of the column. The columns of a nested struct field are prefixed with the column
+	// of the field, e.g. "address.city".
+	name string
+	// field is the name of the field, prefixed with the names of the nested struct fields, e.g. "Address.City".
+	field string
+	// index is the index path of the field.
+	index []int
+	pk    bool
+}
+
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+// structColumns returns the columns of a struct type following the rules of dbx: the "db" tag of a field is
+// either a column name, "pk", or "pk," followed by a column name, and the fields tagged `db:"-"` are skipped.
+// The fields of embedded structs belong to the struct, unless shadowed by a field of the same name closer
+// to it, and the fields of the other struct fields, except time.Time and sql.Scanner ones, are nested columns.
+// If no field is tagged "pk", a field named ID or Id is the primary key.
+func structColumns(t reflect.Type, mapper dbx.FieldMapFunc) []column {
+	var columns []column
+	byField := map[string]int{}
+	buildColumns(t, nil, "", "", mapper, &columns, byField)
+	pk := false
+	for _, c := range columns {
+		pk = pk || c.pk
+	}
+	if !pk {
+		for _, name := range []string{"ID", "Id"} {
+			if i, ok := byField[name]; ok {
+				columns[i].pk = true
+				break
+			}
+		}
+	}
+	return columns
+}
+
+// buildColumns adds the columns of the fields of a struct type, like dbx builds its struct info.
+func buildColumns(t reflect.Type, index []int, fieldPrefix, columnPrefix string, mapper dbx.FieldMapFunc, columns *[]column, byField map[string]int) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("db")
+		if !field.Anonymous && field.PkgPath != "" || tag == "-" {
+			continue
+		}
+		fieldIndex := append(append([]int{}, index...), i)
+		ft := field.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		name, pk := parseTag(tag)
+		if name == "" && !field.Anonymous {
+			name = mapper(field.Name)
+		}
+		fieldName := field.Name
+		if field.Anonymous {
+			fieldName = ""
+		}
+		if isNestedStruct(ft) {
+			buildColumns(ft, fieldIndex, join(fieldPrefix, fieldName), join(columnPrefix, name), mapper, columns, byField)
+			continue
+		}
+		if name == "" {
+			continue
+		}
+		c := column{name: join(columnPrefix, name), field: join(fieldPrefix, fieldName), index: fieldIndex, pk: pk}
+		// a field of an embedded struct is shadowed by a field of the same name closer to the struct
+		if j, ok := byField[c.field]; !ok {
+			byField[c.field] = len(*columns)
+			*columns = append(*columns, c)
+		} else if len(fieldIndex) < len((*columns)[j].index) {
+			(*columns)[j] = c
+		}
+	}
+}
+
+// parseTag returns the column name of a "db" tag, and whether it marks the primary key.
+func parseTag(tag string) (string, bool) {
+	if tag == "pk" {
+		return "", true
+	}
+	if strings.HasPrefix(tag, "pk,") {
+		return tag[3:], true
+	}
+	return tag, false
+}
+
+// isNestedStruct reports whether the fields of a struct field are columns, rather than the field itself.
+func isNestedStruct(t reflect.Type) bool {
+	if t.PkgPath() == "time" && t.Name() == "Time" {
+		return false
+	}
+	return t.Kind() == reflect.Struct && !reflect.PtrTo(t).Implements(scannerType)
+}
+
+func join(prefix, name string) string {
+	if prefix == "" {
+		return name
+	} else if name == "" {
+		return prefix
+	}
+	return prefix + "." + name
+}
+
+// fieldValue returns the value of the field at the index path, or nil if a pointer on the way is nil.
+func fieldValue(v reflect.Value, index []int) interface{} {
+	for _, i := range index {
+		v = v.Field(i)
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return nil
+			}
+			v = v.Elem()
+		}
+	}
+	return v.Interface()
+}
+
+// fieldByIndex returns the field at the index path, allocating the nil pointers to structs on the way.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for _, i := range index[:len(index)-1] {
+		v = v.Field(i)
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+	}
+	return v.Field(index[len(index)-1])
+}
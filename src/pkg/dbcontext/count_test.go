@@ -0,0 +1,37 @@
+package dbcontext
+
+import (
+	"context"
+	"database/sql/driver"
+	dbx "github.com/go-ozzo/ozzo-dbx"
+	"github.com/stretchr/testify/assert"
+	"pkg/dbtest"
+	"testing"
+)
+
+func TestDB_Count(t *testing.T) {
+	db, server := dbtest.Open("mysql", func(query string, args []driver.Value) (*dbtest.Result, error) {
+		// two of the items are named "abc"
+		count := int64(0)
+		if len(args) == 1 && args[0] == "abc" {
+			count = 2
+		}
+		return &dbtest.Result{Columns: []string{"COUNT(*)"}, Rows: [][]driver.Value{{count}}}, nil
+	})
+	dbc := New(db)
+	ctx := context.Background()
+
+	q := dbc.With(ctx).Select().From("item").Where(dbx.HashExp{"name": "abc"}).OrderBy("id").Limit(10).Offset(20)
+	count, err := dbc.Count(ctx, q)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+
+	statements := server.Statements()
+	if assert.Equal(t, 1, len(statements)) {
+		assert.Equal(t, "SELECT COUNT(*) FROM (SELECT * FROM `item` WHERE `name`=?) AS t", statements[0].SQL)
+		assert.Equal(t, []driver.Value{"abc"}, statements[0].Args)
+	}
+
+	// the original query keeps its clauses
+	assert.Equal(t, "SELECT * FROM `item` WHERE `name`={:p0} ORDER BY `id` LIMIT 10 OFFSET 20", q.Build().SQL())
+}
@@ -4,16 +4,35 @@ package dbcontext
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
 
 	dbx "github.com/go-ozzo/ozzo-dbx"
 	routing "github.com/go-ozzo/ozzo-routing/v2"
 )
 
 // DB represents a DB connection that can be used to run SQL queries.
+// If a read replica is configured, SELECT queries are sent to the replica while
+// the other statements and transactions are sent to the primary database.
 type DB struct {
-	db *dbx.DB
+	db      *dbx.DB
+	replica *dbx.DB
+	// maxRows is the maximum number of rows read by All, or 0 if not limited.
+	maxRows int
 }
 
+// ErrNotFound is returned by One when no row matches the query.
+// It wraps sql.ErrNoRows, so errors.Is(err, sql.ErrNoRows) holds as well.
+var ErrNotFound = fmt.Errorf("dbcontext: no matching row: %w", sql.ErrNoRows)
+
+// ErrTooManyRows is returned by All when a query returns more rows than the maximum set by WithMaxRows.
+var ErrTooManyRows = errors.New("dbcontext: too many rows")
+
+// RowFunc is called for each row of a query result. The row can be read via ScanStruct, ScanMap or Scan.
+type RowFunc func(row *dbx.Rows) error
+
 // TransactionFunc represents a function that will start a transaction and run the given function.
 type TransactionFunc func(ctx context.Context, f func(ctx context.Context) error) error
 
@@ -21,26 +40,223 @@ type contextKey int
 
 const (
 	txKey contextKey = iota
+	primaryKey
+	routeKey
 )
 
 // New returns a new DB connection that wraps the given dbx.DB instance.
 func New(db *dbx.DB) *DB {
-	return &DB{db}
+	return &DB{db: db}
+}
+
+// NewWithReplica returns a new DB connection that sends SELECT queries to the replica
+// and everything else to the primary database. The replica is not used if it is nil.
+func NewWithReplica(primary, replica *dbx.DB) *DB {
+	return &DB{db: primary, replica: replica}
+}
+
+// WithMaxRows returns a copy of the DB whose All and AllTruncated methods read at most max rows,
+// so that a query matching millions of rows cannot exhaust the memory. The rows are not limited if max is 0.
+func (db *DB) WithMaxRows(max int) *DB {
+	c := *db
+	c.maxRows = max
+	return &c
 }
 
-// DB returns the dbx.DB wrapped by this object.
+// DB returns the primary dbx.DB wrapped by this object.
 func (db *DB) DB() *dbx.DB {
 	return db.db
 }
 
+// Replica returns the dbx.DB of the read replica, or nil if there is no replica.
+func (db *DB) Replica() *dbx.DB {
+	return db.replica
+}
+
+// WithPrimary returns a context in which all queries are sent to the primary database.
+// Use it to read data that has just been written, which may not have reached the replica yet.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey, true)
+}
+
 // With returns a Builder that can be used to build and execute SQL queries.
 // With will return the transaction if it is found in the given context.
-// Otherwise it will return a DB connection associated with the context.
+// Otherwise it will return a DB connection associated with the context, which sends SELECT queries
+// to the replica unless there is none or the context is created by WithPrimary.
 func (db *DB) With(ctx context.Context) dbx.Builder {
 	if tx, ok := ctx.Value(txKey).(*dbx.Tx); ok {
 		return tx
 	}
-	return db.db.WithContext(ctx)
+	if db.replica == nil {
+		return db.db.WithContext(ctx)
+	}
+	if primary, _ := ctx.Value(primaryKey).(bool); primary {
+		return db.db.WithContext(ctx)
+	}
+	return replicaBuilder{db.db.WithContext(ctx), db.replica.WithContext(ctx)}
+}
+
+// replicaBuilder is a Builder that builds SELECT queries on the replica and other statements on the primary database.
+type replicaBuilder struct {
+	dbx.Builder
+	replica dbx.Builder
+}
+
+// Select returns a new SelectQuery running on the replica.
+func (b replicaBuilder) Select(cols ...string) *dbx.SelectQuery {
+	return b.replica.Select(cols...)
+}
+
+// One runs the query and populates dest with the first row of the result.
+// ErrNotFound is returned if the query returns no rows.
+func (db *DB) One(q *dbx.SelectQuery, dest interface{}) error {
+	err := q.One(dest)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// All runs the query and populates slice with all the rows of the result. Unlike q.All, slice may also hold
+// scalars, such as the IDs selected by the query, and the rows replace its previous content.
+// ErrTooManyRows is returned if the query returns more rows than the maximum set by WithMaxRows,
+// in which case the slice is left unchanged.
+func (db *DB) All(q *dbx.SelectQuery, slice interface{}) error {
+	return db.all(selectRows(q, slice), slice)
+}
+
+// all populates slice with all the rows of a query, up to the maximum.
+func (db *DB) all(query func() (*dbx.Rows, error), slice interface{}) error {
+	result, truncated, err := db.readRows(query, slice)
+	if err != nil {
+		return err
+	} else if truncated {
+		return fmt.Errorf("%w: the query returns more than %d rows", ErrTooManyRows, db.maxRows)
+	}
+	reflect.ValueOf(slice).Elem().Set(result)
+	return nil
+}
+
+// AllTruncated runs the query and populates slice with the rows of the result like All, up to the maximum
+// set by WithMaxRows. It reports whether the result was truncated because the query returns more rows.
+func (db *DB) AllTruncated(q *dbx.SelectQuery, slice interface{}) (bool, error) {
+	result, truncated, err := db.readRows(selectRows(q, slice), slice)
+	if err != nil {
+		return false, err
+	}
+	reflect.ValueOf(slice).Elem().Set(result)
+	return truncated, nil
+}
+
+// selectRows returns a function running the query. Like q.All, it selects from the table of the elements
+// of slice if the query has no FROM clause.
+func selectRows(q *dbx.SelectQuery, slice interface{}) func() (*dbx.Rows, error) {
+	return func() (*dbx.Rows, error) {
+		// the FROM clause is unexported, but its length can be read
+		if reflect.ValueOf(q).Elem().FieldByName("from").Len() == 0 {
+			if table := q.TableMapper(slice); table != "" {
+				q.From(table)
+			}
+		}
+		return q.Rows()
+	}
+}
+
+// readRows reads the rows of a query into a new slice of the type slice points to, up to maxRows if positive,
+// and reports whether there are more. The elements may be structs, NullStringMaps or scalars. The rows are read
+// one at a time, so the rows past the maximum are never held in memory.
+func (db *DB) readRows(query func() (*dbx.Rows, error), slice interface{}) (reflect.Value, bool, error) {
+	sv := reflect.ValueOf(slice)
+	if sv.Kind() != reflect.Ptr || sv.Elem().Kind() != reflect.Slice {
+		return reflect.Value{}, false, errors.New("dbcontext: must be a pointer to a slice")
+	}
+	et := sv.Elem().Type().Elem()
+	rows, err := query()
+	if err != nil {
+		return reflect.Value{}, false, err
+	}
+	defer rows.Close()
+	result := reflect.MakeSlice(sv.Elem().Type(), 0, 0)
+	for rows.Next() {
+		if db.maxRows > 0 && result.Len() == db.maxRows {
+			return result, true, nil
+		}
+		elem := reflect.New(et)
+		switch {
+		case et.Kind() == reflect.Struct:
+			err = rows.ScanStruct(elem.Interface())
+		case et == reflect.TypeOf(dbx.NullStringMap{}):
+			m := dbx.NullStringMap{}
+			err = rows.ScanMap(m)
+			elem.Elem().Set(reflect.ValueOf(m))
+		default:
+			err = rows.Scan(elem.Interface())
+		}
+		if err != nil {
+			return reflect.Value{}, false, err
+		}
+		result = reflect.Append(result, elem.Elem())
+	}
+	return result, false, rows.Err()
+}
+
+// RawQuery runs a SQL query that the query builder cannot express, such as a complex report, and populates
+// dest with its result: all the rows like All if dest points to a slice, or the first row like One otherwise.
+// The params are bound to the named placeholders of the query, e.g. {:since}, so they are never part of the SQL.
+// Table and column names enclosed in {{ }} or [[ ]] are quoted. Like Count, the query runs in the transaction
+// of the context or on the replica if there is one, so it must only read.
+func (db *DB) RawQuery(ctx context.Context, query string, params dbx.Params, dest interface{}) error {
+	builder := db.With(ctx)
+	if rb, ok := builder.(replicaBuilder); ok {
+		builder = rb.replica
+	}
+	q := builder.NewQuery(query).Bind(params).WithContext(ctx)
+
+	if v := reflect.ValueOf(dest); v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice || v.Elem().Type().Elem().Kind() == reflect.Uint8 {
+		err := q.One(dest)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+	return db.all(q.Rows, dest)
+}
+
+// Count returns the number of rows the query would return without fetching them.
+// The query is wrapped in SELECT COUNT(*) after removing its ORDER BY, LIMIT and OFFSET clauses,
+// so the count covers all matching rows, as needed by pagination. The given query is not modified.
+// Like With, the count runs in the transaction of the context or on the replica if there is one.
+func (db *DB) Count(ctx context.Context, q *dbx.SelectQuery) (int, error) {
+	inner := *q
+	built := inner.OrderBy().Limit(-1).Offset(-1).Build()
+
+	builder := db.With(ctx)
+	if rb, ok := builder.(replicaBuilder); ok {
+		builder = rb.replica
+	}
+	var count int
+	err := builder.NewQuery("SELECT COUNT(*) FROM (" + built.SQL() + ") AS t").
+		Bind(built.Params()).
+		WithContext(ctx).
+		Row(&count)
+	return count, err
+}
+
+// Each runs the query and calls f for each row of the result, one row at a time, so that
+// large result sets can be processed without loading them into memory.
+// Iteration stops at the first error returned by f, and that error is returned.
+func (db *DB) Each(ctx context.Context, q *dbx.SelectQuery, f RowFunc) error {
+	rows, err := q.Build().WithContext(ctx).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := f(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
 }
 
 // Transactional starts a transaction and calls the given function with a context storing the transaction.
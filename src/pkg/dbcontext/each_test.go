@@ -0,0 +1,56 @@
+package dbcontext
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	dbx "github.com/go-ozzo/ozzo-dbx"
+	"github.com/stretchr/testify/assert"
+	"pkg/dbtest"
+	"testing"
+)
+
+func TestDB_Each(t *testing.T) {
+	const total = 10000
+	db, _ := dbtest.Open("mysql", func(query string, args []driver.Value) (*dbtest.Result, error) {
+		result := &dbtest.Result{Columns: []string{"id", "name"}}
+		for i := 0; i < total; i++ {
+			result.Rows = append(result.Rows, []driver.Value{int64(i), "name"})
+		}
+		return result, nil
+	})
+	dbc := New(db)
+	ctx := context.Background()
+
+	// iterate all rows
+	type item struct {
+		ID   int
+		Name string
+	}
+	count, sum := 0, 0
+	err := dbc.Each(ctx, dbc.With(ctx).Select("id", "name").From("item"), func(row *dbx.Rows) error {
+		var it item
+		if err := row.ScanStruct(&it); err != nil {
+			return err
+		}
+		count++
+		sum += it.ID
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, total, count)
+	assert.Equal(t, total*(total-1)/2, sum)
+
+	// early callback error stops iteration
+	errStop := errors.New("stop")
+	count = 0
+	err = dbc.Each(ctx, dbc.With(ctx).Select("id", "name").From("item"), func(row *dbx.Rows) error {
+		count++
+		if count == 3 {
+			return errStop
+		}
+		return nil
+	})
+	assert.Equal(t, errStop, err)
+	assert.Equal(t, 3, count)
+}
@@ -0,0 +1,37 @@
+package dbcontext
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	dbx "github.com/go-ozzo/ozzo-dbx"
+	"github.com/stretchr/testify/assert"
+	"pkg/dbtest"
+	"testing"
+)
+
+func TestDB_One(t *testing.T) {
+	db, _ := dbtest.Open("mysql", func(query string, args []driver.Value) (*dbtest.Result, error) {
+		result := &dbtest.Result{Columns: []string{"id", "name"}}
+		if args[0] == "found" {
+			result.Rows = [][]driver.Value{{int64(1), "found"}, {int64(2), "found"}}
+		}
+		return result, nil
+	})
+	dbc := New(db)
+	ctx := context.Background()
+	type item struct {
+		ID   int
+		Name string
+	}
+
+	var it item
+	err := dbc.One(dbc.With(ctx).Select("id", "name").From("item").Where(dbx.HashExp{"name": "found"}), &it)
+	assert.Nil(t, err)
+	assert.Equal(t, item{1, "found"}, it)
+
+	err = dbc.One(dbc.With(ctx).Select("id", "name").From("item").Where(dbx.HashExp{"name": "missing"}), &it)
+	assert.Equal(t, ErrNotFound, err)
+	assert.True(t, errors.Is(err, sql.ErrNoRows))
+}
@@ -0,0 +1,121 @@
+package dbcontext
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	dbx "github.com/go-ozzo/ozzo-dbx"
+	"time"
+)
+
+// Open opens a database like dbx.Open, and runs the given statements on every new connection
+// before it is used, e.g. to set session variables such as the one returned by StatementTimeout.
+// A connection on which a statement fails is discarded.
+func Open(driverName, dsn string, init ...string) (*dbx.DB, error) {
+	if len(init) == 0 {
+		return dbx.Open(driverName, dsn)
+	}
+	sqlDB, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	d := sqlDB.Driver()
+	_ = sqlDB.Close()
+
+	var connector driver.Connector = dsnConnector{d, dsn}
+	if dc, ok := d.(driver.DriverContext); ok {
+		if connector, err = dc.OpenConnector(dsn); err != nil {
+			return nil, err
+		}
+	}
+	return dbx.NewFromDB(sql.OpenDB(initConnector{connector, init}), driverName), nil
+}
+
+// StatementTimeout returns the statement setting the maximum execution time of the statements of
+// a session, or "" if the database is not supported. The supported drivers are "mysql", where
+// the limit only applies to SELECT statements, and "postgres".
+func StatementTimeout(driverName string, timeout time.Duration) string {
+	ms := int64(timeout / time.Millisecond)
+	switch driverName {
+	case "mysql":
+		return fmt.Sprintf("SET SESSION max_execution_time = %d", ms)
+	case "postgres":
+		return fmt.Sprintf("SET statement_timeout = %d", ms)
+	}
+	return ""
+}
+
+// dsnConnector opens connections with a driver that does not implement driver.DriverContext.
+type dsnConnector struct {
+	driver driver.Driver
+	dsn    string
+}
+
+func (c dsnConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// initConnector runs statements on the connections opened by another connector.
+type initConnector struct {
+	driver.Connector
+	statements []string
+}
+
+func (c initConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, statement := range c.statements {
+		if err := execConn(ctx, conn, statement); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("failed to initialize connection with %q: %w", statement, err)
+		}
+	}
+	return conn, nil
+}
+
+// execConn executes a statement without arguments on a driver connection.
+func execConn(ctx context.Context, conn driver.Conn, statement string) error {
+	if execer, ok := conn.(driver.ExecerContext); ok {
+		_, err := execer.ExecContext(ctx, statement, nil)
+		if err != driver.ErrSkip {
+			return err
+		}
+	}
+	stmt, err := conn.Prepare(statement)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(nil)
+	return err
+}
+
+// Warm opens n connections of the pool at once and pings them, so that the first requests do not
+// pay for establishing connections. The connections are returned to the pool, which keeps them
+// provided its maximum number of idle connections (see sql.DB.SetMaxIdleConns) is at least n.
+func Warm(ctx context.Context, db *sql.DB, n int) error {
+	conns := make([]*sql.Conn, 0, n)
+	defer func() {
+		for _, conn := range conns {
+			_ = conn.Close()
+		}
+	}()
+	for i := 0; i < n; i++ {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			return err
+		}
+		conns = append(conns, conn)
+		if err := conn.PingContext(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package dbcontext
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"pkg/dbtest"
+	"testing"
+	"time"
+)
+
+func TestOpen(t *testing.T) {
+	_, server := dbtest.Open("mysql", nil)
+	db, err := Open(dbtest.DriverName, server.DSN(), StatementTimeout("mysql", 5*time.Second))
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer db.Close()
+
+	_, err = db.NewQuery("UPDATE album SET name='x'").Execute()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"SET SESSION max_execution_time = 5000", "UPDATE album SET name='x'"}, server.SQL())
+
+	// the statement is run once per connection
+	_, err = db.NewQuery("UPDATE album SET name='y'").Execute()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, server.Connections())
+	assert.Equal(t, 3, len(server.SQL()))
+}
+
+func TestOpen_initError(t *testing.T) {
+	_, server := dbtest.Open("mysql", func(query string, args []driver.Value) (*dbtest.Result, error) {
+		return nil, errors.New("unknown system variable")
+	})
+	db, err := Open(dbtest.DriverName, server.DSN(), "SET SESSION unknown = 1")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer db.Close()
+
+	err = db.DB().Ping()
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "unknown system variable")
+	}
+}
+
+func TestStatementTimeout(t *testing.T) {
+	assert.Equal(t, "SET SESSION max_execution_time = 1500", StatementTimeout("mysql", 1500*time.Millisecond))
+	assert.Equal(t, "SET statement_timeout = 2000", StatementTimeout("postgres", 2*time.Second))
+	assert.Equal(t, "", StatementTimeout("sqlite3", time.Second))
+}
+
+func TestWarm(t *testing.T) {
+	db, server := dbtest.Open("mysql", nil)
+	db.DB().SetMaxIdleConns(5)
+	assert.Nil(t, Warm(context.Background(), db.DB(), 5))
+	assert.Equal(t, 5, server.Connections())
+
+	// the warm connections are reused
+	_, err := db.NewQuery("UPDATE album SET name='x'").Execute()
+	assert.Nil(t, err)
+	assert.Equal(t, 5, server.Connections())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.NotNil(t, Warm(ctx, db.DB(), 10))
+}
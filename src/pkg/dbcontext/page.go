@@ -0,0 +1,111 @@
+package dbcontext
+
+import (
+	"context"
+	"errors"
+	dbx "github.com/go-ozzo/ozzo-dbx"
+	"reflect"
+	"strings"
+)
+
+// totalColumn is the column of the window function counting the rows matched by a page query.
+const totalColumn = "total_count"
+
+// WindowDrivers lists the drivers of the databases supporting window functions, which Page uses
+// to fetch a page and the total number of rows in one query. Remove "mysql" for MySQL servers older than 8.0.
+var WindowDrivers = map[string]bool{"mysql": true, "postgres": true, "pgx": true, "sqlite3": true}
+
+// Page runs the query with the given offset and limit, populates slice, a pointer to a slice of structs,
+// with the rows of the page, and returns the number of rows the query matches regardless of offset and limit,
+// as needed by pagination. The given query is not modified.
+//
+// If the database supports window functions (see WindowDrivers), the total is selected along with the rows
+// as COUNT(*) OVER() in a single query. Otherwise, and for the DISTINCT and UNION queries, whose rows the window
+// function would miscount, the total is counted by a second query like Count does. So is the total of a page
+// past the last row, which has no row to carry it. The struct fields are mapped to columns like dbx does.
+func (db *DB) Page(ctx context.Context, q *dbx.SelectQuery, offset, limit int, slice interface{}) (int, error) {
+	page := *q
+	page.Offset(int64(offset)).Limit(int64(limit))
+
+	windowed, ok := db.windowed(page)
+	if !ok {
+		if err := page.WithContext(ctx).All(slice); err != nil {
+			return 0, err
+		}
+		return db.Count(ctx, q)
+	}
+	rows, err := windowed.WithContext(ctx).Rows()
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	n, total, err := scanPage(rows, slice, db.db.FieldMapper)
+	if err != nil || n > 0 || offset <= 0 {
+		return total, err
+	}
+	return db.Count(ctx, q)
+}
+
+// windowed returns the page query selecting the total number of rows with a window function,
+// or false if the database does not support it or the query is not suitable.
+func (db *DB) windowed(page dbx.SelectQuery) (*dbx.Query, bool) {
+	if !WindowDrivers[db.db.DriverName()] {
+		return nil, false
+	}
+	window := "COUNT(*) OVER() AS " + totalColumn
+	q := page
+	built := q.AndSelect(window).Build()
+	sql := built.SQL()
+	if strings.HasPrefix(sql, "SELECT DISTINCT") || strings.Contains(sql, " UNION ") {
+		return nil, false
+	}
+	// a query without columns selects them all
+	if strings.HasPrefix(sql, "SELECT COUNT(*) OVER()") {
+		q = page
+		built = q.Select("*", window).Build()
+	}
+	return built, true
+}
+
+// scanPage populates slice with the rows, and returns the number of rows and the total read from totalColumn.
+func scanPage(rows *dbx.Rows, slice interface{}, mapper dbx.FieldMapFunc) (int, int, error) {
+	sv := reflect.ValueOf(slice)
+	if sv.Kind() != reflect.Ptr || sv.Elem().Kind() != reflect.Slice || sv.Elem().Type().Elem().Kind() != reflect.Struct {
+		return 0, 0, errors.New("dbcontext: the page must be read into a pointer to a slice of structs")
+	}
+	sv = sv.Elem()
+	et := sv.Type().Elem()
+	fields := map[string][]int{}
+	for _, c := range structColumns(et, mapper) {
+		fields[c.name] = c.index
+	}
+	cols, err := rows.Columns()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	result := reflect.MakeSlice(sv.Type(), 0, 0)
+	total := 0
+	for rows.Next() {
+		elem := reflect.New(et).Elem()
+		refs := make([]interface{}, len(cols))
+		for i, col := range cols {
+			if col == totalColumn {
+				refs[i] = &total
+			} else if index, ok := fields[col]; ok {
+				refs[i] = fieldByIndex(elem, index).Addr().Interface()
+			} else {
+				refs[i] = new(interface{})
+			}
+		}
+		if err := rows.Scan(refs...); err != nil {
+			return 0, 0, err
+		}
+		result = reflect.Append(result, elem)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, 0, err
+	}
+	sv.Set(result)
+	return result.Len(), total, nil
+}
@@ -0,0 +1,123 @@
+package dbcontext
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	dbx "github.com/go-ozzo/ozzo-dbx"
+	"github.com/stretchr/testify/assert"
+	"pkg/dbtest"
+	"strings"
+	"testing"
+)
+
+type pageItem struct {
+	ID   int `db:"pk"`
+	Name string
+}
+
+// openPageDB opens a mock database of five items, which serves the pages with or without their total.
+func openPageDB() (*DB, *dbtest.Server) {
+	items := [][]driver.Value{{int64(1), "a"}, {int64(2), "b"}, {int64(3), "c"}, {int64(4), "d"}, {int64(5), "e"}}
+	db, server := dbtest.Open("mysql", func(query string, args []driver.Value) (*dbtest.Result, error) {
+		if strings.HasPrefix(query, "SELECT COUNT(*) FROM") {
+			return &dbtest.Result{Columns: []string{"COUNT(*)"}, Rows: [][]driver.Value{{int64(len(items))}}}, nil
+		}
+		var limit, offset int
+		fmt.Sscanf(query[strings.Index(query, "LIMIT"):], "LIMIT %d OFFSET %d", &limit, &offset)
+		result := &dbtest.Result{Columns: []string{"id", "name"}}
+		windowed := strings.Contains(query, "OVER()")
+		if windowed {
+			result.Columns = append(result.Columns, "total_count")
+		}
+		for i := offset; i < offset+limit && i < len(items); i++ {
+			row := items[i]
+			if windowed {
+				row = append(row[:2:2], int64(len(items)))
+			}
+			result.Rows = append(result.Rows, row)
+		}
+		return result, nil
+	})
+	return New(db), server
+}
+
+func TestDB_Page(t *testing.T) {
+	db, server := openPageDB()
+	ctx := context.Background()
+	q := db.With(ctx).Select().From("item").Where(dbx.NewExp("1=1")).OrderBy("id")
+
+	var items []pageItem
+	total, err := db.Page(ctx, q, 2, 2, &items)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, total)
+	assert.Equal(t, []pageItem{{3, "c"}, {4, "d"}}, items)
+	if statements := server.Statements(); assert.Equal(t, 1, len(statements)) {
+		assert.Equal(t, "SELECT *, COUNT(*) OVER() AS `total_count` FROM `item` WHERE 1=1 ORDER BY `id` LIMIT 2 OFFSET 2", statements[0].SQL)
+	}
+
+	// the total of a page past the last row is counted separately
+	total, err = db.Page(ctx, q, 10, 2, &items)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, total)
+	assert.Empty(t, items)
+	assert.Equal(t, 3, len(server.Statements()))
+
+	// the selected columns are kept
+	total, err = db.Page(ctx, db.With(ctx).Select("id", "name").From("item"), 4, 2, &items)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, total)
+	assert.Equal(t, []pageItem{{5, "e"}}, items)
+	assert.Equal(t, "SELECT `id`, `name`, COUNT(*) OVER() AS `total_count` FROM `item` LIMIT 2 OFFSET 4", server.SQL()[3])
+
+	// the original query keeps its clauses
+	assert.Equal(t, "SELECT * FROM `item` WHERE 1=1 ORDER BY `id`", q.Build().SQL())
+
+	// the rows are read into structs only
+	_, err = db.Page(ctx, q, 0, 2, &[]int{})
+	assert.NotNil(t, err)
+}
+
+func TestDB_Page_twoQueries(t *testing.T) {
+	delete(WindowDrivers, "mysql")
+	defer func() { WindowDrivers["mysql"] = true }()
+	db, server := openPageDB()
+	ctx := context.Background()
+
+	var items []pageItem
+	total, err := db.Page(ctx, db.With(ctx).Select().From("item").OrderBy("id"), 0, 3, &items)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, total)
+	assert.Equal(t, []pageItem{{1, "a"}, {2, "b"}, {3, "c"}}, items)
+	assert.Equal(t, []string{
+		"SELECT * FROM `item` ORDER BY `id` LIMIT 3",
+		"SELECT COUNT(*) FROM (SELECT * FROM `item`) AS t",
+	}, server.SQL())
+}
+
+type taggedPageItem struct {
+	Key   int    `db:"pk_id"`
+	Label string `db:"pk,name"`
+	Place *place
+}
+
+type place struct {
+	City string
+}
+
+func TestDB_Page_tags(t *testing.T) {
+	db, _ := dbtest.Open("mysql", func(query string, args []driver.Value) (*dbtest.Result, error) {
+		return &dbtest.Result{
+			Columns: []string{"pk_id", "name", "place.city", "total_count"},
+			Rows:    [][]driver.Value{{int64(7), "a", "Paris", int64(1)}},
+		}, nil
+	})
+	ctx := context.Background()
+
+	// the columns are mapped like dbx does, and the nil pointers to nested structs are allocated
+	var items []taggedPageItem
+	total, err := New(db).Page(ctx, New(db).With(ctx).Select().From("item"), 0, 2, &items)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, total)
+	assert.Equal(t, []taggedPageItem{{7, "a", &place{"Paris"}}}, items)
+}
@@ -0,0 +1,52 @@
+package dbcontext
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	dbx "github.com/go-ozzo/ozzo-dbx"
+	"github.com/stretchr/testify/assert"
+	"pkg/dbtest"
+	"testing"
+)
+
+func TestDB_RawQuery(t *testing.T) {
+	db, server := dbtest.Open("mysql", func(query string, args []driver.Value) (*dbtest.Result, error) {
+		result := &dbtest.Result{Columns: []string{"artist", "albums"}}
+		// the mock database only knows the albums of 2019
+		if args[0] == "2019-01-01" {
+			result.Rows = [][]driver.Value{{"abba", int64(2)}, {"queen", int64(1)}}
+		}
+		return result, nil
+	})
+	dbc := New(db)
+	ctx := context.Background()
+	query := "SELECT [[artist]], COUNT(*) AS albums FROM {{album}} WHERE [[created_at]] >= {:since} AND [[name]] <> {:name} GROUP BY [[artist]]"
+
+	type report struct {
+		Artist string
+		Albums int
+	}
+	var reports []report
+	err := dbc.RawQuery(ctx, query, dbx.Params{"since": "2019-01-01", "name": "x'; DROP TABLE album; --"}, &reports)
+	assert.Nil(t, err)
+	assert.Equal(t, []report{{"abba", 2}, {"queen", 1}}, reports)
+	if statements := server.Statements(); assert.Equal(t, 1, len(statements)) {
+		assert.Equal(t, "SELECT `artist`, COUNT(*) AS albums FROM `album` WHERE `created_at` >= ? AND `name` <> ? GROUP BY `artist`", statements[0].SQL)
+		// the parameters are bound, not interpolated
+		assert.Equal(t, []driver.Value{"2019-01-01", "x'; DROP TABLE album; --"}, statements[0].Args)
+	}
+
+	// a single row
+	var top report
+	assert.Nil(t, dbc.RawQuery(ctx, query, map[string]interface{}{"since": "2019-01-01", "name": ""}, &top))
+	assert.Equal(t, report{"abba", 2}, top)
+	assert.Equal(t, ErrNotFound, dbc.RawQuery(ctx, query, dbx.Params{"since": "2020-01-01", "name": ""}, &top))
+
+	// the rows are limited like those of All
+	err = dbc.WithMaxRows(1).RawQuery(ctx, query, dbx.Params{"since": "2019-01-01", "name": ""}, &reports)
+	assert.True(t, errors.Is(err, ErrTooManyRows))
+
+	// missing parameters fail
+	assert.NotNil(t, dbc.RawQuery(ctx, query, dbx.Params{"since": "2019-01-01"}, &reports))
+}
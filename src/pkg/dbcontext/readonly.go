@@ -0,0 +1,33 @@
+package dbcontext
+
+import (
+	"errors"
+	"github.com/go-sql-driver/mysql"
+	"strings"
+)
+
+// MySQL errors returned when a statement writes to a read-only server.
+const (
+	// errOptionPreventsStatement is returned by a server running with an option preventing the statement,
+	// such as --read-only or --super-read-only.
+	errOptionPreventsStatement = 1290
+	// errReadOnlyMode is returned by a server running in read-only mode.
+	errReadOnlyMode = 1836
+)
+
+// IsReadOnly reports whether the error was returned by a database refusing a write because it is read-only,
+// typically during maintenance. Reads keep working in the meantime.
+func IsReadOnly(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return false
+	}
+	switch mysqlErr.Number {
+	case errOptionPreventsStatement:
+		// the error is also returned for options unrelated to writes, such as --secure-file-priv
+		return strings.Contains(mysqlErr.Message, "read-only")
+	case errReadOnlyMode:
+		return true
+	}
+	return false
+}
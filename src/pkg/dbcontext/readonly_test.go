@@ -0,0 +1,21 @@
+package dbcontext
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestIsReadOnly(t *testing.T) {
+	readOnly := &mysql.MySQLError{Number: 1290, Message: "The MySQL server is running with the --read-only option so it cannot execute this statement"}
+	assert.True(t, IsReadOnly(readOnly))
+	assert.True(t, IsReadOnly(fmt.Errorf("insert album: %w", readOnly)))
+	assert.True(t, IsReadOnly(&mysql.MySQLError{Number: 1836, Message: "Running in read-only mode"}))
+
+	assert.False(t, IsReadOnly(&mysql.MySQLError{Number: 1290, Message: "The MySQL server is running with the --secure-file-priv option so it cannot execute this statement"}))
+	assert.False(t, IsReadOnly(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry '1' for key 'PRIMARY'"}))
+	assert.False(t, IsReadOnly(errors.New("read-only")))
+	assert.False(t, IsReadOnly(nil))
+}
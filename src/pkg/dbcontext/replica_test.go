@@ -0,0 +1,49 @@
+package dbcontext
+
+import (
+	"context"
+	"database/sql/driver"
+	dbx "github.com/go-ozzo/ozzo-dbx"
+	"github.com/stretchr/testify/assert"
+	"pkg/dbtest"
+	"testing"
+)
+
+func TestDB_replica(t *testing.T) {
+	handler := func(query string, args []driver.Value) (*dbtest.Result, error) {
+		return &dbtest.Result{Columns: []string{"id"}, Rows: [][]driver.Value{{int64(1)}}, RowsAffected: 1}, nil
+	}
+	primary, primaryServer := dbtest.Open("mysql", handler)
+	replica, replicaServer := dbtest.Open("mysql", handler)
+	db := NewWithReplica(primary, replica)
+	assert.Equal(t, replica, db.Replica())
+	ctx := context.Background()
+
+	// reads go to the replica, writes to the primary
+	var id int
+	assert.Nil(t, db.With(ctx).Select("id").From("item").Row(&id))
+	_, err := db.With(ctx).Update("item", dbx.Params{"name": "x"}, dbx.HashExp{"id": 1}).Execute()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"SELECT `id` FROM `item`"}, replicaServer.SQL())
+	assert.Equal(t, []string{"UPDATE `item` SET `name`=? WHERE `id`=?"}, primaryServer.SQL())
+
+	// reads are forced to the primary
+	assert.Nil(t, db.With(WithPrimary(ctx)).Select("id").From("item").Row(&id))
+	assert.Equal(t, 1, len(replicaServer.SQL()))
+	assert.Equal(t, "SELECT `id` FROM `item`", primaryServer.SQL()[1])
+
+	// transactions run on the primary
+	err = db.Transactional(ctx, func(ctx context.Context) error {
+		return db.With(ctx).Select("id").From("item").Row(&id)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(replicaServer.SQL()))
+	assert.Equal(t, []string{"BEGIN", "SELECT `id` FROM `item`", "COMMIT"}, primaryServer.SQL()[2:])
+
+	// without replica, everything goes to the primary
+	db = New(primary)
+	assert.Nil(t, db.Replica())
+	assert.Nil(t, db.With(ctx).Select("id").From("item").Row(&id))
+	assert.Equal(t, 1, len(replicaServer.SQL()))
+	assert.Equal(t, 6, len(primaryServer.SQL()))
+}
@@ -0,0 +1,51 @@
+package dbcontext
+
+import (
+	"context"
+	dbx "github.com/go-ozzo/ozzo-dbx"
+	"strings"
+)
+
+// currentSchemaFuncs maps the drivers to the SQL function returning the current schema, which is the
+// database with MySQL and the first schema of the search path with PostgreSQL.
+var currentSchemaFuncs = map[string]string{"postgres": "current_schema()", "pgx": "current_schema()"}
+
+// MissingTables returns the names of the given tables that do not exist in the current database schema.
+// The tables are looked up in information_schema, so the database must support it. The current schema is
+// found with DATABASE() (MySQL), or current_schema() with the PostgreSQL drivers "postgres" and "pgx".
+func (db *DB) MissingTables(ctx context.Context, tables ...string) ([]string, error) {
+	if len(tables) == 0 {
+		return nil, nil
+	}
+	names := make([]interface{}, len(tables))
+	for i, table := range tables {
+		names[i] = table
+	}
+
+	currentSchema, ok := currentSchemaFuncs[db.db.DriverName()]
+	if !ok {
+		currentSchema = "DATABASE()"
+	}
+	var existing []string
+	err := db.db.WithContext(ctx).
+		Select("table_name").
+		From("information_schema.tables").
+		Where(dbx.NewExp("table_schema = " + currentSchema)).
+		AndWhere(dbx.In("table_name", names...)).
+		Column(&existing)
+	if err != nil {
+		return nil, err
+	}
+
+	found := map[string]bool{}
+	for _, table := range existing {
+		found[strings.ToLower(table)] = true
+	}
+	var missing []string
+	for _, table := range tables {
+		if !found[strings.ToLower(table)] {
+			missing = append(missing, table)
+		}
+	}
+	return missing, nil
+}
@@ -0,0 +1,46 @@
+package dbcontext
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"pkg/dbtest"
+	"testing"
+)
+
+func TestDB_MissingTables(t *testing.T) {
+	db, server := dbtest.Open("mysql", func(query string, args []driver.Value) (*dbtest.Result, error) {
+		return &dbtest.Result{Columns: []string{"table_name"}, Rows: [][]driver.Value{{"LOGUSER"}, {"album"}}}, nil
+	})
+	dbc := New(db)
+	ctx := context.Background()
+
+	missing, err := dbc.MissingTables(ctx, "loguser", "album")
+	assert.Nil(t, err)
+	assert.Empty(t, missing)
+	assert.Equal(t, "SELECT `table_name` FROM `information_schema`.`tables` WHERE (table_schema = DATABASE()) AND (`table_name` IN (?, ?))", server.SQL()[0])
+
+	missing, err = dbc.MissingTables(ctx, "loguser", "album", "tenant")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"tenant"}, missing)
+
+	missing, err = dbc.MissingTables(ctx)
+	assert.Nil(t, err)
+	assert.Empty(t, missing)
+
+	// PostgreSQL has no DATABASE() function
+	db, server = dbtest.Open("postgres", func(query string, args []driver.Value) (*dbtest.Result, error) {
+		return &dbtest.Result{Columns: []string{"table_name"}, Rows: [][]driver.Value{{"album"}}}, nil
+	})
+	missing, err = New(db).MissingTables(ctx, "loguser", "album")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"loguser"}, missing)
+	assert.Equal(t, `SELECT "table_name" FROM "information_schema"."tables" WHERE (table_schema = current_schema()) AND ("table_name" IN ($1, $2))`, server.SQL()[0])
+
+	db, _ = dbtest.Open("mysql", func(query string, args []driver.Value) (*dbtest.Result, error) {
+		return nil, errors.New("access denied")
+	})
+	_, err = New(db).MissingTables(ctx, "loguser")
+	assert.NotNil(t, err)
+}
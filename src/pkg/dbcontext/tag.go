@@ -0,0 +1,77 @@
+package dbcontext
+
+import (
+	"context"
+	"database/sql"
+	dbx "github.com/go-ozzo/ozzo-dbx"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"pkg/routeinfo"
+	"strings"
+	"sync"
+)
+
+// tagged lists the drivers whose queries are tagged by TagQueries.
+var tagged sync.Map
+
+// TagQueries makes the databases of the given driver (e.g. "mysql") prefix the SQL statements they run
+// with a comment naming the route of the request, such as "/* route:/v1/login */ SELECT ...", so that
+// slow queries reported by the database can be attributed to endpoints. The route is taken from the
+// context of the query, where it is stored by RouteHandler. Statements run without a route are not changed.
+//
+// It replaces the dbx builder of the driver, so it should be called before the databases are opened.
+func TagQueries(driverName string) {
+	if _, loaded := tagged.LoadOrStore(driverName, true); loaded {
+		return
+	}
+	builderFunc, ok := dbx.BuilderFuncMap[driverName]
+	if !ok {
+		builderFunc = dbx.NewStandardBuilder
+	}
+	dbx.BuilderFuncMap[driverName] = func(db *dbx.DB, executor dbx.Executor) dbx.Builder {
+		return builderFunc(db, taggingExecutor{executor})
+	}
+}
+
+// RouteHandler returns a middleware that stores the pattern of the route matching the request
+// (e.g. "/v1/albums/<id>") in the request context, so that TagSQL can attribute queries to it.
+func RouteHandler() routing.Handler {
+	return func(c *routing.Context) error {
+		if route := routeinfo.Pattern(c); route != "" {
+			c.Request = c.Request.WithContext(WithRoute(c.Request.Context(), route))
+		}
+		return nil
+	}
+}
+
+// WithRoute returns a context whose queries are attributed to the given route.
+func WithRoute(ctx context.Context, route string) context.Context {
+	return context.WithValue(ctx, routeKey, route)
+}
+
+// TagSQL returns the SQL statement prefixed with a comment naming the route stored in the context.
+// The statement is returned unchanged if the context has no route. Use it in the dbx log functions
+// to log the statements as the database receives them.
+func TagSQL(ctx context.Context, sql string) string {
+	if ctx == nil {
+		return sql
+	}
+	route, _ := ctx.Value(routeKey).(string)
+	if route == "" {
+		return sql
+	}
+	// the route must not end the comment early
+	return "/* route:" + strings.Replace(route, "*/", "* /", -1) + " */ " + sql
+}
+
+// taggingExecutor tags the statements executed with a context, which all queries built by DB.With are.
+type taggingExecutor struct {
+	dbx.Executor
+}
+
+func (e taggingExecutor) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return e.Executor.ExecContext(ctx, TagSQL(ctx, query), args...)
+}
+
+func (e taggingExecutor) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return e.Executor.QueryContext(ctx, TagSQL(ctx, query), args...)
+}
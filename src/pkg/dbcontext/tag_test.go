@@ -0,0 +1,53 @@
+package dbcontext
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	dbx "github.com/go-ozzo/ozzo-dbx"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"pkg/dbtest"
+	"testing"
+	"time"
+)
+
+func TestTagQueries(t *testing.T) {
+	// a builder driver of its own keeps the other tests untagged
+	TagQueries("tagged")
+	TagQueries("tagged")
+	sqlDB, server := dbtest.Open("tagged", func(query string, args []driver.Value) (*dbtest.Result, error) {
+		return &dbtest.Result{Columns: []string{"id"}, Rows: [][]driver.Value{{int64(1)}}, RowsAffected: 1}, nil
+	})
+	var logged []string
+	sqlDB.QueryLogFunc = func(ctx context.Context, t time.Duration, sql string, rows *sql.Rows, err error) {
+		logged = append(logged, TagSQL(ctx, sql))
+	}
+	db := New(sqlDB)
+
+	router := routing.New()
+	router.Use(RouteHandler())
+	router.Get("/v1/albums/<id>", func(c *routing.Context) error {
+		var id int
+		return db.With(c.Request.Context()).Select("id").From("album").Where(dbx.HashExp{"id": c.Param("id")}).Row(&id)
+	})
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/v1/albums/1", nil)
+	router.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, []string{`/* route:/v1/albums/<id> */ SELECT "id" FROM "album" WHERE "id"=?`}, server.SQL())
+	assert.Equal(t, []string{`/* route:/v1/albums/<id> */ SELECT "id" FROM "album" WHERE "id"='1'`}, logged)
+
+	// statements outside of a request are not tagged
+	_, err := db.With(context.Background()).Update("album", dbx.Params{"name": "x"}, nil).Execute()
+	assert.Nil(t, err)
+	assert.Equal(t, `UPDATE "album" SET "name"=?`, server.SQL()[1])
+}
+
+func TestTagSQL(t *testing.T) {
+	assert.Equal(t, "SELECT 1", TagSQL(context.Background(), "SELECT 1"))
+	assert.Equal(t, "/* route:/v1/login */ SELECT 1", TagSQL(WithRoute(context.Background(), "/v1/login"), "SELECT 1"))
+	assert.Equal(t, "/* route:/static/* /x */ SELECT 1", TagSQL(WithRoute(context.Background(), "/static/*/x"), "SELECT 1"))
+}
@@ -0,0 +1,49 @@
+package dbcontext
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	dbx "github.com/go-ozzo/ozzo-dbx"
+	"reflect"
+)
+
+// Upsert inserts the given model, a pointer to a struct, into its table, or updates the existing row if the insert
+// conflicts with a unique key. This makes writes idempotent, e.g. for sync jobs replaying the same records.
+//
+// The SQL depends on the database: MySQL uses INSERT ... ON DUPLICATE KEY UPDATE, which applies to any unique key,
+// and PostgreSQL uses INSERT ... ON CONFLICT (conflict) DO UPDATE, where conflict lists the columns of the unique key.
+// It defaults to the primary key of the model, and an error is returned if the model has none.
+// The table and columns are derived from the model like ModelQuery.Insert does: the table from its TableName method
+// or type name, and the columns from the exported fields, honoring the "db" tags and skipping the fields tagged
+// `db:"-"`. Like With, the statement runs in the transaction of the context if there is one, and on the primary
+// database otherwise.
+func (db *DB) Upsert(ctx context.Context, model interface{}, conflict ...string) error {
+	v := reflect.Indirect(reflect.ValueOf(model))
+	if v.Kind() != reflect.Struct {
+		return errors.New("dbcontext: the model must be a pointer to a struct")
+	}
+	params := dbx.Params{}
+	var pk []string
+	for _, c := range structColumns(v.Type(), db.db.FieldMapper) {
+		params[c.name] = fieldValue(v, c.index)
+		if c.pk {
+			pk = append(pk, c.name)
+		}
+	}
+	if len(conflict) == 0 && db.db.DriverName() != "mysql" {
+		if len(pk) == 0 {
+			return fmt.Errorf("dbcontext: the model %T has no primary key to detect the conflicts with", model)
+		}
+		conflict = pk
+	}
+	builder := db.With(ctx)
+	q := builder.Upsert(db.db.TableMapper(model), params, conflict...)
+	if q.LastError != nil {
+		return q.LastError
+	}
+	// the MySQL builder appends the update clause to the SQL of the query without preparing it again,
+	// so the query is recreated from its complete SQL.
+	_, err := builder.NewQuery(q.SQL()).Bind(q.Params()).Execute()
+	return err
+}
@@ -0,0 +1,102 @@
+package dbcontext
+
+import (
+	"context"
+	"database/sql/driver"
+	"github.com/stretchr/testify/assert"
+	"pkg/dbtest"
+	"testing"
+)
+
+type syncedItem struct {
+	ID       string `db:"pk"`
+	Name     string
+	Internal string `db:"-"`
+	itemMeta
+}
+
+type itemMeta struct {
+	Source string `db:"origin"`
+}
+
+func (syncedItem) TableName() string {
+	return "item"
+}
+
+func TestDB_Upsert(t *testing.T) {
+	// the fake database reports one affected row for an insert and two for an update, like MySQL
+	existing := map[driver.Value]bool{}
+	db, server := dbtest.Open("mysql", func(query string, args []driver.Value) (*dbtest.Result, error) {
+		affected := int64(1)
+		if existing[args[0]] {
+			affected = 2
+		}
+		existing[args[0]] = true
+		return &dbtest.Result{RowsAffected: affected}, nil
+	})
+	dbc := New(db)
+	ctx := context.Background()
+
+	// insert
+	item := &syncedItem{ID: "1", Name: "first", Internal: "x", itemMeta: itemMeta{"sync"}}
+	assert.Nil(t, dbc.Upsert(ctx, item))
+	// update on conflict
+	item.Name = "renamed"
+	assert.Nil(t, dbc.Upsert(ctx, item))
+
+	statements := server.Statements()
+	if assert.Equal(t, 2, len(statements)) {
+		want := "INSERT INTO `item` (`id`, `name`, `origin`) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE `id`=?, `name`=?, `origin`=?"
+		assert.Equal(t, want, statements[0].SQL)
+		assert.Equal(t, []driver.Value{"1", "first", "sync", "1", "first", "sync"}, statements[0].Args)
+		assert.Equal(t, want, statements[1].SQL)
+		assert.Equal(t, []driver.Value{"1", "renamed", "sync", "1", "renamed", "sync"}, statements[1].Args)
+	}
+}
+
+func TestDB_Upsert_postgres(t *testing.T) {
+	db, server := dbtest.Open("postgres", nil)
+	assert.Nil(t, New(db).Upsert(context.Background(), &syncedItem{ID: "1", Name: "first"}, "id"))
+	assert.Equal(t, []string{`INSERT INTO "item" ("id", "name", "origin") VALUES ($1, $2, $3) ON CONFLICT ("id") DO UPDATE SET "id"=$4, "name"=$5, "origin"=$6`},
+		server.SQL())
+}
+
+type taggedItem struct {
+	Code    string `db:"pk,code"`
+	OwnerID string `db:"pk_id"`
+	Package string `db:"pkg"`
+	Note    *string
+	Address address
+	itemMeta
+	Source string `db:"source"`
+}
+
+type address struct {
+	City string
+}
+
+func TestDB_Upsert_postgresPrimaryKey(t *testing.T) {
+	db, server := dbtest.Open("postgres", nil)
+	dbc := New(db)
+
+	// the conflicts are detected with the primary key by default
+	assert.Nil(t, dbc.Upsert(context.Background(), &syncedItem{ID: "1", Name: "first"}))
+	assert.Equal(t, []string{`INSERT INTO "item" ("id", "name", "origin") VALUES ($1, $2, $3) ON CONFLICT ("id") DO UPDATE SET "id"=$4, "name"=$5, "origin"=$6`},
+		server.SQL())
+
+	// the fields are mapped like dbx does: only "pk" and "pk," mark the primary key, the fields of struct fields
+	// are nested columns, a field shadows the field of the same name of an embedded struct, and nil pointers are NULL
+	assert.Nil(t, dbc.Upsert(context.Background(), &taggedItem{Code: "a", OwnerID: "o", Package: "p", Address: address{"Paris"}, Source: "s"}))
+	statements := server.Statements()
+	if assert.Equal(t, 2, len(statements)) {
+		assert.Equal(t, `INSERT INTO "tagged_item" ("address"."city", "code", "note", "pk_id", "pkg", "source") VALUES ($1, $2, $3, $4, $5, $6) `+
+			`ON CONFLICT ("code") DO UPDATE SET "address"."city"=$7, "code"=$8, "note"=$9, "pk_id"=$10, "pkg"=$11, "source"=$12`, statements[1].SQL)
+		assert.Equal(t, []driver.Value{"Paris", "a", nil, "o", "p", "s"}, statements[1].Args[:6])
+	}
+
+	// a model without a primary key needs the conflict columns
+	err := dbc.Upsert(context.Background(), &itemMeta{Source: "s"})
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "has no primary key")
+	}
+}
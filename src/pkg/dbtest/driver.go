@@ -0,0 +1,235 @@
+// Package dbtest provides a fake SQL database for testing database code without a database server.
+//
+// The fake database records every statement it receives and answers queries using a Handler
+// supplied by the test.
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	dbx "github.com/go-ozzo/ozzo-dbx"
+	"io"
+	"sync"
+)
+
+// DriverName is the name under which the fake driver is registered with database/sql.
+const DriverName = "dbtest"
+
+var (
+	registerOnce sync.Once
+	serversMu    sync.Mutex
+	servers      = map[string]*Server{}
+)
+
+// Result is the answer of the fake database to a statement.
+type Result struct {
+	// Columns lists the column names of the rows returned by a query.
+	Columns []string
+	// Rows lists the rows returned by a query.
+	Rows [][]driver.Value
+	// RowsAffected is the number of rows affected by an execution.
+	RowsAffected int64
+	// LastInsertID is the ID generated by an execution.
+	LastInsertID int64
+}
+
+// Handler answers a statement sent to the fake database.
+// A nil result is treated as an empty result.
+type Handler func(query string, args []driver.Value) (*Result, error)
+
+// Statement is a statement received by the fake database.
+type Statement struct {
+	SQL  string
+	Args []driver.Value
+}
+
+// Server is a fake database.
+type Server struct {
+	handler Handler
+	dsn     string
+
+	mu          sync.Mutex
+	statements  []Statement
+	connections int
+	// PingError is returned when a connection is pinged.
+	PingError error
+}
+
+// Open creates a fake database answering statements with the given handler and returns
+// a dbx.DB connected to it. The dbx builder is chosen according to builderDriver (e.g. "mysql").
+func Open(builderDriver string, handler Handler) (*dbx.DB, *Server) {
+	registerOnce.Do(func() {
+		sql.Register(DriverName, fakeDriver{})
+	})
+	serversMu.Lock()
+	dsn := fmt.Sprintf("server-%d", len(servers))
+	server := &Server{handler: handler, dsn: dsn}
+	servers[dsn] = server
+	serversMu.Unlock()
+
+	sqlDB, _ := sql.Open(DriverName, dsn)
+	return dbx.NewFromDB(sqlDB, builderDriver), server
+}
+
+// DSN returns the data source name under which the fake database can be opened with the DriverName driver.
+func (s *Server) DSN() string {
+	return s.dsn
+}
+
+// Statements returns the statements received so far.
+// Transaction boundaries are recorded as "BEGIN", "COMMIT" and "ROLLBACK".
+func (s *Server) Statements() []Statement {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Statement(nil), s.statements...)
+}
+
+// SQL returns the SQL of the statements received so far.
+func (s *Server) SQL() []string {
+	var result []string
+	for _, st := range s.Statements() {
+		result = append(result, st.SQL)
+	}
+	return result
+}
+
+// Connections returns the number of connections opened so far.
+func (s *Server) Connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.connections
+}
+
+func (s *Server) record(query string, args []driver.Value) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statements = append(s.statements, Statement{query, args})
+}
+
+func (s *Server) handle(query string, args []driver.Value) (*Result, error) {
+	s.record(query, args)
+	if s.handler == nil {
+		return &Result{}, nil
+	}
+	result, err := s.handler(query, args)
+	if result == nil {
+		result = &Result{}
+	}
+	return result, err
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(dsn string) (driver.Conn, error) {
+	serversMu.Lock()
+	server, ok := servers[dsn]
+	serversMu.Unlock()
+	if !ok {
+		return nil, errors.New("dbtest: unknown server " + dsn)
+	}
+	server.mu.Lock()
+	server.connections++
+	server.mu.Unlock()
+	return &conn{server}, nil
+}
+
+type conn struct {
+	server *Server
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{c.server, query}, nil
+}
+
+func (c *conn) Close() error {
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	c.server.record("BEGIN", nil)
+	return &tx{c.server}, nil
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	return c.server.PingError
+}
+
+type tx struct {
+	server *Server
+}
+
+func (t *tx) Commit() error {
+	t.server.record("COMMIT", nil)
+	return nil
+}
+
+func (t *tx) Rollback() error {
+	t.server.record("ROLLBACK", nil)
+	return nil
+}
+
+type stmt struct {
+	server *Server
+	query  string
+}
+
+func (s *stmt) Close() error {
+	return nil
+}
+
+func (s *stmt) NumInput() int {
+	return -1
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	result, err := s.server.handle(s.query, args)
+	if err != nil {
+		return nil, err
+	}
+	return execResult{result}, nil
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	result, err := s.server.handle(s.query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &rows{result: result}, nil
+}
+
+type execResult struct {
+	result *Result
+}
+
+func (r execResult) LastInsertId() (int64, error) {
+	return r.result.LastInsertID, nil
+}
+
+func (r execResult) RowsAffected() (int64, error) {
+	return r.result.RowsAffected, nil
+}
+
+type rows struct {
+	result *Result
+	index  int
+}
+
+func (r *rows) Columns() []string {
+	return r.result.Columns
+}
+
+func (r *rows) Close() error {
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.index >= len(r.result.Rows) {
+		return io.EOF
+	}
+	copy(dest, r.result.Rows[r.index])
+	r.index++
+	return nil
+}
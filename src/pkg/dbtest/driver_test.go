@@ -0,0 +1,51 @@
+package dbtest
+
+import (
+	"database/sql/driver"
+	"errors"
+	dbx "github.com/go-ozzo/ozzo-dbx"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestOpen(t *testing.T) {
+	db, server := Open("mysql", func(query string, args []driver.Value) (*Result, error) {
+		if query == "SELECT `id`, `name` FROM `user`" {
+			return &Result{
+				Columns: []string{"id", "name"},
+				Rows:    [][]driver.Value{{int64(1), "a"}, {int64(2), "b"}},
+			}, nil
+		}
+		if query == "DELETE FROM `user`" {
+			return &Result{RowsAffected: 2}, nil
+		}
+		return nil, errors.New("unexpected query")
+	})
+	assert.Equal(t, "mysql", db.DriverName())
+
+	var users []struct {
+		ID   int
+		Name string
+	}
+	assert.Nil(t, db.Select("id", "name").From("user").All(&users))
+	assert.Equal(t, 2, len(users))
+	assert.Equal(t, "b", users[1].Name)
+
+	err := db.Transactional(func(tx *dbx.Tx) error {
+		result, err := tx.Delete("user", nil).Execute()
+		if assert.Nil(t, err) {
+			n, _ := result.RowsAffected()
+			assert.Equal(t, int64(2), n)
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.NotNil(t, db.NewQuery("SELECT 1").Row(new(int)))
+
+	assert.Equal(t, []string{"SELECT `id`, `name` FROM `user`", "BEGIN", "DELETE FROM `user`", "COMMIT", "SELECT 1"}, server.SQL())
+	assert.True(t, server.Connections() > 0)
+
+	assert.Nil(t, db.DB().Ping())
+	server.PingError = errors.New("down")
+	assert.NotNil(t, db.DB().Ping())
+}
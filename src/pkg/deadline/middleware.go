@@ -0,0 +1,123 @@
+// Package deadline provides a middleware that lets clients limit how long their requests may be processed.
+package deadline
+
+import (
+	"context"
+	"fmt"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"net/http"
+	"path"
+	"pkg/ndjson"
+	"strconv"
+	"time"
+)
+
+// HeaderName is the name of the request header with which clients set the processing time limit,
+// either as a duration such as "500ms" or "2s", or as a number of milliseconds.
+const HeaderName = "X-Request-Timeout"
+
+// Options represents the options of the deadline middleware.
+type Options struct {
+	// Default is the time limit of the requests without the header, also clamped to the maximum.
+	// They have no limit if zero.
+	Default time.Duration
+	// ExcludedPaths are the patterns of the request paths that have no time limit, as matched by path.Match,
+	// e.g. the long-polling endpoints, which hold the requests on purpose.
+	ExcludedPaths []string
+}
+
+// Handler returns a middleware that sets the deadline of the request context to the time limit
+// requested in the X-Request-Timeout header, clamped to max. A max that is not positive does not clamp.
+// Requests with a malformed or non-positive limit are rejected with 400.
+//
+// Handlers must pass the request context to their operations for the deadline to stop them.
+// If the deadline has passed when the handler returns, the request is answered with 504 Gateway Timeout,
+// unless the handler has already written a response. The requests accepting an NDJSON stream and those
+// matching the excluded paths have no time limit, as they are meant to last.
+func Handler(max time.Duration, options ...Options) routing.Handler {
+	var opt Options
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	return func(c *routing.Context) error {
+		if ndjson.Accepts(c.Request) || excluded(c.Request.URL.Path, opt.ExcludedPaths) {
+			return nil
+		}
+		timeout := opt.Default
+		if value := c.Request.Header.Get(HeaderName); value != "" {
+			requested, err := parse(value)
+			if err != nil {
+				return routing.NewHTTPError(http.StatusBadRequest,
+					fmt.Sprintf("the %v header must be a positive duration, e.g. \"500ms\" or \"2s\"", HeaderName))
+			}
+			timeout = requested
+		}
+		if max > 0 && timeout > max {
+			timeout = max
+		}
+		if timeout <= 0 {
+			return nil
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		rw := &responseWriter{ResponseWriter: c.Response}
+		c.Response = rw
+
+		err := c.Next()
+		if ctx.Err() == context.DeadlineExceeded && !rw.written {
+			return routing.NewHTTPError(http.StatusGatewayTimeout,
+				fmt.Sprintf("the request could not be processed within %v", timeout))
+		}
+		return err
+	}
+}
+
+// excluded reports whether the path matches one of the patterns.
+func excluded(p string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// parse reads the value of the X-Request-Timeout header.
+func parse(value string) (time.Duration, error) {
+	var d time.Duration
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		d = time.Duration(ms) * time.Millisecond
+	} else if d, err = time.ParseDuration(value); err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("non-positive timeout %v", value)
+	}
+	return d, nil
+}
+
+// responseWriter records whether the response has been started.
+type responseWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	w.written = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.written = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush sends any buffered data to the client if the underlying writer supports it.
+func (w *responseWriter) Flush() {
+	w.written = true
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
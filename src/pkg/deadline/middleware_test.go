@@ -0,0 +1,129 @@
+package deadline
+
+import (
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandler(t *testing.T) {
+	var remaining time.Duration
+	var hasDeadline bool
+	router := routing.New()
+	router.Use(Handler(time.Second, Options{Default: 500 * time.Millisecond}))
+	router.Get("/albums", func(c *routing.Context) error {
+		var deadline time.Time
+		deadline, hasDeadline = c.Request.Context().Deadline()
+		remaining = time.Until(deadline)
+		return c.Write("ok")
+	})
+	get := func(timeout string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/albums", nil)
+		if timeout != "" {
+			req.Header.Set(HeaderName, timeout)
+		}
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		return res
+	}
+	tests := []struct {
+		name    string
+		header  string
+		timeout time.Duration
+	}{
+		{"requested duration", "200ms", 200 * time.Millisecond},
+		{"requested milliseconds", "300", 300 * time.Millisecond},
+		{"clamped to max", "1m", time.Second},
+		{"default", "", 500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := get(tt.header)
+			assert.Equal(t, http.StatusOK, res.Code)
+			assert.True(t, hasDeadline)
+			assert.True(t, remaining <= tt.timeout && remaining > tt.timeout-100*time.Millisecond, remaining.String())
+		})
+	}
+
+	for _, invalid := range []string{"soon", "0", "-1s"} {
+		assert.Equal(t, http.StatusBadRequest, get(invalid).Code, invalid)
+	}
+}
+
+func TestHandler_noDefault(t *testing.T) {
+	router := routing.New()
+	router.Use(Handler(0))
+	router.Get("/albums", func(c *routing.Context) error {
+		_, ok := c.Request.Context().Deadline()
+		return c.Write(ok)
+	})
+	req, _ := http.NewRequest("GET", "/albums", nil)
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	assert.Equal(t, "false", res.Body.String())
+
+	// there is no maximum
+	req.Header.Set(HeaderName, "1h")
+	res = httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	assert.Equal(t, "true", res.Body.String())
+}
+
+func TestHandler_exceeded(t *testing.T) {
+	router := routing.New()
+	router.Use(Handler(50 * time.Millisecond))
+	router.Get("/slow", func(c *routing.Context) error {
+		<-c.Request.Context().Done()
+		return c.Request.Context().Err()
+	})
+	router.Get("/written", func(c *routing.Context) error {
+		c.Response.WriteHeader(http.StatusAccepted)
+		<-c.Request.Context().Done()
+		return nil
+	})
+
+	// the client asks for more time than the server allows
+	req, _ := http.NewRequest("GET", "/slow", nil)
+	req.Header.Set(HeaderName, "10s")
+	res := httptest.NewRecorder()
+	start := time.Now()
+	router.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusGatewayTimeout, res.Code)
+	assert.True(t, time.Since(start) < time.Second)
+	assert.Contains(t, res.Body.String(), "within 50ms")
+
+	// a response already started is left alone
+	req, _ = http.NewRequest("GET", "/written", nil)
+	req.Header.Set(HeaderName, "10ms")
+	res = httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusAccepted, res.Code)
+}
+
+func TestHandler_excluded(t *testing.T) {
+	router := routing.New()
+	router.Use(Handler(time.Second, Options{Default: time.Second, ExcludedPaths: []string{"/notifications/*"}}))
+	handler := func(c *routing.Context) error {
+		_, ok := c.Request.Context().Deadline()
+		return c.Write(ok)
+	}
+	router.Get("/albums", handler)
+	router.Get("/notifications/poll", handler)
+
+	for _, tt := range []struct {
+		url, accept, want string
+	}{
+		{"/albums", "application/json", "true"},
+		{"/albums", "application/x-ndjson", "false"},
+		{"/notifications/poll", "", "false"},
+	} {
+		req, _ := http.NewRequest("GET", tt.url, nil)
+		req.Header.Set("Accept", tt.accept)
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		assert.Equal(t, tt.want, res.Body.String(), tt.url+" "+tt.accept)
+	}
+}
@@ -0,0 +1,188 @@
+// Package deprecation marks response fields as deprecated, lists them in the Deprecation header of the responses
+// carrying them and omits them from the responses to the API versions they were removed from.
+//
+// A field is deprecated by the "deprecated" struct tag, whose value is the API version from which the field is
+// omitted, or empty if it is still sent to all versions:
+//
+//	type Album struct {
+//		Title string `json:"title"`
+//		Name  string `json:"name,omitempty" deprecated:"2"` // replaced by title, omitted from version 2 on
+//	}
+//
+// Omitted fields are set to their zero value, so they need the omitempty option of the json tag to be left out.
+package deprecation
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// HeaderName is the name of the response header listing the deprecated fields of the response.
+const HeaderName = "Deprecation"
+
+// TagName is the name of the struct tag marking deprecated fields.
+const TagName = "deprecated"
+
+// deprecatedTypes caches whether the values of a type may contain deprecated fields.
+var deprecatedTypes sync.Map
+
+// Prepare returns a copy of data whose deprecated fields removed from the given API version are zeroed,
+// along with the paths of the deprecated fields it still contains, such as "name" or "tracks.length".
+// The paths are made of the field names of the json tags. Fields are not removed if version is empty.
+// The data is returned as is if it has no deprecated fields.
+func Prepare(data interface{}, version string) (interface{}, []string) {
+	v := reflect.ValueOf(data)
+	if !v.IsValid() || !mayDeprecate(v.Type(), map[reflect.Type]bool{}) {
+		return data, nil
+	}
+	p := preparer{version: version, seen: map[string]bool{}}
+	return p.copy(v, "").Interface(), p.fields
+}
+
+// Compare compares two API versions and returns -1, 0 or 1 if a is older than, the same as or newer than b.
+// The versions are compared by their segments separated by "." or "-", numerically when both segments
+// are numbers, so that "1.10" is newer than "1.9" and "2024-01-15" newer than "2023-12-01".
+func Compare(a, b string) int {
+	sa, sb := splitVersion(a), splitVersion(b)
+	for i := 0; i < len(sa) && i < len(sb); i++ {
+		na, errA := strconv.Atoi(sa[i])
+		nb, errB := strconv.Atoi(sb[i])
+		switch {
+		case errA == nil && errB == nil && na != nb:
+			if na < nb {
+				return -1
+			}
+			return 1
+		case (errA != nil || errB != nil) && sa[i] != sb[i]:
+			if sa[i] < sb[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case len(sa) < len(sb):
+		return -1
+	case len(sa) > len(sb):
+		return 1
+	}
+	return 0
+}
+
+func splitVersion(version string) []string {
+	return strings.FieldsFunc(strings.TrimPrefix(version, "v"), func(r rune) bool { return r == '.' || r == '-' })
+}
+
+// mayDeprecate reports whether the values of the type may contain deprecated fields.
+// Interfaces may hold anything. visiting guards against recursive types.
+func mayDeprecate(t reflect.Type, visiting map[reflect.Type]bool) bool {
+	if result, ok := deprecatedTypes.Load(t); ok {
+		return result.(bool)
+	}
+	if visiting[t] {
+		return false
+	}
+	visiting[t] = true
+	result := false
+	switch t.Kind() {
+	case reflect.Interface:
+		result = true
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		result = mayDeprecate(t.Elem(), visiting)
+	case reflect.Struct:
+		for i := 0; i < t.NumField() && !result; i++ {
+			f := t.Field(i)
+			if _, ok := f.Tag.Lookup(TagName); ok {
+				result = true
+			} else if f.PkgPath == "" || f.Anonymous {
+				result = mayDeprecate(f.Type, visiting)
+			}
+		}
+	}
+	delete(visiting, t)
+	deprecatedTypes.Store(t, result)
+	return result
+}
+
+// preparer copies the response data, zeroing the removed fields and collecting the deprecated ones.
+type preparer struct {
+	version string
+	fields  []string
+	seen    map[string]bool
+}
+
+func (p *preparer) copy(v reflect.Value, path string) reflect.Value {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(p.copy(v.Elem(), path))
+		return c
+	case reflect.Ptr:
+		if v.IsNil() || !mayDeprecate(v.Type(), map[reflect.Type]bool{}) {
+			return v
+		}
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(p.copy(v.Elem(), path))
+		return c
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() || !mayDeprecate(v.Type(), map[reflect.Type]bool{}) {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		if v.Kind() == reflect.Slice {
+			c.Set(reflect.MakeSlice(v.Type(), v.Len(), v.Len()))
+		}
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(p.copy(v.Index(i), path))
+		}
+		return c
+	case reflect.Struct:
+		if !mayDeprecate(v.Type(), map[reflect.Type]bool{}) {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			name := fieldName(f)
+			if name == "-" || !c.Field(i).CanSet() {
+				continue
+			}
+			fieldPath := path
+			if !f.Anonymous {
+				fieldPath = strings.TrimPrefix(path+"."+name, ".")
+			}
+			if removedIn, ok := f.Tag.Lookup(TagName); ok {
+				if removedIn != "" && p.version != "" && Compare(p.version, removedIn) >= 0 {
+					c.Field(i).Set(reflect.Zero(f.Type))
+					continue
+				}
+				p.add(fieldPath)
+			}
+			c.Field(i).Set(p.copy(v.Field(i), fieldPath))
+		}
+		return c
+	}
+	return v
+}
+
+func (p *preparer) add(path string) {
+	if !p.seen[path] {
+		p.seen[path] = true
+		p.fields = append(p.fields, path)
+	}
+}
+
+// fieldName returns the name of a field in the JSON responses.
+func fieldName(f reflect.StructField) string {
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
@@ -0,0 +1,72 @@
+package deprecation
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type track struct {
+	Title  string `json:"title"`
+	Length int    `json:"length,omitempty" deprecated:""`
+}
+
+type album struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Name      string    `json:"name,omitempty" deprecated:"2"`
+	Tracks    []track   `json:"tracks"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func TestPrepare(t *testing.T) {
+	now := time.Now()
+	a := album{"1", "demo", "demo", []track{{"intro", 60}, {"outro", 90}}, now}
+
+	data, fields := Prepare(a, "1")
+	assert.Equal(t, a, data)
+	assert.Equal(t, []string{"name", "tracks.length"}, fields)
+
+	data, fields = Prepare(&a, "2.1")
+	assert.Equal(t, &album{"1", "demo", "", []track{{"intro", 60}, {"outro", 90}}, now}, data)
+	assert.Equal(t, []string{"tracks.length"}, fields)
+	// the data is not modified
+	assert.Equal(t, "demo", a.Name)
+
+	// the values of maps are not searched, unlike those of interfaces
+	_, fields = Prepare(map[string]interface{}{"items": []interface{}{a}}, "")
+	assert.Nil(t, fields)
+	// fields are not removed without a version
+	data, fields = Prepare(struct {
+		Items interface{} `json:"items"`
+	}{[]album{a}}, "")
+	assert.Equal(t, []string{"items.name", "items.tracks.length"}, fields)
+	assert.Equal(t, "demo", data.(struct {
+		Items interface{} `json:"items"`
+	}).Items.([]album)[0].Name)
+
+	data, fields = Prepare("text", "2")
+	assert.Equal(t, "text", data)
+	assert.Nil(t, fields)
+	data, fields = Prepare(nil, "2")
+	assert.Nil(t, data)
+	assert.Nil(t, fields)
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b   string
+		result int
+	}{
+		{"1", "1", 0},
+		{"1", "2", -1},
+		{"1.10", "1.9", 1},
+		{"v2", "2", 0},
+		{"2", "2.1", -1},
+		{"2024-01-15", "2023-12-01", 1},
+		{"1.0-beta", "1.0-alpha", 1},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.result, Compare(tc.a, tc.b), tc.a+" vs "+tc.b)
+	}
+}
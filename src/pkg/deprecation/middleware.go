@@ -0,0 +1,50 @@
+package deprecation
+
+import (
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/go-ozzo/ozzo-routing/v2/content"
+	"net/http"
+	"pkg/apiversion"
+	"strings"
+)
+
+// Options represents the options of the deprecation middleware.
+type Options struct {
+	// Writer writes the JSON responses once their deprecated fields are handled. Defaults to content.JSONDataWriter.
+	Writer routing.DataWriter
+}
+
+// Handler returns a middleware that lists the deprecated fields of the JSON responses in the Deprecation header
+// and omits those removed from the API version requested in the X-API-Version header. Nothing is omitted from
+// the responses to requests without a version. It must follow the content negotiation middleware, whose JSON
+// data writer it replaces, and the middleware replacing it in turn, whose writer should be given as Options.Writer.
+// The header is missing from the responses written by WriteWithStatus, which sends the headers first.
+func Handler(options ...Options) routing.Handler {
+	var opt Options
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	if opt.Writer == nil {
+		opt.Writer = &content.JSONDataWriter{}
+	}
+	return func(c *routing.Context) error {
+		if c.Response.Header().Get("Content-Type") == "application/json" {
+			c.SetDataWriter(&dataWriter{opt.Writer, c.Request.Header.Get(apiversion.HeaderName)})
+		}
+		return nil
+	}
+}
+
+// dataWriter handles the deprecated fields of the response data for an API version.
+type dataWriter struct {
+	routing.DataWriter
+	version string
+}
+
+func (w *dataWriter) Write(res http.ResponseWriter, data interface{}) error {
+	data, fields := Prepare(data, w.version)
+	if len(fields) > 0 {
+		res.Header().Set(HeaderName, strings.Join(fields, ", "))
+	}
+	return w.DataWriter.Write(res, data)
+}
@@ -0,0 +1,49 @@
+package deprecation
+
+import (
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/go-ozzo/ozzo-routing/v2/content"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"pkg/apiversion"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	router := routing.New()
+	router.Use(content.TypeNegotiator(content.JSON, content.XML), Handler())
+	router.Get("/albums/1", func(c *routing.Context) error {
+		return c.Write(album{ID: "1", Title: "demo", Name: "demo", Tracks: []track{}})
+	})
+	call := func(version, accept string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/albums/1", nil)
+		if version != "" {
+			req.Header.Set(apiversion.HeaderName, version)
+		}
+		req.Header.Set("Accept", accept)
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		return res
+	}
+
+	// the deprecated field is still sent to version 1
+	res := call("1", "application/json")
+	assert.Equal(t, "name", res.Header().Get(HeaderName))
+	assert.JSONEq(t, `{"id":"1","title":"demo","name":"demo","tracks":[],"created_at":"0001-01-01T00:00:00Z"}`, res.Body.String())
+
+	// but not to version 2
+	res = call("2", "application/json")
+	assert.Empty(t, res.Header().Get(HeaderName))
+	assert.JSONEq(t, `{"id":"1","title":"demo","tracks":[],"created_at":"0001-01-01T00:00:00Z"}`, res.Body.String())
+
+	// clients not specifying a version get every field
+	res = call("", "application/json")
+	assert.Equal(t, "name", res.Header().Get(HeaderName))
+	assert.Contains(t, res.Body.String(), `"name":"demo"`)
+
+	// other formats are left alone
+	res = call("2", "application/xml")
+	assert.Empty(t, res.Header().Get(HeaderName))
+	assert.Contains(t, res.Body.String(), "<Name>demo</Name>")
+}
@@ -0,0 +1,27 @@
+// Package download serves files and reports, supporting resumable downloads with HTTP range requests.
+package download
+
+import (
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+)
+
+// Serve responds to a request with the given content. A request with a Range header is answered with
+// 206 Partial Content and the requested byte ranges, described by the Content-Range header, or with
+// 416 Range Not Satisfiable if none of the ranges overlaps the content. Other requests get the whole content.
+//
+// If name is not empty, the content is sent as an attachment of that name, and its Content-Type is
+// derived from the name's extension; otherwise it is sniffed from the content. If modtime is not zero,
+// it is sent as Last-Modified and conditional requests (If-Modified-Since, If-Range) are honored.
+func Serve(c *routing.Context, name string, modtime time.Time, content io.ReadSeeker) error {
+	// the content negotiation middleware has already chosen a type for data responses
+	c.Response.Header().Del("Content-Type")
+	if name != "" {
+		c.Response.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	}
+	http.ServeContent(c.Response, c.Request, name, modtime, content)
+	return nil
+}
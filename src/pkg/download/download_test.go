@@ -0,0 +1,69 @@
+package download
+
+import (
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/go-ozzo/ozzo-routing/v2/content"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServe(t *testing.T) {
+	modtime := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+	router := routing.New()
+	router.Use(content.TypeNegotiator(content.JSON))
+	router.Get("/report", func(c *routing.Context) error {
+		return Serve(c, "report.csv", modtime, strings.NewReader("id,name\n1,alice\n"))
+	})
+	get := func(header http.Header) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/report", nil)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		return res
+	}
+
+	t.Run("full content", func(t *testing.T) {
+		res := get(nil)
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "id,name\n1,alice\n", res.Body.String())
+		assert.Equal(t, "bytes", res.Header().Get("Accept-Ranges"))
+		assert.Equal(t, "16", res.Header().Get("Content-Length"))
+		assert.Equal(t, "text/csv; charset=utf-8", res.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename=report.csv`, res.Header().Get("Content-Disposition"))
+		assert.Equal(t, "Fri, 16 Oct 2026 08:00:00 GMT", res.Header().Get("Last-Modified"))
+	})
+
+	t.Run("valid range", func(t *testing.T) {
+		res := get(http.Header{"Range": {"bytes=8-14"}})
+		assert.Equal(t, http.StatusPartialContent, res.Code)
+		assert.Equal(t, "1,alice", res.Body.String())
+		assert.Equal(t, "bytes 8-14/16", res.Header().Get("Content-Range"))
+		assert.Equal(t, "7", res.Header().Get("Content-Length"))
+	})
+
+	t.Run("suffix range", func(t *testing.T) {
+		res := get(http.Header{"Range": {"bytes=-6"}})
+		assert.Equal(t, http.StatusPartialContent, res.Code)
+		assert.Equal(t, "alice\n", res.Body.String())
+		assert.Equal(t, "bytes 10-15/16", res.Header().Get("Content-Range"))
+	})
+
+	t.Run("unsatisfiable range", func(t *testing.T) {
+		res := get(http.Header{"Range": {"bytes=100-200"}})
+		assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, res.Code)
+		assert.Equal(t, "bytes */16", res.Header().Get("Content-Range"))
+	})
+
+	t.Run("outdated range", func(t *testing.T) {
+		// the whole content is sent if it has changed since the client's partial download
+		res := get(http.Header{"Range": {"bytes=8-14"}, "If-Range": {"Thu, 15 Oct 2026 08:00:00 GMT"}})
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "id,name\n1,alice\n", res.Body.String())
+	})
+}
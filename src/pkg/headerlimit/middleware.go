@@ -0,0 +1,28 @@
+// Package headerlimit provides a middleware that rejects requests carrying too many headers.
+package headerlimit
+
+import (
+	"fmt"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"net/http"
+)
+
+// Handler returns a middleware that rejects requests with more than maxCount header fields
+// with 431 Request Header Fields Too Large. Repeated headers count once per value.
+// The number of headers is not limited if maxCount is not positive.
+func Handler(maxCount int) routing.Handler {
+	return func(c *routing.Context) error {
+		if maxCount <= 0 {
+			return nil
+		}
+		count := 0
+		for _, values := range c.Request.Header {
+			count += len(values)
+		}
+		if count > maxCount {
+			return routing.NewHTTPError(http.StatusRequestHeaderFieldsTooLarge,
+				fmt.Sprintf("the request must not have more than %v headers", maxCount))
+		}
+		return nil
+	}
+}
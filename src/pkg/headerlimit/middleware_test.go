@@ -0,0 +1,43 @@
+package headerlimit
+
+import (
+	"fmt"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	call := func(handler routing.Handler, header http.Header) error {
+		req, _ := http.NewRequest("GET", "http://127.0.0.1/users", nil)
+		req.Header = header
+		return handler(routing.NewContext(httptest.NewRecorder(), req))
+	}
+
+	// acceptable headers
+	header := http.Header{}
+	header.Set("Accept", "application/json")
+	header.Add("X-Forwarded-For", "10.0.0.1")
+	header.Add("X-Forwarded-For", "10.0.0.2")
+	assert.Nil(t, call(Handler(3), header))
+
+	// repeated values count as separate headers
+	header.Add("X-Forwarded-For", "10.0.0.3")
+	err := call(Handler(3), header)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, http.StatusRequestHeaderFieldsTooLarge, err.(routing.HTTPError).StatusCode())
+	}
+
+	// abusive header set
+	header = http.Header{}
+	for i := 0; i < 1000; i++ {
+		header.Set(fmt.Sprintf("X-Bomb-%d", i), "x")
+	}
+	err = call(Handler(100), header)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, http.StatusRequestHeaderFieldsTooLarge, err.(routing.HTTPError).StatusCode())
+	}
+	assert.Nil(t, call(Handler(0), header))
+}
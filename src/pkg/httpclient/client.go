@@ -0,0 +1,36 @@
+// Package httpclient provides an HTTP client for calling downstream services.
+package httpclient
+
+import (
+	"net/http"
+	"pkg/tracing"
+	"time"
+)
+
+// Transport is an http.RoundTripper that propagates the trace context of the request context
+// to downstream services using the traceparent header.
+type Transport struct {
+	// Base is the RoundTripper sending the requests. http.DefaultTransport is used if nil.
+	Base http.RoundTripper
+}
+
+// RoundTrip sends the request with the traceparent header of the trace context stored in the request context.
+// The request is sent as is if the context has no trace context.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if tc, ok := tracing.FromContext(req.Context()); ok {
+		// a RoundTripper must not modify the request it is given
+		req = req.Clone(req.Context())
+		req.Header.Set(tracing.HeaderName, tc.String())
+	}
+	return base.RoundTrip(req)
+}
+
+// New creates an HTTP client propagating the trace context with the given timeout.
+// There is no timeout if it is zero.
+func New(timeout time.Duration) *http.Client {
+	return &http.Client{Transport: &Transport{}, Timeout: timeout}
+}
@@ -0,0 +1,46 @@
+package httpclient
+
+import (
+	"context"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"pkg/tracing"
+	"testing"
+)
+
+func TestTransport(t *testing.T) {
+	// the downstream service extracts the trace context with the tracing middleware
+	var received tracing.TraceContext
+	var header string
+	router := routing.New()
+	router.Use(tracing.Handler())
+	router.Get("/", func(c *routing.Context) error {
+		header = c.Request.Header.Get(tracing.HeaderName)
+		received, _ = tracing.FromContext(c.Request.Context())
+		return nil
+	})
+	server := httptest.NewServer(router)
+	defer server.Close()
+	client := New(0)
+
+	// the trace context is injected
+	tc := tracing.New()
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	res, err := client.Do(req.WithContext(tracing.WithTraceContext(context.Background(), tc)))
+	if assert.Nil(t, err) {
+		res.Body.Close()
+	}
+	assert.Equal(t, tc.String(), header)
+	assert.Equal(t, tc.TraceID, received.TraceID)
+	assert.Empty(t, req.Header.Get(tracing.HeaderName), "the original request must not be modified")
+
+	// nothing is injected without trace context
+	res, err = client.Do(req)
+	if assert.Nil(t, err) {
+		res.Body.Close()
+	}
+	assert.Empty(t, header)
+	assert.NotEqual(t, tc.TraceID, received.TraceID)
+}
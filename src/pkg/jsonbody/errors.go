@@ -0,0 +1,56 @@
+package jsonbody
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// ErrorMessage returns a message telling the client why a JSON body could not be decoded, such as
+// "invalid JSON at position 42" for a syntax error, or `invalid value for field "name": expected string
+// but got number` for a value of the wrong type. It accepts the errors of encoding/json, which
+// routing.Context.Read returns for JSON bodies. An empty string is returned if err is not a JSON decoding error.
+func ErrorMessage(err error) string {
+	var syntaxError *json.SyntaxError
+	var typeError *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxError):
+		return fmt.Sprintf("invalid JSON at position %v", syntaxError.Offset)
+	case errors.As(err, &typeError):
+		if typeError.Field == "" {
+			return fmt.Sprintf("invalid JSON value: expected %v but got %v", jsonType(typeError.Type), typeError.Value)
+		}
+		return fmt.Sprintf("invalid value for field %q: expected %v but got %v", typeError.Field, jsonType(typeError.Type), typeError.Value)
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return "invalid JSON: unexpected end of input"
+	case errors.Is(err, io.EOF):
+		return "the request body is empty"
+	}
+	return ""
+}
+
+// jsonType returns the name of the JSON type a Go type is decoded from, so that messages do not expose Go types.
+func jsonType(t reflect.Type) string {
+	if t == nil {
+		return "value"
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return jsonType(t.Elem())
+	case reflect.Struct, reflect.Map:
+		return "object"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	}
+	return "value"
+}
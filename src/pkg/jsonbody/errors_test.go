@@ -0,0 +1,36 @@
+package jsonbody
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestErrorMessage(t *testing.T) {
+	decode := func(body string) error {
+		var data struct {
+			Name  string `json:"name"`
+			Album struct {
+				Year int `json:"year"`
+			} `json:"album"`
+		}
+		return json.NewDecoder(strings.NewReader(body)).Decode(&data)
+	}
+
+	// syntax error
+	assert.Equal(t, "invalid JSON at position 9", ErrorMessage(decode(`{"name":}`)))
+	assert.Equal(t, "invalid JSON at position 9", ErrorMessage(fmt.Errorf("reading: %w", decode(`{"name":}`))))
+	// type mismatch
+	assert.Equal(t, `invalid value for field "name": expected string but got number`, ErrorMessage(decode(`{"name":1}`)))
+	assert.Equal(t, `invalid value for field "album.year": expected number but got string`, ErrorMessage(decode(`{"album":{"year":"x"}}`)))
+	assert.Equal(t, "invalid JSON value: expected object but got array", ErrorMessage(decode(`[]`)))
+	// truncated or empty body
+	assert.Equal(t, "invalid JSON: unexpected end of input", ErrorMessage(decode(`{"name":"x"`)))
+	assert.Equal(t, "the request body is empty", ErrorMessage(decode(``)))
+
+	assert.Equal(t, "", ErrorMessage(errors.New("connection reset")))
+	assert.Equal(t, "", ErrorMessage(nil))
+}
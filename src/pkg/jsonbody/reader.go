@@ -0,0 +1,96 @@
+// Package jsonbody provides a guarded way of reading JSON request bodies.
+package jsonbody
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+var (
+	// DefaultMaxBytes specifies the default maximum size of a request body
+	DefaultMaxBytes int64 = 1 << 20
+	// DefaultMaxDepth specifies the default maximum nesting depth of JSON objects and arrays
+	DefaultMaxDepth = 32
+)
+
+// Options specifies how a JSON request body should be read.
+type Options struct {
+	// MaxBytes is the maximum size of the body. Defaults to DefaultMaxBytes if not positive.
+	MaxBytes int64
+	// MaxDepth is the maximum nesting depth of the JSON data. Defaults to DefaultMaxDepth if not positive.
+	MaxDepth int
+	// Strict specifies whether fields not found in the target struct should be rejected.
+	Strict bool
+}
+
+// Read populates data with the JSON body of the current request, replacing routing.Context.Read.
+// It returns a 413 HTTP error if the body is larger than allowed, and a 400 HTTP error if the body
+// is nested too deeply, is not valid JSON, or contains unknown fields in strict mode.
+// Decoding errors are described by ErrorMessage.
+func Read(c *routing.Context, data interface{}, opts Options) error {
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultMaxBytes
+	}
+	if opts.MaxDepth <= 0 {
+		opts.MaxDepth = DefaultMaxDepth
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(c.Request.Body, opts.MaxBytes+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(body)) > opts.MaxBytes {
+		return routing.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("The request body must not exceed %v bytes.", opts.MaxBytes))
+	}
+	if depth(body) > opts.MaxDepth {
+		return routing.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("The request body must not be nested more than %v levels.", opts.MaxDepth))
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	if opts.Strict {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(data); err != nil {
+		if message := ErrorMessage(err); message != "" {
+			return routing.NewHTTPError(http.StatusBadRequest, message)
+		}
+		return routing.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return nil
+}
+
+// depth returns the maximum nesting depth of objects and arrays in the given JSON data.
+// Brackets appearing in strings are ignored.
+func depth(data []byte) int {
+	current, max := 0, 0
+	inString, escaped := false, false
+	for _, b := range data {
+		if inString {
+			if escaped {
+				escaped = false
+			} else if b == '\\' {
+				escaped = true
+			} else if b == '"' {
+				inString = false
+			}
+			continue
+		}
+		switch b {
+		case '"':
+			inString = true
+		case '{', '[':
+			current++
+			if current > max {
+				max = current
+			}
+		case '}', ']':
+			current--
+		}
+	}
+	return max
+}
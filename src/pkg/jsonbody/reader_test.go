@@ -0,0 +1,51 @@
+package jsonbody
+
+import (
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type user struct {
+	Name string `json:"name"`
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		opts       Options
+		wantStatus int
+	}{
+		{"success", `{"name":"test"}`, Options{}, 0},
+		{"unknown field", `{"name":"test","age":1}`, Options{}, 0},
+		{"unknown field in strict mode", `{"name":"test","age":1}`, Options{Strict: true}, http.StatusBadRequest},
+		{"too deep", `{"name":"test","x":` + strings.Repeat("[", 10) + strings.Repeat("]", 10) + `}`, Options{MaxDepth: 5}, http.StatusBadRequest},
+		{"brackets in string", `{"name":"test[[[[[[[[[["}`, Options{MaxDepth: 5}, 0},
+		{"too large", `{"name":"` + strings.Repeat("a", 100) + `"}`, Options{MaxBytes: 50}, http.StatusRequestEntityTooLarge},
+		{"bad json", `"name":"test"}`, Options{}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "http://127.0.0.1/users", strings.NewReader(tt.body))
+			c := routing.NewContext(httptest.NewRecorder(), req)
+			var u user
+			err := Read(c, &u, tt.opts)
+			if tt.wantStatus == 0 {
+				assert.Nil(t, err)
+				assert.Equal(t, "test", u.Name[:4])
+			} else if assert.NotNil(t, err) {
+				assert.Equal(t, tt.wantStatus, err.(routing.HTTPError).StatusCode())
+			}
+		})
+	}
+}
+
+func Test_depth(t *testing.T) {
+	assert.Equal(t, 0, depth([]byte(`"abc"`)))
+	assert.Equal(t, 1, depth([]byte(`{"a":1}`)))
+	assert.Equal(t, 3, depth([]byte(`{"a":[{"b":"\"}}"}]}`)))
+}
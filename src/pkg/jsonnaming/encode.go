@@ -0,0 +1,282 @@
+package jsonnaming
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	marshalerType     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// Marshal returns the JSON encoding of v like json.Marshal does, without escaping HTML characters
+// like the JSON data writer of ozzo-routing, except that the struct fields
+// without a name in their json tag are named following the strategy. The names given by json tags
+// are kept, as are the map keys. The values implementing json.Marshaler or encoding.TextMarshaler
+// encode themselves.
+func (s Strategy) Marshal(v interface{}) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if s == GoNames {
+		err := marshal(buf, reflect.ValueOf(v))
+		return buf.Bytes(), err
+	}
+	if err := s.encode(buf, reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s Strategy) encode(buf *bytes.Buffer, v reflect.Value) error {
+	if !v.IsValid() {
+		buf.WriteString("null")
+		return nil
+	}
+	t := v.Type()
+	if t.Implements(marshalerType) || t.Implements(textMarshalerType) ||
+		v.CanAddr() && (reflect.PtrTo(t).Implements(marshalerType) || reflect.PtrTo(t).Implements(textMarshalerType)) {
+		return marshal(buf, v)
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		return s.encode(buf, v.Elem())
+	case reflect.Struct:
+		buf.WriteByte('{')
+		first := true
+		for _, f := range s.fields(t) {
+			fv, ok := fieldByIndex(v, f.index)
+			if !ok || f.omitEmpty && isEmpty(fv) {
+				continue
+			}
+			if !first {
+				buf.WriteByte(',')
+			}
+			first = false
+			_ = marshal(buf, reflect.ValueOf(f.name))
+			buf.WriteByte(':')
+			if f.quoted && isScalar(fv) {
+				b := &bytes.Buffer{}
+				if err := marshal(b, fv); err != nil {
+					return err
+				}
+				_ = marshal(buf, reflect.ValueOf(b.String()))
+				continue
+			}
+			if err := s.encode(buf, fv); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+	case reflect.Map:
+		if v.IsNil() || t.Key().Kind() != reflect.String {
+			return marshal(buf, v)
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		buf.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			_ = marshal(buf, reflect.ValueOf(key.String()))
+			buf.WriteByte(':')
+			if err := s.encode(buf, v.MapIndex(key)); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && (v.IsNil() || t.Elem().Kind() == reflect.Uint8) {
+			return marshal(buf, v)
+		}
+		buf.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := s.encode(buf, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	}
+	return marshal(buf, v)
+}
+
+// marshal encodes a value with encoding/json.
+func marshal(buf *bytes.Buffer, v reflect.Value) error {
+	var value interface{}
+	if v.IsValid() {
+		value = v.Interface()
+	}
+	b := &bytes.Buffer{}
+	enc := json.NewEncoder(b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(value); err != nil {
+		return err
+	}
+	buf.Write(bytes.TrimSuffix(b.Bytes(), []byte("\n")))
+	return nil
+}
+
+// field describes how a struct field is encoded.
+type field struct {
+	name      string
+	index     []int
+	omitEmpty bool
+	// quoted is set by the ",string" option, which encodes scalar values within JSON strings.
+	quoted bool
+}
+
+type fieldsKey struct {
+	strategy Strategy
+	t        reflect.Type
+}
+
+// fieldCache caches the fields of the struct types by strategy.
+var fieldCache sync.Map
+
+// fields returns the encoded fields of a struct type, in declaration order. The fields of exported
+// embedded structs without a json name are promoted, unless a shallower field has the same name.
+func (s Strategy) fields(t reflect.Type) []field {
+	key := fieldsKey{s, t}
+	if cached, ok := fieldCache.Load(key); ok {
+		return cached.([]field)
+	}
+	var fields []field
+	seen := map[string]bool{}
+	// the fields are collected breadth-first, so that shallower fields take precedence
+	type level struct {
+		t     reflect.Type
+		index []int
+	}
+	current := []level{{t, nil}}
+	for len(current) > 0 {
+		var next []level
+		var found []field
+		for _, l := range current {
+			for i := 0; i < l.t.NumField(); i++ {
+				sf := l.t.Field(i)
+				tag := sf.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				parts := strings.Split(tag, ",")
+				index := append(append([]int{}, l.index...), i)
+				if sf.Anonymous && parts[0] == "" {
+					ft := sf.Type
+					if ft.Kind() == reflect.Ptr {
+						ft = ft.Elem()
+					}
+					if ft.Kind() == reflect.Struct {
+						if sf.PkgPath == "" {
+							next = append(next, level{ft, index})
+						}
+						continue
+					}
+				}
+				if sf.PkgPath != "" {
+					continue
+				}
+				f := field{name: parts[0], index: index}
+				if f.name == "" {
+					f.name = s.Name(sf.Name)
+				}
+				for _, option := range parts[1:] {
+					f.omitEmpty = f.omitEmpty || option == "omitempty"
+					f.quoted = f.quoted || option == "string"
+				}
+				found = append(found, f)
+			}
+		}
+		for _, f := range found {
+			if !seen[f.name] {
+				seen[f.name] = true
+				fields = append(fields, f)
+			}
+		}
+		current = next
+	}
+	fieldCache.Store(key, fields)
+	return fields
+}
+
+// fieldByIndex returns the field with the given index, or false if it is in a nil embedded struct pointer.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// isScalar reports whether a value is a boolean, a number or a string.
+func isScalar(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// isEmpty reports whether a value is empty according to the omitempty option.
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// DataWriter writes the response data as JSON following a naming strategy.
+// It replaces the JSON data writer of the content negotiation, see Handler.
+type DataWriter struct {
+	Strategy Strategy
+}
+
+// SetHeader sets the Content-Type response header.
+func (w *DataWriter) SetHeader(res http.ResponseWriter) {
+	res.Header().Set("Content-Type", "application/json")
+}
+
+// Write writes the data in JSON format to the response, followed by a newline like the default JSON data writer.
+func (w *DataWriter) Write(res http.ResponseWriter, data interface{}) error {
+	b, err := w.Strategy.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = res.Write(append(b, '\n'))
+	return err
+}
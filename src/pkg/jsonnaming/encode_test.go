@@ -0,0 +1,85 @@
+package jsonnaming
+
+import (
+	"github.com/stretchr/testify/assert"
+	"pkg/jsontime"
+	"testing"
+	"time"
+)
+
+type Audit struct {
+	CreatedBy string
+	UpdatedAt jsontime.Time
+}
+
+type album struct {
+	ID        int
+	AlbumName string
+	// explicit names are kept
+	ArtistID int    `json:"artist"`
+	Notes    string `json:",omitempty"`
+	Secret   string `json:"-"`
+	Count    int    `json:",string"`
+	Tags     map[string]int
+	Tracks   []track
+	Cover    *track
+	internal string
+	Audit
+}
+
+type track struct {
+	TrackNo int
+	Title   string
+}
+
+func TestStrategy_Marshal(t *testing.T) {
+	a := album{
+		ID:        1,
+		AlbumName: "Rock & Roll <live>",
+		ArtistID:  2,
+		Secret:    "x",
+		Count:     3,
+		Tags:      map[string]int{"SomeKey": 1},
+		Tracks:    []track{{1, "Intro"}},
+		internal:  "y",
+		Audit:     Audit{CreatedBy: "alice", UpdatedAt: jsontime.New(time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC))},
+	}
+
+	b, err := SnakeCase.Marshal(a)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"id":1,"album_name":"Rock & Roll <live>","artist":2,"count":"3","tags":{"SomeKey":1},`+
+		`"tracks":[{"track_no":1,"title":"Intro"}],"cover":null,"created_by":"alice","updated_at":"2026-10-16T08:00:00.000Z"}`, string(b))
+
+	b, err = CamelCase.Marshal(a)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"id":1,"albumName":"Rock & Roll <live>","artist":2,"count":"3","tags":{"SomeKey":1},`+
+		`"tracks":[{"trackNo":1,"title":"Intro"}],"cover":null,"createdBy":"alice","updatedAt":"2026-10-16T08:00:00.000Z"}`, string(b))
+
+	// the Go names are kept by default
+	b, err = GoNames.Marshal(track{2, "Outro"})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"TrackNo":2,"Title":"Outro"}`, string(b))
+}
+
+func TestStrategy_Marshal_values(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{nil, `null`},
+		{"a<b", `"a<b"`},
+		{[]track(nil), `null`},
+		{[]byte("hi"), `"aGk="`},
+		{&track{3, "x"}, `{"track_no":3,"title":"x"}`},
+		{[]interface{}{1, track{4, "y"}}, `[1,{"track_no":4,"title":"y"}]`},
+		{map[int]string{1: "a"}, `{"1":"a"}`},
+	}
+	for _, tt := range tests {
+		b, err := SnakeCase.Marshal(tt.value)
+		assert.Nil(t, err)
+		assert.Equal(t, tt.want, string(b))
+	}
+
+	_, err := SnakeCase.Marshal(map[string]interface{}{"f": func() {}})
+	assert.NotNil(t, err)
+}
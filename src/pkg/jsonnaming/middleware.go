@@ -0,0 +1,17 @@
+package jsonnaming
+
+import (
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+)
+
+// Handler returns a middleware that writes the JSON response data following the naming strategy.
+// It must follow the content negotiation middleware, whose JSON data writer it replaces.
+func Handler(s Strategy) routing.Handler {
+	writer := &DataWriter{s}
+	return func(c *routing.Context) error {
+		if c.Response.Header().Get("Content-Type") == "application/json" {
+			c.SetDataWriter(writer)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,24 @@
+package jsonnaming
+
+import (
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/go-ozzo/ozzo-routing/v2/content"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	router := routing.New()
+	router.Use(content.TypeNegotiator(content.JSON), Handler(CamelCase))
+	router.Get("/tracks/1", func(c *routing.Context) error {
+		return c.Write(track{1, "Intro"})
+	})
+	req, _ := http.NewRequest("GET", "/tracks/1", nil)
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "application/json", res.Header().Get("Content-Type"))
+	assert.Equal(t, "{\"trackNo\":1,\"title\":\"Intro\"}\n", res.Body.String())
+}
@@ -0,0 +1,96 @@
+// Package jsonnaming encodes JSON responses following a naming strategy, such as snake_case or camelCase,
+// so that struct fields without a json tag get names of a consistent style.
+package jsonnaming
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Strategy is a convention for naming the JSON fields of structs.
+type Strategy string
+
+const (
+	// GoNames keeps the Go field names, like encoding/json does.
+	GoNames Strategy = ""
+	// SnakeCase names the fields like "user_id".
+	SnakeCase Strategy = "snake_case"
+	// CamelCase names the fields like "userId".
+	CamelCase Strategy = "camelCase"
+)
+
+// Strategies lists the supported strategies.
+var Strategies = []Strategy{GoNames, SnakeCase, CamelCase}
+
+// Name returns the JSON name of a Go field following the strategy.
+// Acronyms are treated as words, e.g. "HTTPServerID" is "http_server_id" in snake_case and "httpServerId" in camelCase.
+func (s Strategy) Name(field string) string {
+	switch s {
+	case SnakeCase:
+		words := split(field)
+		name := ""
+		for i, word := range words {
+			if i > 0 {
+				name += "_"
+			}
+			name += lower(word)
+		}
+		return name
+	case CamelCase:
+		words := split(field)
+		name := ""
+		for i, word := range words {
+			if i == 0 {
+				name += lower(word)
+			} else {
+				name += title(word)
+			}
+		}
+		return name
+	}
+	return field
+}
+
+// split splits a Go identifier into words. A word starts with an uppercase letter following a lowercase
+// letter or a digit, or with the last uppercase letter of an acronym followed by a lowercase letter.
+// Underscores separate words too.
+func split(name string) []string {
+	runes := []rune(name)
+	var words []string
+	start := 0
+	for i := 0; i < len(runes); i++ {
+		if runes[i] == '_' {
+			if i > start {
+				words = append(words, string(runes[start:i]))
+			}
+			start = i + 1
+			continue
+		}
+		if i == start || !unicode.IsUpper(runes[i]) {
+			continue
+		}
+		prev := runes[i-1]
+		if unicode.IsLower(prev) || unicode.IsDigit(prev) ||
+			unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	if start < len(runes) {
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
+
+func lower(word string) string {
+	runes := []rune(word)
+	for i, r := range runes {
+		runes[i] = unicode.ToLower(r)
+	}
+	return string(runes)
+}
+
+func title(word string) string {
+	r, size := utf8.DecodeRuneInString(word)
+	return string(unicode.ToUpper(r)) + lower(word[size:])
+}
@@ -0,0 +1,26 @@
+package jsonnaming
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestStrategy_Name(t *testing.T) {
+	tests := []struct {
+		field, snake, camel string
+	}{
+		{"ID", "id", "id"},
+		{"Name", "name", "name"},
+		{"CreatedAt", "created_at", "createdAt"},
+		{"UserID", "user_id", "userId"},
+		{"HTTPServerID", "http_server_id", "httpServerId"},
+		{"OAuth2Token", "o_auth2_token", "oAuth2Token"},
+		{"Page2Size", "page2_size", "page2Size"},
+		{"Already_snake", "already_snake", "alreadySnake"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.snake, SnakeCase.Name(tt.field), tt.field)
+		assert.Equal(t, tt.camel, CamelCase.Name(tt.field), tt.field)
+		assert.Equal(t, tt.field, GoNames.Name(tt.field), tt.field)
+	}
+}
@@ -0,0 +1,68 @@
+package jsonschema
+
+import (
+	"bytes"
+	"fmt"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"pkg/jsonbody"
+	"strings"
+)
+
+// Options represents the options of the JSON Schema middleware.
+type Options struct {
+	// MaxBytes is the maximum size of the request body. Defaults to jsonbody.DefaultMaxBytes if not positive.
+	MaxBytes int64
+}
+
+// Handler returns a middleware validating the JSON body of the requests against the given schema,
+// before the handlers of the route decode it. It is registered with the route it validates, e.g.
+//
+//	r.Post("/albums", jsonschema.Handler(albumSchema), res.create)
+//
+// The violations of the schema are returned as validation.Errors keyed by the path of the offending values,
+// which the error middleware reports as a detailed 400 response. A body that is not valid JSON is rejected
+// with 400, a body larger than allowed with 413, and a body of another content type with 415, as it would
+// be decoded without being validated. The body is restored for the handlers.
+func Handler(schema *Schema, options ...Options) routing.Handler {
+	var opt Options
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	if opt.MaxBytes <= 0 {
+		opt.MaxBytes = jsonbody.DefaultMaxBytes
+	}
+	return func(c *routing.Context) error {
+		if contentType := c.Request.Header.Get("Content-Type"); contentType != "" && !isJSON(contentType) {
+			return routing.NewHTTPError(http.StatusUnsupportedMediaType, "The request body must be JSON.")
+		}
+		body, err := ioutil.ReadAll(io.LimitReader(c.Request.Body, opt.MaxBytes+1))
+		if err != nil {
+			return err
+		}
+		if int64(len(body)) > opt.MaxBytes {
+			return routing.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("The request body must not exceed %v bytes.", opt.MaxBytes))
+		}
+		errs, err := schema.Validate(body)
+		if err != nil {
+			if message := jsonbody.ErrorMessage(err); message != "" {
+				return routing.NewHTTPError(http.StatusBadRequest, message)
+			}
+			return routing.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		if errs != nil {
+			return errs
+		}
+		c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+		return nil
+	}
+}
+
+// isJSON reports whether the content type is application/json or a JSON-based type such as application/merge-patch+json.
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
@@ -0,0 +1,57 @@
+package jsonschema
+
+import (
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	handler := Handler(MustCompile(`{"type":"object","required":["name"],"properties":{"name":{"type":"string"}}}`), Options{MaxBytes: 64})
+	call := func(body, contentType string) (*routing.Context, error) {
+		req, _ := http.NewRequest("POST", "/albums", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		c := routing.NewContext(httptest.NewRecorder(), req)
+		return c, handler(c)
+	}
+	status := func(err error) int {
+		if e, ok := err.(routing.HTTPError); ok {
+			return e.StatusCode()
+		}
+		return 0
+	}
+
+	// a valid body is left for the handler to decode
+	c, err := call(`{"name":"Hits"}`, "application/json; charset=utf-8")
+	assert.Nil(t, err)
+	var album struct {
+		Name string `json:"name"`
+	}
+	assert.Nil(t, c.Read(&album))
+	assert.Equal(t, "Hits", album.Name)
+
+	// the violations are reported for each field
+	_, err = call(`{"name":1}`, "application/json")
+	if errs, ok := err.(validation.Errors); assert.True(t, ok) {
+		assert.Equal(t, "must be a string", errs["name"].Error())
+	}
+
+	_, err = call(`{"name":`, "application/json")
+	assert.Equal(t, http.StatusBadRequest, status(err))
+	assert.Equal(t, "invalid JSON: unexpected end of input", err.Error())
+	_, err = call(`{"name":"`+strings.Repeat("x", 64)+`"}`, "application/json")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, status(err))
+	_, err = call(`name=Hits`, "application/x-www-form-urlencoded")
+	assert.Equal(t, http.StatusUnsupportedMediaType, status(err))
+
+	// the body of a request without a content type is validated as JSON
+	c, err = call(`{"name":"Hits"}`, "")
+	assert.Nil(t, err)
+	body, _ := ioutil.ReadAll(c.Request.Body)
+	assert.Equal(t, `{"name":"Hits"}`, string(body))
+}
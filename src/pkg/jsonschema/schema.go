@@ -0,0 +1,334 @@
+// Package jsonschema validates JSON request bodies against JSON Schemas registered with the routes.
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// RootField is the field the violations of the whole document, such as a body that is not an object, are reported for.
+const RootField = "body"
+
+// Schema is a compiled JSON Schema. It supports the keywords of the validation vocabulary needed by request
+// bodies: type, enum, properties, required, additionalProperties (true or false), items, minItems, maxItems,
+// minLength, maxLength, pattern, minimum and maximum. The annotations ($schema, $id, title, description,
+// default, examples) are ignored. Other keywords, such as $ref, are rejected by Compile, so that a schema
+// is never silently validated less strictly than it reads.
+type Schema struct {
+	Type                 types              `json:"type"`
+	Enum                 []interface{}      `json:"enum"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Pattern              string             `json:"pattern"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+
+	pattern *regexp.Regexp
+}
+
+// types is the value of the type keyword, either a type name or an array of them.
+type types []string
+
+func (t *types) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*t = types{name}
+		return nil
+	}
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return fmt.Errorf("type must be a string or an array of strings")
+	}
+	*t = names
+	return nil
+}
+
+// keywords lists the keywords Compile accepts.
+var keywords = map[string]bool{
+	"type": true, "enum": true, "properties": true, "required": true, "additionalProperties": true, "items": true,
+	"minItems": true, "maxItems": true, "minLength": true, "maxLength": true, "pattern": true, "minimum": true, "maximum": true,
+	"$schema": true, "$id": true, "title": true, "description": true, "default": true, "examples": true,
+}
+
+// typeNames lists the names of the JSON Schema types.
+var typeNames = map[string]bool{
+	"object": true, "array": true, "string": true, "number": true, "integer": true, "boolean": true, "null": true,
+}
+
+// Compile parses a JSON Schema. It returns an error if the schema is not valid JSON or uses unsupported keywords.
+func Compile(data []byte) (*Schema, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("jsonschema: %v", err)
+	}
+	if err := check(raw, ""); err != nil {
+		return nil, fmt.Errorf("jsonschema: %v", err)
+	}
+	var s Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("jsonschema: %v", err)
+	}
+	if err := s.compile(""); err != nil {
+		return nil, fmt.Errorf("jsonschema: %v", err)
+	}
+	return &s, nil
+}
+
+// MustCompile is like Compile but panics if the schema cannot be compiled. It is meant for the schemas
+// declared as package variables next to the handlers.
+func MustCompile(data string) *Schema {
+	s, err := Compile([]byte(data))
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// check rejects the unsupported keywords of a schema and of its subschemas.
+func check(raw map[string]json.RawMessage, path string) error {
+	for keyword, value := range raw {
+		if !keywords[keyword] {
+			return fmt.Errorf("unsupported keyword %q at %q", keyword, "#"+path)
+		}
+		switch keyword {
+		case "items":
+			var sub map[string]json.RawMessage
+			if err := json.Unmarshal(value, &sub); err != nil {
+				return fmt.Errorf("items at %q must be a schema", "#"+path)
+			}
+			if err := check(sub, path+"/items"); err != nil {
+				return err
+			}
+		case "properties":
+			var subs map[string]map[string]json.RawMessage
+			if err := json.Unmarshal(value, &subs); err != nil {
+				return fmt.Errorf("properties at %q must map names to schemas", "#"+path)
+			}
+			for name, sub := range subs {
+				if err := check(sub, path+"/properties/"+name); err != nil {
+					return err
+				}
+			}
+		case "additionalProperties":
+			if string(value) != "true" && string(value) != "false" {
+				return fmt.Errorf("additionalProperties at %q must be true or false", "#"+path)
+			}
+		}
+	}
+	return nil
+}
+
+// compile checks the values of the keywords and compiles the patterns.
+func (s *Schema) compile(path string) error {
+	for _, t := range s.Type {
+		if !typeNames[t] {
+			return fmt.Errorf("unknown type %q at %q", t, "#"+path)
+		}
+	}
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern at %q: %v", "#"+path, err)
+		}
+		s.pattern = re
+	}
+	for name, property := range s.Properties {
+		if err := property.compile(path + "/properties/" + name); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.compile(path + "/items")
+	}
+	return nil
+}
+
+// Validate validates a JSON document against the schema. It returns the violations keyed by the path
+// of the offending value, such as "tracks[0].title", or RootField for the document itself,
+// or nil if the document is valid. Only the first violation of each value is reported.
+func (s *Schema) Validate(data []byte) (validation.Errors, error) {
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	errs := validation.Errors{}
+	s.validate(value, "", errs)
+	if len(errs) == 0 {
+		return nil, nil
+	}
+	return errs, nil
+}
+
+// validate records the violations of a value and of the values it contains.
+func (s *Schema) validate(value interface{}, path string, errs validation.Errors) {
+	field := path
+	if field == "" {
+		field = RootField
+	}
+	if len(s.Type) > 0 && !s.hasType(value) {
+		errs[field] = validation.NewError("validation_schema_type", "must be "+article(s.Type))
+		return
+	}
+	if len(s.Enum) > 0 && !s.inEnum(value) {
+		var values []string
+		for _, v := range s.Enum {
+			data, _ := json.Marshal(v)
+			values = append(values, string(data))
+		}
+		errs[field] = validation.NewError("validation_schema_enum", "must be one of "+strings.Join(values, ", "))
+		return
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		s.validateObject(v, path, errs)
+	case []interface{}:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			errs[field] = validation.NewError("validation_schema_min_items", fmt.Sprintf("must contain at least %v items", *s.MinItems))
+			return
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			errs[field] = validation.NewError("validation_schema_max_items", fmt.Sprintf("must contain at most %v items", *s.MaxItems))
+			return
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(item, fmt.Sprintf("%v[%v]", path, i), errs)
+			}
+		}
+	case string:
+		length := utf8.RuneCountInString(v)
+		if s.MinLength != nil && length < *s.MinLength {
+			errs[field] = validation.NewError("validation_schema_min_length", fmt.Sprintf("must be at least %v characters long", *s.MinLength))
+		} else if s.MaxLength != nil && length > *s.MaxLength {
+			errs[field] = validation.NewError("validation_schema_max_length", fmt.Sprintf("must be at most %v characters long", *s.MaxLength))
+		} else if s.pattern != nil && !s.pattern.MatchString(v) {
+			errs[field] = validation.NewError("validation_schema_pattern", "must match the pattern "+s.Pattern)
+		}
+	case json.Number:
+		n, _ := v.Float64()
+		if s.Minimum != nil && n < *s.Minimum {
+			errs[field] = validation.NewError("validation_schema_minimum", "must be no less than "+format(*s.Minimum))
+		} else if s.Maximum != nil && n > *s.Maximum {
+			errs[field] = validation.NewError("validation_schema_maximum", "must be no greater than "+format(*s.Maximum))
+		}
+	}
+}
+
+// validateObject validates the properties of an object, in the order of their names so that the reported
+// violations do not depend on the order of the map.
+func (s *Schema) validateObject(object map[string]interface{}, path string, errs validation.Errors) {
+	prefix := path
+	if prefix != "" {
+		prefix += "."
+	}
+	for _, name := range s.Required {
+		if _, ok := object[name]; !ok {
+			errs[prefix+name] = validation.NewError("validation_schema_required", "is required")
+		}
+	}
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if property, ok := s.Properties[name]; ok {
+			property.validate(object[name], prefix+name, errs)
+		} else if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+			errs[prefix+name] = validation.NewError("validation_schema_additional_property", "is not allowed")
+		}
+	}
+}
+
+// hasType reports whether the value is of one of the types of the schema.
+func (s *Schema) hasType(value interface{}) bool {
+	for _, t := range s.Type {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if t == "object" {
+				return true
+			}
+		case []interface{}:
+			if t == "array" {
+				return true
+			}
+		case string:
+			if t == "string" {
+				return true
+			}
+		case bool:
+			if t == "boolean" {
+				return true
+			}
+		case nil:
+			if t == "null" {
+				return true
+			}
+		case json.Number:
+			if t == "number" {
+				return true
+			}
+			if f, err := v.Float64(); t == "integer" && err == nil && f == math.Trunc(f) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// inEnum reports whether the value equals one of the values of the enum keyword.
+func (s *Schema) inEnum(value interface{}) bool {
+	data, _ := json.Marshal(value)
+	for _, v := range s.Enum {
+		if allowed, _ := json.Marshal(v); string(allowed) == string(data) {
+			return true
+		}
+	}
+	// numbers are compared by value, as 1 and 1.0 are the same number
+	if n, ok := value.(json.Number); ok {
+		f, _ := n.Float64()
+		for _, v := range s.Enum {
+			if allowed, ok := v.(float64); ok && allowed == f {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// article returns the description of the allowed types, such as "a string" or "an integer or null".
+func article(types []string) string {
+	var names []string
+	for _, t := range types {
+		switch t {
+		case "null":
+			names = append(names, "null")
+		case "object", "array", "integer":
+			names = append(names, "an "+t)
+		default:
+			names = append(names, "a "+t)
+		}
+	}
+	return strings.Join(names, " or ")
+}
+
+// format formats a number of a schema without a useless fraction.
+func format(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
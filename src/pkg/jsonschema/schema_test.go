@@ -0,0 +1,89 @@
+package jsonschema
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+var albumSchema = MustCompile(`{
+	"$schema": "http://json-schema.org/draft-07/schema#",
+	"title": "album",
+	"type": "object",
+	"required": ["name", "year"],
+	"additionalProperties": false,
+	"properties": {
+		"name": {"type": "string", "minLength": 1, "maxLength": 10},
+		"year": {"type": "integer", "minimum": 1900, "maximum": 2100},
+		"format": {"enum": ["cd", "vinyl"]},
+		"code": {"type": ["string", "null"], "pattern": "^[A-Z]{3}-\\d+$"},
+		"tracks": {
+			"type": "array",
+			"minItems": 1,
+			"items": {"type": "object", "required": ["title"], "properties": {"title": {"type": "string"}}}
+		}
+	}
+}`)
+
+func TestSchema_Validate(t *testing.T) {
+	errs, err := albumSchema.Validate([]byte(`{"name":"Hits","year":2001,"format":"cd","code":"ABC-12","tracks":[{"title":"A"}]}`))
+	assert.Nil(t, err)
+	assert.Nil(t, errs)
+	errs, _ = albumSchema.Validate([]byte(`{"name":"Hits","year":2001.0,"code":null}`))
+	assert.Nil(t, errs)
+
+	tests := []struct {
+		name   string
+		body   string
+		errors map[string]string
+	}{
+		{"not an object", `[]`, map[string]string{"body": "must be an object"}},
+		{"required", `{"name":"Hits"}`, map[string]string{"year": "is required"}},
+		{"type", `{"name":1,"year":"2001"}`, map[string]string{"name": "must be a string", "year": "must be an integer"}},
+		{"integer", `{"name":"Hits","year":2001.5}`, map[string]string{"year": "must be an integer"}},
+		{"length", `{"name":"","year":2001}`, map[string]string{"name": "must be at least 1 characters long"}},
+		{"length in characters", `{"name":"最新专辑最新专辑最新专","year":2001}`, map[string]string{"name": "must be at most 10 characters long"}},
+		{"range", `{"name":"Hits","year":1800}`, map[string]string{"year": "must be no less than 1900"}},
+		{"enum", `{"name":"Hits","year":2001,"format":"tape"}`, map[string]string{"format": `must be one of "cd", "vinyl"`}},
+		{"pattern", `{"name":"Hits","year":2001,"code":"abc"}`, map[string]string{"code": `must match the pattern ^[A-Z]{3}-\d+$`}},
+		{"nullable", `{"name":"Hits","year":2001,"code":1}`, map[string]string{"code": "must be a string or null"}},
+		{"additional property", `{"name":"Hits","year":2001,"price":10}`, map[string]string{"price": "is not allowed"}},
+		{"min items", `{"name":"Hits","year":2001,"tracks":[]}`, map[string]string{"tracks": "must contain at least 1 items"}},
+		{"items", `{"name":"Hits","year":2001,"tracks":[{"title":"A"},{"title":2},{}]}`,
+			map[string]string{"tracks[1].title": "must be a string", "tracks[2].title": "is required"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			errs, err := albumSchema.Validate([]byte(tc.body))
+			assert.Nil(t, err)
+			messages := map[string]string{}
+			for field, e := range errs {
+				messages[field] = e.Error()
+			}
+			assert.Equal(t, tc.errors, messages)
+		})
+	}
+
+	_, err = albumSchema.Validate([]byte(`{"name":`))
+	assert.NotNil(t, err)
+}
+
+func TestCompile(t *testing.T) {
+	_, err := Compile([]byte(`{"type":"object","properties":{"a":{"type":"string"}}}`))
+	assert.Nil(t, err)
+
+	invalid := []string{
+		`[]`,
+		`{"type":"text"}`,
+		`{"type":1}`,
+		`{"$ref":"#/definitions/album"}`,
+		`{"properties":{"a":{"oneOf":[]}}}`,
+		`{"items":{"format":"email"}}`,
+		`{"additionalProperties":{"type":"string"}}`,
+		`{"pattern":"("}`,
+	}
+	for _, schema := range invalid {
+		_, err := Compile([]byte(schema))
+		assert.NotNil(t, err, schema)
+	}
+	assert.Panics(t, func() { MustCompile(`{"$ref":"x"}`) })
+}
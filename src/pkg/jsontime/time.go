@@ -0,0 +1,124 @@
+// Package jsontime provides a time type with a consistent JSON representation for API responses.
+package jsontime
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Layout is the format of a Time in JSON: RFC 3339 in UTC with millisecond precision.
+const Layout = "2006-01-02T15:04:05.000Z07:00"
+
+// inputLayouts are the formats accepted when parsing a time. Times without a time zone are in UTC.
+var inputLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// Time is a time that is serialized in JSON as RFC 3339 in UTC with millisecond precision,
+// e.g. "2026-10-16T09:30:00.123Z", or as null if it is zero. It parses the common formats of
+// dates and times on input (see Parse). Use it for the timestamps of the response structs so that
+// clients get the same format everywhere. It can also be stored in and read from the database.
+type Time struct {
+	time.Time
+}
+
+// New returns the given time as a Time.
+func New(t time.Time) Time {
+	return Time{t}
+}
+
+// Now returns the current time as a Time.
+func Now() Time {
+	return Time{time.Now()}
+}
+
+// Parse parses a time in RFC 3339, with or without fractional seconds, with a space instead of the "T",
+// without a time zone (UTC is assumed), or a date alone such as "2026-10-16", which is midnight UTC.
+func Parse(s string) (Time, error) {
+	for _, layout := range inputLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+			return Time{t}, nil
+		}
+	}
+	return Time{}, fmt.Errorf("invalid time %q", s)
+}
+
+// String returns the time in the JSON format.
+func (t Time) String() string {
+	return t.UTC().Format(Layout)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (t Time) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+	return []byte(`"` + t.String() + `"`), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Besides the formats accepted by Parse,
+// it accepts null, for the zero time, and Unix times in seconds, such as 1792143000.
+func (t *Time) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		*t = Time{}
+		return nil
+	}
+	if len(data) > 0 && data[0] != '"' {
+		seconds, err := strconv.ParseFloat(string(data), 64)
+		if err != nil {
+			return fmt.Errorf("invalid time %s", data)
+		}
+		*t = Time{time.Unix(0, int64(seconds*float64(time.Second))).UTC()}
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
+
+// Value implements driver.Valuer, so that the time is stored like a time.Time.
+func (t Time) Value() (driver.Value, error) {
+	return t.Time, nil
+}
+
+// Scan implements sql.Scanner. It reads times returned as time.Time, and as text by drivers
+// that don't parse them, such as MySQL without parseTime.
+func (t *Time) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*t = Time{}
+	case time.Time:
+		*t = Time{v}
+	case []byte:
+		return t.scanString(string(v))
+	case string:
+		return t.scanString(v)
+	default:
+		return fmt.Errorf("cannot scan %T into a time", src)
+	}
+	return nil
+}
+
+func (t *Time) scanString(s string) error {
+	parsed, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
@@ -0,0 +1,80 @@
+package jsontime
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestTime_MarshalJSON(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*3600)
+	b, err := json.Marshal(struct {
+		CreatedAt Time  `json:"created_at"`
+		DeletedAt Time  `json:"deleted_at"`
+		SeenAt    *Time `json:"seen_at,omitempty"`
+	}{CreatedAt: New(time.Date(2026, 10, 16, 18, 30, 0, 123456789, tokyo))})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"created_at":"2026-10-16T09:30:00.123Z","deleted_at":null}`, string(b))
+}
+
+func TestTime_roundTrip(t *testing.T) {
+	original := New(time.Date(2026, 10, 16, 9, 30, 0, 123000000, time.UTC))
+	b, err := json.Marshal(original)
+	assert.Nil(t, err)
+	var parsed Time
+	assert.Nil(t, json.Unmarshal(b, &parsed))
+	assert.True(t, original.Equal(parsed.Time))
+
+	// the precision is reduced to milliseconds
+	b, _ = json.Marshal(New(time.Date(2026, 10, 16, 9, 30, 0, 123999999, time.UTC)))
+	assert.Nil(t, json.Unmarshal(b, &parsed))
+	assert.Equal(t, 123000000, parsed.Nanosecond())
+}
+
+func TestTime_UnmarshalJSON(t *testing.T) {
+	expected := time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		input    string
+		expected time.Time
+	}{
+		{`"2026-10-16T09:30:00Z"`, expected},
+		{`"2026-10-16T09:30:00.500Z"`, expected.Add(500 * time.Millisecond)},
+		{`"2026-10-16T18:30:00+09:00"`, expected},
+		{`"2026-10-16T09:30:00"`, expected},
+		{`"2026-10-16 09:30:00"`, expected},
+		{`"2026-10-16 11:30:00+02:00"`, expected},
+		{`"2026-10-16"`, time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)},
+		{`1792143000`, expected},
+		{`null`, time.Time{}},
+	}
+	for _, tc := range tests {
+		var parsed Time
+		if assert.Nil(t, json.Unmarshal([]byte(tc.input), &parsed), tc.input) {
+			assert.True(t, tc.expected.Equal(parsed.Time), "%v: got %v", tc.input, parsed.Time)
+		}
+	}
+
+	for _, input := range []string{`"yesterday"`, `"16/10/2026"`, `true`, `{}`} {
+		var parsed Time
+		assert.NotNil(t, json.Unmarshal([]byte(input), &parsed), input)
+	}
+}
+
+func TestTime_Scan(t *testing.T) {
+	expected := time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC)
+	for _, src := range []interface{}{expected, []byte("2026-10-16 09:30:00"), "2026-10-16T09:30:00Z"} {
+		var scanned Time
+		if assert.Nil(t, scanned.Scan(src)) {
+			assert.True(t, expected.Equal(scanned.Time))
+		}
+	}
+	var scanned Time
+	assert.Nil(t, scanned.Scan(nil))
+	assert.True(t, scanned.IsZero())
+	assert.NotNil(t, scanned.Scan(42))
+
+	value, err := New(expected).Value()
+	assert.Nil(t, err)
+	assert.Equal(t, expected, value)
+}
@@ -39,8 +39,12 @@ type contextKey int
 const (
 	requestIDKey contextKey = iota
 	correlationIDKey
+	loggerKey
 )
 
+// defaultLogger is returned by FromContext for the contexts that carry no logger.
+var defaultLogger = New()
+
 // New creates a new logger using the default configuration.
 func New() Logger {
 	l, _ := zap.NewProduction()
@@ -93,6 +97,23 @@ func WithRequest(ctx context.Context, req *http.Request) context.Context {
 	return ctx
 }
 
+// WithLogger returns a context carrying the given logger, which FromContext returns.
+func WithLogger(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, l)
+}
+
+// FromContext returns the logger stored in the context by WithLogger, typically the request-scoped logger
+// set up by Handler, which already carries the request fields. If there is none, a logger with the
+// default configuration is returned, decorated with the request and correlation IDs found in the context.
+func FromContext(ctx context.Context) Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(loggerKey).(Logger); ok {
+			return l
+		}
+	}
+	return defaultLogger.With(ctx)
+}
+
 // getCorrelationID extracts the correlation ID from the HTTP request
 func getCorrelationID(req *http.Request) string {
 	return req.Header.Get("X-Correlation-ID")
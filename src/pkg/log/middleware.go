@@ -0,0 +1,43 @@
+package log
+
+import (
+	"context"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"pkg/routeinfo"
+)
+
+// Options represents the options of the context logger middleware.
+type Options struct {
+	// User returns the ID of the user sending the request, which is logged in the "user" field.
+	// The field is omitted if User is nil or returns an empty ID.
+	User func(ctx context.Context) string
+}
+
+// Handler returns a middleware that stores a request-scoped logger in the request context, so that
+// the handlers can log with FromContext instead of decorating a logger for each message.
+// The logger is derived from the given one and carries the request ID and correlation ID
+// (see WithRequest), the pattern of the matched route in the "route" field, and the user.
+// The user is only known once the request is authenticated, so the middleware should follow the authentication.
+func Handler(logger Logger, options ...Options) routing.Handler {
+	var opt Options
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	return func(c *routing.Context) error {
+		ctx := c.Request.Context()
+		if _, ok := ctx.Value(requestIDKey).(string); !ok {
+			ctx = WithRequest(ctx, c.Request)
+		}
+		var args []interface{}
+		if route := routeinfo.Pattern(c); route != "" {
+			args = append(args, "route", route)
+		}
+		if opt.User != nil {
+			if user := opt.User(ctx); user != "" {
+				args = append(args, "user", user)
+			}
+		}
+		c.Request = c.Request.WithContext(WithLogger(ctx, logger.With(ctx, args...)))
+		return nil
+	}
+}
@@ -0,0 +1,72 @@
+package log
+
+import (
+	"context"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	logger, entries := NewForTest()
+	router := routing.New()
+	router.Use(Handler(logger, Options{
+		User: func(ctx context.Context) string { return ctx.Value(userKey).(string) },
+	}))
+	router.Get("/albums/<id>", func(c *routing.Context) error {
+		FromContext(c.Request.Context()).Info("album read")
+		return nil
+	})
+
+	req, _ := http.NewRequest("GET", "/albums/1", nil)
+	req.Header.Set("X-Request-ID", "abc")
+	req.Header.Set("X-Correlation-ID", "123")
+	req = req.WithContext(context.WithValue(req.Context(), userKey, "100"))
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	if assert.Equal(t, 1, entries.Len()) {
+		assert.Equal(t, map[string]interface{}{
+			"request_id":     "abc",
+			"correlation_id": "123",
+			"route":          "/albums/<id>",
+			"user":           "100",
+		}, entries.All()[0].ContextMap())
+	}
+}
+
+func TestHandler_anonymous(t *testing.T) {
+	logger, entries := NewForTest()
+	router := routing.New()
+	router.Use(Handler(logger, Options{User: func(ctx context.Context) string { return "" }}))
+	router.Get("/healthz", func(c *routing.Context) error {
+		FromContext(c.Request.Context()).Info("healthy")
+		return nil
+	})
+
+	req, _ := http.NewRequest("GET", "/healthz", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	// the request gets an ID if it has none, and there is no user
+	if assert.Equal(t, 1, entries.Len()) {
+		fields := entries.All()[0].ContextMap()
+		assert.NotEmpty(t, fields["request_id"])
+		assert.Equal(t, "/healthz", fields["route"])
+		assert.NotContains(t, fields, "user")
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	logger, _ := NewForTest()
+	ctx := WithLogger(context.Background(), logger)
+	assert.Equal(t, logger, FromContext(ctx))
+
+	// the default logger is used without a logger in the context
+	assert.NotNil(t, FromContext(nil))
+	assert.NotNil(t, FromContext(context.Background()))
+}
+
+type testContextKey int
+
+const userKey testContextKey = 0
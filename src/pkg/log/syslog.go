@@ -0,0 +1,21 @@
+package log
+
+import (
+	"io"
+	"os"
+)
+
+// fallback receives the log entries when syslog is unreachable or not supported.
+var fallback io.Writer = os.Stderr
+
+// SyslogOptions specifies where and how the log entries are sent to syslog.
+type SyslogOptions struct {
+	// Network is "udp" or "tcp" to send the entries to a remote syslog server, or empty to use the local syslog daemon.
+	Network string
+	// Address is the address of the remote syslog server, e.g. "logs.example.com:514". It is ignored if Network is empty.
+	Address string
+	// Facility is the name of the facility of the entries, e.g. "daemon". Defaults to "local0".
+	Facility string
+	// Tag identifies the program in the entries. Defaults to the name of the executable.
+	Tag string
+}
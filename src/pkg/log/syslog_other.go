@@ -0,0 +1,26 @@
+//go:build windows || plan9
+
+package log
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"runtime"
+)
+
+// SyslogSupported reports whether the log entries can be sent to syslog on this platform.
+const SyslogSupported = false
+
+// ParseFacility returns an error, as syslog is not supported on this platform.
+func ParseFacility(name string) (int, error) {
+	return 0, fmt.Errorf("syslog is not supported on %v", runtime.GOOS)
+}
+
+// NewSyslog creates a logger writing the entries to stderr, as syslog is not supported on this platform.
+func NewSyslog(opts SyslogOptions) Logger {
+	encoder := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	l := NewWithZap(zap.New(zapcore.NewCore(encoder, zapcore.AddSync(fallback), zapcore.InfoLevel)))
+	l.Errorf("logging to stderr: syslog is not supported on %v", runtime.GOOS)
+	return l
+}
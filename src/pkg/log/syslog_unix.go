@@ -0,0 +1,119 @@
+//go:build !windows && !plan9
+
+package log
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"log/syslog"
+	"strings"
+)
+
+// SyslogSupported reports whether the log entries can be sent to syslog on this platform.
+const SyslogSupported = true
+
+// facilities maps the names of the syslog facilities to their priorities.
+var facilities = map[string]syslog.Priority{
+	"kern": syslog.LOG_KERN, "user": syslog.LOG_USER, "mail": syslog.LOG_MAIL, "daemon": syslog.LOG_DAEMON,
+	"auth": syslog.LOG_AUTH, "syslog": syslog.LOG_SYSLOG, "lpr": syslog.LOG_LPR, "news": syslog.LOG_NEWS,
+	"uucp": syslog.LOG_UUCP, "cron": syslog.LOG_CRON, "authpriv": syslog.LOG_AUTHPRIV, "ftp": syslog.LOG_FTP,
+	"local0": syslog.LOG_LOCAL0, "local1": syslog.LOG_LOCAL1, "local2": syslog.LOG_LOCAL2, "local3": syslog.LOG_LOCAL3,
+	"local4": syslog.LOG_LOCAL4, "local5": syslog.LOG_LOCAL5, "local6": syslog.LOG_LOCAL6, "local7": syslog.LOG_LOCAL7,
+}
+
+// ParseFacility returns the syslog priority of the facility with the given name, such as "local0".
+func ParseFacility(name string) (int, error) {
+	facility, ok := facilities[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown syslog facility %q", name)
+	}
+	return int(facility), nil
+}
+
+// NewSyslog creates a logger sending the entries to syslog as JSON, with the syslog severity matching their level.
+// If syslog cannot be reached, the logger writes to stderr instead, and so do the entries syslog fails to receive
+// later on, so that no entry is lost while the syslog server is down.
+func NewSyslog(opts SyslogOptions) Logger {
+	if opts.Facility == "" {
+		opts.Facility = "local0"
+	}
+	encoder := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	stderr := zapcore.NewCore(encoder.Clone(), zapcore.AddSync(fallback), zapcore.InfoLevel)
+
+	facility, err := ParseFacility(opts.Facility)
+	if err != nil {
+		l := NewWithZap(zap.New(stderr))
+		l.Errorf("logging to stderr: %v", err)
+		return l
+	}
+	address := opts.Address
+	if opts.Network == "" {
+		address = ""
+	}
+	writer, err := syslog.Dial(opts.Network, address, syslog.Priority(facility)|syslog.LOG_INFO, opts.Tag)
+	if err != nil {
+		l := NewWithZap(zap.New(stderr))
+		l.Errorf("logging to stderr as syslog is unreachable: %v", err)
+		return l
+	}
+	return NewWithZap(zap.New(&syslogCore{
+		LevelEnabler: zapcore.InfoLevel,
+		encoder:      encoder,
+		writer:       writer,
+		fallback:     stderr,
+	}))
+}
+
+// syslogCore is a zap core writing the entries to syslog, and to the fallback core when syslog fails.
+type syslogCore struct {
+	zapcore.LevelEnabler
+	encoder  zapcore.Encoder
+	writer   *syslog.Writer
+	fallback zapcore.Core
+}
+
+func (c *syslogCore) With(fields []zapcore.Field) zapcore.Core {
+	encoder := c.encoder.Clone()
+	for _, field := range fields {
+		field.AddTo(encoder)
+	}
+	return &syslogCore{c.LevelEnabler, encoder, c.writer, c.fallback.With(fields)}
+}
+
+func (c *syslogCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *syslogCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.encoder.EncodeEntry(entry, fields)
+	if err != nil {
+		return err
+	}
+	message := strings.TrimSuffix(buf.String(), "\n")
+	buf.Free()
+
+	switch entry.Level {
+	case zapcore.DebugLevel:
+		err = c.writer.Debug(message)
+	case zapcore.InfoLevel:
+		err = c.writer.Info(message)
+	case zapcore.WarnLevel:
+		err = c.writer.Warning(message)
+	case zapcore.ErrorLevel:
+		err = c.writer.Err(message)
+	default:
+		err = c.writer.Crit(message)
+	}
+	if err != nil {
+		return c.fallback.Write(entry, fields)
+	}
+	return nil
+}
+
+func (c *syslogCore) Sync() error {
+	return nil
+}
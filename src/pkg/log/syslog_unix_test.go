@@ -0,0 +1,101 @@
+//go:build !windows && !plan9
+
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewSyslog_udp(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	l := NewSyslog(SyslogOptions{Network: "udp", Address: conn.LocalAddr().String(), Facility: "daemon", Tag: "server"})
+	l.With(nil, "version", "1.0").Info("server started")
+	l.Errorf("failed %v", 1)
+
+	read := func() string {
+		buf := make([]byte, 4096)
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		assert.Nil(t, err)
+		return string(buf[:n])
+	}
+	// daemon is facility 3, info is severity 6 and err is severity 3
+	line := read()
+	assert.True(t, strings.HasPrefix(line, "<30>"), line)
+	assert.Contains(t, line, " server[")
+	assert.Contains(t, line, `"msg":"server started","version":"1.0"`)
+	line = read()
+	assert.True(t, strings.HasPrefix(line, "<27>"), line)
+	assert.Contains(t, line, `"msg":"failed 1"`)
+}
+
+func TestNewSyslog_tcp(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer ln.Close()
+	lines := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		lines <- line
+	}()
+
+	l := NewSyslog(SyslogOptions{Network: "tcp", Address: ln.Addr().String(), Tag: "server"})
+	l.Info("server started")
+	select {
+	case line := <-lines:
+		// local0 is facility 16
+		assert.True(t, strings.HasPrefix(line, "<134>"), line)
+		assert.Contains(t, line, `"msg":"server started"`)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the log entry did not reach the syslog server")
+	}
+}
+
+func TestNewSyslog_fallback(t *testing.T) {
+	stderr := &bytes.Buffer{}
+	defer func(w io.Writer) { fallback = w }(fallback)
+	fallback = stderr
+
+	// nothing listens on the port of a closed listener
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	address := ln.Addr().String()
+	ln.Close()
+
+	l := NewSyslog(SyslogOptions{Network: "tcp", Address: address})
+	l.Info("server started")
+	assert.Contains(t, stderr.String(), "logging to stderr as syslog is unreachable")
+	assert.Contains(t, stderr.String(), `"msg":"server started"`)
+
+	stderr.Reset()
+	l = NewSyslog(SyslogOptions{Network: "udp", Address: address, Facility: "local9"})
+	l.Info("server started")
+	assert.Contains(t, stderr.String(), `unknown syslog facility \"local9\"`)
+	assert.Contains(t, stderr.String(), `"msg":"server started"`)
+}
+
+func TestParseFacility(t *testing.T) {
+	facility, err := ParseFacility("LOCAL7")
+	assert.Nil(t, err)
+	assert.Equal(t, 23<<3, int(facility))
+	_, err = ParseFacility("kernel")
+	assert.NotNil(t, err)
+}
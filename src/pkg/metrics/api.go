@@ -0,0 +1,16 @@
+package metrics
+
+import (
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+)
+
+// RegisterHandlers registers the administrative endpoint GET /admin/metrics.json, which returns
+// the current values of the metrics in the registry as JSON for dashboards that don't scrape Prometheus.
+func RegisterHandlers(rg *routing.RouteGroup, registry *Registry, authHandler routing.Handler) {
+	rg.Use(authHandler)
+
+	// the following endpoints require admin authentication
+	rg.Get("/admin/metrics.json", func(c *routing.Context) error {
+		return c.Write(registry.Snapshot())
+	})
+}
@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"encoding/json"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/go-ozzo/ozzo-routing/v2/content"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPI(t *testing.T) {
+	registry := NewRegistry()
+	router := routing.New()
+	router.Use(content.TypeNegotiator(content.JSON), Handler(registry))
+	router.Get("/users", func(c *routing.Context) error { return c.Write("ok") })
+	RegisterHandlers(router.Group(""), registry, func(c *routing.Context) error {
+		if c.Request.Header.Get("Authorization") != "TEST" {
+			return routing.NewHTTPError(http.StatusUnauthorized)
+		}
+		return nil
+	})
+
+	req, _ := http.NewRequest("GET", "http://127.0.0.1/users", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	req, _ = http.NewRequest("GET", "http://127.0.0.1/admin/metrics.json", nil)
+	req.Header.Set("Authorization", "TEST")
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+	var snapshot Snapshot
+	if assert.Nil(t, json.Unmarshal(res.Body.Bytes(), &snapshot)) {
+		// the metrics request itself is still being processed
+		assert.Equal(t, int64(1), snapshot.Counters[Requests])
+		assert.Equal(t, int64(1), snapshot.Gauges[ActiveRequests])
+		assert.Equal(t, int64(1), snapshot.Histograms[RequestDuration].Count)
+		assert.Equal(t, len(DefaultBuckets), len(snapshot.Histograms[RequestDuration].Buckets))
+	}
+
+	req, _ = http.NewRequest("GET", "http://127.0.0.1/admin/metrics.json", nil)
+	res = httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+}
@@ -0,0 +1,189 @@
+// Package metrics provides simple in-process metrics for observing the application.
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// ActiveRequests is the name of the gauge tracking the number of HTTP requests being processed.
+	ActiveRequests = "http_requests_active"
+	// Requests is the name of the counter of the HTTP requests processed.
+	Requests = "http_requests_total"
+	// RequestDuration is the name of the histogram of the time taken to process HTTP requests in seconds.
+	RequestDuration = "http_request_duration_seconds"
+)
+
+// DefaultBuckets are the default upper bounds of the buckets of a histogram, suited to durations in seconds.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Gauge is a metric whose value can go up and down.
+type Gauge struct {
+	value int64
+}
+
+// Inc increments the gauge by 1.
+func (g *Gauge) Inc() {
+	atomic.AddInt64(&g.value, 1)
+}
+
+// Dec decrements the gauge by 1.
+func (g *Gauge) Dec() {
+	atomic.AddInt64(&g.value, -1)
+}
+
+// Value returns the current value of the gauge.
+func (g *Gauge) Value() int64 {
+	return atomic.LoadInt64(&g.value)
+}
+
+// Counter is a metric whose value only goes up.
+type Counter struct {
+	value int64
+}
+
+// Inc increments the counter by 1.
+func (c *Counter) Inc() {
+	atomic.AddInt64(&c.value, 1)
+}
+
+// Value returns the current value of the counter.
+func (c *Counter) Value() int64 {
+	return atomic.LoadInt64(&c.value)
+}
+
+// Histogram counts observed values in buckets, e.g. to track the distribution of request durations.
+type Histogram struct {
+	mu      sync.Mutex
+	bounds  []float64
+	buckets []int64
+	count   int64
+	sum     float64
+}
+
+// Observe records a value.
+func (h *Histogram) Observe(value float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// the buckets are cumulative: each counts the values less than or equal to its bound
+	for i := sort.SearchFloat64s(h.bounds, value); i < len(h.bounds); i++ {
+		h.buckets[i]++
+	}
+	h.count++
+	h.sum += value
+}
+
+// Snapshot returns the current state of the histogram.
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	buckets := make([]Bucket, len(h.bounds))
+	for i, bound := range h.bounds {
+		buckets[i] = Bucket{bound, h.buckets[i]}
+	}
+	return HistogramSnapshot{Count: h.count, Sum: h.sum, Buckets: buckets}
+}
+
+// HistogramSnapshot is the state of a histogram at a point in time.
+type HistogramSnapshot struct {
+	// Count is the number of values observed.
+	Count int64 `json:"count"`
+	// Sum is the sum of the values observed.
+	Sum     float64  `json:"sum"`
+	Buckets []Bucket `json:"buckets"`
+}
+
+// Bucket is the number of observed values less than or equal to an upper bound.
+type Bucket struct {
+	UpperBound float64 `json:"le"`
+	Count      int64   `json:"count"`
+}
+
+// Snapshot is the state of all the metrics of a registry at a point in time, by metric name.
+type Snapshot struct {
+	Gauges     map[string]int64             `json:"gauges"`
+	Counters   map[string]int64             `json:"counters"`
+	Histograms map[string]HistogramSnapshot `json:"histograms"`
+}
+
+// Registry holds the metrics of the application by name.
+type Registry struct {
+	mu         sync.Mutex
+	gauges     map[string]*Gauge
+	counters   map[string]*Counter
+	histograms map[string]*Histogram
+}
+
+// NewRegistry creates a new metrics registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		gauges:     map[string]*Gauge{},
+		counters:   map[string]*Counter{},
+		histograms: map[string]*Histogram{},
+	}
+}
+
+// Gauge returns the gauge with the given name, creating it if it does not exist yet.
+func (r *Registry) Gauge(name string) *Gauge {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	g, ok := r.gauges[name]
+	if !ok {
+		g = &Gauge{}
+		r.gauges[name] = g
+	}
+	return g
+}
+
+// Counter returns the counter with the given name, creating it if it does not exist yet.
+func (r *Registry) Counter(name string) *Counter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.counters[name]
+	if !ok {
+		c = &Counter{}
+		r.counters[name] = c
+	}
+	return c
+}
+
+// Histogram returns the histogram with the given name, creating it with the given bucket upper bounds
+// if it does not exist yet. DefaultBuckets are used if no bounds are given.
+func (r *Registry) Histogram(name string, bounds ...float64) *Histogram {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h, ok := r.histograms[name]
+	if !ok {
+		if len(bounds) == 0 {
+			bounds = DefaultBuckets
+		}
+		bounds = append([]float64(nil), bounds...)
+		sort.Float64s(bounds)
+		h = &Histogram{bounds: bounds, buckets: make([]int64, len(bounds))}
+		r.histograms[name] = h
+	}
+	return h
+}
+
+// Snapshot returns the current values of all the metrics in the registry.
+func (r *Registry) Snapshot() Snapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := Snapshot{
+		Gauges:     make(map[string]int64, len(r.gauges)),
+		Counters:   make(map[string]int64, len(r.counters)),
+		Histograms: make(map[string]HistogramSnapshot, len(r.histograms)),
+	}
+	for name, g := range r.gauges {
+		s.Gauges[name] = g.Value()
+	}
+	for name, c := range r.counters {
+		s.Counters[name] = c.Value()
+	}
+	for name, h := range r.histograms {
+		s.Histograms[name] = h.Snapshot()
+	}
+	return s
+}
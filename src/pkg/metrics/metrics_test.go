@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestGauge(t *testing.T) {
+	g := &Gauge{}
+	assert.Equal(t, int64(0), g.Value())
+	g.Inc()
+	g.Inc()
+	g.Dec()
+	assert.Equal(t, int64(1), g.Value())
+}
+
+func TestRegistry_Gauge(t *testing.T) {
+	r := NewRegistry()
+	g := r.Gauge("test")
+	g.Inc()
+	assert.Equal(t, g, r.Gauge("test"))
+	assert.Equal(t, int64(1), r.Gauge("test").Value())
+	assert.Equal(t, int64(0), r.Gauge("other").Value())
+}
+
+func TestRegistry_Counter(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("test").Inc()
+	r.Counter("test").Inc()
+	assert.Equal(t, int64(2), r.Counter("test").Value())
+	assert.Equal(t, int64(0), r.Counter("other").Value())
+}
+
+func TestHistogram(t *testing.T) {
+	h := NewRegistry().Histogram("test", 1, 0.1)
+	for _, v := range []float64{0.05, 0.1, 0.5, 3} {
+		h.Observe(v)
+	}
+	assert.Equal(t, HistogramSnapshot{
+		Count:   4,
+		Sum:     3.65,
+		Buckets: []Bucket{{0.1, 2}, {1, 3}},
+	}, h.Snapshot())
+}
@@ -0,0 +1,25 @@
+package metrics
+
+import (
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"time"
+)
+
+// Handler returns a middleware that keeps track of the HTTP requests in the given registry:
+// those being processed in the ActiveRequests gauge, those processed in the Requests counter,
+// and the time taken to process them in the RequestDuration histogram.
+func Handler(registry *Registry) routing.Handler {
+	active := registry.Gauge(ActiveRequests)
+	requests := registry.Counter(Requests)
+	duration := registry.Histogram(RequestDuration)
+	return func(c *routing.Context) error {
+		start := time.Now()
+		active.Inc()
+		defer func() {
+			active.Dec()
+			requests.Inc()
+			duration.Observe(time.Since(start).Seconds())
+		}()
+		return c.Next()
+	}
+}
@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	registry := NewRegistry()
+	active := registry.Gauge(ActiveRequests)
+	var during int64
+	res := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "http://127.0.0.1/users", nil)
+	c := routing.NewContext(res, req, Handler(registry), func(c *routing.Context) error {
+		during = active.Value()
+		return nil
+	})
+
+	assert.Nil(t, c.Next())
+	assert.Equal(t, int64(1), during)
+	assert.Equal(t, int64(0), active.Value())
+	assert.Equal(t, int64(1), registry.Counter(Requests).Value())
+	assert.Equal(t, int64(1), registry.Histogram(RequestDuration).Snapshot().Count)
+}
@@ -0,0 +1,49 @@
+// Package module lets features describe their routing so that they can be wired into a router uniformly.
+package module
+
+import (
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+)
+
+// Module is a feature exposing HTTP endpoints.
+type Module interface {
+	// Prefix returns the path prefix of the module's routes, relative to the group the module is registered with.
+	Prefix() string
+	// Middleware returns the handlers run before every route of the module, after those of the parent group.
+	Middleware() []routing.Handler
+	// Register registers the module's routes with the given group, which already applies the prefix and middleware.
+	Register(rg *routing.RouteGroup)
+}
+
+// Register wires the modules into the given route group. Each module gets its own subgroup,
+// so the middleware of one module does not apply to the routes of another.
+func Register(rg *routing.RouteGroup, modules ...Module) {
+	for _, m := range modules {
+		group := rg.Group(m.Prefix())
+		group.Use(m.Middleware()...)
+		m.Register(group)
+	}
+}
+
+type funcModule struct {
+	prefix     string
+	register   func(rg *routing.RouteGroup)
+	middleware []routing.Handler
+}
+
+// New creates a module whose routes are registered by the given function.
+func New(prefix string, register func(rg *routing.RouteGroup), middleware ...routing.Handler) Module {
+	return funcModule{prefix, register, middleware}
+}
+
+func (m funcModule) Prefix() string {
+	return m.prefix
+}
+
+func (m funcModule) Middleware() []routing.Handler {
+	return m.middleware
+}
+
+func (m funcModule) Register(rg *routing.RouteGroup) {
+	m.register(rg)
+}
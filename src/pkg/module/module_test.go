@@ -0,0 +1,75 @@
+package module
+
+import (
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeModule serves GET <prefix>/items and tags its responses with its middleware.
+type fakeModule struct {
+	prefix string
+	tag    string
+}
+
+func (m fakeModule) Prefix() string {
+	return m.prefix
+}
+
+func (m fakeModule) Middleware() []routing.Handler {
+	if m.tag == "" {
+		return nil
+	}
+	return []routing.Handler{func(c *routing.Context) error {
+		c.Response.Header().Add("X-Module", m.tag)
+		return nil
+	}}
+}
+
+func (m fakeModule) Register(rg *routing.RouteGroup) {
+	rg.Get("/items", func(c *routing.Context) error {
+		return c.Write("items of " + m.prefix)
+	})
+}
+
+func TestRegister(t *testing.T) {
+	router := routing.New()
+	v1 := router.Group("/v1")
+	v1.Use(func(c *routing.Context) error {
+		c.Response.Header().Add("X-Group", "v1")
+		return nil
+	})
+	Register(v1,
+		fakeModule{"/fake", "fake"},
+		fakeModule{"/plain", ""},
+		New("/func", func(rg *routing.RouteGroup) {
+			rg.Get("/items", func(c *routing.Context) error { return c.Write("func items") })
+		}),
+	)
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+		module []string
+	}{
+		{"/v1/fake/items", http.StatusOK, "items of /fake", []string{"fake"}},
+		{"/v1/plain/items", http.StatusOK, "items of /plain", nil},
+		{"/v1/func/items", http.StatusOK, "func items", nil},
+		{"/v1/items", http.StatusNotFound, "", nil},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", tt.path, nil)
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		assert.Equal(t, tt.status, res.Code, tt.path)
+		if tt.status == http.StatusOK {
+			assert.Equal(t, tt.body, res.Body.String(), tt.path)
+			// the group middleware applies to every module, a module's middleware only to its own routes
+			assert.Equal(t, []string{"v1"}, res.Header()["X-Group"], tt.path)
+			assert.Equal(t, tt.module, res.Header()["X-Module"], tt.path)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+// Package ndjson streams records as newline-delimited JSON (JSON Lines).
+package ndjson
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// ContentType is the media type of newline-delimited JSON.
+const ContentType = "application/x-ndjson"
+
+// Accepts reports whether the request asks for a newline-delimited JSON response in its Accept header.
+func Accepts(r *http.Request) bool {
+	for _, value := range strings.Split(r.Header.Get("Accept"), ",") {
+		if strings.TrimSpace(strings.Split(value, ";")[0]) == ContentType {
+			return true
+		}
+	}
+	return false
+}
+
+// Writer writes records to an HTTP response, one JSON document per line.
+// Each record is flushed to the client as soon as it is written, so that a response
+// can be streamed without being buffered in memory.
+type Writer struct {
+	w       http.ResponseWriter
+	encoder *json.Encoder
+}
+
+// NewWriter creates a Writer streaming to the given response and sets the response content type.
+func NewWriter(w http.ResponseWriter) *Writer {
+	w.Header().Set("Content-Type", ContentType)
+	return &Writer{w: w, encoder: json.NewEncoder(w)}
+}
+
+// Write writes a record as a line of JSON and flushes it.
+func (w *Writer) Write(record interface{}) error {
+	// Encode terminates each document with a newline
+	if err := w.encoder.Encode(record); err != nil {
+		return err
+	}
+	if f, ok := w.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
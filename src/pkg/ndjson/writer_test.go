@@ -0,0 +1,56 @@
+package ndjson
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAccepts(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   bool
+	}{
+		{"", false},
+		{"application/json", false},
+		{"application/x-ndjson", true},
+		{"application/json, application/x-ndjson;q=0.9", true},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest("GET", "/albums", nil)
+		req.Header.Set("Accept", tt.accept)
+		assert.Equal(t, tt.want, Accepts(req), tt.accept)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	res := httptest.NewRecorder()
+	w := NewWriter(res)
+	assert.Equal(t, ContentType, res.Header().Get("Content-Type"))
+
+	type record struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	for i, name := range []string{"a", "b\nc", "d"} {
+		assert.Nil(t, w.Write(record{i, name}))
+		// each record is flushed as soon as it is written
+		assert.True(t, res.Flushed)
+		assert.Equal(t, i+1, strings.Count(res.Body.String(), "\n"))
+		res.Flushed = false
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(res.Body.String()))
+	lines := 0
+	for scanner.Scan() {
+		var r record
+		assert.Nil(t, json.Unmarshal(scanner.Bytes(), &r), scanner.Text())
+		assert.Equal(t, lines, r.ID)
+		lines++
+	}
+	assert.Equal(t, 3, lines)
+}
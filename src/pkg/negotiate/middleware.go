@@ -0,0 +1,98 @@
+// Package negotiate provides a content negotiation middleware offering custom response formats besides JSON.
+package negotiate
+
+import (
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/go-ozzo/ozzo-routing/v2/content"
+	"net/http"
+)
+
+// Format is a response format that clients can request with the Accept header.
+type Format struct {
+	// MIME is the media type of the format, e.g. "application/xml".
+	MIME string
+	// Writer sets the Content-Type header and writes the response data in the format.
+	// If nil, the handlers write the responses in the format themselves, and the data they write
+	// with Context.Write, such as errors, is written in JSON.
+	Writer routing.DataWriter
+}
+
+// XML is the XML response format, written by encoding/xml.
+var XML = Format{content.XML, &content.XMLDataWriter{}}
+
+// Passthrough returns a format written by the handlers themselves, such as an NDJSON stream or a file download,
+// so that the requests accepting only that format are not rejected.
+func Passthrough(mime string) Format {
+	return Format{MIME: mime}
+}
+
+// NewFormat returns a response format whose data is encoded by marshal, such as the Marshal function
+// of a MessagePack library, and sent with the given media type.
+func NewFormat(mime string, marshal func(v interface{}) ([]byte, error)) Format {
+	return Format{mime, marshalWriter{mime, marshal}}
+}
+
+// Handler returns a middleware that negotiates the response format from the Accept header of the request
+// among JSON and the given formats, and sets the data writer of the format chosen. JSON is the default,
+// used when the request has no Accept header or accepts several formats equally. The data written with
+// Context.Write for requests accepting none of the formats is rejected with 406 Not Acceptable, which the error
+// middleware writes in JSON, while the handlers writing the response themselves, such as file downloads, are left
+// to choose its type. It replaces content.TypeNegotiator, which falls back to its default format instead.
+func Handler(formats ...Format) routing.Handler {
+	writers := map[string]routing.DataWriter{content.JSON: &content.JSONDataWriter{}}
+	// the negotiation picks the last of the formats accepted equally, so the default goes last
+	offers := []string{content.JSON}
+	for _, f := range formats {
+		if _, ok := writers[f.MIME]; !ok {
+			offers = append([]string{f.MIME}, offers...)
+		}
+		writers[f.MIME] = f.Writer
+		if f.Writer == nil {
+			writers[f.MIME] = writers[content.JSON]
+		}
+	}
+	return func(c *routing.Context) error {
+		if len(c.Request.Header["Accept"]) == 0 {
+			c.SetDataWriter(writers[content.JSON])
+			return nil
+		}
+		format := content.NegotiateContentType(c.Request, offers, "")
+		if format == "" {
+			c.SetDataWriter(notAcceptable{})
+			return nil
+		}
+		c.SetDataWriter(writers[format])
+		return nil
+	}
+}
+
+// notAcceptable is the data writer of the requests accepting none of the formats. It sets no Content-Type,
+// so that the error middleware writes the error in JSON, and rejects the data written with Context.Write.
+type notAcceptable struct{}
+
+// SetHeader leaves the Content-Type response header to the handler.
+func (notAcceptable) SetHeader(res http.ResponseWriter) {}
+
+func (notAcceptable) Write(res http.ResponseWriter, data interface{}) error {
+	return routing.NewHTTPError(http.StatusNotAcceptable)
+}
+
+// marshalWriter writes the response data encoded by a marshal function.
+type marshalWriter struct {
+	mime    string
+	marshal func(v interface{}) ([]byte, error)
+}
+
+// SetHeader sets the Content-Type response header.
+func (w marshalWriter) SetHeader(res http.ResponseWriter) {
+	res.Header().Set("Content-Type", w.mime)
+}
+
+func (w marshalWriter) Write(res http.ResponseWriter, data interface{}) error {
+	bytes, err := w.marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = res.Write(bytes)
+	return err
+}
@@ -0,0 +1,74 @@
+package negotiate
+
+import (
+	"encoding/json"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type album struct {
+	ID   string `json:"id" xml:"id"`
+	Name string `json:"name" xml:"name"`
+}
+
+func TestHandler(t *testing.T) {
+	// a binary format standing in for MessagePack
+	binary := NewFormat("application/x-test", func(v interface{}) ([]byte, error) {
+		bytes, err := json.Marshal(v)
+		return append([]byte("bin:"), bytes...), err
+	})
+	router := routing.New()
+	router.Use(Handler(XML, binary, Passthrough("application/x-ndjson")))
+	router.Get("/albums/1", func(c *routing.Context) error {
+		return c.Write(album{"1", "demo"})
+	})
+	// a handler writing the response itself, like a file download
+	router.Get("/albums/1.csv", func(c *routing.Context) error {
+		c.Response.Header().Set("Content-Type", "text/csv")
+		_, err := c.Response.Write([]byte("1,demo\n"))
+		return err
+	})
+
+	tests := []struct {
+		name        string
+		accept      string
+		status      int
+		contentType string
+		body        string
+	}{
+		{"no accept", "", http.StatusOK, "application/json", `{"id":"1","name":"demo"}` + "\n"},
+		{"json", "application/json", http.StatusOK, "application/json", `{"id":"1","name":"demo"}` + "\n"},
+		{"any", "*/*", http.StatusOK, "application/json", `{"id":"1","name":"demo"}` + "\n"},
+		{"xml", "application/xml", http.StatusOK, "application/xml; charset=UTF-8", `<album><id>1</id><name>demo</name></album>`},
+		{"preferred xml", "application/json;q=0.5, application/xml", http.StatusOK, "application/xml; charset=UTF-8", `<album><id>1</id><name>demo</name></album>`},
+		{"custom", "application/x-test", http.StatusOK, "application/x-test", `bin:{"id":"1","name":"demo"}`},
+		{"passthrough", "application/x-ndjson", http.StatusOK, "application/json", `{"id":"1","name":"demo"}` + "\n"},
+		{"unsupported", "text/csv", http.StatusNotAcceptable, "", ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/albums/1", nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			res := httptest.NewRecorder()
+			router.ServeHTTP(res, req)
+			assert.Equal(t, tc.status, res.Code)
+			if tc.body != "" {
+				assert.Equal(t, tc.contentType, res.Header().Get("Content-Type"))
+				assert.Equal(t, tc.body, res.Body.String())
+			}
+		})
+	}
+	// the handlers writing the response themselves are not rejected
+	req, _ := http.NewRequest("GET", "/albums/1.csv", nil)
+	req.Header.Set("Accept", "text/csv")
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "text/csv", res.Header().Get("Content-Type"))
+	assert.Equal(t, "1,demo\n", res.Body.String())
+}
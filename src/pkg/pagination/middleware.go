@@ -0,0 +1,80 @@
+package pagination
+
+import (
+	"context"
+	"fmt"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"net/http"
+	"strconv"
+)
+
+// Pagination represents the pagination parameters of a list request.
+type Pagination struct {
+	// Page is the 1-based page number.
+	Page int
+	// PerPage is the number of items on each page.
+	PerPage int
+}
+
+// Pages creates a Pages instance for the pagination parameters.
+// total specifies the total number of data items. Use -1 if this is unknown.
+func (p Pagination) Pages(total int) *Pages {
+	return New(p.Page, p.PerPage, total)
+}
+
+type contextKey int
+
+const paginationKey contextKey = iota
+
+// Handler returns a middleware that parses and validates the pagination query parameters
+// and stores them in the request context, from which handlers can read them using FromContext.
+// A parameter that is not a positive integer results in a 400 error.
+// A page size greater than MaxPageSize is clamped to MaxPageSize.
+func Handler() routing.Handler {
+	return func(c *routing.Context) error {
+		query := c.Request.URL.Query()
+		page, err := parsePositiveInt(query.Get(PageVar), 1)
+		if err != nil {
+			return routing.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("%v must be a positive integer", PageVar))
+		}
+		perPage, err := parsePositiveInt(query.Get(PageSizeVar), DefaultPageSize)
+		if err != nil {
+			return routing.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("%v must be a positive integer", PageSizeVar))
+		}
+		if perPage > MaxPageSize {
+			perPage = MaxPageSize
+		}
+		ctx := WithPagination(c.Request.Context(), Pagination{Page: page, PerPage: perPage})
+		c.Request = c.Request.WithContext(ctx)
+		return nil
+	}
+}
+
+// WithPagination returns a context that contains the given pagination parameters.
+func WithPagination(ctx context.Context, p Pagination) context.Context {
+	return context.WithValue(ctx, paginationKey, p)
+}
+
+// FromContext returns the pagination parameters stored in the given context.
+// The first page with DefaultPageSize is returned if the context contains no pagination parameters.
+func FromContext(ctx context.Context) Pagination {
+	if p, ok := ctx.Value(paginationKey).(Pagination); ok {
+		return p
+	}
+	return Pagination{Page: 1, PerPage: DefaultPageSize}
+}
+
+// parsePositiveInt parses a string into a positive integer. defaultValue is returned if the string is empty.
+func parsePositiveInt(value string, defaultValue int) (int, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	result, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+	if result < 1 {
+		return 0, fmt.Errorf("%v is not positive", result)
+	}
+	return result, nil
+}
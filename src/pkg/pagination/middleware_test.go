@@ -0,0 +1,58 @@
+package pagination
+
+import (
+	"context"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	handler := Handler()
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		page       int
+		perPage    int
+	}{
+		{"defaults", "", 0, 1, DefaultPageSize},
+		{"explicit", "?page=3&per_page=20", 0, 3, 20},
+		{"clamped", "?per_page=5000", 0, 1, MaxPageSize},
+		{"page not a number", "?page=abc", http.StatusBadRequest, 0, 0},
+		{"page zero", "?page=0", http.StatusBadRequest, 0, 0},
+		{"per_page negative", "?per_page=-1", http.StatusBadRequest, 0, 0},
+		{"per_page not a number", "?per_page=1.5", http.StatusBadRequest, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "http://127.0.0.1/albums"+tt.query, nil)
+			c := routing.NewContext(httptest.NewRecorder(), req)
+			err := handler(c)
+			if tt.wantStatus != 0 {
+				if assert.NotNil(t, err) {
+					assert.Equal(t, tt.wantStatus, err.(routing.HTTPError).StatusCode())
+				}
+				return
+			}
+			assert.Nil(t, err)
+			p := FromContext(c.Request.Context())
+			assert.Equal(t, tt.page, p.Page)
+			assert.Equal(t, tt.perPage, p.PerPage)
+		})
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	assert.Equal(t, Pagination{1, DefaultPageSize}, FromContext(context.Background()))
+
+	ctx := WithPagination(context.Background(), Pagination{2, 20})
+	pages := FromContext(ctx).Pages(100)
+	assert.Equal(t, 2, pages.Page)
+	assert.Equal(t, 20, pages.PerPage)
+	assert.Equal(t, 20, pages.Offset())
+	assert.Equal(t, 5, pages.PageCount)
+}
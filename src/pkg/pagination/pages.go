@@ -4,6 +4,7 @@ package pagination
 import (
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 )
@@ -88,6 +89,18 @@ func (p *Pages) Limit() int {
 	return p.PerPage
 }
 
+// BaseURL returns the path and query of the given request URL without the pagination query parameters.
+// Passed to BuildLinkHeader, it makes the links keep the other query parameters of the request, such as filters.
+func BaseURL(u *url.URL) string {
+	query := u.Query()
+	query.Del(PageVar)
+	query.Del(PageSizeVar)
+	if len(query) == 0 {
+		return u.Path
+	}
+	return u.Path + "?" + query.Encode()
+}
+
 // BuildLinkHeader returns an HTTP header containing the links about the pagination.
 func (p *Pages) BuildLinkHeader(baseURL string, defaultPerPage int) string {
 	links := p.BuildLinks(baseURL, defaultPerPage)
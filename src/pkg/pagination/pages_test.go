@@ -3,6 +3,7 @@ package pagination
 import (
 	"bytes"
 	"net/http"
+	"net/url"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -100,3 +101,10 @@ func TestNewFromRequest(t *testing.T) {
 	assert.Equal(t, 100, p.TotalCount)
 	assert.Equal(t, 5, p.PageCount)
 }
+
+func TestBaseURL(t *testing.T) {
+	u, _ := url.Parse("/albums?page=2&per_page=10&name=x&sort=name")
+	assert.Equal(t, "/albums?name=x&sort=name", BaseURL(u))
+	u, _ = url.Parse("/albums?page=2")
+	assert.Equal(t, "/albums", BaseURL(u))
+}
@@ -0,0 +1,26 @@
+// Package password provides hashing and verification of user passwords.
+package password
+
+import (
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+)
+
+// Cost is the bcrypt cost used when hashing passwords.
+var Cost = bcrypt.DefaultCost
+
+// Hash returns the bcrypt hash of the password.
+func Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), Cost)
+	return string(hash), err
+}
+
+// IsHash returns whether the value is a bcrypt hash rather than a plain text password.
+func IsHash(value string) bool {
+	return strings.HasPrefix(value, "$2") && len(value) == 60
+}
+
+// Verify returns whether the password matches the hash.
+func Verify(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
@@ -0,0 +1,23 @@
+package password
+
+import (
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	Cost = bcrypt.MinCost
+	defer func() { Cost = bcrypt.DefaultCost }()
+
+	hash, err := Hash("secret")
+	assert.Nil(t, err)
+	assert.True(t, IsHash(hash))
+	assert.False(t, IsHash("secret"))
+	assert.True(t, Verify(hash, "secret"))
+	assert.False(t, Verify(hash, "wrong"))
+	assert.False(t, Verify("secret", "secret"))
+
+	other, _ := Hash("secret")
+	assert.NotEqual(t, hash, other, "hashes must be salted")
+}
@@ -0,0 +1,95 @@
+package password
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// MaxLength is the maximum number of characters of a password.
+	MaxLength = 72
+	// MaxBytes is the maximum size of a password encoded as UTF-8, as bcrypt cannot hash longer ones.
+	MaxBytes = 72
+)
+
+// CommonPasswords lists frequently used passwords rejected by policies with RejectCommon set.
+var CommonPasswords = []string{
+	"123456", "123456789", "12345678", "1234567", "12345", "1234567890", "111111", "000000",
+	"password", "password1", "password123", "passw0rd", "qwerty", "qwerty123", "qwertyuiop",
+	"abc123", "abcd1234", "iloveyou", "admin", "admin123", "welcome", "welcome1", "letmein",
+	"monkey", "dragon", "football", "baseball", "sunshine", "princess", "1q2w3e4r", "zaq12wsx",
+}
+
+// Policy describes the rules passwords must follow. The zero value accepts any password bcrypt can hash.
+type Policy struct {
+	// MinLength is the minimum number of characters.
+	MinLength int
+	// RequireUpper requires at least one uppercase letter.
+	RequireUpper bool
+	// RequireLower requires at least one lowercase letter.
+	RequireLower bool
+	// RequireDigit requires at least one digit.
+	RequireDigit bool
+	// RequireSymbol requires at least one character that is neither a letter, a digit nor a space.
+	RequireSymbol bool
+	// RejectCommon rejects the passwords in CommonPasswords and Disallowed, ignoring case.
+	RejectCommon bool
+	// Disallowed lists additional passwords to reject when RejectCommon is set.
+	Disallowed []string
+}
+
+// Check returns a message for each rule of the policy the password violates,
+// or nil if the password follows them all.
+func (p Policy) Check(password string) []string {
+	var violations []string
+	if n := utf8.RuneCountInString(password); n < p.MinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %v characters long", p.MinLength))
+	} else if n > MaxLength {
+		violations = append(violations, fmt.Sprintf("must be at most %v characters long", MaxLength))
+	} else if len(password) > MaxBytes {
+		// characters outside ASCII take several bytes
+		violations = append(violations, fmt.Sprintf("must be at most %v bytes long in UTF-8", MaxBytes))
+	}
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsLetter(r) && !unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if p.RequireUpper && !upper {
+		violations = append(violations, "must contain an uppercase letter")
+	}
+	if p.RequireLower && !lower {
+		violations = append(violations, "must contain a lowercase letter")
+	}
+	if p.RequireDigit && !digit {
+		violations = append(violations, "must contain a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		violations = append(violations, "must contain a symbol")
+	}
+	if p.RejectCommon && p.isCommon(password) {
+		violations = append(violations, "is too common")
+	}
+	return violations
+}
+
+func (p Policy) isCommon(password string) bool {
+	for _, lists := range [][]string{CommonPasswords, p.Disallowed} {
+		for _, common := range lists {
+			if strings.EqualFold(password, common) {
+				return true
+			}
+		}
+	}
+	return false
+}
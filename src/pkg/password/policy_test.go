@@ -0,0 +1,49 @@
+package password
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestPolicy_Check(t *testing.T) {
+	strict := Policy{
+		MinLength:     8,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+		RejectCommon:  true,
+		Disallowed:    []string{"Acme-2026!"},
+	}
+	tests := []struct {
+		name       string
+		policy     Policy
+		password   string
+		violations []string
+	}{
+		{"zero policy", Policy{}, "", nil},
+		{"strict pass", strict, "Tr0ub4dor&3", nil},
+		{"unicode pass", strict, "Ünïcödé-1x", nil},
+		{"too short", strict, "Ab1!", []string{"must be at least 8 characters long"}},
+		{"no upper", strict, "tr0ub4dor&3", []string{"must contain an uppercase letter"}},
+		{"no lower", strict, "TR0UB4DOR&3", []string{"must contain a lowercase letter"}},
+		{"no digit", strict, "Troubador&x", []string{"must contain a digit"}},
+		{"no symbol", strict, "Tr0ub4dor33", []string{"must contain a symbol"}},
+		{"common", Policy{RejectCommon: true}, "Password1", []string{"is too common"}},
+		{"disallowed", strict, "acme-2026!", []string{"must contain an uppercase letter", "is too common"}},
+		{"common allowed", Policy{}, "password", nil},
+		{"longest", Policy{}, strings.Repeat("a", MaxLength), nil},
+		{"too long", Policy{}, strings.Repeat("a", MaxLength+1), []string{"must be at most 72 characters long"}},
+		{"too many bytes", Policy{}, strings.Repeat("é", MaxLength/2+1), []string{"must be at most 72 bytes long in UTF-8"}},
+		{"several", strict, "abc", []string{
+			"must be at least 8 characters long",
+			"must contain an uppercase letter",
+			"must contain a digit",
+			"must contain a symbol",
+		}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.violations, tt.policy.Check(tt.password), tt.name)
+	}
+}
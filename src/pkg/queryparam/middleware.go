@@ -0,0 +1,30 @@
+// Package queryparam provides a middleware that checks the query parameters of requests.
+package queryparam
+
+import (
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"net/http"
+	"strings"
+)
+
+// RequireQuery returns a middleware that rejects requests missing any of the given query parameters
+// with 400 Bad Request, listing all the missing ones, before the handler runs.
+// A parameter given without a value, as in "?from=", is missing.
+func RequireQuery(names ...string) routing.Handler {
+	return func(c *routing.Context) error {
+		query := c.Request.URL.Query()
+		var missing []string
+		for _, name := range names {
+			if query.Get(name) == "" {
+				missing = append(missing, name)
+			}
+		}
+		if len(missing) == 1 {
+			return routing.NewHTTPError(http.StatusBadRequest, "missing required query parameter: "+missing[0])
+		}
+		if len(missing) > 1 {
+			return routing.NewHTTPError(http.StatusBadRequest, "missing required query parameters: "+strings.Join(missing, ", "))
+		}
+		return nil
+	}
+}
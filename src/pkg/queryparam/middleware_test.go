@@ -0,0 +1,34 @@
+package queryparam
+
+import (
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireQuery(t *testing.T) {
+	call := func(url string) error {
+		req := httptest.NewRequest("GET", url, nil)
+		return RequireQuery("from", "to")(routing.NewContext(httptest.NewRecorder(), req))
+	}
+
+	assert.Nil(t, call("/reports?from=2020-01-01&to=2020-02-01"))
+	assert.Nil(t, call("/reports?to=2020-02-01&from=2020-01-01&page=2"))
+
+	tests := []struct {
+		name, url, message string
+	}{
+		{"one missing", "/reports?from=2020-01-01", "missing required query parameter: to"},
+		{"one empty", "/reports?from=2020-01-01&to=", "missing required query parameter: to"},
+		{"several missing", "/reports?page=2", "missing required query parameters: from, to"},
+	}
+	for _, tc := range tests {
+		err := call(tc.url)
+		if assert.NotNil(t, err, tc.name) {
+			assert.Equal(t, http.StatusBadRequest, err.(routing.HTTPError).StatusCode(), tc.name)
+			assert.Equal(t, tc.message, err.Error(), tc.name)
+		}
+	}
+}
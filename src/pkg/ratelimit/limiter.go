@@ -0,0 +1,75 @@
+// Package ratelimit provides a middleware that limits the rate of requests of each client.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// pruneThreshold is the number of tracked keys above which idle buckets are removed.
+const pruneThreshold = 10000
+
+// Limit is the number of requests a client may send per minute.
+// Up to that many requests can be sent at once, after which they are allowed at a steady rate.
+type Limit int
+
+// Limiter applies a Limit to each of many keys using token buckets. It is safe for concurrent use.
+type Limiter struct {
+	limit Limit
+	now   func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter creates a limiter applying the given limit to each key.
+func NewLimiter(limit Limit) *Limiter {
+	return &Limiter{limit: limit, now: time.Now, buckets: map[string]*bucket{}}
+}
+
+// rate returns the number of tokens added to a bucket per second.
+func (l *Limiter) rate() float64 {
+	return float64(l.limit) / 60
+}
+
+// Allow reports whether a request for the given key is allowed, consuming a token if so.
+// If the request is not allowed, it also returns how long to wait until it would be.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= pruneThreshold {
+			l.prune(now)
+		}
+		b = &bucket{tokens: float64(l.limit), last: now}
+		l.buckets[key] = b
+	} else {
+		b.tokens = math.Min(float64(l.limit), b.tokens+now.Sub(b.last).Seconds()*l.rate())
+		b.last = now
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / l.rate() * float64(time.Second))
+	return false, wait
+}
+
+// prune removes the buckets that have refilled completely, as they are equivalent to new ones.
+func (l *Limiter) prune(now time.Time) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate() >= float64(l.limit) {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package ratelimit
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	now := time.Now()
+	l := NewLimiter(3)
+	l.now = func() time.Time { return now }
+
+	// the whole limit can be used at once
+	for i := 0; i < 3; i++ {
+		ok, _ := l.Allow("a")
+		assert.True(t, ok)
+	}
+	ok, wait := l.Allow("a")
+	assert.False(t, ok)
+	assert.Equal(t, 20*time.Second, wait)
+
+	// keys are limited separately
+	ok, _ = l.Allow("b")
+	assert.True(t, ok)
+
+	// a token is added every 20 seconds
+	now = now.Add(20 * time.Second)
+	ok, _ = l.Allow("a")
+	assert.True(t, ok)
+	ok, _ = l.Allow("a")
+	assert.False(t, ok)
+
+	// buckets never hold more than the limit
+	now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		ok, _ := l.Allow("a")
+		assert.True(t, ok)
+	}
+	ok, _ = l.Allow("a")
+	assert.False(t, ok)
+}
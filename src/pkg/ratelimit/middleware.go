@@ -0,0 +1,145 @@
+package ratelimit
+
+import (
+	"context"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Options represents the options of the rate limiting middleware.
+type Options struct {
+	// Anonymous is the limit of each client IP sending unauthenticated requests. Not limited if 0.
+	Anonymous Limit
+	// Authenticated is the limit of each authenticated user, regardless of the IPs the requests come from.
+	// Not limited if 0.
+	Authenticated Limit
+	// UserID returns the ID of the user who sent the request, or an empty string if the request is not
+	// authenticated. All requests are treated as anonymous if nil.
+	UserID func(*http.Request) string
+	// ClientIP returns the IP address of the client sending the request. The remote address is used if nil.
+	ClientIP func(*http.Request) string
+	// TenantID returns the ID of the tenant the request is sent for, or an empty string if there is none.
+	// The tenant limits and quotas don't apply if nil.
+	TenantID func(*http.Request) string
+	// Tenant is the limit of each tenant, shared by all of its users and clients. Not limited if 0.
+	Tenant Limit
+	// Tenants overrides Tenant for the tenants with the given IDs.
+	Tenants map[string]Limit
+	// DailyQuota is the number of requests each tenant may send per day (UTC). Not limited if 0.
+	DailyQuota int
+	// DailyQuotas overrides DailyQuota for the tenants with the given IDs.
+	DailyQuotas map[string]int
+	// Quotas counts the daily requests of the tenants. The quotas are only enforced if set.
+	Quotas QuotaCounter
+	// Error returns the error of the rejected requests given the number of seconds until the client may retry.
+	// It is rendered by the error handler like the other errors, so it should have the status 429.
+	// If nil, the request fails with a plain 429 HTTP error.
+	Error func(retryAfter int) error
+	// Now returns the current time. It defaults to time.Now and is meant to be replaced in tests.
+	Now func() time.Time
+}
+
+// QuotaCounter counts the requests of each tenant per day, typically in a database shared by the servers.
+type QuotaCounter interface {
+	// Increment counts a request of the tenant on the given day, formatted as "2006-01-02",
+	// and returns the number of requests of the tenant on that day, including this one.
+	Increment(ctx context.Context, tenantID, day string) (int, error)
+}
+
+// Handler returns a middleware that limits the request rate of each client.
+// Authenticated requests are counted per user ID and anonymous ones per client IP, each tier with its own limit.
+// Requests over the limit are rejected with 429 and a Retry-After header giving the number of seconds,
+// rounded up, until the client's next request would be allowed.
+//
+// Requests with a tenant are also counted against the limit of their tenant first, then, once allowed by
+// the rate limits, against the daily quota of the tenant. Requests over the quota are rejected with 429
+// until the next day. They are let through if the quota can't be counted, e.g. while the database is down.
+func Handler(options Options) routing.Handler {
+	if options.UserID == nil {
+		options.UserID = func(*http.Request) string { return "" }
+	}
+	if options.ClientIP == nil {
+		options.ClientIP = func(req *http.Request) string {
+			host, _, _ := net.SplitHostPort(req.RemoteAddr)
+			return host
+		}
+	}
+	if options.TenantID == nil {
+		options.TenantID = func(*http.Request) string { return "" }
+	}
+	if options.Now == nil {
+		options.Now = time.Now
+	}
+	anonymous := NewLimiter(options.Anonymous)
+	authenticated := NewLimiter(options.Authenticated)
+	anonymous.now, authenticated.now = options.Now, options.Now
+	// the tenants with a limit of their own have a limiter of their own
+	tenant := NewLimiter(options.Tenant)
+	tenant.now = options.Now
+	tenants := map[string]*Limiter{}
+	for id, limit := range options.Tenants {
+		tenants[id] = NewLimiter(limit)
+		tenants[id].now = options.Now
+	}
+
+	return func(c *routing.Context) error {
+		tenantID := options.TenantID(c.Request)
+		if tenantID != "" {
+			limiter, ok := tenants[tenantID]
+			if !ok {
+				limiter = tenant
+			}
+			if ok, wait := allow(limiter, tenantID); !ok {
+				return reject(c, wait, options.Error)
+			}
+		}
+
+		limiter, key := anonymous, options.ClientIP(c.Request)
+		if id := options.UserID(c.Request); id != "" {
+			limiter, key = authenticated, id
+		}
+		if ok, wait := allow(limiter, key); !ok {
+			return reject(c, wait, options.Error)
+		}
+
+		if tenantID == "" || options.Quotas == nil {
+			return nil
+		}
+		quota, ok := options.DailyQuotas[tenantID]
+		if !ok {
+			quota = options.DailyQuota
+		}
+		if quota <= 0 {
+			return nil
+		}
+		now := options.Now().UTC()
+		count, err := options.Quotas.Increment(c.Request.Context(), tenantID, now.Format("2006-01-02"))
+		if err != nil || count <= quota {
+			return nil
+		}
+		tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+		return reject(c, tomorrow.Sub(now), options.Error)
+	}
+}
+
+// allow reports whether the limiter allows a request for the key. Requests are always allowed if it has no limit.
+func allow(limiter *Limiter, key string) (bool, time.Duration) {
+	if limiter.limit <= 0 {
+		return true, 0
+	}
+	return limiter.Allow(key)
+}
+
+// reject fails the request with 429, telling the client to retry after the given time rounded up to seconds.
+func reject(c *routing.Context, wait time.Duration, newError func(retryAfter int) error) error {
+	retryAfter := int(math.Ceil(wait.Seconds()))
+	c.Response.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	if newError == nil {
+		return routing.NewHTTPError(http.StatusTooManyRequests)
+	}
+	return newError(retryAfter)
+}
@@ -0,0 +1,215 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler(t *testing.T) {
+	handler := Handler(Options{
+		Anonymous:     2,
+		Authenticated: 4,
+		UserID:        func(req *http.Request) string { return req.Header.Get("X-User") },
+	})
+	call := func(user, addr string) (int, string) {
+		req, _ := http.NewRequest("GET", "/albums", nil)
+		req.RemoteAddr = addr
+		req.Header.Set("X-User", user)
+		res := httptest.NewRecorder()
+		err := handler(routing.NewContext(res, req))
+		if err != nil {
+			return err.(routing.HTTPError).StatusCode(), res.Header().Get("Retry-After")
+		}
+		return http.StatusOK, ""
+	}
+
+	t.Run("anonymous", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			status, _ := call("", "10.0.0.1:1000")
+			assert.Equal(t, http.StatusOK, status)
+		}
+		status, retryAfter := call("", "10.0.0.1:1001")
+		assert.Equal(t, http.StatusTooManyRequests, status)
+		assert.Equal(t, "30", retryAfter)
+
+		// another IP has its own limit
+		status, _ = call("", "10.0.0.2:1000")
+		assert.Equal(t, http.StatusOK, status)
+	})
+
+	t.Run("authenticated", func(t *testing.T) {
+		// the user is limited across IPs, including one whose anonymous limit is exhausted
+		for i := 0; i < 4; i++ {
+			status, _ := call("100", "10.0.0.1:1000")
+			assert.Equal(t, http.StatusOK, status)
+		}
+		status, retryAfter := call("100", "10.0.0.3:1000")
+		assert.Equal(t, http.StatusTooManyRequests, status)
+		assert.Equal(t, "15", retryAfter)
+
+		// another user has their own limit
+		status, _ = call("101", "10.0.0.3:1000")
+		assert.Equal(t, http.StatusOK, status)
+	})
+}
+
+func TestHandler_unlimited(t *testing.T) {
+	handler := Handler(Options{Authenticated: 1, UserID: func(req *http.Request) string { return "" }})
+	for i := 0; i < 100; i++ {
+		req, _ := http.NewRequest("GET", "/albums", nil)
+		assert.Nil(t, handler(routing.NewContext(httptest.NewRecorder(), req)))
+	}
+}
+
+func TestHandler_retryAfter(t *testing.T) {
+	now := time.Now()
+	router := routing.New()
+	router.Use(Handler(Options{
+		Anonymous: 2,
+		Now:       func() time.Time { return now },
+		Error: func(retryAfter int) error {
+			return routing.NewHTTPError(http.StatusTooManyRequests, fmt.Sprintf("slow down for %vs", retryAfter))
+		},
+	}))
+	router.Get("/albums", func(c *routing.Context) error { return c.Write("ok") })
+	call := func() *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/albums", nil)
+		req.RemoteAddr = "10.0.0.1:1000"
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		return res
+	}
+
+	// a token is added every 30 seconds
+	assert.Equal(t, http.StatusOK, call().Code)
+	assert.Equal(t, http.StatusOK, call().Code)
+	tests := []struct {
+		elapsed    time.Duration
+		retryAfter string
+	}{
+		{0, "30"},
+		{10 * time.Second, "20"},
+		{29500 * time.Millisecond, "1"},
+	}
+	start := now
+	for _, tt := range tests {
+		now = start.Add(tt.elapsed)
+		res := call()
+		assert.Equal(t, http.StatusTooManyRequests, res.Code)
+		assert.Equal(t, tt.retryAfter, res.Header().Get("Retry-After"))
+		assert.Equal(t, "slow down for "+tt.retryAfter+"s", strings.TrimSpace(res.Body.String()))
+	}
+
+	now = start.Add(30 * time.Second)
+	res := call()
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "ok", res.Body.String())
+}
+
+// quotaCounter counts the daily requests of the tenants in memory.
+type quotaCounter struct {
+	counts map[string]int
+	err    error
+}
+
+func (q *quotaCounter) Increment(ctx context.Context, tenantID, day string) (int, error) {
+	if q.err != nil {
+		return 0, q.err
+	}
+	q.counts[tenantID+"|"+day]++
+	return q.counts[tenantID+"|"+day], nil
+}
+
+func TestHandler_tenants(t *testing.T) {
+	handler := Handler(Options{
+		Authenticated: 100,
+		UserID:        func(req *http.Request) string { return req.Header.Get("X-User") },
+		TenantID:      func(req *http.Request) string { return req.Header.Get("X-Tenant-ID") },
+		Tenant:        2,
+		Tenants:       map[string]Limit{"acme": 3},
+	})
+	call := func(tenant, user string) int {
+		req, _ := http.NewRequest("GET", "/albums", nil)
+		req.RemoteAddr = "10.0.0.1:1000"
+		req.Header.Set("X-Tenant-ID", tenant)
+		req.Header.Set("X-User", user)
+		if err := handler(routing.NewContext(httptest.NewRecorder(), req)); err != nil {
+			return err.(routing.HTTPError).StatusCode()
+		}
+		return http.StatusOK
+	}
+
+	// the users of a tenant share its limit, and the tenants don't affect each other
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, call("acme", "user"+strconv.Itoa(i)))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, call("acme", "other"))
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, http.StatusOK, call("globex", "user"+strconv.Itoa(i)))
+	}
+	assert.Equal(t, http.StatusTooManyRequests, call("globex", "other"))
+	assert.Equal(t, http.StatusTooManyRequests, call("acme", "user0"))
+
+	// requests without a tenant are only limited per user
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, http.StatusOK, call("", "user0"))
+	}
+}
+
+func TestHandler_quotas(t *testing.T) {
+	now := time.Date(2026, 10, 16, 23, 0, 0, 0, time.UTC)
+	quotas := &quotaCounter{counts: map[string]int{}}
+	handler := Handler(Options{
+		TenantID:    func(req *http.Request) string { return req.Header.Get("X-Tenant-ID") },
+		DailyQuota:  2,
+		DailyQuotas: map[string]int{"acme": 3},
+		Quotas:      quotas,
+		Now:         func() time.Time { return now },
+	})
+	call := func(tenant string) (int, string) {
+		req, _ := http.NewRequest("GET", "/albums", nil)
+		req.Header.Set("X-Tenant-ID", tenant)
+		res := httptest.NewRecorder()
+		if err := handler(routing.NewContext(res, req)); err != nil {
+			return err.(routing.HTTPError).StatusCode(), res.Header().Get("Retry-After")
+		}
+		return http.StatusOK, ""
+	}
+
+	for i := 0; i < 3; i++ {
+		status, _ := call("acme")
+		assert.Equal(t, http.StatusOK, status)
+	}
+	status, retryAfter := call("acme")
+	assert.Equal(t, http.StatusTooManyRequests, status)
+	// the quota is reset at midnight UTC
+	assert.Equal(t, "3600", retryAfter)
+	for i := 0; i < 2; i++ {
+		status, _ := call("globex")
+		assert.Equal(t, http.StatusOK, status)
+	}
+	status, _ = call("globex")
+	assert.Equal(t, http.StatusTooManyRequests, status)
+	assert.Equal(t, 4, quotas.counts["acme|2026-10-16"])
+
+	now = now.Add(time.Hour)
+	status, _ = call("acme")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, 1, quotas.counts["acme|2026-10-17"])
+
+	// requests are let through when the quota can't be counted
+	quotas.err = errors.New("database is down")
+	for i := 0; i < 5; i++ {
+		status, _ := call("globex")
+		assert.Equal(t, http.StatusOK, status)
+	}
+}
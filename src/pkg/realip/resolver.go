@@ -0,0 +1,103 @@
+// Package realip resolves the client information of requests passing through trusted reverse proxies.
+package realip
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Resolver determines the client IP, scheme and host of HTTP requests.
+// Forwarding headers (X-Forwarded-For, X-Forwarded-Proto, X-Forwarded-Host) are only
+// honored when the request comes from a trusted proxy.
+type Resolver struct {
+	trusted []*net.IPNet
+}
+
+// New creates a Resolver trusting the given proxies, specified as IP addresses or CIDR ranges.
+func New(trustedProxies []string) (*Resolver, error) {
+	r := &Resolver{}
+	for _, proxy := range trustedProxies {
+		if !strings.Contains(proxy, "/") {
+			if strings.Contains(proxy, ":") {
+				proxy += "/128"
+			} else {
+				proxy += "/32"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %v", proxy, err)
+		}
+		r.trusted = append(r.trusted, ipNet)
+	}
+	return r, nil
+}
+
+// ClientIP returns the IP address of the client sending the request.
+// If the request comes from a trusted proxy, X-Forwarded-For is examined from right to left
+// and the first address which is not a trusted proxy is returned.
+func (r *Resolver) ClientIP(req *http.Request) string {
+	ip := remoteIP(req)
+	if !r.isTrusted(ip) {
+		return ip
+	}
+	forwarded := strings.Split(req.Header.Get("X-Forwarded-For"), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		addr := strings.TrimSpace(forwarded[i])
+		if addr == "" {
+			continue
+		}
+		ip = addr
+		if !r.isTrusted(addr) {
+			break
+		}
+	}
+	return ip
+}
+
+// Scheme returns the scheme (http or https) used by the client.
+func (r *Resolver) Scheme(req *http.Request) string {
+	if r.isTrusted(remoteIP(req)) {
+		if proto := req.Header.Get("X-Forwarded-Proto"); proto != "" {
+			return strings.ToLower(proto)
+		}
+	}
+	if req.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// Host returns the host requested by the client.
+func (r *Resolver) Host(req *http.Request) string {
+	if r.isTrusted(remoteIP(req)) {
+		if host := req.Header.Get("X-Forwarded-Host"); host != "" {
+			return host
+		}
+	}
+	return req.Host
+}
+
+// isTrusted checks if the given IP address belongs to a trusted proxy.
+func (r *Resolver) isTrusted(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range r.trusted {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteIP returns the IP address of the peer that sent the request.
+func remoteIP(req *http.Request) string {
+	if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		return host
+	}
+	return req.RemoteAddr
+}
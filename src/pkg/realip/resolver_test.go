@@ -0,0 +1,58 @@
+package realip
+
+import (
+	"crypto/tls"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	_, err := New([]string{"10.0.0.1", "192.168.0.0/16", "::1"})
+	assert.Nil(t, err)
+	_, err = New([]string{"abc"})
+	assert.NotNil(t, err)
+}
+
+func TestResolver_ClientIP(t *testing.T) {
+	r, _ := New([]string{"10.0.0.0/8"})
+	tests := []struct {
+		name, remoteAddr, forwardedFor, want string
+	}{
+		{"direct", "1.2.3.4:1234", "", "1.2.3.4"},
+		{"untrusted proxy", "1.2.3.4:1234", "5.6.7.8", "1.2.3.4"},
+		{"trusted proxy", "10.0.0.1:1234", "5.6.7.8", "5.6.7.8"},
+		{"spoofed header behind trusted proxy", "10.0.0.1:1234", "6.6.6.6, 5.6.7.8", "5.6.7.8"},
+		{"chain of trusted proxies", "10.0.0.1:1234", "5.6.7.8, 10.0.0.2", "5.6.7.8"},
+		{"trusted proxy without header", "10.0.0.1:1234", "", "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "http://example.com", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			assert.Equal(t, tt.want, r.ClientIP(req))
+		})
+	}
+}
+
+func TestResolver_SchemeAndHost(t *testing.T) {
+	r, _ := New([]string{"10.0.0.1"})
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	req.Header.Set("X-Forwarded-Proto", "HTTPS")
+	req.Header.Set("X-Forwarded-Host", "api.example.com")
+
+	req.RemoteAddr = "1.2.3.4:1234"
+	assert.Equal(t, "http", r.Scheme(req))
+	assert.Equal(t, "example.com", r.Host(req))
+
+	req.RemoteAddr = "10.0.0.1:1234"
+	assert.Equal(t, "https", r.Scheme(req))
+	assert.Equal(t, "api.example.com", r.Host(req))
+
+	req, _ = http.NewRequest("GET", "https://example.com", nil)
+	req.TLS = &tls.ConnectionState{}
+	assert.Equal(t, "https", r.Scheme(req))
+}
@@ -0,0 +1,37 @@
+package redirect
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAllowlist_Validate(t *testing.T) {
+	a := NewAllowlist("app.example.com", "Admin.example.com:8443")
+	tests := []struct {
+		target  string
+		allowed bool
+	}{
+		{"https://app.example.com/dashboard?tab=1", true},
+		{"http://APP.example.com", true},
+		{"https://admin.example.com:8443/", true},
+		{"/dashboard", true},
+		{"https://evil.com/", false},
+		{"https://app.example.com.evil.com/", false},
+		{"https://admin.example.com/", false},
+		{"https://app.example.com@evil.com/", false},
+		{"//evil.com", false},
+		{"/\\evil.com", false},
+		{"javascript:alert(1)", false},
+		{"ftp://app.example.com/", false},
+		{"dashboard", false},
+		{"%zz", false},
+	}
+	for _, tt := range tests {
+		err := a.Validate(tt.target)
+		if tt.allowed {
+			assert.Nil(t, err, tt.target)
+		} else {
+			assert.Equal(t, ErrNotAllowed, err, tt.target)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Package restart restarts a server without downtime by handing its listening socket over to a new process,
+// which starts accepting connections while the old one finishes its in-flight requests.
+//
+// The new process runs the same executable with the same arguments. It gets the socket from Listen and calls
+// Ready once it can serve requests, upon which Restart returns in the old process, which may then shut down.
+package restart
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// Environment variables telling a restarted process the descriptors of the files it inherited.
+const (
+	// ListenerEnv names the variable holding the descriptor of the listening socket.
+	ListenerEnv = "RESTART_LISTENER_FD"
+	// ReadyEnv names the variable holding the descriptor of the pipe closed by Ready.
+	ReadyEnv = "RESTART_READY_FD"
+)
+
+// the descriptors of the inherited files: 0 to 2 are the standard streams, followed by the extra files.
+const (
+	listenerFD = 3
+	readyFD    = 4
+)
+
+// ready is the pipe closed by Ready, or nil if the process was not started by Restart.
+var ready *os.File
+
+// Listen returns the listening socket inherited from the process that started this one with Restart,
+// and true, or a new TCP listener on the address and false if the process was not started by Restart.
+func Listen(address string) (net.Listener, bool, error) {
+	value := os.Getenv(ListenerEnv)
+	if value == "" {
+		ln, err := net.Listen("tcp", address)
+		return ln, false, err
+	}
+	// the descriptors are not passed on to the processes started by this one
+	os.Unsetenv(ListenerEnv)
+	fd, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid %v: %w", ListenerEnv, err)
+	}
+	if value := os.Getenv(ReadyEnv); value != "" {
+		os.Unsetenv(ReadyEnv)
+		if fd, err := strconv.Atoi(value); err == nil {
+			ready = os.NewFile(uintptr(fd), "ready")
+		}
+	}
+	f := os.NewFile(uintptr(fd), "listener")
+	defer f.Close()
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil, false, fmt.Errorf("inherited listener: %w", err)
+	}
+	return ln, true, nil
+}
+
+// Ready tells the process that started this one with Restart that this process serves requests,
+// so that it can shut down. It does nothing if the process was not started by Restart.
+func Ready() {
+	if ready != nil {
+		ready.Write([]byte{1})
+		ready.Close()
+		ready = nil
+	}
+}
+
+// Restart starts a new process of the current executable with the same arguments and environment,
+// which inherits the listening socket, and waits until it calls Ready. The new process is killed
+// if it is not ready within the timeout, and an error is returned if it exits before being ready.
+func Restart(ln net.Listener, timeout time.Duration) (*os.Process, error) {
+	filer, ok := ln.(interface{ File() (*os.File, error) })
+	if !ok {
+		return nil, fmt.Errorf("the %T listener cannot be handed over", ln)
+	}
+	listener, err := filer.File()
+	if err != nil {
+		return nil, err
+	}
+	defer listener.Close()
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.Env = append(os.Environ(), fmt.Sprintf("%v=%v", ListenerEnv, listenerFD), fmt.Sprintf("%v=%v", ReadyEnv, readyFD))
+	cmd.ExtraFiles = []*os.File{listener, w}
+	err = cmd.Start()
+	// the pipe is closed by the new process once ready, or when it exits
+	w.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		b := make([]byte, 1)
+		if n, _ := r.Read(b); n == 0 {
+			result <- errors.New("the new process exited before being ready")
+		} else {
+			result <- nil
+		}
+	}()
+	select {
+	case err = <-result:
+	case <-time.After(timeout):
+		err = fmt.Errorf("the new process was not ready within %v", timeout)
+	}
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, err
+	}
+	// the new process is not waited for, but its resources are released once it exits
+	go cmd.Wait()
+	return cmd.Process, nil
+}
@@ -0,0 +1,87 @@
+package restart
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestMain makes the test binary serve as the restarted process: started by Restart, it answers
+// a single connection on the inherited listener with its process ID.
+func TestMain(m *testing.M) {
+	if os.Getenv(ListenerEnv) == "" {
+		os.Exit(m.Run())
+	}
+	if os.Getenv("RESTART_TEST_FAIL") != "" {
+		os.Exit(1)
+	}
+	ln, inherited, err := Listen("127.0.0.1:0")
+	if err != nil || !inherited {
+		fmt.Fprintln(os.Stderr, "no inherited listener:", err)
+		os.Exit(1)
+	}
+	if os.Getenv(ListenerEnv) != "" {
+		fmt.Fprintln(os.Stderr, "the listener descriptor is passed on")
+		os.Exit(1)
+	}
+	Ready()
+	conn, err := ln.Accept()
+	if err != nil {
+		os.Exit(1)
+	}
+	fmt.Fprintln(conn, os.Getpid())
+	conn.Close()
+	os.Exit(0)
+}
+
+func TestRestart(t *testing.T) {
+	ln, inherited, err := Listen("127.0.0.1:0")
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.False(t, inherited)
+
+	process, err := Restart(ln, 10*time.Second)
+	if !assert.Nil(t, err) {
+		ln.Close()
+		return
+	}
+	// the old process stops listening, the new one keeps serving on the same address
+	address := ln.Addr().String()
+	ln.Close()
+	conn, err := net.DialTimeout("tcp", address, 5*time.Second)
+	if !assert.Nil(t, err) {
+		process.Kill()
+		return
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	assert.Nil(t, err)
+	assert.Equal(t, strconv.Itoa(process.Pid)+"\n", line)
+}
+
+func TestRestart_failure(t *testing.T) {
+	ln, _, err := Listen("127.0.0.1:0")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer ln.Close()
+
+	os.Setenv("RESTART_TEST_FAIL", "1")
+	defer os.Unsetenv("RESTART_TEST_FAIL")
+	_, err = Restart(ln, 10*time.Second)
+	assert.NotNil(t, err)
+}
+
+func TestListen(t *testing.T) {
+	os.Setenv(ListenerEnv, "x")
+	defer os.Unsetenv(ListenerEnv)
+	_, _, err := Listen("127.0.0.1:0")
+	assert.NotNil(t, err)
+}
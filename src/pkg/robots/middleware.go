@@ -0,0 +1,41 @@
+// Package robots provides a middleware that keeps robots away from selected routes.
+package robots
+
+import (
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"net/http"
+	"pkg/routeinfo"
+	"regexp"
+)
+
+// DefaultPattern matches the user agents of common crawlers and scripted clients.
+const DefaultPattern = `(?i)bot\b|crawl|spider|slurp|curl|wget|python-requests|go-http-client|httpclient|java/`
+
+// Options represents the options of the robot blocking middleware.
+type Options struct {
+	// Routes lists the patterns of the routes that robots are denied, e.g. "/v1/login".
+	// Robots are denied every route if empty.
+	Routes []string
+}
+
+// Handler returns a middleware that rejects the requests whose User-Agent header matches pattern
+// with 403 Forbidden. Requests without a User-Agent are let through, as are all requests if pattern is nil.
+func Handler(pattern *regexp.Regexp, options ...Options) routing.Handler {
+	var opt Options
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	routes := map[string]bool{}
+	for _, route := range opt.Routes {
+		routes[route] = true
+	}
+	return func(c *routing.Context) error {
+		if pattern == nil || len(routes) > 0 && !routes[routeinfo.Pattern(c)] {
+			return nil
+		}
+		if agent := c.Request.UserAgent(); agent != "" && pattern.MatchString(agent) {
+			return routing.NewHTTPError(http.StatusForbidden, "Automated clients are not allowed.")
+		}
+		return nil
+	}
+}
@@ -0,0 +1,62 @@
+package robots
+
+import (
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	router := routing.New()
+	router.Use(Handler(regexp.MustCompile(DefaultPattern), Options{Routes: []string{"/v1/login"}}))
+	ok := func(c *routing.Context) error { return nil }
+	router.Post("/v1/login", ok)
+	router.Get("/v1/albums", ok)
+
+	call := func(method, url, agent string) int {
+		req, _ := http.NewRequest(method, url, nil)
+		req.Header.Set("User-Agent", agent)
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		return res.Code
+	}
+
+	bots := []string{
+		"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+		"Mozilla/5.0 (compatible; bingbot/2.0; +http://www.bing.com/bingbot.htm)",
+		"Mozilla/5.0 (compatible; Yahoo! Slurp; http://help.yahoo.com/help/us/ysearch/slurp)",
+		"Baiduspider+(+http://www.baidu.com/search/spider.htm)",
+		"curl/7.68.0",
+		"python-requests/2.25.1",
+		"Go-http-client/1.1",
+	}
+	for _, agent := range bots {
+		assert.Equal(t, http.StatusForbidden, call("POST", "/v1/login", agent), agent)
+		// robots may use the other routes
+		assert.Equal(t, http.StatusOK, call("GET", "/v1/albums", agent), agent)
+	}
+
+	browsers := []string{
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Safari/605.1.15",
+		"",
+	}
+	for _, agent := range browsers {
+		assert.Equal(t, http.StatusOK, call("POST", "/v1/login", agent), agent)
+	}
+}
+
+func TestHandler_allRoutes(t *testing.T) {
+	handler := Handler(regexp.MustCompile(`(?i)bot`))
+	req, _ := http.NewRequest("GET", "/v1/albums", nil)
+	req.Header.Set("User-Agent", "AhrefsBot/7.0")
+	err := handler(routing.NewContext(httptest.NewRecorder(), req))
+	if assert.NotNil(t, err) {
+		assert.Equal(t, http.StatusForbidden, err.(routing.HTTPError).StatusCode())
+	}
+
+	assert.Nil(t, Handler(nil)(routing.NewContext(httptest.NewRecorder(), req)))
+}
@@ -0,0 +1,105 @@
+// Package routeinfo provides information about the route matching the current request.
+package routeinfo
+
+import (
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// patternKey is the key of the routing context data storing the resolved route pattern.
+const patternKey = "routeinfo.pattern"
+
+// matchers caches the compiled regular expressions of the routes.
+var matchers sync.Map
+
+type matcher struct {
+	re      *regexp.Regexp
+	params  int
+	literal int
+}
+
+// Pattern returns the pattern of the route (e.g. "/v1/albums/<id>") matching the request being processed.
+// An empty string is returned if no route matches the request.
+// When several routes match, the most specific one (the one with the fewest parameters and the
+// most literal characters) is returned, which is consistent with how the router dispatches.
+func Pattern(c *routing.Context) string {
+	if pattern, ok := c.Get(patternKey).(string); ok {
+		return pattern
+	}
+	pattern := ""
+	if router := c.Router(); router != nil {
+		var best *matcher
+		path := c.Request.URL.Path
+		for _, route := range router.Routes() {
+			if route.Method() != c.Request.Method {
+				continue
+			}
+			m := getMatcher(route)
+			if !m.re.MatchString(path) {
+				continue
+			}
+			if best == nil || m.params < best.params || m.params == best.params && m.literal > best.literal {
+				best, pattern = m, route.Path()
+			}
+		}
+	}
+	c.Set(patternKey, pattern)
+	return pattern
+}
+
+// getMatcher returns the matcher for the given route, compiling it on first use.
+func getMatcher(route *routing.Route) *matcher {
+	if m, ok := matchers.Load(route); ok {
+		return m.(*matcher)
+	}
+	m := compile(route.Path())
+	matchers.Store(route, m)
+	return m
+}
+
+// compile converts a route path into a matcher.
+// Parameter tokens "<name>" match any characters except "/", while "<name:regexp>" match the given
+// regular expression. A trailing asterisk matches any number of characters.
+func compile(path string) *matcher {
+	m := &matcher{}
+	original := path
+	wildcard := strings.HasSuffix(path, "*")
+	if wildcard {
+		path = path[:len(path)-1]
+	}
+
+	expr := "^"
+	for len(path) > 0 {
+		start := strings.IndexByte(path, '<')
+		end := strings.IndexByte(path, '>')
+		if start < 0 || end < start {
+			expr += regexp.QuoteMeta(path)
+			m.literal += len(path)
+			break
+		}
+		expr += regexp.QuoteMeta(path[:start])
+		m.literal += start
+		token := path[start+1 : end]
+		if i := strings.IndexByte(token, ':'); i >= 0 {
+			expr += "(?:" + token[i+1:] + ")"
+		} else {
+			expr += "[^/]*"
+		}
+		m.params++
+		path = path[end+1:]
+	}
+	if wildcard {
+		expr += ".*"
+		m.params++
+	}
+
+	re, err := regexp.Compile(expr + "$")
+	if err != nil {
+		// fall back to an exact match of the pattern
+		re = regexp.MustCompile("^" + regexp.QuoteMeta(original) + "$")
+	}
+	m.re = re
+	return m
+}
@@ -0,0 +1,49 @@
+package routeinfo
+
+import (
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPattern(t *testing.T) {
+	router := routing.New()
+	var pattern string
+	handler := func(c *routing.Context) error {
+		pattern = Pattern(c)
+		return nil
+	}
+	router.NotFound(handler)
+	router.Get("/healthcheck", handler)
+	v1 := router.Group("/v1")
+	v1.Get("/test2/<id>", handler)
+	v1.Get("/test2/new", handler)
+	v1.Put(`/test4/<id:\d+>`, handler)
+	v1.Get("/files/*", handler)
+
+	tests := []struct {
+		method, url, pattern string
+	}{
+		{"GET", "/healthcheck", "/healthcheck"},
+		{"GET", "/v1/test2/123", "/v1/test2/<id>"},
+		{"GET", "/v1/test2/new", "/v1/test2/new"},
+		{"PUT", "/v1/test4/123", `/v1/test4/<id:\d+>`},
+		{"PUT", "/v1/test4/abc", ""},
+		{"GET", "/v1/files/a/b.txt", "/v1/files/*"},
+		{"GET", "/unknown", ""},
+	}
+	for _, tt := range tests {
+		pattern = "-"
+		req, _ := http.NewRequest(tt.method, tt.url, nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		assert.Equal(t, tt.pattern, pattern, tt.url)
+	}
+}
+
+func TestPattern_noRouter(t *testing.T) {
+	req, _ := http.NewRequest("GET", "http://127.0.0.1/users", nil)
+	c := routing.NewContext(httptest.NewRecorder(), req)
+	assert.Equal(t, "", Pattern(c))
+}
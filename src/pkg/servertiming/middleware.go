@@ -0,0 +1,138 @@
+// Package servertiming provides a middleware that reports in the Server-Timing response header
+// how the time spent on a request breaks down.
+package servertiming
+
+import (
+	"context"
+	"fmt"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HeaderName is the name of the response header carrying the timing metrics.
+const HeaderName = "Server-Timing"
+
+// Metric names reported by Handler.
+const (
+	// DB is the metric for the time spent in database queries and executions.
+	DB = "db"
+	// App is the metric for the total time spent on the request until the response is written.
+	App = "app"
+)
+
+// Timing collects the durations of the metrics of a request.
+// It is safe for concurrent use.
+type Timing struct {
+	mu        sync.Mutex
+	names     []string
+	durations map[string]time.Duration
+}
+
+// Add adds a duration to the named metric.
+func (t *Timing) Add(name string, d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.durations == nil {
+		t.durations = map[string]time.Duration{}
+	}
+	if _, ok := t.durations[name]; !ok {
+		t.names = append(t.names, name)
+	}
+	t.durations[name] += d
+}
+
+// String formats the metrics as the value of a Server-Timing header, e.g. "db;dur=1.25, app;dur=3.5".
+// Durations are in milliseconds. The metrics are listed in the order in which they were first added.
+func (t *Timing) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	metrics := make([]string, len(t.names))
+	for i, name := range t.names {
+		metrics[i] = fmt.Sprintf("%s;dur=%.2f", name, float64(t.durations[name])/float64(time.Millisecond))
+	}
+	return strings.Join(metrics, ", ")
+}
+
+type contextKey int
+
+const timingKey contextKey = iota
+
+// WithTiming returns a context that contains the given timing.
+func WithTiming(ctx context.Context, t *Timing) context.Context {
+	return context.WithValue(ctx, timingKey, t)
+}
+
+// FromContext returns the timing stored in the given context, or nil if there is none.
+func FromContext(ctx context.Context) *Timing {
+	t, _ := ctx.Value(timingKey).(*Timing)
+	return t
+}
+
+// Record adds a duration to the named metric of the timing stored in the given context.
+// It does nothing if the context contains no timing, so it can be called unconditionally,
+// e.g. from the dbx query and execution log functions.
+func Record(ctx context.Context, name string, d time.Duration) {
+	if ctx == nil {
+		return
+	}
+	if t := FromContext(ctx); t != nil {
+		t.Add(name, d)
+	}
+}
+
+// Handler returns a middleware that adds a Server-Timing header to every response.
+// The header always reports the "app" metric, plus the "db" metric if any database time
+// has been recorded for the request using Record. Since headers cannot be changed once the
+// response has started, the metrics cover the time until the response header is written.
+func Handler() routing.Handler {
+	return func(c *routing.Context) error {
+		timing := &Timing{}
+		rw := &responseWriter{ResponseWriter: c.Response, timing: timing, start: time.Now()}
+		c.Response = rw
+		c.Request = c.Request.WithContext(WithTiming(c.Request.Context(), timing))
+		err := c.Next()
+		if err == nil {
+			// make sure the header is sent if the handler has not written anything
+			rw.writeTiming()
+		}
+		return err
+	}
+}
+
+// responseWriter sets the Server-Timing header right before the response header is written.
+type responseWriter struct {
+	http.ResponseWriter
+	timing  *Timing
+	start   time.Time
+	written bool
+}
+
+func (w *responseWriter) writeTiming() {
+	if w.written {
+		return
+	}
+	w.written = true
+	w.timing.Add(App, time.Since(w.start))
+	w.Header().Set(HeaderName, w.timing.String())
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	w.writeTiming()
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.writeTiming()
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush sends any buffered data to the client if the underlying writer supports it.
+func (w *responseWriter) Flush() {
+	w.writeTiming()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
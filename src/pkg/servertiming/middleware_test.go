@@ -0,0 +1,47 @@
+package servertiming
+
+import (
+	"context"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestHandler(t *testing.T) {
+	router := routing.New()
+	router.Use(Handler())
+	router.Get("/albums", func(c *routing.Context) error {
+		// simulates the dbx log functions reporting two queries
+		Record(c.Request.Context(), DB, 2*time.Millisecond)
+		Record(c.Request.Context(), DB, 3*time.Millisecond)
+		return c.Write("ok")
+	})
+	router.Get("/empty", func(c *routing.Context) error { return nil })
+
+	req, _ := http.NewRequest("GET", "/albums", nil)
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	header := res.Header().Get(HeaderName)
+	assert.Regexp(t, regexp.MustCompile(`^db;dur=5\.00, app;dur=\d+\.\d{2}$`), header)
+
+	req, _ = http.NewRequest("GET", "/empty", nil)
+	res = httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	assert.Regexp(t, regexp.MustCompile(`^app;dur=\d+\.\d{2}$`), res.Header().Get(HeaderName))
+}
+
+func TestRecord(t *testing.T) {
+	// no timing in the context
+	Record(context.Background(), DB, time.Millisecond)
+
+	timing := &Timing{}
+	ctx := WithTiming(context.Background(), timing)
+	assert.Equal(t, timing, FromContext(ctx))
+	Record(ctx, DB, 1500*time.Microsecond)
+	Record(ctx, "cache", time.Millisecond)
+	assert.Equal(t, "db;dur=1.50, cache;dur=1.00", timing.String())
+}
@@ -0,0 +1,72 @@
+package signature
+
+import (
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler(t *testing.T) {
+	secret := []byte("shared-secret")
+	now := time.Unix(1700000000, 0)
+	handler := Handler(secret, Options{Window: time.Minute, Now: func() time.Time { return now }, MaxBytes: 32})
+
+	newRequest := func(body string, signedBody string, at time.Time) *http.Request {
+		timestamp := strconv.FormatInt(at.Unix(), 10)
+		req, _ := http.NewRequest("POST", "http://127.0.0.1/internal/albums?limit=10", strings.NewReader(body))
+		req.Header.Set(HeaderTimestamp, timestamp)
+		req.Header.Set(HeaderSignature, Sign(secret, "POST", "/internal/albums?limit=10", timestamp, []byte(signedBody)))
+		return req
+	}
+	tamperedQuery := newRequest(`{"name":"abc"}`, `{"name":"abc"}`, now)
+	tamperedQuery.URL.RawQuery = "limit=1000"
+
+	tests := []struct {
+		name    string
+		req     *http.Request
+		wantErr string
+	}{
+		{"valid signature", newRequest(`{"name":"abc"}`, `{"name":"abc"}`, now.Add(-30*time.Second)), ""},
+		{"tampered body", newRequest(`{"name":"xyz"}`, `{"name":"abc"}`, now), "invalid signature"},
+		{"tampered query", tamperedQuery, "invalid signature"},
+		{"expired timestamp", newRequest(`{"name":"abc"}`, `{"name":"abc"}`, now.Add(-2*time.Minute)), "outside the allowed window"},
+		{"future timestamp", newRequest(`{"name":"abc"}`, `{"name":"abc"}`, now.Add(2*time.Minute)), "outside the allowed window"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := routing.NewContext(httptest.NewRecorder(), tt.req)
+			err := handler(c)
+			if tt.wantErr == "" {
+				assert.Nil(t, err)
+				// the body can still be read by the handlers
+				body, _ := ioutil.ReadAll(c.Request.Body)
+				assert.Equal(t, `{"name":"abc"}`, string(body))
+				return
+			}
+			if assert.NotNil(t, err) {
+				assert.Equal(t, http.StatusUnauthorized, err.(routing.HTTPError).StatusCode())
+				assert.Contains(t, err.Error(), tt.wantErr)
+			}
+		})
+	}
+
+	// the body is not read beyond the limit
+	large := strings.Repeat("x", 33)
+	err := handler(routing.NewContext(httptest.NewRecorder(), newRequest(large, large, now)))
+	if assert.NotNil(t, err) {
+		assert.Equal(t, http.StatusRequestEntityTooLarge, err.(routing.HTTPError).StatusCode())
+	}
+
+	// missing headers
+	req, _ := http.NewRequest("POST", "http://127.0.0.1/internal/albums", nil)
+	err = handler(routing.NewContext(httptest.NewRecorder(), req))
+	if assert.NotNil(t, err) {
+		assert.Equal(t, http.StatusUnauthorized, err.(routing.HTTPError).StatusCode())
+	}
+}
@@ -0,0 +1,91 @@
+// Package slowlog provides a middleware that logs the requests taking longer than a threshold,
+// with details of where the time went, separately from the access log.
+package slowlog
+
+import (
+	"context"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"pkg/log"
+	"pkg/routeinfo"
+	"sync"
+	"time"
+)
+
+// Stats collects the database activity of a request. It is safe for concurrent use.
+type Stats struct {
+	mu      sync.Mutex
+	queries int
+	dbTime  time.Duration
+}
+
+// Add records a database statement that took the given time.
+func (s *Stats) Add(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queries++
+	s.dbTime += d
+}
+
+// Queries returns the number of database statements recorded.
+func (s *Stats) Queries() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.queries
+}
+
+// DBTime returns the total time of the database statements recorded.
+func (s *Stats) DBTime() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dbTime
+}
+
+type contextKey int
+
+const statsKey contextKey = iota
+
+// WithStats returns a context that contains the given stats.
+func WithStats(ctx context.Context, s *Stats) context.Context {
+	return context.WithValue(ctx, statsKey, s)
+}
+
+// FromContext returns the stats stored in the given context, or nil if there are none.
+func FromContext(ctx context.Context) *Stats {
+	s, _ := ctx.Value(statsKey).(*Stats)
+	return s
+}
+
+// Record adds a database statement to the stats stored in the given context.
+// It does nothing if the context contains no stats, so it can be called unconditionally,
+// e.g. from the dbx query and execution log functions.
+func Record(ctx context.Context, d time.Duration) {
+	if ctx == nil {
+		return
+	}
+	if s := FromContext(ctx); s != nil {
+		s.Add(d)
+	}
+}
+
+// Handler returns a middleware that logs a "slow request" message for every request taking at least
+// threshold. Besides the duration, the message has the route pattern, and the number and total time of
+// the database statements run for the request, as recorded with Record. It should follow the access log
+// middleware, so that the message carries the same request ID as the access log message.
+func Handler(logger log.Logger, threshold time.Duration) routing.Handler {
+	return func(c *routing.Context) error {
+		start := time.Now()
+		stats := &Stats{}
+		ctx := WithStats(c.Request.Context(), stats)
+		c.Request = c.Request.WithContext(ctx)
+
+		err := c.Next()
+
+		duration := time.Since(start)
+		if duration >= threshold {
+			logger.With(ctx, "duration", duration.Milliseconds(), "route", routeinfo.Pattern(c),
+				"queries", stats.Queries(), "db_time", stats.DBTime().Milliseconds()).
+				Infof("slow request: %s %s", c.Request.Method, c.Request.URL.Path)
+		}
+		return err
+	}
+}
@@ -0,0 +1,58 @@
+package slowlog
+
+import (
+	"context"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"pkg/log"
+	"testing"
+	"time"
+)
+
+func TestHandler(t *testing.T) {
+	logger, entries := log.NewForTest()
+	router := routing.New()
+	router.Use(Handler(logger, 20*time.Millisecond))
+	router.Get("/albums/<id>", func(c *routing.Context) error {
+		ctx := c.Request.Context()
+		Record(ctx, 5*time.Millisecond)
+		Record(ctx, 10*time.Millisecond)
+		if c.Param("id") == "slow" {
+			time.Sleep(30 * time.Millisecond)
+		}
+		return c.Write("ok")
+	})
+	get := func(url string) {
+		req, _ := http.NewRequest("GET", url, nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	get("/albums/fast")
+	assert.Equal(t, 0, entries.Len())
+
+	get("/albums/slow")
+	logs := entries.FilterMessage("slow request: GET /albums/slow").All()
+	if assert.Equal(t, 1, len(logs)) {
+		fields := logs[0].ContextMap()
+		assert.Equal(t, "/albums/<id>", fields["route"])
+		assert.Equal(t, int64(2), fields["queries"])
+		assert.Equal(t, int64(15), fields["db_time"])
+		assert.True(t, fields["duration"].(int64) >= 30, fields["duration"])
+	}
+}
+
+func TestRecord(t *testing.T) {
+	// nothing is recorded without stats in the context
+	Record(nil, time.Second)
+	Record(context.Background(), time.Second)
+
+	stats := &Stats{}
+	ctx := WithStats(context.Background(), stats)
+	Record(ctx, time.Second)
+	Record(ctx, time.Millisecond)
+	assert.Equal(t, 2, stats.Queries())
+	assert.Equal(t, time.Second+time.Millisecond, stats.DBTime())
+	assert.Equal(t, stats, FromContext(ctx))
+}
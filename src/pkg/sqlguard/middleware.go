@@ -0,0 +1,87 @@
+// Package sqlguard provides a middleware that blocks requests whose parameters look like SQL injection attempts.
+// It is a defense in depth: the queries must still bind their parameters instead of building SQL from input.
+package sqlguard
+
+import (
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"net/http"
+	"net/url"
+	"pkg/log"
+	"pkg/routeinfo"
+	"regexp"
+	"strings"
+)
+
+// patterns match the fragments of SQL injection attempts. Each one combines SQL syntax with the quotes,
+// operators or functions that only an injection needs, so that names like "O'Brien" or text mentioning
+// "select" or "union" are let through.
+var patterns = []*regexp.Regexp{
+	// tautologies after a closing quote or a number: ' OR '1'='1, 1 OR 1=1, ' AND 1=0 --
+	regexp.MustCompile(`(?i)['"]\s*\)?\s*\b(or|and|xor)\b\s*\(?\s*['"][^'"]*['"]\s*(=|<>|!=|<|>|\blike\b)\s*['"]`),
+	regexp.MustCompile(`(?i)(['"]|\b\d+)\s*\)?\s*\b(or|and|xor)\b\s*\(?\s*\d+\s*(=|<>|!=|<|>)\s*\d+`),
+	// UNION SELECT, UNION ALL SELECT, UNION/**/SELECT
+	regexp.MustCompile(`(?i)\bunion\b(\s|/\*.*?\*/)+(all\b(\s|/\*.*?\*/)+)?select\b`),
+	// stacked statements: '; DROP TABLE ...
+	regexp.MustCompile(`(?i)(['"]|\d|\))\s*;\s*(drop|delete|insert|update|alter|truncate|create|exec|execute|shutdown|grant)\s`),
+	// a quote ending the string literal followed by a comment discarding the rest of the query: admin'--
+	regexp.MustCompile(`['"]\s*\)?\s*(;\s*)?(--|#)\s*$|['"]\s*\)?\s*/\*`),
+	// time-based blind injections, called after a quote, an operator or a keyword: 1 AND SLEEP(5), '||pg_sleep(5)
+	regexp.MustCompile(`(?i)(['"=(,;|+]|\b(and|or|xor|not|select|if|then|else|when|where|union)\b)\s*(sleep|benchmark|pg_sleep)\s*\(`),
+	// file or system access
+	regexp.MustCompile(`(?i)\bload_file\s*\(|\bwaitfor\s+delay\b|\binto\s+(out|dump)file\b|\bxp_cmdshell\b`),
+	// schema discovery
+	regexp.MustCompile(`(?i)\binformation_schema\s*\.|\bsys\s*\.\s*(tables|columns|objects)\b`),
+}
+
+// Suspicious reports whether the value contains a pattern of SQL injection.
+func Suspicious(value string) bool {
+	for _, p := range patterns {
+		if p.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Options represents the options of the SQL injection middleware.
+type Options struct {
+	// Routes lists the patterns of the routes whose parameters are checked, e.g. "/v1/albums".
+	// The parameters of every route are checked if empty.
+	Routes []string
+}
+
+// Handler returns a middleware that rejects the requests with a query parameter or a path segment matching
+// a pattern of SQL injection with 403 Forbidden, and logs them. Request bodies are not checked.
+func Handler(logger log.Logger, options ...Options) routing.Handler {
+	var opt Options
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	routes := map[string]bool{}
+	for _, route := range opt.Routes {
+		routes[route] = true
+	}
+	return func(c *routing.Context) error {
+		if len(routes) > 0 && !routes[routeinfo.Pattern(c)] {
+			return nil
+		}
+		for name, values := range c.Request.URL.Query() {
+			for _, value := range values {
+				if Suspicious(value) || Suspicious(name) {
+					return blocked(c, logger, "query parameter "+name, value)
+				}
+			}
+		}
+		for _, segment := range strings.Split(c.Request.URL.EscapedPath(), "/") {
+			if value, err := url.PathUnescape(segment); err == nil && Suspicious(value) {
+				return blocked(c, logger, "path", value)
+			}
+		}
+		return nil
+	}
+}
+
+func blocked(c *routing.Context, logger log.Logger, source, value string) error {
+	logger.With(c.Request.Context(), "input", value).Infof("blocked suspicious %v in %v %v", source, c.Request.Method, c.Request.URL.Path)
+	return routing.NewHTTPError(http.StatusForbidden, "The request contains forbidden input.")
+}
@@ -0,0 +1,92 @@
+package sqlguard
+
+import (
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"pkg/log"
+	"testing"
+)
+
+func TestSuspicious(t *testing.T) {
+	malicious := []string{
+		"' OR '1'='1",
+		"' or ''='",
+		"1 OR 1=1",
+		"1) or (1=1",
+		"admin'--",
+		"admin' #",
+		"x' AND 1=0 --",
+		"1 UNION SELECT password FROM loguser",
+		"1 union all select null",
+		"1 UNION/**/SELECT 1",
+		"1; DROP TABLE album",
+		"'; delete from loguser where 1=1; --",
+		"1 AND SLEEP(5)",
+		"'||pg_sleep(5)--",
+		"1 or benchmark(1000000,md5(1))",
+		"1; WAITFOR DELAY '0:0:5'",
+		"1 AND (SELECT 1 FROM information_schema.tables)",
+		"' INTO OUTFILE '/tmp/x",
+	}
+	for _, value := range malicious {
+		assert.True(t, Suspicious(value), value)
+	}
+
+	benign := []string{
+		"O'Brien",
+		"Tom's and Jerry's",
+		"rock 'n' roll",
+		"Select Your Union",
+		"union station",
+		"Drop it like it's hot",
+		"what's up -- not much",
+		`say "hi" #1`,
+		"C# and F#",
+		"1 or 2 albums",
+		"black & white",
+		"50% off; update soon",
+		"sleep tight",
+		"sleep (deep)",
+		"year 1999 and rating>4",
+		"price>10 and qty<5",
+		`"The Wall" and <b>`,
+		"2024-01-01",
+		"a=1&b=2",
+		"最新专辑",
+	}
+	for _, value := range benign {
+		assert.False(t, Suspicious(value), value)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	logger, entries := log.NewForTest()
+	router := routing.New()
+	router.Use(Handler(logger, Options{Routes: []string{"/v1/albums", "/v1/albums/<id>"}}))
+	ok := func(c *routing.Context) error { return nil }
+	router.Get("/v1/albums", ok)
+	router.Get("/v1/albums/<id>", ok)
+	router.Get("/v1/search", ok)
+	call := func(path string) int {
+		req, _ := http.NewRequest("GET", path, nil)
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		return res.Code
+	}
+
+	assert.Equal(t, http.StatusOK, call("/v1/albums?name="+url.QueryEscape("O'Brien")+"&page=2"))
+	assert.Equal(t, http.StatusOK, call("/v1/albums/"+url.PathEscape("Tom's and Jerry's")))
+	assert.Zero(t, entries.Len())
+
+	assert.Equal(t, http.StatusForbidden, call("/v1/albums?name="+url.QueryEscape("' OR '1'='1")))
+	assert.Equal(t, http.StatusForbidden, call("/v1/albums/"+url.PathEscape("1 UNION SELECT logpassword FROM loguser")))
+	if logs := entries.FilterMessageSnippet("blocked suspicious").All(); assert.Equal(t, 2, len(logs)) {
+		assert.Equal(t, "' OR '1'='1", logs[0].ContextMap()["input"])
+	}
+
+	// the other routes are not checked
+	assert.Equal(t, http.StatusOK, call("/v1/search?q="+url.QueryEscape("' OR '1'='1")))
+}
@@ -0,0 +1,76 @@
+// Package stream writes long responses in chunks and stops once the client has gone.
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"net/http"
+)
+
+// ErrCanceled is returned by Writer.Write once the request has been canceled, usually because
+// the client has disconnected. The error wraps the error of the request context as well.
+var ErrCanceled = errors.New("stream: request canceled")
+
+// Writer writes a response in chunks, flushing each chunk to the client. Before each chunk, it checks
+// whether the request context is done, and fails with ErrCanceled if so, so that the producer of a long
+// response stops doing work nobody will receive.
+type Writer struct {
+	ctx context.Context
+	w   http.ResponseWriter
+}
+
+// NewWriter creates a Writer streaming to the given response for the request with the given context.
+func NewWriter(ctx context.Context, w http.ResponseWriter) *Writer {
+	return &Writer{ctx, w}
+}
+
+// Write writes a chunk and flushes it, unless the request has been canceled.
+func (w *Writer) Write(p []byte) (int, error) {
+	if err := w.ctx.Err(); err != nil {
+		return 0, canceled{err}
+	}
+	n, err := w.w.Write(p)
+	if err != nil {
+		return n, err
+	}
+	if f, ok := w.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return n, nil
+}
+
+// Done returns a channel that is closed when the request is canceled, for producers waiting on other events.
+func (w *Writer) Done() <-chan struct{} {
+	return w.ctx.Done()
+}
+
+// Stream responds to a request with the given content type and the chunks written by produce.
+// produce should return as soon as a write fails, and release its resources with defer.
+// As nothing can be sent to a client that has gone, Stream returns nil when the request is canceled.
+func Stream(c *routing.Context, contentType string, produce func(w *Writer) error) error {
+	c.Response.Header().Set("Content-Type", contentType)
+	err := produce(NewWriter(c.Request.Context(), c.Response))
+	if errors.Is(err, ErrCanceled) || c.Request.Context().Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// canceled is the error of a write to a canceled request.
+type canceled struct {
+	err error
+}
+
+func (e canceled) Error() string {
+	return fmt.Sprintf("%v: %v", ErrCanceled, e.err)
+}
+
+func (e canceled) Is(target error) bool {
+	return target == ErrCanceled
+}
+
+func (e canceled) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,68 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	res := httptest.NewRecorder()
+	w := NewWriter(ctx, res)
+
+	_, err := w.Write([]byte("chunk 1\n"))
+	assert.Nil(t, err)
+	assert.True(t, res.Flushed)
+
+	cancel()
+	n, err := w.Write([]byte("chunk 2\n"))
+	assert.Zero(t, n)
+	assert.True(t, errors.Is(err, ErrCanceled))
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, "chunk 1\n", res.Body.String())
+	select {
+	case <-w.Done():
+	default:
+		t.Error("Done is not closed")
+	}
+}
+
+func TestStream(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequest("GET", "/reports", nil)
+	req = req.WithContext(ctx)
+	res := httptest.NewRecorder()
+	c := routing.NewContext(res, req)
+
+	produced, cleanedUp := 0, false
+	err := Stream(c, "text/csv", func(w *Writer) error {
+		defer func() { cleanedUp = true }()
+		for i := 1; i <= 100; i++ {
+			produced++
+			if _, err := fmt.Fprintf(w, "row %d\n", i); err != nil {
+				return err
+			}
+			// the client disconnects after the second row
+			if i == 2 {
+				cancel()
+			}
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, produced, "the production stops at the first write after the cancellation")
+	assert.True(t, cleanedUp)
+	assert.Equal(t, "row 1\nrow 2\n", res.Body.String())
+	assert.Equal(t, "text/csv", res.Header().Get("Content-Type"))
+
+	// other errors are returned
+	errFailed := errors.New("failed")
+	c = routing.NewContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/reports", nil))
+	assert.Equal(t, errFailed, Stream(c, "text/csv", func(w *Writer) error { return errFailed }))
+}
@@ -0,0 +1,149 @@
+// Package throttle slows down repeated failed attempts, such as wrong passwords, with a delay
+// that doubles after each consecutive failure.
+package throttle
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// idleExpiration is how long the failures of a key are remembered after the last one.
+const idleExpiration = 15 * time.Minute
+
+// pruneThreshold is the number of tracked keys above which expired keys are removed.
+const pruneThreshold = 1024
+
+// Throttle tracks consecutive failures by key and computes the delay to impose on the next attempt.
+// The delay is zero until the first failure, then base, doubling with each further failure up to max.
+// It is safe for concurrent use.
+type Throttle struct {
+	base, max time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	failures map[string]failure
+	slots    map[string]*slot
+}
+
+type failure struct {
+	count int
+	last  time.Time
+}
+
+// slot lets one attempt of a key proceed at a time. It is removed once no attempt holds or waits for it.
+type slot struct {
+	ch    chan struct{}
+	users int
+}
+
+// New creates a throttle whose delay starts at base and is capped at max.
+func New(base, max time.Duration) *Throttle {
+	return &Throttle{base: base, max: max, now: time.Now, failures: map[string]failure{}, slots: map[string]*slot{}}
+}
+
+// Delay returns the delay to impose on the next attempt for the given key.
+func (t *Throttle) Delay(key string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	f, ok := t.failures[key]
+	if !ok || f.count == 0 || t.now().Sub(f.last) > idleExpiration {
+		return 0
+	}
+	delay := t.base
+	for i := 1; i < f.count && delay < t.max; i++ {
+		delay *= 2
+	}
+	if delay > t.max {
+		delay = t.max
+	}
+	return delay
+}
+
+// Wait blocks for the delay of the given key. It returns early with the context error
+// if the context is done before the delay has elapsed.
+func (t *Throttle) Wait(ctx context.Context, key string) error {
+	delay := t.Delay(key)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Acquire waits until no other attempt of the given key is in progress, then blocks for the delay of the key
+// like Wait does. The attempt must be ended by calling release once its outcome has been recorded with Fail
+// or Reset. As the attempts of a key are serialized, each one waits for the delay resulting from the previous
+// ones, so that sending them concurrently does not bypass the delay. The error of a context done before the
+// attempt may proceed is returned, in which case there is nothing to release.
+func (t *Throttle) Acquire(ctx context.Context, key string) (release func(), err error) {
+	t.mu.Lock()
+	s, ok := t.slots[key]
+	if !ok {
+		s = &slot{ch: make(chan struct{}, 1)}
+		t.slots[key] = s
+	}
+	s.users++
+	t.mu.Unlock()
+
+	select {
+	case s.ch <- struct{}{}:
+	case <-ctx.Done():
+		t.leave(key, s)
+		return nil, ctx.Err()
+	}
+	var once sync.Once
+	release = func() {
+		once.Do(func() {
+			<-s.ch
+			t.leave(key, s)
+		})
+	}
+	if err := t.Wait(ctx, key); err != nil {
+		release()
+		return nil, err
+	}
+	return release, nil
+}
+
+// leave removes the slot of a key once no attempt holds or waits for it.
+func (t *Throttle) leave(key string, s *slot) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if s.users--; s.users == 0 {
+		delete(t.slots, key)
+	}
+}
+
+// Fail records a failed attempt for the given key.
+func (t *Throttle) Fail(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	f := t.failures[key]
+	if now.Sub(f.last) > idleExpiration {
+		f.count = 0
+	}
+	t.failures[key] = failure{f.count + 1, now}
+
+	if len(t.failures) > pruneThreshold {
+		for k, f := range t.failures {
+			if now.Sub(f.last) > idleExpiration {
+				delete(t.failures, k)
+			}
+		}
+	}
+}
+
+// Reset forgets the failures of the given key, typically after a successful attempt.
+func (t *Throttle) Reset(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.failures, key)
+}
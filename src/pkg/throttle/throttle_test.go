@@ -0,0 +1,113 @@
+package throttle
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestThrottle_Delay(t *testing.T) {
+	th := New(100*time.Millisecond, time.Second)
+	assert.Equal(t, time.Duration(0), th.Delay("alice"))
+
+	var delays []time.Duration
+	for i := 0; i < 6; i++ {
+		th.Fail("alice")
+		delays = append(delays, th.Delay("alice"))
+	}
+	assert.Equal(t, []time.Duration{
+		100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond,
+		800 * time.Millisecond, time.Second, time.Second,
+	}, delays)
+
+	// keys are independent
+	assert.Equal(t, time.Duration(0), th.Delay("bob"))
+
+	th.Reset("alice")
+	assert.Equal(t, time.Duration(0), th.Delay("alice"))
+}
+
+func TestThrottle_expiration(t *testing.T) {
+	now := time.Now()
+	th := New(time.Second, time.Minute)
+	th.now = func() time.Time { return now }
+	th.Fail("alice")
+	th.Fail("alice")
+	assert.Equal(t, 2*time.Second, th.Delay("alice"))
+
+	now = now.Add(idleExpiration + time.Second)
+	assert.Equal(t, time.Duration(0), th.Delay("alice"))
+	th.Fail("alice")
+	assert.Equal(t, time.Second, th.Delay("alice"))
+}
+
+func TestThrottle_Wait(t *testing.T) {
+	th := New(20*time.Millisecond, time.Second)
+	ctx := context.Background()
+
+	var elapsed []time.Duration
+	for i := 0; i < 3; i++ {
+		start := time.Now()
+		assert.Nil(t, th.Wait(ctx, "alice"))
+		elapsed = append(elapsed, time.Since(start))
+		th.Fail("alice")
+	}
+	assert.True(t, elapsed[0] < 20*time.Millisecond)
+	assert.True(t, elapsed[1] >= 20*time.Millisecond)
+	assert.True(t, elapsed[2] >= 40*time.Millisecond)
+
+	// the wait ends with the context
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	assert.Equal(t, context.DeadlineExceeded, th.Wait(ctx, "alice"))
+	assert.True(t, time.Since(start) < 80*time.Millisecond)
+}
+
+func TestThrottle_Acquire(t *testing.T) {
+	th := New(30*time.Millisecond, time.Second)
+	ctx := context.Background()
+	th.Fail("alice")
+
+	// concurrent attempts wait for each other, and each one waits for the delay left by the previous failures
+	start := time.Now()
+	done := make(chan time.Duration, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			release, err := th.Acquire(ctx, "alice")
+			if assert.Nil(t, err) {
+				th.Fail("alice")
+				release()
+			}
+			done <- time.Since(start)
+		}()
+	}
+	var last time.Duration
+	for i := 0; i < 3; i++ {
+		if elapsed := <-done; elapsed > last {
+			last = elapsed
+		}
+	}
+	// 30ms, then 60ms, then 120ms
+	assert.True(t, last >= 210*time.Millisecond, last)
+	assert.Empty(t, th.slots)
+
+	// other keys are not held up
+	th.Reset("alice")
+	release, _ := th.Acquire(ctx, "alice")
+	start = time.Now()
+	releaseBob, err := th.Acquire(ctx, "bob")
+	assert.Nil(t, err)
+	assert.True(t, time.Since(start) < 20*time.Millisecond)
+	releaseBob()
+
+	// an attempt waiting for the slot gives up with its context
+	waitCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = th.Acquire(waitCtx, "alice")
+	assert.Equal(t, context.DeadlineExceeded, err)
+	release()
+	release()
+	assert.Empty(t, th.slots)
+}
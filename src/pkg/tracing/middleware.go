@@ -0,0 +1,38 @@
+package tracing
+
+import (
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"pkg/routeinfo"
+)
+
+// Options represents the options of the tracing middleware.
+type Options struct {
+	// Sampler decides whether the new traces are sampled, by route. Every new trace is sampled if nil.
+	Sampler *Sampler
+}
+
+// Handler returns a middleware that extracts the trace context from the traceparent header of
+// incoming requests and stores a span of the same trace in the request context, from which
+// it can be read using FromContext. A new trace is started if the header is absent or malformed.
+//
+// The sampling decision of an incoming trace is honored. A new trace is sampled according to
+// the sampler of the route matching the request.
+func Handler(options ...Options) routing.Handler {
+	var opt Options
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	return func(c *routing.Context) error {
+		tc, err := Parse(c.Request.Header.Get(HeaderName))
+		if err != nil {
+			tc = New()
+			if opt.Sampler != nil && !opt.Sampler.Sample(routeinfo.Pattern(c)) {
+				tc.Flags = "00"
+			}
+		} else {
+			tc = tc.Child()
+		}
+		c.Request = c.Request.WithContext(WithTraceContext(c.Request.Context(), tc))
+		return nil
+	}
+}
@@ -0,0 +1,34 @@
+package tracing
+
+import (
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	// the incoming trace is continued
+	req, _ := http.NewRequest("GET", "http://127.0.0.1/users", nil)
+	req.Header.Set(HeaderName, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	c := routing.NewContext(httptest.NewRecorder(), req)
+	assert.Nil(t, Handler()(c))
+	tc, ok := FromContext(c.Request.Context())
+	if assert.True(t, ok) {
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", tc.TraceID)
+		assert.NotEqual(t, "00f067aa0ba902b7", tc.SpanID)
+		assert.Equal(t, "00", tc.Flags)
+	}
+
+	// a new trace is started otherwise
+	req, _ = http.NewRequest("GET", "http://127.0.0.1/users", nil)
+	req.Header.Set(HeaderName, "invalid")
+	c = routing.NewContext(httptest.NewRecorder(), req)
+	assert.Nil(t, Handler()(c))
+	tc, ok = FromContext(c.Request.Context())
+	if assert.True(t, ok) {
+		_, err := Parse(tc.String())
+		assert.Nil(t, err)
+	}
+}
@@ -0,0 +1,50 @@
+package tracing
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Sampler makes head-based sampling decisions for the traces started by the server.
+// Each route has its own sampling ratio, so that e.g. every login is traced while only
+// a small fraction of the health checks are. It is safe for concurrent use.
+type Sampler struct {
+	ratio  float64
+	routes map[string]float64
+
+	mu     sync.Mutex
+	random func() float64
+}
+
+// NewSampler creates a sampler that samples the given ratio (between 0 and 1) of the traces.
+// routes overrides the ratio for the routes with the given patterns, e.g. "/v1/login".
+func NewSampler(ratio float64, routes map[string]float64) *Sampler {
+	return &Sampler{
+		ratio:  ratio,
+		routes: routes,
+		random: rand.New(rand.NewSource(time.Now().UnixNano())).Float64,
+	}
+}
+
+// Ratio returns the sampling ratio of the route with the given pattern.
+func (s *Sampler) Ratio(route string) float64 {
+	if ratio, ok := s.routes[route]; ok {
+		return ratio
+	}
+	return s.ratio
+}
+
+// Sample decides whether a new trace of a request to the route with the given pattern is sampled.
+func (s *Sampler) Sample(route string) bool {
+	ratio := s.Ratio(route)
+	if ratio >= 1 {
+		return true
+	}
+	if ratio <= 0 {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.random() < ratio
+}
@@ -0,0 +1,64 @@
+package tracing
+
+import (
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSampler(t *testing.T) {
+	s := NewSampler(0.1, map[string]float64{"/v1/login": 1, "/healthcheck": 0.01, "/v1/debug": 0})
+	s.random = rand.New(rand.NewSource(1)).Float64
+
+	router := routing.New()
+	router.Use(Handler(Options{Sampler: s}))
+	sampled := map[string]int{}
+	handler := func(c *routing.Context) error {
+		if tc, _ := FromContext(c.Request.Context()); tc.Sampled() {
+			sampled[c.Request.URL.Path]++
+		}
+		return nil
+	}
+	router.Post("/v1/login", handler)
+	router.Get("/healthcheck", handler)
+	router.Get("/v1/albums/<id>", handler)
+	router.Get("/v1/debug", handler)
+
+	const n = 10000
+	requests := []struct {
+		method, path string
+		min, max     int
+	}{
+		{"POST", "/v1/login", n, n},
+		{"GET", "/healthcheck", n / 200, n / 50},
+		{"GET", "/v1/albums/1", n / 20, n / 5},
+		{"GET", "/v1/debug", 0, 0},
+	}
+	for _, r := range requests {
+		for i := 0; i < n; i++ {
+			req, _ := http.NewRequest(r.method, r.path, nil)
+			router.ServeHTTP(httptest.NewRecorder(), req)
+		}
+		assert.True(t, sampled[r.path] >= r.min && sampled[r.path] <= r.max, "%v: %v sampled", r.path, sampled[r.path])
+	}
+
+	// the decision of an incoming trace is honored
+	sampled = map[string]int{}
+	for _, flags := range []string{"00", "01"} {
+		req, _ := http.NewRequest("GET", "/v1/debug", nil)
+		req.Header.Set(HeaderName, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-"+flags)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	assert.Equal(t, 1, sampled["/v1/debug"])
+}
+
+func TestTraceContext_Sampled(t *testing.T) {
+	assert.True(t, TraceContext{Flags: "01"}.Sampled())
+	assert.True(t, TraceContext{Flags: "03"}.Sampled())
+	assert.False(t, TraceContext{Flags: "00"}.Sampled())
+	assert.False(t, TraceContext{Flags: "02"}.Sampled())
+	assert.False(t, TraceContext{}.Sampled())
+}
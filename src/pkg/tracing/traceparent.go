@@ -0,0 +1,101 @@
+// Package tracing provides W3C Trace Context (traceparent) propagation.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// HeaderName is the name of the HTTP header carrying the trace context.
+const HeaderName = "traceparent"
+
+// TraceContext identifies the trace a request belongs to and the span processing it.
+type TraceContext struct {
+	// TraceID is the 32-hex-digit ID of the whole trace.
+	TraceID string
+	// SpanID is the 16-hex-digit ID of the current span.
+	SpanID string
+	// Flags are the 2-hex-digit trace flags, e.g. "01" if the trace is sampled.
+	Flags string
+}
+
+// ErrInvalid is returned when parsing a malformed traceparent header.
+var ErrInvalid = errors.New("invalid traceparent")
+
+// Parse parses the value of a traceparent header.
+func Parse(value string) (TraceContext, error) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || !isHex(parts[0], 2) || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return TraceContext{}, ErrInvalid
+	}
+	tc := TraceContext{TraceID: parts[1], SpanID: parts[2], Flags: parts[3]}
+	if !isHex(tc.TraceID, 32) || isZero(tc.TraceID) || !isHex(tc.SpanID, 16) || isZero(tc.SpanID) || !isHex(tc.Flags, 2) {
+		return TraceContext{}, ErrInvalid
+	}
+	return tc, nil
+}
+
+// New starts a new sampled trace with random IDs.
+func New() TraceContext {
+	return TraceContext{TraceID: randomHex(16), SpanID: randomHex(8), Flags: "01"}
+}
+
+// Child returns the trace context of a new span within the same trace.
+func (tc TraceContext) Child() TraceContext {
+	return TraceContext{TraceID: tc.TraceID, SpanID: randomHex(8), Flags: tc.Flags}
+}
+
+// Sampled reports whether the trace is sampled, i.e. whether the sampled flag is set.
+func (tc TraceContext) Sampled() bool {
+	b, err := hex.DecodeString(tc.Flags)
+	return err == nil && len(b) == 1 && b[0]&1 == 1
+}
+
+// String returns the trace context in the traceparent header format.
+func (tc TraceContext) String() string {
+	return "00-" + tc.TraceID + "-" + tc.SpanID + "-" + tc.Flags
+}
+
+type contextKey int
+
+const traceKey contextKey = iota
+
+// WithTraceContext returns a context that contains the given trace context.
+func WithTraceContext(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, traceKey, tc)
+}
+
+// FromContext returns the trace context stored in the given context.
+// The second return value is false if there is no trace context.
+func FromContext(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(traceKey).(TraceContext)
+	return tc, ok
+}
+
+// isHex returns whether s consists of n lowercase hexadecimal digits.
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// isZero returns whether s consists of zeros only.
+func isZero(s string) bool {
+	return strings.Trim(s, "0") == ""
+}
+
+// randomHex returns n random bytes encoded in hexadecimal.
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
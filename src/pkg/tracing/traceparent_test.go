@@ -0,0 +1,56 @@
+package tracing
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{"valid", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"future version", "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false},
+		{"empty", "", true},
+		{"extra field in version 00", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", true},
+		{"invalid version", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true},
+		{"uppercase", "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", true},
+		{"short trace ID", "00-4bf92f3577b34da6-00f067aa0ba902b7-01", true},
+		{"zero trace ID", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", true},
+		{"zero span ID", "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc, err := Parse(tt.value)
+			if tt.wantErr {
+				assert.Equal(t, ErrInvalid, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", tc.TraceID)
+			assert.Equal(t, "00f067aa0ba902b7", tc.SpanID)
+			assert.Equal(t, "01", tc.Flags)
+		})
+	}
+}
+
+func TestTraceContext(t *testing.T) {
+	tc := New()
+	parsed, err := Parse(tc.String())
+	assert.Nil(t, err)
+	assert.Equal(t, tc, parsed)
+
+	child := tc.Child()
+	assert.Equal(t, tc.TraceID, child.TraceID)
+	assert.NotEqual(t, tc.SpanID, child.SpanID)
+	assert.Equal(t, tc.Flags, child.Flags)
+
+	_, ok := FromContext(context.Background())
+	assert.False(t, ok)
+	stored, ok := FromContext(WithTraceContext(context.Background(), tc))
+	assert.True(t, ok)
+	assert.Equal(t, tc, stored)
+}
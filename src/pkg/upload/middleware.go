@@ -0,0 +1,70 @@
+// Package upload provides a middleware that parses and bounds multipart/form-data request bodies.
+package upload
+
+import (
+	"errors"
+	"fmt"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"mime"
+	"net/http"
+)
+
+const (
+	// DefaultMaxMemory is the default number of bytes of file parts kept in memory. Larger files are stored in temporary files.
+	DefaultMaxMemory = 32 << 20
+	// DefaultMaxBytes is the default maximum size of a multipart request body.
+	DefaultMaxBytes = 100 << 20
+)
+
+// Options represents the options of the upload middleware.
+type Options struct {
+	// MaxMemory is the number of bytes of file parts kept in memory. Defaults to DefaultMaxMemory.
+	MaxMemory int64
+	// MaxBytes is the maximum size of the whole request body. Defaults to DefaultMaxBytes.
+	MaxBytes int64
+}
+
+// Handler returns a middleware that parses multipart/form-data request bodies so that the following
+// handlers can access the uploaded files through c.Request.MultipartForm or c.Request.FormFile.
+// A body larger than the configured limit is rejected with 413, and a malformed one with 400.
+// Temporary files created for large file parts are removed once the request has been handled.
+// Requests of other content types are left untouched.
+func Handler(options ...Options) routing.Handler {
+	var opt Options
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	if opt.MaxMemory <= 0 {
+		opt.MaxMemory = DefaultMaxMemory
+	}
+	if opt.MaxBytes <= 0 {
+		opt.MaxBytes = DefaultMaxBytes
+	}
+
+	return func(c *routing.Context) error {
+		if mediaType, _, _ := mime.ParseMediaType(c.Request.Header.Get("Content-Type")); mediaType != "multipart/form-data" {
+			return nil
+		}
+		if c.Request.ContentLength > opt.MaxBytes {
+			return tooLarge(opt.MaxBytes)
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Response, c.Request.Body, opt.MaxBytes)
+		err := c.Request.ParseMultipartForm(opt.MaxMemory)
+		if c.Request.MultipartForm != nil {
+			// remove the temporary files even if parsing failed halfway
+			defer c.Request.MultipartForm.RemoveAll()
+		}
+		if err != nil {
+			if errors.As(err, new(*http.MaxBytesError)) {
+				return tooLarge(opt.MaxBytes)
+			}
+			return routing.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("The multipart body is malformed: %v", err))
+		}
+		return c.Next()
+	}
+}
+
+func tooLarge(maxBytes int64) error {
+	return routing.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("The request body must not exceed %v bytes.", maxBytes))
+}
@@ -0,0 +1,80 @@
+package upload
+
+import (
+	"bytes"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// newRequest creates a multipart request uploading a file of the given size in the "file" field.
+func newRequest(size int) *http.Request {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("file", "data.bin")
+	_, _ = part.Write(bytes.Repeat([]byte("x"), size))
+	_ = writer.WriteField("name", "test")
+	_ = writer.Close()
+	req, _ := http.NewRequest("POST", "/upload", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestHandler(t *testing.T) {
+	var tempFile string
+	router := routing.New()
+	router.Use(Handler(Options{MaxMemory: 1024, MaxBytes: 64 << 10}))
+	router.Post("/upload", func(c *routing.Context) error {
+		file, header, err := c.Request.FormFile("file")
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		// files larger than MaxMemory are stored on disk
+		if f, ok := file.(*os.File); ok {
+			tempFile = f.Name()
+		}
+		data, _ := ioutil.ReadAll(file)
+		return c.Write(header.Filename + " " + c.Request.FormValue("name") + " " + strconv.Itoa(len(data)))
+	})
+
+	t.Run("within limit", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, newRequest(20000))
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, "data.bin test 20000", res.Body.String())
+		if assert.NotEmpty(t, tempFile) {
+			_, err := os.Stat(tempFile)
+			assert.True(t, os.IsNotExist(err), "the temporary file should have been removed")
+		}
+	})
+
+	t.Run("over limit", func(t *testing.T) {
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, newRequest(100000))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, res.Code)
+	})
+
+	t.Run("over limit without content length", func(t *testing.T) {
+		req := newRequest(100000)
+		req.ContentLength = -1
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, res.Code)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "/upload", strings.NewReader("garbage"))
+		req.Header.Set("Content-Type", "multipart/form-data; boundary=xyz")
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		assert.Equal(t, http.StatusBadRequest, res.Code)
+	})
+}
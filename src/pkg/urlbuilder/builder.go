@@ -0,0 +1,65 @@
+// Package urlbuilder builds the canonical URLs of the resources served by a router.
+package urlbuilder
+
+import (
+	"fmt"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"net/url"
+	"strings"
+)
+
+// URLBuilder builds absolute URLs from the named routes of a router and an external base URL.
+// The base URL is the address under which clients reach the server, e.g. "https://api.example.com/service"
+// when a proxy forwards that path to the server. Its scheme, host and path prefix are kept in the built URLs.
+type URLBuilder struct {
+	base   string
+	router *routing.Router
+}
+
+// New creates a URLBuilder for the routes of the given router. baseURL must be an absolute URL
+// without query or fragment. If it is empty, the built URLs are paths relative to the host.
+func New(baseURL string, router *routing.Router) (*URLBuilder, error) {
+	if baseURL != "" {
+		u, err := url.Parse(baseURL)
+		if err != nil {
+			return nil, err
+		}
+		if !u.IsAbs() || u.Host == "" {
+			return nil, fmt.Errorf("the base URL %q is not absolute", baseURL)
+		}
+		if u.RawQuery != "" || u.Fragment != "" {
+			return nil, fmt.Errorf("the base URL %q must not have a query or fragment", baseURL)
+		}
+	}
+	return &URLBuilder{strings.TrimSuffix(baseURL, "/"), router}, nil
+}
+
+// URL returns the URL of the named route. params lists the values of the route parameters as name-value
+// pairs, e.g. URL("album", "id", "123") for the route "/v1/albums/<id>". The values are escaped.
+// An error is returned if the route does not exist, a parameter is missing or the last name has no value.
+func (b *URLBuilder) URL(name string, params ...interface{}) (string, error) {
+	route := b.router.Route(name)
+	if route == nil {
+		return "", fmt.Errorf("unknown route %q", name)
+	}
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("parameter %v of route %q has no value", params[len(params)-1], name)
+	}
+	path := route.URL()
+	for i := 0; i < len(params); i += 2 {
+		token := fmt.Sprintf("<%v>", params[i])
+		path = strings.Replace(path, token, url.PathEscape(fmt.Sprint(params[i+1])), -1)
+	}
+	if i := strings.IndexByte(path, '<'); i >= 0 {
+		return "", fmt.Errorf("missing parameter %v for route %q", path[i:strings.IndexByte(path[i:], '>')+i+1], name)
+	}
+	return b.Path(path), nil
+}
+
+// Path returns the URL of the given path, which is relative to the server root.
+func (b *URLBuilder) Path(path string) string {
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return b.base + path
+}
@@ -0,0 +1,60 @@
+package urlbuilder
+
+import (
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newRouter() *routing.Router {
+	router := routing.New()
+	handler := func(c *routing.Context) error { return nil }
+	v1 := router.Group("/v1")
+	v1.Get("/albums", handler).Name("albums")
+	v1.Get(`/albums/<id:\d+>/photos/<photo>`, handler).Name("photo")
+	return router
+}
+
+func TestURLBuilder_URL(t *testing.T) {
+	b, err := New("https://api.example.com/service/", newRouter())
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	url, err := b.URL("albums")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://api.example.com/service/v1/albums", url)
+
+	url, err = b.URL("photo", "id", 123, "photo", "summer beach")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://api.example.com/service/v1/albums/123/photos/summer%20beach", url)
+
+	_, err = b.URL("photo", "id", 123)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "missing parameter <photo>")
+	}
+	_, err = b.URL("photo", "id", 123, "photo")
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "parameter photo of route \"photo\" has no value")
+	}
+	_, err = b.URL("unknown")
+	assert.NotNil(t, err)
+}
+
+func TestURLBuilder_relative(t *testing.T) {
+	b, err := New("", newRouter())
+	if !assert.Nil(t, err) {
+		return
+	}
+	url, err := b.URL("albums")
+	assert.Nil(t, err)
+	assert.Equal(t, "/v1/albums", url)
+	assert.Equal(t, "/healthcheck", b.Path("healthcheck"))
+}
+
+func TestNew(t *testing.T) {
+	for _, base := range []string{"api.example.com", "/service", "https://api.example.com?x=1", "https://api.example.com#top", "http://[::1"} {
+		_, err := New(base, routing.New())
+		assert.NotNil(t, err, base)
+	}
+}
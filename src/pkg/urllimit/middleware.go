@@ -0,0 +1,29 @@
+// Package urllimit provides a middleware that rejects requests with overlong URLs.
+package urllimit
+
+import (
+	"fmt"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"net/http"
+)
+
+// Handler returns a middleware that rejects requests whose URL is longer than maxURL bytes, or whose
+// query string is longer than maxQuery bytes, with 414 URI Too Long. The URL is measured as sent
+// in the request line, including the query string. A limit that is not positive is not enforced.
+func Handler(maxURL, maxQuery int) routing.Handler {
+	return func(c *routing.Context) error {
+		uri := c.Request.RequestURI
+		if uri == "" {
+			uri = c.Request.URL.RequestURI()
+		}
+		if maxURL > 0 && len(uri) > maxURL {
+			return routing.NewHTTPError(http.StatusRequestURITooLong,
+				fmt.Sprintf("the request URL must not be longer than %v bytes", maxURL))
+		}
+		if maxQuery > 0 && len(c.Request.URL.RawQuery) > maxQuery {
+			return routing.NewHTTPError(http.StatusRequestURITooLong,
+				fmt.Sprintf("the query string must not be longer than %v bytes", maxQuery))
+		}
+		return nil
+	}
+}
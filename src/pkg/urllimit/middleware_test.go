@@ -0,0 +1,34 @@
+package urllimit
+
+import (
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	call := func(handler routing.Handler, url string) error {
+		req := httptest.NewRequest("GET", url, nil)
+		return handler(routing.NewContext(httptest.NewRecorder(), req))
+	}
+
+	// acceptable URLs
+	assert.Nil(t, call(Handler(30, 10), "/users?page=1"))
+	assert.Nil(t, call(Handler(30, 10), "/users/"+strings.Repeat("a", 23)))
+	assert.Nil(t, call(Handler(0, 0), "/users?q="+strings.Repeat("a", 10000)))
+
+	// over-length URL
+	err := call(Handler(30, 0), "/users/"+strings.Repeat("a", 24))
+	if assert.NotNil(t, err) {
+		assert.Equal(t, http.StatusRequestURITooLong, err.(routing.HTTPError).StatusCode())
+	}
+
+	// over-length query string
+	err = call(Handler(0, 10), "/users?name="+strings.Repeat("a", 10))
+	if assert.NotNil(t, err) {
+		assert.Equal(t, http.StatusRequestURITooLong, err.(routing.HTTPError).StatusCode())
+	}
+}
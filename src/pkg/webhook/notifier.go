@@ -0,0 +1,152 @@
+// Package webhook delivers events to an HTTP endpoint as JSON, in the background.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"pkg/httpclient"
+	"pkg/log"
+	"pkg/workerpool"
+	"time"
+)
+
+const (
+	// DefaultTimeout is the default time limit of a delivery attempt.
+	DefaultTimeout = 2 * time.Second
+	// DefaultRetries is the default number of times a failed delivery is retried.
+	DefaultRetries = 3
+	// DefaultBackoff is the default delay before the first retry. It doubles after each retry.
+	DefaultBackoff = 500 * time.Millisecond
+	// DefaultWorkers is the default number of events delivered concurrently.
+	DefaultWorkers = 4
+	// DefaultQueueSize is the default number of events waiting for delivery.
+	DefaultQueueSize = 100
+)
+
+// Options represents the options of a Notifier.
+type Options struct {
+	// Timeout limits each delivery attempt. Defaults to DefaultTimeout if zero.
+	Timeout time.Duration
+	// Retries is the number of times a failed delivery is retried. Defaults to DefaultRetries if zero.
+	// Failed deliveries are not retried if negative.
+	Retries int
+	// Backoff is the delay before the first retry, which doubles after each retry. Defaults to DefaultBackoff if zero.
+	Backoff time.Duration
+	// Workers is the number of events delivered concurrently. Defaults to DefaultWorkers if zero.
+	Workers int
+	// QueueSize is the number of events waiting for delivery, beyond which new events are dropped.
+	// Defaults to DefaultQueueSize if zero.
+	QueueSize int
+}
+
+// Notifier POSTs events as JSON to a URL. The events are delivered asynchronously, so that the code
+// sending them is neither slowed down nor failed by the endpoint. A delivery is successful when the
+// endpoint responds with a 2xx status, and is retried otherwise. Failed deliveries are logged.
+// The events are delivered by a worker pool, which Close drains on shutdown.
+//
+// A nil *Notifier is valid and discards the events, so that webhooks can be made optional.
+type Notifier struct {
+	url     string
+	client  *http.Client
+	retries int
+	backoff time.Duration
+	logger  log.Logger
+	pool    *workerpool.Pool
+}
+
+// New creates a Notifier delivering the events to the given URL.
+func New(url string, logger log.Logger, options ...Options) *Notifier {
+	var opt Options
+	if len(options) > 0 {
+		opt = options[0]
+	}
+	if opt.Timeout == 0 {
+		opt.Timeout = DefaultTimeout
+	}
+	if opt.Retries == 0 {
+		opt.Retries = DefaultRetries
+	} else if opt.Retries < 0 {
+		opt.Retries = 0
+	}
+	if opt.Backoff == 0 {
+		opt.Backoff = DefaultBackoff
+	}
+	if opt.Workers == 0 {
+		opt.Workers = DefaultWorkers
+	}
+	if opt.QueueSize == 0 {
+		opt.QueueSize = DefaultQueueSize
+	}
+	return &Notifier{
+		url:     url,
+		client:  httpclient.New(opt.Timeout),
+		retries: opt.Retries,
+		backoff: opt.Backoff,
+		logger:  logger,
+		pool:    workerpool.New(opt.Workers, opt.QueueSize, logger),
+	}
+}
+
+// Send delivers the event, which is marshaled to JSON, in the background.
+func (n *Notifier) Send(event interface{}) {
+	if n == nil {
+		return
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		n.logger.Errorf("failed to encode webhook event: %v", err)
+		return
+	}
+	n.pool.Submit(func(ctx context.Context) {
+		if err := n.deliver(ctx, body); err != nil {
+			n.logger.Errorf("failed to deliver webhook event to %v: %v", n.url, err)
+		}
+	})
+}
+
+// Close stops accepting events and waits until the pending ones are delivered or have failed.
+// If the context is done first, the deliveries in progress are aborted and the pending events are dropped.
+func (n *Notifier) Close(ctx context.Context) error {
+	if n == nil {
+		return nil
+	}
+	return n.pool.Close(ctx)
+}
+
+// deliver posts the event body, retrying failed attempts with an exponential backoff until the context is done.
+func (n *Notifier) deliver(ctx context.Context, body []byte) error {
+	backoff := n.backoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = n.post(ctx, body); err == nil || attempt == n.retries {
+			return err
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+// post makes a delivery attempt.
+func (n *Notifier) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequest("POST", n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := n.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %v", res.Status)
+	}
+	return nil
+}
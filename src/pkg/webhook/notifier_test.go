@@ -0,0 +1,85 @@
+package webhook
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"pkg/log"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNotifier(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		// the first attempt fails
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, r.Header.Get("Content-Type")+" "+string(body))
+	}))
+	defer server.Close()
+
+	logger, entries := log.NewForTest()
+	n := New(server.URL, logger, Options{Backoff: time.Millisecond})
+	n.Send(map[string]string{"event": "login"})
+	assert.Nil(t, n.Close(context.Background()))
+
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, []string{`application/json {"event":"login"}`}, bodies)
+	assert.Zero(t, entries.Len())
+}
+
+func TestNotifier_failure(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	logger, entries := log.NewForTest()
+	n := New(server.URL, logger, Options{Retries: 2, Backoff: time.Millisecond})
+	n.Send(map[string]string{"event": "login"})
+	assert.Nil(t, n.Close(context.Background()))
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, 1, entries.FilterMessageSnippet("failed to deliver webhook event").Len())
+
+	// a nil notifier discards the events
+	var none *Notifier
+	none.Send("event")
+	assert.Nil(t, none.Close(context.Background()))
+}
+
+func TestNotifier_Close_timeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	logger, entries := log.NewForTest()
+	n := New(server.URL, logger, Options{Timeout: time.Minute, Retries: -1, Workers: 1})
+	for i := 0; i < 3; i++ {
+		n.Send(map[string]int{"event": i})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, n.Close(ctx))
+	// the event being delivered is aborted, and those still queued are dropped
+	assert.Equal(t, 1, entries.FilterMessageSnippet("pending jobs on shutdown").Len())
+}
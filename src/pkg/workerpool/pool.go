@@ -0,0 +1,118 @@
+// Package workerpool runs background jobs on a fixed number of goroutines fed by a bounded queue.
+package workerpool
+
+import (
+	"context"
+	"pkg/log"
+	"sync"
+	"sync/atomic"
+)
+
+// Job is a background job. Its context is canceled when the pool is closed and the jobs
+// have not finished in time, so long-running jobs should give up when it is done.
+type Job func(ctx context.Context)
+
+// Pool runs jobs on a fixed number of workers. The jobs waiting for a worker are held in a bounded
+// queue; jobs submitted while it is full are dropped, so that a slow consumer can't exhaust the memory.
+type Pool struct {
+	jobs   chan Job
+	ctx    context.Context
+	cancel context.CancelFunc
+	logger log.Logger
+	wg     sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
+	// skipped counts the queued jobs the workers took but did not run because the pool was canceled.
+	skipped int64
+}
+
+// New creates a pool with the given numbers of workers and of queued jobs, and starts the workers.
+func New(workers, queueSize int, logger log.Logger) *Pool {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Pool{
+		jobs:   make(chan Job, queueSize),
+		ctx:    ctx,
+		cancel: cancel,
+		logger: logger,
+	}
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+// Submit queues a job. It reports false, and logs the job as dropped, if the queue is full or the pool is closed.
+func (p *Pool) Submit(job Job) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		p.logger.Errorf("dropped a job submitted after the worker pool was closed")
+		return false
+	}
+	select {
+	case p.jobs <- job:
+		return true
+	default:
+		p.logger.Errorf("dropped a job because the worker pool queue is full")
+		return false
+	}
+}
+
+// Close stops accepting jobs and waits until the queued and running jobs are finished.
+// If the context is done first, the context of the running jobs is canceled, the jobs still
+// in the queue are dropped and logged, and the context error is returned.
+func (p *Pool) Close(ctx context.Context) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.jobs)
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+	}
+
+	p.cancel()
+	// the workers racing with the drain skip the jobs they take
+	dropped := 0
+	for range p.jobs {
+		dropped++
+	}
+	dropped += int(atomic.LoadInt64(&p.skipped))
+	if dropped > 0 {
+		p.logger.Errorf("dropped %v pending jobs on shutdown: %v", dropped, ctx.Err())
+	}
+	return ctx.Err()
+}
+
+// work runs the queued jobs until the queue is closed and empty. Once the pool is canceled,
+// the remaining jobs are skipped, as they would only start after Close has given up on them.
+func (p *Pool) work() {
+	defer p.wg.Done()
+	for job := range p.jobs {
+		if p.ctx.Err() != nil {
+			atomic.AddInt64(&p.skipped, 1)
+			continue
+		}
+		job(p.ctx)
+	}
+}
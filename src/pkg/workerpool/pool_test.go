@@ -0,0 +1,65 @@
+package workerpool
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"pkg/log"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPool_Close(t *testing.T) {
+	logger, entries := log.NewForTest()
+	p := New(2, 10, logger)
+	var done int64
+	for i := 0; i < 5; i++ {
+		assert.True(t, p.Submit(func(ctx context.Context) {
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt64(&done, 1)
+		}))
+	}
+
+	// the pending jobs are flushed
+	assert.Nil(t, p.Close(context.Background()))
+	assert.Equal(t, int64(5), atomic.LoadInt64(&done))
+	assert.Zero(t, entries.Len())
+
+	// the pool no longer accepts jobs
+	assert.False(t, p.Submit(func(ctx context.Context) {}))
+	assert.Equal(t, 1, entries.FilterMessageSnippet("after the worker pool was closed").Len())
+	assert.Nil(t, p.Close(context.Background()))
+}
+
+func TestPool_Close_timeout(t *testing.T) {
+	logger, entries := log.NewForTest()
+	p := New(1, 3, logger)
+	started := make(chan struct{})
+	canceled := make(chan struct{})
+	release := make(chan struct{})
+	assert.True(t, p.Submit(func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+		close(canceled)
+		<-release
+	}))
+	<-started
+	var ran int64
+	for i := 0; i < 3; i++ {
+		assert.True(t, p.Submit(func(ctx context.Context) { atomic.AddInt64(&ran, 1) }))
+	}
+	// the queue is full
+	assert.False(t, p.Submit(func(ctx context.Context) {}))
+	assert.Equal(t, 1, entries.FilterMessageSnippet("queue is full").Len())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, p.Close(ctx))
+	close(release)
+
+	// the running job is canceled and the queued ones are dropped, even after the worker is free again
+	<-canceled
+	p.wg.Wait()
+	assert.Equal(t, int64(0), atomic.LoadInt64(&ran))
+	assert.Equal(t, 1, entries.FilterMessageSnippet("dropped 3 pending jobs on shutdown").Len())
+}